@@ -4,33 +4,46 @@ package main
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"encoding/json"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"cloud.google.com/go/auth/credentials"
 	"google.golang.org/genai"
 
+	"swarmtest/internal/agent"
 	"swarmtest/internal/api"
+	"swarmtest/internal/config"
 	"swarmtest/internal/gemini"
 	"swarmtest/internal/models"
+	"swarmtest/internal/notify"
 	"swarmtest/internal/services"
 	"swarmtest/internal/store"
+	"swarmtest/internal/tracing"
 	"swarmtest/internal/utils"
 )
 
 const (
-	serverPort      = ":8080"
-	eventBusBuffer  = 1000
-	readTimeout     = 15 * time.Second
-	writeTimeout    = 15 * time.Second
-	idleTimeout     = 60 * time.Second
+	eventBusBuffer  = 4096
 	shutdownTimeout = 10 * time.Second
+	dbPingTimeout   = 10 * time.Second
 	
+	// missionShutdownTimeout bounds how long shutdown waits for agents to stop
+	missionShutdownTimeout = 30 * time.Second
+
+	// loggerSendTimeout is how long the fan-out waits for room in the event
+	// logger's channel; the logger is the source of truth for mission metrics
+	loggerSendTimeout = time.Second
+
+	// cloudPlatformScope is the OAuth scope Vertex AI calls need
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
 	queryParamKey   = "default_query_exec_mode"
 	queryParamValue = "simple_protocol"
 )
@@ -41,15 +54,41 @@ var (
 )
 
 func main() {
-	ctx := context.Background()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancelled on SIGINT/SIGTERM to start the shutdown sequence
+	signalCtx, stopSignals := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	if cfg.OTLPEndpoint != "" {
+		shutdownTracing, err := tracing.Setup(ctx, cfg.OTLPEndpoint, cfg.OTELServiceName)
+		if err != nil {
+			log.Fatalf("Failed to set up tracing: %v", err)
+		}
+		// Runs after the shutdown sequence below, so the missions' final spans are flushed
+		defer func() {
+			flushCtx, cancelFlush := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancelFlush()
+			if err := shutdownTracing(flushCtx); err != nil {
+				log.Printf("Failed to flush traces: %v", err)
+			}
+		}()
+		log.Printf("Exporting traces to %s", cfg.OTLPEndpoint)
+	}
 
 	// Initialize dependencies
-	genaiClient := initGeminiClient(ctx)
-	db := initDatabase()
+	genaiClient := initGeminiClient(ctx, cfg)
+	db := initDatabase(ctx, cfg.DatabaseURL)
 	defer db.Close()
 
 	eventBus := make(chan models.Event, eventBusBuffer)
-	browserPool := initBrowserPool()
+	browserPool := initBrowserPool(cfg.BrowserHeadless, cfg.BrowserWarmTabs)
 	if browserPool != nil {
 		defer browserPool.Close()
 	}
@@ -57,60 +96,216 @@ func main() {
 	// Create dedicated channels for services (Fan-out pattern)
 	wsEventChan := make(chan models.Event, eventBusBuffer)
 	loggerEventChan := make(chan models.Event, eventBusBuffer)
+	webhookEventChan := make(chan models.Event, eventBusBuffer)
+	var notifyEventChan chan models.Event
+	if cfg.SlackWebhookURL != "" {
+		notifyEventChan = make(chan models.Event, eventBusBuffer)
+	}
 
-	// Start fan-out broadcaster
+	// Start fan-out broadcaster. Only drops on the way to the logger cost a
+	// mission metrics, so only they are counted against it.
+	drops := agent.NewDropCounter()
 	go func() {
 		for event := range eventBus {
 			// Non-blocking send to avoid one slow consumer blocking the other
 			select {
 			case wsEventChan <- event:
 			default:
-				log.Println("Warning: WebSocket event channel full, dropping event")
+				log.Printf("Warning: WebSocket event channel full, dropping %s event", event.Type)
+			}
+
+			if !forwardWithin(loggerEventChan, event, loggerSendTimeout) {
+				dropLoggerEvent(drops, event)
 			}
 
 			select {
-			case loggerEventChan <- event:
+			case webhookEventChan <- event:
 			default:
-				log.Println("Warning: Logger event channel full, dropping event")
+				log.Printf("Warning: Webhook event channel full, dropping %s event", event.Type)
+			}
+
+			if notifyEventChan != nil {
+				select {
+				case notifyEventChan <- event:
+				default:
+					log.Printf("Warning: Notifier event channel full, dropping %s event", event.Type)
+				}
 			}
 		}
 	}()
 
 	// Initialize services
 	missionStore := store.NewSupabaseStore(db)
+	missionStore.RecentEventsLimit = cfg.RecentEventsLimit
+	if err := missionStore.Migrate(ctx); err != nil {
+		log.Fatalf("Failed to migrate database schema: %v", err)
+	}
 	wsHub := api.NewWebSocketHub(wsEventChan)
-	geminiService := gemini.NewGeminiService(genaiClient)
-	restAPI := api.NewRESTAPI(missionStore, geminiService, eventBus)
+	api.WebSocketUpgrader.CheckOrigin = api.CheckOriginAllowlist(
+		cfg.WSAllowedOrigins,
+		cfg.WSAllowAllOrigins,
+	)
+	// Cache hits are still served while the breaker is open
+	breaker := gemini.NewCircuitBreaker(
+		gemini.NewGeminiService(genaiClient, loadPriceTable(cfg.GeminiPricing), cfg.GeminiModel),
+		cfg.GeminiBreakerThreshold,
+		cfg.GeminiBreakerWindow(),
+		cfg.GeminiBreakerCooldown(),
+	)
+	geminiService := gemini.NewCachingClient(
+		breaker,
+		gemini.DefaultDecisionCacheTTL,
+		gemini.DefaultDecisionCacheMaxEntries,
+	)
+	restAPI := api.NewRESTAPI(signalCtx, missionStore, geminiService, eventBus, drops, cfg.MaxConcurrentAgents)
+	restAPI.AllowInsecureTLS = cfg.AllowInsecureTLS
+	restAPI.WebhookSecret = cfg.WebhookSecret
+	restAPI.DefaultRateLimitPerSecond = cfg.DefaultRateLimitPerSecond
+	restAPI.DefaultMaxPageSize = cfg.MaxPageSizeBytes
+	restAPI.MaxAgentsPerMission = cfg.MaxAgentsPerMission
+	restAPI.MaxRateLimitPerSecond = cfg.MaxRateLimitPerSecond
+	log.Printf("Mission ceilings: %s agents, %s requests/second (default %g)",
+		orLimit(float64(cfg.MaxAgentsPerMission)), orLimit(cfg.MaxRateLimitPerSecond), cfg.DefaultRateLimitPerSecond)
+	restAPI.Events = wsHub
+	utils.UploadFixturesDir = cfg.UploadFixturesDir
+	if restAPI.AllowInsecureTLS {
+		log.Println("WARNING: ALLOW_INSECURE_TLS is set; missions may disable TLS certificate verification")
+	}
 
 	// Start background services
 	go wsHub.Run(ctx)
-	go services.NewEventLogger(missionStore, loggerEventChan).Run(ctx)
+	loggerDone := make(chan struct{})
+	go func() {
+		services.NewEventLogger(missionStore, loggerEventChan, eventBus, restAPI).Run(ctx)
+		close(loggerDone)
+	}()
 	log.Println("EventLogger service started")
+	go restAPI.RunWebhooks(ctx, webhookEventChan)
+
+	if notifyEventChan != nil {
+		slack := notify.NewSlackNotifier(
+			cfg.SlackWebhookURL,
+			strings.TrimSuffix(cfg.DashboardURL, "/"),
+			missionStore,
+			notifyEventChan,
+		)
+		go slack.Run(ctx)
+		log.Println("Slack notifier started")
+		restAPI.DefaultAlertErrorRatePercent = float64(cfg.SlackAlertErrorRatePercent)
+	}
+
+	// Reap or resume missions left running by a previous process
+	restAPI.ReconcileInterruptedMissions(ctx, cfg.ResumeInterruptedMissions)
+
+	go services.NewScheduler(missionStore, restAPI).Run(ctx)
+	log.Println("Mission scheduler started")
 
 	// Setup and start HTTP server
-	server := setupServer(restAPI, wsHub)
-	startServer(server)
+	server := setupServer(cfg, restAPI, wsHub, breaker)
+	startServer(signalCtx, server)
+
+	// Stop running missions, so their agents' final state reaches the event
+	// logger before it shuts down
+	missionCtx, cancelMissions := context.WithTimeout(context.Background(), missionShutdownTimeout)
+	restAPI.Shutdown(missionCtx)
+	cancelMissions()
+
+	// Stop background services and wait for buffered logs to be flushed, then
+	// for webhooks of missions that finished meanwhile
+	cancel()
+	<-loggerDone
+	webhookCtx, cancelWebhooks := context.WithTimeout(context.Background(), shutdownTimeout)
+	restAPI.WaitWebhooks(webhookCtx)
+	cancelWebhooks()
 }
 
-// initGeminiClient initializes the Gemini AI client
-func initGeminiClient(ctx context.Context) *genai.Client {
-	apiKey := requireEnv("GEMINI_API_KEY")
-	
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  apiKey,
+// initGeminiClient initializes the Gemini AI client on the configured backend
+func initGeminiClient(ctx context.Context, cfg *config.Config) *genai.Client {
+	clientConfig := &genai.ClientConfig{
+		APIKey:  cfg.GeminiAPIKey,
 		Backend: genai.BackendGeminiAPI,
-	})
+	}
+	if cfg.GeminiBackend == config.BackendVertexAI {
+		clientConfig = &genai.ClientConfig{
+			Backend:  genai.BackendVertexAI,
+			Project:  cfg.GoogleCloudProject,
+			Location: cfg.GoogleCloudLocation,
+		}
+		// Without a credentials file the client uses Application Default Credentials
+		if cfg.GoogleCredentialsFile != "" {
+			creds, err := credentials.DetectDefault(&credentials.DetectOptions{
+				Scopes:          []string{cloudPlatformScope},
+				CredentialsFile: cfg.GoogleCredentialsFile,
+			})
+			if err != nil {
+				log.Fatalf("Failed to load Google credentials: %v", err)
+			}
+			clientConfig.Credentials = creds
+		}
+	}
+
+	client, err := genai.NewClient(ctx, clientConfig)
 	if err != nil {
 		log.Fatalf("Failed to create Gemini client: %v", err)
 	}
 
-	log.Println("Gemini client initialized successfully")
+	log.Printf("Gemini client initialized successfully (backend: %s)", cfg.GeminiBackend)
 	return client
 }
 
+// forwardWithin sends event to ch, waiting up to timeout for room, and
+// reports whether it was sent
+func forwardWithin(ch chan<- models.Event, event models.Event, timeout time.Duration) bool {
+	select {
+	case ch <- event:
+		return true
+	default:
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case ch <- event:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// dropLoggerEvent counts an event the fan-out couldn't hand to the event
+// logger against its mission, so the mission's summary reports it
+func dropLoggerEvent(drops *agent.DropCounter, event models.Event) {
+	missionID := agent.EventMissionID(event)
+	if missionID == "" {
+		log.Printf("Warning: Logger event channel full, dropping %s event", event.Type)
+		return
+	}
+	total := drops.Count(missionID)
+	log.Printf("Warning: Logger event channel full, dropping %s event (%d dropped for mission %s)", event.Type, total, missionID)
+}
+
+// orLimit formats a configured ceiling, 0 meaning the absolute limit of 1000
+func orLimit(ceiling float64) string {
+	if ceiling == 0 {
+		return "1000"
+	}
+	return strconv.FormatFloat(ceiling, 'g', -1, 64)
+}
+
+// loadPriceTable parses the configured Gemini price table override, if any
+func loadPriceTable(raw []byte) gemini.PriceTable {
+	if len(raw) == 0 {
+		return gemini.DefaultPriceTable
+	}
+
+	table, err := gemini.ParsePriceTable(string(raw))
+	if err != nil {
+		log.Fatalf("Failed to parse GEMINI_PRICING: %v", err)
+	}
+	return table
+}
+
 // initDatabase initializes the database connection
-func initDatabase() *sql.DB {
-	dbURL := requireEnv("SUPABASE_DB_URL")
+func initDatabase(ctx context.Context, dbURL string) *sql.DB {
 	dbURL = addQueryParam(dbURL, queryParamKey, queryParamValue)
 
 	db, err := sql.Open("pgx", dbURL)
@@ -118,7 +313,10 @@ func initDatabase() *sql.DB {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	if err := db.Ping(); err != nil {
+	pingCtx, cancel := context.WithTimeout(ctx, dbPingTimeout)
+	defer cancel()
+
+	if err := db.PingContext(pingCtx); err != nil {
 		log.Fatalf("Failed to ping database: %v", err)
 	}
 
@@ -126,61 +324,88 @@ func initDatabase() *sql.DB {
 	return db
 }
 
-// initBrowserPool initializes the headless browser pool
-func initBrowserPool() *utils.BrowserPool {
-	pool, err := utils.NewBrowserPool(true)
+// initBrowserPool initializes the browser pool
+func initBrowserPool(headless bool, warmTabs int) *utils.BrowserPool {
+	pool, err := utils.NewBrowserPool(headless, warmTabs)
 	if err != nil {
 		log.Printf("Warning: Failed to initialize browser pool: %v. Browser execution mode will be unavailable.", err)
 		return nil
 	}
 
 	utils.SharedBrowserPool = pool
-	log.Println("Browser pool initialized successfully (headless chrome)")
+	if headless {
+		log.Println("Browser pool initialized successfully (headless chrome)")
+	} else {
+		log.Println("Browser pool initialized successfully (headed chrome)")
+	}
 	return pool
 }
 
 // setupServer creates and configures the HTTP server
-func setupServer(restAPI *api.RESTAPI, wsHub *api.WebSocketHub) *http.Server {
+func setupServer(cfg *config.Config, restAPI *api.RESTAPI, wsHub *api.WebSocketHub, breaker *gemini.CircuitBreaker) *http.Server {
 	mux := http.NewServeMux()
 
 	restAPI.RegisterRoutes(mux)
-	mux.HandleFunc("/api/health", handleHealth)
+	mux.HandleFunc("/api/health", healthHandler(breaker))
 	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
 		api.ServeWebSocket(wsHub, w, r)
 	})
+	mux.HandleFunc("/api/events", func(w http.ResponseWriter, r *http.Request) {
+		api.ServeEvents(wsHub, w, r)
+	})
+
+	apiKeys, err := api.ParseAPIKeys(cfg.APIKeys)
+	if err != nil {
+		log.Fatalf("Failed to parse API_KEYS: %v", err)
+	}
+	if len(apiKeys) == 0 {
+		log.Println("WARNING: API_KEYS is not set; the REST and WebSocket APIs are unauthenticated")
+	}
+
+	handler := api.RateLimitMutations(
+		float64(cfg.APIRateLimitPerMinute),
+		cfg.APIRateLimitBurst,
+		mux,
+	)
 
 	return &http.Server{
-		Addr:         serverPort,
-		Handler:      enableCORS(mux),
-		ReadTimeout:  readTimeout,
-		WriteTimeout: writeTimeout,
-		IdleTimeout:  idleTimeout,
+		Addr:         cfg.Addr(),
+		Handler:      enableCORS(cfg.CORSAllowedOrigins, api.RequireAPIKey(apiKeys, handler)),
+		ReadTimeout:  cfg.ReadTimeout(),
+		WriteTimeout: cfg.WriteTimeout(),
+		IdleTimeout:  cfg.IdleTimeout(),
 	}
 }
 
-// handleHealth handles the health check endpoint
-func handleHealth(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+// healthHandler serves the health check endpoint
+func healthHandler(breaker *gemini.CircuitBreaker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
 
-	browserMode := "disabled"
-	if utils.SharedBrowserPool != nil {
-		browserMode = "enabled"
-	}
+		browserMode := "disabled"
+		if utils.SharedBrowserPool != nil {
+			browserMode = "enabled"
+		}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, `{"status":"healthy","version":"%s","build_time":"%s","browser_mode":"%s"}`, 
-		version, buildTime, browserMode)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"status":         "healthy",
+			"version":        version,
+			"build_time":     buildTime,
+			"browser_mode":   browserMode,
+			"gemini_circuit": breaker.Stats(),
+		})
+	}
 }
 
-// startServer starts the HTTP server with graceful shutdown
-func startServer(server *http.Server) {
-	go handleShutdown(server)
+// startServer starts the HTTP server, shutting it down gracefully once ctx is cancelled
+func startServer(ctx context.Context, server *http.Server) {
+	go handleShutdown(ctx, server)
 
-	logServerInfo()
+	logServerInfo(server)
 
 	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Server failed: %v", err)
@@ -189,11 +414,9 @@ func startServer(server *http.Server) {
 	log.Println("Server stopped")
 }
 
-// handleShutdown handles graceful server shutdown
-func handleShutdown(server *http.Server) {
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	<-sigChan
+// handleShutdown stops the server once ctx is cancelled
+func handleShutdown(ctx context.Context, server *http.Server) {
+	<-ctx.Done()
 
 	log.Println("Shutting down server...")
 	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
@@ -205,35 +428,48 @@ func handleShutdown(server *http.Server) {
 }
 
 // logServerInfo logs server startup information
-func logServerInfo() {
+func logServerInfo(server *http.Server) {
 	browserMode := "disabled"
 	if utils.SharedBrowserPool != nil {
 		browserMode = "enabled"
 	}
 
-	log.Printf("SwarmTest server starting on %s (version %s)", serverPort, version)
+	log.Printf("SwarmTest server starting on %s (version %s)", server.Addr, version)
+	log.Printf("Timeouts: read %s, write %s, idle %s", server.ReadTimeout, server.WriteTimeout, server.IdleTimeout)
 	log.Printf("Endpoints:")
 	log.Printf("  POST   /api/missions        - Create new mission")
 	log.Printf("  GET    /api/missions        - List all missions")
 	log.Printf("  GET    /api/missions/{id}   - Get mission status")
+	log.Printf("  GET    /api/missions/{id}/actions - Get recent action logs")
+	log.Printf("  GET    /api/missions/{id}/metrics - Get mission metrics summary")
 	log.Printf("  GET    /api/health          - Health check")
 	log.Printf("  GET    /ws                  - WebSocket events")
 	log.Printf("  Browser Mode:              %s", browserMode)
 }
 
-// enableCORS adds CORS headers to responses
-func enableCORS(next http.Handler) http.Handler {
+// enableCORS adds CORS headers to responses. The request origin is echoed back
+// when it is in the allow-list; "*" in the list allows any origin.
+func enableCORS(allowedOrigins []string, next http.Handler) http.Handler {
+	allowAny := false
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAny = true
+		}
+		allowed[strings.TrimRight(origin, "/")] = true
+	}
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Allow both common dev ports for the dashboard
 		origin := r.Header.Get("Origin")
-		if origin == "http://localhost:3000" || origin == "http://localhost:3001" {
+		if allowAny {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else if allowed[origin] {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
-		} else {
-			w.Header().Set("Access-Control-Allow-Origin", "http://localhost:3000")
+			w.Header().Add("Vary", "Origin")
 		}
-		
+
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key")
 		w.Header().Set("Access-Control-Max-Age", "86400")
 
 		if r.Method == "OPTIONS" {
@@ -245,15 +481,6 @@ func enableCORS(next http.Handler) http.Handler {
 	})
 }
 
-// requireEnv gets an environment variable or exits if not set
-func requireEnv(key string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		log.Fatalf("%s environment variable is required", key)
-	}
-	return value
-}
-
 // addQueryParam adds a query parameter to a URL if not already present
 func addQueryParam(url, key, value string) string {
 	if strings.Contains(url, key) {
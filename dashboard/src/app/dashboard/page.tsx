@@ -45,6 +45,14 @@ export default function HomePage() {
     selectedMissionId
   );
 
+  // Select the mission named in ?mission= (e.g. from a Slack link)
+  useEffect(() => {
+    const linked = new URLSearchParams(window.location.search).get("mission");
+    if (linked) {
+      setSelectedMissionId(linked);
+    }
+  }, []);
+
   // Auto-select first mission
   useEffect(() => {
     if (missionsData?.missions && missionsData.missions.length > 0 && !selectedMissionId) {
@@ -0,0 +1,120 @@
+"use client";
+
+import { useEffect, useRef, useCallback, useState } from "react";
+import { WebSocketEvent } from "@/lib/types";
+
+const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8080";
+const API_KEY = process.env.NEXT_PUBLIC_API_KEY;
+
+// EventSource can't set headers either, so the key goes in the query
+function eventsURL(missionId?: string | null): string {
+  const params = new URLSearchParams();
+  if (missionId) {
+    params.set("mission", missionId);
+  }
+  if (API_KEY) {
+    params.set("access_token", API_KEY);
+  }
+  const query = params.toString();
+  return `${API_BASE_URL}/api/events${query ? `?${query}` : ""}`;
+}
+
+interface UseEventSourceOptions {
+  missionId?: string | null;
+  onMessage?: (event: WebSocketEvent) => void;
+  onConnect?: () => void;
+  onDisconnect?: () => void;
+  onError?: (error: Event) => void;
+}
+
+interface UseEventSourceReturn {
+  isConnected: boolean;
+  lastMessage: WebSocketEvent | null;
+  connect: () => void;
+  disconnect: () => void;
+  error: Event | null;
+}
+
+// useEventSource receives the same events as useWebSocket over Server-Sent
+// Events. EventSource reconnects by itself, as often as the server's retry says.
+export function useEventSource({
+  missionId,
+  onMessage,
+  onConnect,
+  onDisconnect,
+  onError,
+}: UseEventSourceOptions = {}): UseEventSourceReturn {
+  const [isConnected, setIsConnected] = useState(false);
+  const [lastMessage, setLastMessage] = useState<WebSocketEvent | null>(null);
+  const [error, setError] = useState<Event | null>(null);
+  const sourceRef = useRef<EventSource | null>(null);
+  const onMessageRef = useRef(onMessage);
+  const onConnectRef = useRef(onConnect);
+  const onDisconnectRef = useRef(onDisconnect);
+  const onErrorRef = useRef(onError);
+
+  // Update callback refs
+  useEffect(() => {
+    onMessageRef.current = onMessage;
+    onConnectRef.current = onConnect;
+    onDisconnectRef.current = onDisconnect;
+    onErrorRef.current = onError;
+  }, [onMessage, onConnect, onDisconnect, onError]);
+
+  const connect = useCallback(() => {
+    if (sourceRef.current) {
+      return;
+    }
+
+    const source = new EventSource(eventsURL(missionId));
+    sourceRef.current = source;
+
+    source.onopen = () => {
+      setIsConnected(true);
+      setError(null);
+      onConnectRef.current?.();
+    };
+
+    source.onmessage = (event) => {
+      try {
+        const data: WebSocketEvent = JSON.parse(event.data);
+        setLastMessage(data);
+        onMessageRef.current?.(data);
+      } catch (err) {
+        console.error("Failed to parse event:", err);
+      }
+    };
+
+    // Fires on every dropped connection; EventSource retries unless it gave up
+    source.onerror = (event) => {
+      setIsConnected(false);
+      setError(event);
+      onErrorRef.current?.(event);
+      onDisconnectRef.current?.();
+      if (source.readyState === EventSource.CLOSED) {
+        sourceRef.current = null;
+      }
+    };
+  }, [missionId]);
+
+  const disconnect = useCallback(() => {
+    sourceRef.current?.close();
+    sourceRef.current = null;
+    setIsConnected(false);
+  }, []);
+
+  // Cleanup on unmount
+  useEffect(() => {
+    return () => {
+      disconnect();
+    };
+  }, [disconnect]);
+
+  return {
+    isConnected,
+    lastMessage,
+    connect,
+    disconnect,
+    error,
+  };
+}
@@ -2,8 +2,13 @@
 
 import { useState, useCallback, useEffect } from "react";
 import { useWebSocket } from "./use-websocket";
+import { useEventSource } from "./use-event-source";
 import { WebSocketEvent, AgentEvent, SummaryEvent } from "@/lib/types";
 
+// Chosen at build time, so every render calls the same hook
+const useLiveEvents =
+  process.env.NEXT_PUBLIC_EVENTS_TRANSPORT === "sse" ? useEventSource : useWebSocket;
+
 interface MissionEventsState {
   agentEvents: AgentEvent[];
   summary: SummaryEvent | null;
@@ -54,10 +59,11 @@ export function useMissionEvents(missionId: string | null) {
     }
   }, [missionId]);
 
-  const { isConnected, connect, disconnect } = useWebSocket({
+  const { isConnected, connect, disconnect } = useLiveEvents({
+    missionId,
     onMessage: handle_message,
     onConnect: () => {
-      console.log("WebSocket connected");
+      console.log("Live events connected");
     },
     onDisconnect: () => {
       setEvents((prev) => ({ ...prev, isLive: false }));
@@ -3,7 +3,12 @@
 import { useEffect, useRef, useCallback, useState } from "react";
 import { WebSocketEvent } from "@/lib/types";
 
-const WS_URL = process.env.NEXT_PUBLIC_WS_URL || "ws://localhost:8080/ws";
+const WS_BASE_URL = process.env.NEXT_PUBLIC_WS_URL || "ws://localhost:8080/ws";
+const API_KEY = process.env.NEXT_PUBLIC_API_KEY;
+// Browsers can't set headers on a WebSocket handshake, so the key goes in the query
+const WS_URL = API_KEY
+  ? `${WS_BASE_URL}${WS_BASE_URL.includes("?") ? "&" : "?"}access_token=${encodeURIComponent(API_KEY)}`
+  : WS_BASE_URL;
 
 interface UseWebSocketOptions {
   onMessage?: (event: WebSocketEvent) => void;
@@ -1,6 +1,7 @@
 import { CreateMissionRequest, CreateMissionResponse, Mission, MissionStatusResponse } from "./types";
 
 const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8080";
+const API_KEY = process.env.NEXT_PUBLIC_API_KEY;
 
 class ApiClient {
   private baseUrl: string;
@@ -18,6 +19,7 @@ class ApiClient {
       ...options,
       headers: {
         "Content-Type": "application/json",
+        ...(API_KEY ? { Authorization: `Bearer ${API_KEY}` } : {}),
         ...options?.headers,
       },
     });
@@ -9,15 +9,76 @@ export interface Mission {
   max_duration_seconds: number;
   rate_limit_per_second: number;
   initial_system_prompt: string;
-  status: "pending" | "running" | "completed" | "cancelled";
+  status: "pending" | "scheduled" | "running" | "completed" | "cancelled" | "interrupted";
   created_at: string;
   started_at?: string;
   completed_at?: string;
+  replay_of?: string;
+  scheduled_from?: string;
+  template_id?: string;
+  next_run_at?: string;
+  stop_reason?: "max_total_actions" | "max_cost_usd" | "drained";
+  scheduled_at?: string;
+  cron?: string;
+  tags: string[];
+  execution_mode: "http" | "browser" | "auto" | "crawl";
+  browser_fallback?: boolean;
   total_actions: number;
   total_errors: number;
   average_latency_ms: number;
   completed_agents: number;
   failed_agents: number;
+  enable_decision_cache?: boolean;
+  steps?: string[];
+  success_criteria?: SuccessCriteria;
+  seed?: number;
+  think_time_min_ms?: number;
+  think_time_max_ms?: number;
+  user_agents?: string[];
+  language?: string;
+  timezone?: string;
+  prompt_examples?: PromptExample[];
+  diversify_agents?: boolean;
+  respect_robots_txt?: boolean;
+  share_visited_urls?: boolean;
+  check_links?: boolean;
+  crawl_max_depth?: number;
+  crawl_max_pages?: number;
+  crawl_strategy?: "bfs" | "dfs";
+  follow_redirects?: boolean;
+  max_redirects?: number;
+  max_consecutive_errors?: number;
+  action_timeout_seconds?: number;
+  ramp_up_seconds?: number;
+  retry_failed_agents?: number;
+  viewport_width?: number;
+  viewport_height?: number;
+  device?: "iphone" | "iphone-se" | "pixel" | "galaxy" | "ipad";
+  proxy?: string;
+  insecure_skip_verify?: boolean;
+  client_cert_file?: string;
+  client_key_file?: string;
+  target_auth?: TargetAuth;
+  form_content_type?: "application/x-www-form-urlencoded" | "multipart/form-data" | "application/json";
+  max_page_size_bytes?: number;
+  element_types?: ("link" | "button" | "input" | "form")[];
+  webhook_url?: string;
+  alert_error_rate_percent?: number;
+  max_total_actions?: number;
+  max_cost_usd?: number;
+  max_unique_urls_per_agent?: number;
+  slow_page_ms?: number;
+  large_page_bytes?: number;
+  unique_urls: number;
+  agent_url_visits: number;
+  js_errors: number;
+  network_failures: number;
+  dropped_events: number;
+  decision_cache_hits: number;
+  decision_cache_misses: number;
+  total_prompt_tokens: number;
+  total_output_tokens: number;
+  estimated_cost_usd: number;
   recent_events: ActionLog[];
   agent_metrics: Record<string, Agent>;
 }
@@ -25,7 +86,7 @@ export interface Mission {
 export interface Agent {
   id: string;
   mission_id: string;
-  status: "starting" | "running" | "completed" | "failed" | "cancelled" | "rate_limited";
+  status: "starting" | "queued" | "running" | "completed" | "failed" | "cancelled" | "rate_limited" | "stopped" | "interrupted" | "paused" | "blocked";
   current_url: string;
   action_history: string[];
   error_count: number;
@@ -34,6 +95,40 @@ export interface Agent {
   consecutive_errors: number;
   url_history: string[];
   last_action_at?: string;
+  steps_completed: number;
+  exploration_hint?: string;
+  failure_reason?: string;
+  execution_mode?: "http" | "browser" | "crawl";
+  criteria_met?: boolean;
+  unique_urls_visited: number;
+  retry_of?: string;
+}
+
+export interface SuccessCriteria {
+  url_pattern?: string;
+  selector?: string;
+  text?: string;
+  status_code?: number;
+}
+
+// A situation in words and the decision an agent should make in it
+export interface PromptExample {
+  context: string;
+  decision: {
+    reasoning: string;
+    action: string;
+    selector?: string;
+    text_input?: string;
+    frame?: string;
+    expected_next_state?: string;
+  };
+}
+
+// Secrets come back as "[REDACTED]"
+export interface TargetAuth {
+  username?: string;
+  password?: string;
+  bearer_token?: string;
 }
 
 export interface ActionLog {
@@ -45,25 +140,36 @@ export interface ActionLog {
   latency_ms: number;
   error_message?: string;
   new_url?: string;
+  text_input?: string;
+  frame?: string;
+  status_code?: number;
+  redirect_url?: string;
+  reasoning?: string;
+  expected_next_state?: string;
 }
 
 export interface StrippedPage {
   url: string;
   title: string;
   description: string;
+  content_type?: string;
   interactive_elements: Element[];
   timestamp: string;
 }
 
 export interface Element {
   id?: string;
-  type: "button" | "link" | "input" | "form";
+  type: "button" | "link" | "input" | "file_input" | "form";
   text?: string;
   selector: string;
   href?: string;
   name?: string;
   placeholder?: string;
   input_type?: string;
+  disabled?: boolean;
+  readonly?: boolean;
+  frame?: string;
+  frame_origin?: string;
 }
 
 export interface CreateMissionRequest {
@@ -74,6 +180,23 @@ export interface CreateMissionRequest {
   max_duration_seconds: number;
   rate_limit_per_second: number;
   initial_system_prompt: string;
+  tags?: string[];
+  execution_mode?: "http" | "browser" | "auto" | "crawl";
+  browser_fallback?: boolean;
+  enable_decision_cache?: boolean;
+  success_criteria?: SuccessCriteria;
+  target_auth?: TargetAuth;
+  scheduled_at?: string;
+  cron?: string;
+  template_id?: string;
+}
+
+// A saved mission configuration without a target
+export interface MissionTemplate extends Partial<Omit<CreateMissionRequest, "name" | "target_url" | "template_id" | "target_auth" | "scheduled_at">> {
+  id: string;
+  name: string;
+  description?: string;
+  created_at: string;
 }
 
 export interface CreateMissionResponse {
@@ -87,9 +210,9 @@ export interface MissionStatusResponse {
 }
 
 export interface WebSocketEvent {
-  type: "agent_status" | "action" | "summary" | "summary_tick";
+  type: "agent_status" | "action" | "summary" | "summary_tick" | "mission_started" | "mission_completed" | "decision" | "thinking" | "goal_verification" | "js_errors" | "network_failures" | "finding" | "alert" | "client_redirect";
   timestamp: string;
-  data: AgentEvent | SummaryEvent;
+  data: AgentEvent | SummaryEvent | AlertEvent;
 }
 
 export interface AgentEvent {
@@ -97,6 +220,11 @@ export interface AgentEvent {
   mission_id: string;
   status: string;
   action_log?: ActionLog;
+  current_url?: string;
+  success_count: number;
+  error_count: number;
+  total_latency_ms: number;
+  consecutive_errors: number;
 }
 
 export interface SummaryEvent {
@@ -109,4 +237,147 @@ export interface SummaryEvent {
   total_errors: number;
   average_latency_ms: number;
   error_rate_percent: number;
+  cache_hit_rate_percent: number;
+  total_prompt_tokens: number;
+  total_output_tokens: number;
+  estimated_cost_usd: number;
+  unique_urls: number;
+  path_overlap_percent: number;
+  js_errors: number;
+  network_failures: number;
+  dropped_events: number;
+  retried_agents: number;
+  passed_agents: number;
+  pass_rate_percent: number;
+  stop_reason?: "max_total_actions" | "max_cost_usd" | "drained";
+  slowest_pages?: Finding[];
+  largest_pages?: Finding[];
+}
+
+export interface CoveragePoint {
+  at: string;
+  unique_urls: number;
+}
+
+export interface Coverage {
+  mission_id: string;
+  unique_urls: number;
+  unique_selectors: number;
+  urls: string[];
+  selectors: string[];
+  discovery: CoveragePoint[];
+  truncated: boolean;
+  updated_at: string;
+  shared_visited?: string[];
+  shared_visited_truncated?: boolean;
+}
+
+export interface SitemapPage {
+  url: string;
+  depth: number;
+  status_code?: number;
+  error?: string;
+  links: string[];
+}
+
+export interface Sitemap {
+  mission_id: string;
+  pages: SitemapPage[];
+  truncated: boolean;
+  updated_at: string;
+}
+
+export interface ComparedMission {
+  id: string;
+  name: string;
+  status: Mission["status"];
+  created_at: string;
+  summary: SummaryEvent;
+}
+
+export interface MetricDiff {
+  name: string;
+  a: number;
+  b: number;
+  delta: number;
+  delta_percent?: number;
+  higher_is_better: boolean;
+  regression: boolean;
+}
+
+export interface AgentOutcomeChange {
+  agent_index: number;
+  agent_a?: string;
+  agent_b?: string;
+  status_a?: string;
+  status_b?: string;
+  errors_a: number;
+  errors_b: number;
+}
+
+export interface MissionComparison {
+  a: ComparedMission;
+  b: ComparedMission;
+  metrics: MetricDiff[];
+  agent_changes: AgentOutcomeChange[];
+}
+
+export interface JSError {
+  kind: "console" | "exception";
+  message: string;
+  source_url?: string;
+}
+
+export interface JSErrorEvent {
+  agent_id: string;
+  mission_id: string;
+  page_url: string;
+  action: string;
+  errors: JSError[];
+}
+
+export interface NetworkFailure {
+  method?: string;
+  url: string;
+  resource_type?: string;
+  status_code?: number;
+  error_text?: string;
+}
+
+export interface AgentTimelineResponse {
+  agent: Agent;
+  action_logs: ActionLog[];
+}
+
+export interface Finding {
+  timestamp: string;
+  mission_id: string;
+  agent_id: string;
+  type: "js_error" | "network" | "http_error" | "broken_link" | "bot_wall" | "performance";
+  severity: "high" | "medium" | "low";
+  page_url: string;
+  action: string;
+  message: string;
+  request_url?: string;
+  method?: string;
+  status_code?: number;
+  duration_ms?: number;
+  content_length?: number;
+}
+
+export interface ClientRedirectEvent {
+  agent_id: string;
+  mission_id: string;
+  from_url: string;
+  to_url: string;
+  followed: boolean;
+}
+
+export interface AlertEvent {
+  mission_id: string;
+  kind: "error_rate";
+  error_rate_percent: number;
+  threshold_percent: number;
+  window_seconds: number;
+  message: string;
 }
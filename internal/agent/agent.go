@@ -2,16 +2,39 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"regexp"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"swarmtest/internal/models"
 	"swarmtest/internal/gemini"
+	"swarmtest/internal/tracing"
 	"swarmtest/internal/utils"
 )
 
+// tracer traces each agent, its loop iterations and their fetch, decide and
+// execute phases
+var tracer = tracing.Tracer("agent")
+
+// defaultMaxConsecutiveErrors is the error streak an agent gives up after when
+// the mission doesn't set max_consecutive_errors
+const defaultMaxConsecutiveErrors = 10
+
+// maxErrorBackoff caps the wait after an error, however long the agent's
+// error streak
+const maxErrorBackoff = 30 * time.Second
+
+// geminiPauseInterval is how often a paused agent checks whether the Gemini
+// circuit breaker has closed
+const geminiPauseInterval = 5 * time.Second
+
 // RuntimeAgent represents a running agent
 type RuntimeAgent struct {
 	id          string
@@ -20,21 +43,84 @@ type RuntimeAgent struct {
 	httpFactory utils.HTTPClientFactory
 	limiter     *utils.RateLimiter
 	eventBus    chan<- models.Event
+	drops       *DropCounter
+	rng         *rand.Rand
 
 	// Browser mode support
 	browserExecutor *utils.BrowserExecutor
 	isBrowserMode   bool
 
+	// robots is set when the mission respects robots.txt
+	robots *utils.RobotsCache
+	// links is set when the mission checks links; shared by its agents
+	links *utils.LinkChecker
+	// visited is set when the mission shares visited URLs; shared by its agents
+	visited *utils.VisitedSet
+	// crawl is the shared frontier of a crawl mode mission
+	crawl *utils.Crawler
+	// userAgent replaces the default User-Agent when the mission has a pool
+	userAgent string
+
 	// State
-	status        string
-	currentURL    string
-	actionHistory []string
-	urlHistory    []string
-	errorCount    int
-	successCount  int
-	totalLatency  time.Duration
+	status            string
+	currentURL        string
+	actionHistory     []string
+	urlHistory        []string
+	errorCount        int
+	successCount      int
+	totalLatency      time.Duration
 	consecutiveErrors int
-	lastActionAt  time.Time
+	lastActionAt      time.Time
+	stepsCompleted    int
+	explorationHint   string
+	failureReason     string
+
+	// lastErrorAction and sameActionErrors track the action behind the current
+	// error streak, to explain a failure
+	lastErrorAction  string
+	sameActionErrors int
+
+	// criteriaMet is set once the mission's success criteria are met;
+	// criteriaURL is the compiled url_pattern
+	criteriaMet bool
+	criteriaURL *regexp.Regexp
+
+	// traceCtx carries the agent's span; step is the span of the loop
+	// iteration in progress, which failures are recorded on
+	traceCtx context.Context
+	step     trace.Span
+
+	// pages caches the parsed current page between loop iterations
+	pages pageCache
+	// parser extracts the element types the mission asks for
+	parser *utils.HTMLParser
+	// reportedFindings de-duplicates this agent's findings
+	reportedFindings map[string]bool
+	// uniqueURLs holds the distinct URLs visited (since a restart, if resumed);
+	// uniqueURLsVisited counts them over the agent's whole run
+	uniqueURLs        map[string]bool
+	uniqueURLsVisited int
+	// unfollowedRefresh is the last page whose meta refresh wasn't followed,
+	// so it is reported once rather than on every reload
+	unfollowedRefresh string
+}
+
+// personas vary how diversified agents approach a site
+var personas = []string{
+	"a first-time visitor who reads pages carefully before acting",
+	"an impatient user who always takes the shortest path",
+	"a power user who prefers search and direct URLs over menus",
+	"a curious explorer who opens secondary menus, footers and less prominent links",
+	"a cautious user who checks help, policy and account pages along the way",
+	"a user who tries unusual inputs and edge cases in forms",
+}
+
+// explorationHint builds the per-agent prompt addition used with DiversifyAgents
+func explorationHint(index, total int, rng *rand.Rand) string {
+	return fmt.Sprintf(
+		"You are agent %d of %d exploring this site in parallel. Prefer paths the other agents are unlikely to take. Behave like %s.",
+		index+1, total, personas[rng.Intn(len(personas))],
+	)
 }
 
 // NewAgent creates a new agent
@@ -45,9 +131,23 @@ func NewAgent(
 	httpFactory utils.HTTPClientFactory,
 	limiter *utils.RateLimiter,
 	eventBus chan<- models.Event,
+	drops *DropCounter,
 	browserExecutor *utils.BrowserExecutor,
+	robots *utils.RobotsCache,
+	links *utils.LinkChecker,
+	visited *utils.VisitedSet,
+	crawl *utils.Crawler,
+	index int,
 ) *RuntimeAgent {
-	isBrowserMode := mission.ExecutionMode == models.ExecutionModeBrowser
+	// Auto mode uses the browser whenever the mission could get a tab for the agent
+	isBrowserMode := mission.ExecutionMode == models.ExecutionModeBrowser ||
+		(mission.ExecutionMode == models.ExecutionModeAuto && browserExecutor != nil)
+	rng := rand.New(rand.NewSource(mission.Seed + int64(index)))
+
+	hint := ""
+	if mission.DiversifyAgents {
+		hint = explorationHint(index, mission.NumAgents, rng)
+	}
 
 	return &RuntimeAgent{
 		id:               id,
@@ -56,46 +156,96 @@ func NewAgent(
 		httpFactory:      httpFactory,
 		limiter:          limiter,
 		eventBus:         eventBus,
+		drops:            drops,
+		rng:              rng,
+		explorationHint:  hint,
 		browserExecutor:  browserExecutor,
 		isBrowserMode:    isBrowserMode,
+		robots:           robots,
+		links:            links,
+		visited:          visited,
+		crawl:            crawl,
+		userAgent:        UserAgentFor(mission, index),
+		parser:           utils.NewHTMLParserFor(mission.ElementTypes),
 		status:           "initialized",
 		currentURL:       mission.TargetURL,
 		actionHistory:    make([]string, 0),
 		urlHistory:       make([]string, 0),
+		uniqueURLs:       make(map[string]bool),
+	}
+}
+
+// Restore seeds the agent with state persisted before a server restart so a
+// resumed agent continues from where it left off
+func (a *RuntimeAgent) Restore(prev *models.Agent) {
+	if prev.CurrentURL != "" {
+		a.currentURL = prev.CurrentURL
 	}
+	a.errorCount = prev.ErrorCount
+	a.successCount = prev.SuccessCount
+	a.totalLatency = time.Duration(prev.TotalLatencyMS) * time.Millisecond
+	a.stepsCompleted = prev.StepsCompleted
+	a.uniqueURLsVisited = prev.UniqueURLsVisited
 }
 
 // Run starts the agent loop
 func (a *RuntimeAgent) Run(ctx context.Context) {
-	log.Printf("[Agent %s] Starting mission: %s (mode: %s)", a.id, a.mission.Goal, a.mission.ExecutionMode)
-	a.status = "running"
+	ctx, span := tracer.Start(ctx, "agent", trace.WithAttributes(
+		tracing.MissionID.String(a.mission.ID),
+		tracing.AgentID.String(a.id),
+	))
+	a.traceCtx = ctx
+	defer func() {
+		a.endStep()
+		span.SetAttributes(attribute.String("swarmtest.agent.status", a.status))
+		span.End()
+	}()
+
+	log.Printf("[Agent %s] Starting mission: %s (mode: %s)%s", a.id, a.mission.Goal, a.mission.ExecutionMode, tracing.LogFields(ctx))
+	a.SetStatus("running")
 	a.urlHistory = append(a.urlHistory, a.currentURL)
+	a.noteVisit()
 
 	// Create HTTP client (always needed for fallback or mixed mode potentially)
-	client := a.httpFactory()
-	
-	// Create executor (only for HTTP mode)
-	var httpExecutor *utils.ActionExecutor
-	var err error
-	
-	if !a.isBrowserMode {
-		httpExecutor, err = utils.NewActionExecutor(client, a.currentURL)
-		if err != nil {
-			a.handleError(err, "init_executor")
-			a.status = "failed"
+	clientOpts, err := httpClientOptions(a.mission)
+	if err != nil {
+		a.handleError(err, "init_client")
+		a.failWith(fmt.Sprintf("could not create HTTP client: %v", err))
+		return
+	}
+	clientOpts.UserAgent = a.userAgent
+	client := a.httpFactory(clientOpts)
+	if a.robots != nil {
+		if !a.robots.Allowed(ctx, a.currentURL) {
+			a.recordSkipped(models.GeminiDecisionResponse{Action: "visit"}, fmt.Errorf("%w: %s", utils.ErrDisallowedByRobots, a.currentURL))
+			a.failWith("start URL disallowed by robots.txt")
 			return
 		}
-	} else {
+		client.Transport = a.robots.Transport(client.Transport)
+	}
+	
+	if a.isBrowserMode {
 		// Browser mode: ensure we have an executor
 		if a.browserExecutor == nil {
-			a.handleError(fmt.Errorf("browser executor is nil"), "init_browser")
-			a.status = "failed"
-			return
+			if !a.canFallBack() {
+				a.handleError(fmt.Errorf("browser executor is nil"), "init_browser")
+				a.failWith("browser unavailable")
+				return
+			}
+			a.fallBackToHTTP("browser unavailable")
 		}
-		
+	}
+	if a.isBrowserMode {
 		// Initial navigation
-		result := a.browserExecutor.ExecuteAction(ctx, models.GeminiDecisionResponse{Action: "visit"}, a.currentURL)
-		if result.Error != nil {
+		visitStart := time.Now()
+		result := a.executeBrowserAction(ctx, models.GeminiDecisionResponse{Action: "visit"})
+		a.emitDiagnostics("visit", result)
+		if result.Error == nil {
+			a.reportPerformance("visit", a.currentURL, time.Since(visitStart), len(result.HTML))
+		}
+		if result.Error != nil && a.canFallBack() {
+			a.fallBackToHTTP(fmt.Sprintf("initial visit failed: %v", result.Error))
+		} else if result.Error != nil {
 			a.handleError(result.Error, "initial_visit")
 			// Try to continue?
 		} else {
@@ -103,101 +253,185 @@ func (a *RuntimeAgent) Run(ctx context.Context) {
 		}
 	}
 
+	if a.crawl != nil {
+		log.Printf("[Agent %s] Running in crawl mode", a.id)
+		a.publish("agent_status", nil)
+		a.runCrawl(ctx, client)
+		return
+	}
+
+	// Create executor (only for HTTP mode)
+	var httpExecutor *utils.ActionExecutor
+
+	if !a.isBrowserMode {
+		httpExecutor, err = utils.NewActionExecutor(client, a.currentURL, a.mission.FormContentType)
+		if err != nil {
+			a.handleError(err, "init_executor")
+			a.failWith(fmt.Sprintf("could not create HTTP executor: %v", err))
+			return
+		}
+	}
+	log.Printf("[Agent %s] Running in %s mode", a.id, a.executionMode())
+	a.publish("agent_status", nil)
+
 	// Clean up browser tab on exit (AFTER the loop)
 	if a.browserExecutor != nil {
 		defer a.browserExecutor.Close()
 	}
+	defer func() {
+		log.Printf("[Agent %s] Page cache: %d hits, %d misses (%.1f%% hit rate)", a.id, a.pages.hits, a.pages.misses, a.pages.hitRate())
+	}()
 
 	for {
 		select {
 		case <-ctx.Done():
 			log.Printf("[Agent %s] Context done, stopping", a.id)
-			a.status = "stopped"
+			a.SetStatus("stopped")
 			return
 		default:
+			if a.status == "failed" {
+				// fail hit the consecutive error threshold
+				a.SetStatus("failed")
+				return
+			}
+
 			// 1. Rate Limiting
 			if err := a.limiter.Wait(ctx); err != nil {
-				a.status = "stopped"
+				a.SetStatus("stopped")
+				return
+			}
+
+			if a.pastURLLimit() {
+				log.Printf("[Agent %s] Visited %d distinct URLs, past the mission's limit of %d; completing", a.id, a.uniqueURLsVisited, a.mission.MaxUniqueURLsPerAgent)
+				a.actionHistory = append(a.actionHistory, fmt.Sprintf("completed (visited more than max_unique_urls_per_agent %d distinct URLs)", a.mission.MaxUniqueURLsPerAgent))
+				a.SetStatus("completed")
 				return
 			}
 
 			startTime := time.Now()
+			stepCtx := a.startStep(ctx)
+
+			// The freshly fetched page comes with its HTML, for the success
+			// criteria; empty when the cached parse was reused and the page
+			// couldn't have changed
+			page, pageHTML, pageStatus, ok := a.loadPage(stepCtx, client)
+			if !ok {
+				continue
+			}
 			
-			var page *models.StrippedPage
-			
-			if a.isBrowserMode {
-				// Get current state from browser
-				htmlContent, urlStr, err := a.browserExecutor.CaptureDOM(ctx)
-				if err != nil {
-					a.handleError(err, "fetch_page_browser")
-					continue
-				}
-				
-				// Update current URL if changed
-				if urlStr != "" && urlStr != a.currentURL {
-					a.currentURL = urlStr
-					a.urlHistory = append(a.urlHistory, a.currentURL)
-				}
-				
-				parser := utils.NewHTMLParser()
-				page, err = parser.ParseHTMLString(a.currentURL, htmlContent)
-				if err != nil {
-					a.handleError(err, "parse_page")
-					continue
-				}
-			} else {
-				// HTTP Mode
-				req, _ := http.NewRequestWithContext(ctx, "GET", a.currentURL, nil)
-				req.Header.Set("User-Agent", "SwarmTest/1.0")
-				resp, err := client.Do(req)
-				if err != nil {
-					a.handleError(err, "fetch_page")
-					continue
-				}
-				defer resp.Body.Close()
-				
-				parser := utils.NewHTMLParser()
-				page, err = parser.ParseHTML(a.currentURL, resp.Body)
-				if err != nil {
-					a.handleError(err, "parse_page")
-					continue
+			if pageHTML != "" && a.meetsSuccessCriteria(pageHTML, pageStatus) {
+				log.Printf("[Agent %s] Success criteria met at %s", a.id, a.currentURL)
+				a.criteriaMet = true
+				a.actionHistory = append(a.actionHistory, "completed (success criteria met)")
+				a.SetStatus("completed")
+				return
+			}
+			if pageHTML != "" {
+				if kind, blocked := utils.DetectBotWall(pageHTML, len(page.InteractiveElements)); blocked {
+					a.blockByBotWall(kind, pageStatus)
+					return
 				}
 			}
-			
+
+			if a.links != nil {
+				a.checkLinks(ctx, client, page)
+			}
+
+			a.markVisitedLinks(page)
+
 			// 3. Ask Gemini
-			decision, err := a.gemini.DecideNextAction(ctx, a.mission, a.GetSnapshot(), page)
+			decisionCtx := stepCtx
+			if a.mission.StreamDecisions {
+				decisionCtx = gemini.WithProgress(ctx, a.emitThinking)
+			}
+			decideCtx, decideSpan := tracer.Start(decisionCtx, "decide")
+			decision, err := a.gemini.DecideNextAction(decideCtx, a.mission, a.GetSnapshot(), page)
+			if err == nil {
+				decideSpan.SetAttributes(tracing.Action.String(decision.Action))
+			}
+			tracing.End(decideSpan, err)
+			if errors.Is(err, gemini.ErrCircuitOpen) {
+				a.pauseForGemini(ctx)
+				continue
+			}
 			if err != nil {
 				a.handleError(err, "gemini_decision")
 				continue
 			}
+			if a.status == "paused" {
+				log.Printf("[Agent %s] Gemini available again, resuming", a.id)
+				a.SetStatus("running")
+			}
+			a.emitDecision(decision)
 
 			// Handle terminal actions immediately
 			if decision.Action == "completed" {
+				if a.mission.SuccessCriteria != nil {
+					// Only the criteria decide completion; they weren't met on this page
+					log.Printf("[Agent %s] Completion claim rejected: success criteria not met", a.id)
+					a.actionHistory = append(a.actionHistory, "completed (rejected: this page does not meet the success criteria)")
+					continue
+				}
+				if a.mission.VerifyCompletion && !a.verifyCompletion(ctx, page, decision) {
+					continue
+				}
 				a.recordAction(*decision, 0, "") 
-				a.status = "completed"
+				if a.advanceStep() {
+					continue
+				}
+				a.SetStatus("completed")
 				return
 			}
 			if decision.Action == "failed" {
 				a.recordAction(*decision, 0, "") 
-				a.status = "failed"
+				a.failWith("model gave up: " + decision.Reasoning)
 				return
 			}
 			
-			// 4. Execute Action
+			// 4. Execute Action, after the mission's think time
+			thought, ok := a.think(ctx)
+			if !ok {
+				a.SetStatus("stopped")
+				return
+			}
 			var result utils.ExecuteActionResult
+			execCtx, execSpan := tracer.Start(stepCtx, "execute", trace.WithAttributes(tracing.Action.String(decision.Action)))
+			execStart := time.Now()
 			
 			if a.isBrowserMode {
-				result = a.browserExecutor.ExecuteAction(ctx, *decision, a.currentURL)
+				result = a.executeBrowserAction(execCtx, *decision)
+				a.emitDiagnostics(decision.Action, result)
 			} else {
-				result = httpExecutor.ExecuteAction(ctx, *decision, a.currentURL)
+				actionCtx, cancel := context.WithTimeout(execCtx, a.actionTimeout())
+				result = httpExecutor.ExecuteAction(actionCtx, *decision, a.currentURL)
+				cancel()
+				a.reportActionFindings(decision.Action, result)
+			}
+			tracing.End(execSpan, result.Error)
+			// An HTTP action's response is a page; a browser action is timed when it navigates
+			if result.Error == nil && (result.NewURL != "" && result.NewURL != a.currentURL || !a.isBrowserMode && result.HTML != "") {
+				a.reportPerformance(decision.Action, result.NewURL, time.Since(execStart), len(result.HTML))
 			}
 			
-			latency := time.Since(startTime)
+			latency := time.Since(startTime) - thought
 			a.totalLatency += latency
 			a.lastActionAt = time.Now()
 
-			if result.Error != nil {
-				a.handleError(result.Error, decision.Action)
+			// Any executed action may have changed the page
+			a.pages.invalidate()
+
+			if errors.Is(result.Error, utils.ErrDisallowedByRobots) {
+				a.recordSkipped(*decision, result.Error)
+			} else if result.Error != nil {
+				if errors.Is(result.Error, utils.ErrWaitForTimeout) {
+					// Tell the model, so it stops waiting for an element that isn't coming
+					a.actionHistory = append(a.actionHistory, fmt.Sprintf("wait_for %s (timed out after %s)", decision.Selector, utils.WaitForTimeout))
+				} else if errors.Is(result.Error, utils.ErrActionTimeout) {
+					a.actionHistory = append(a.actionHistory, fmt.Sprintf("%s %s (timed out after %s)", decision.Action, decision.Selector, a.actionTimeout()))
+				}
+				a.handleDecisionError(result.Error, *decision)
+			} else if result.RedirectURL != "" {
+				a.recordRedirect(*decision, latency.Milliseconds(), result)
 			} else {
 				a.recordAction(*decision, latency.Milliseconds(), result.NewURL)
 				
@@ -205,15 +439,23 @@ func (a *RuntimeAgent) Run(ctx context.Context) {
 				if result.NewURL != "" && result.NewURL != a.currentURL {
 					a.currentURL = result.NewURL
 					a.urlHistory = append(a.urlHistory, a.currentURL)
+					a.noteVisit()
 					
 					// Update executor base URL by recreating it (HTTP only)
 					if !a.isBrowserMode {
-						httpExecutor, _ = utils.NewActionExecutor(client, a.currentURL)
+						httpExecutor, _ = utils.NewActionExecutor(client, a.currentURL, a.mission.FormContentType)
+					}
+				}
+
+				// The action's response is the new page, so the next iteration needn't refetch it
+				if !a.isBrowserMode && result.HTML != "" && result.NewURL == a.currentURL {
+					if newPage, err := a.parser.ParseResponse(a.currentURL, result.ContentType, result.HTML); err == nil {
+						a.pages.put(a.currentURL, result.HTML, newPage)
 					}
 				}
 				
 				if decision.Action == "completed" {
-					a.status = "completed"
+					a.SetStatus("completed")
 					return
 				}
 			}
@@ -223,39 +465,341 @@ func (a *RuntimeAgent) Run(ctx context.Context) {
 
 
 
+// loadPage returns the agent's current page: from the browser, the page cache,
+// or a fresh HTTP request. html and status are set only for a freshly loaded
+// page. Failures are recorded and reported as !ok. In HTTP mode a page's meta
+// refresh is followed as a browser would, up to maxMetaRefreshes pages in a row.
+func (a *RuntimeAgent) loadPage(ctx context.Context, client *http.Client) (page *models.StrippedPage, html string, status int, ok bool) {
+	for refreshes := 0; ; refreshes++ {
+		page, html, status, ok = a.fetchPage(ctx, client)
+		if !ok || a.isBrowserMode || page.MetaRefresh == "" || !a.followMetaRefresh(ctx, page, refreshes) {
+			return page, html, status, ok
+		}
+	}
+}
+
+// fetchPage loads the current page once, for loadPage
+func (a *RuntimeAgent) fetchPage(ctx context.Context, client *http.Client) (page *models.StrippedPage, html string, status int, ok bool) {
+	ctx, span := tracer.Start(ctx, "fetch", trace.WithAttributes(tracing.URL.String(a.currentURL)))
+	defer span.End()
+
+	if a.isBrowserMode {
+		// Get current state from browser
+		htmlContent, urlStr, err := a.browserExecutor.CaptureDOM(ctx)
+		if errors.Is(err, utils.ErrPageTooLarge) {
+			return a.oversizedPage(err), "", 0, true
+		}
+		if err != nil {
+			tracing.Fail(span, err)
+			a.handleError(err, "fetch_page_browser")
+			return nil, "", 0, false
+		}
+
+		// Update current URL if changed
+		if urlStr != "" && urlStr != a.currentURL {
+			a.currentURL = urlStr
+			a.urlHistory = append(a.urlHistory, a.currentURL)
+			a.noteVisit()
+		}
+
+		// Reuse the last parse only if the DOM is byte-for-byte unchanged
+		if cached, ok := a.pages.get(a.currentURL, htmlContent); ok {
+			page = cached
+		} else {
+			html = htmlContent
+			page, err = a.parser.ParseHTMLString(a.currentURL, htmlContent)
+			if err != nil {
+				tracing.Fail(span, err)
+				a.handleError(err, "parse_page")
+				return nil, "", 0, false
+			}
+			a.pages.put(a.currentURL, htmlContent, page)
+		}
+	} else if cached, ok := a.pages.current(a.currentURL); ok {
+		// HTTP Mode: nothing was executed since this page was fetched
+		page = cached
+	} else {
+		// HTTP Mode
+		req, _ := http.NewRequestWithContext(ctx, "GET", a.currentURL, nil)
+		req.Header.Set("User-Agent", "SwarmTest/1.0")
+		fetchStart := time.Now()
+		resp, err := client.Do(req)
+		if errors.Is(err, utils.ErrPageTooLarge) {
+			return a.oversizedPage(err), "", 0, true
+		}
+		if err != nil {
+			tracing.Fail(span, err)
+			a.handleError(err, "fetch_page")
+			return nil, "", 0, false
+		}
+		body, err := utils.ReadBody(resp)
+		resp.Body.Close()
+		if errors.Is(err, utils.ErrPageTooLarge) {
+			return a.oversizedPage(err), "", resp.StatusCode, true
+		}
+		if err != nil {
+			tracing.Fail(span, err)
+			a.handleError(err, "fetch_page")
+			return nil, "", 0, false
+		}
+		a.reportPerformance("fetch_page", a.currentURL, time.Since(fetchStart), len(body))
+		if resp.StatusCode >= http.StatusBadRequest {
+			a.reportHTTPError("fetch_page", a.currentURL, resp.StatusCode)
+		}
+		html, status = string(body), resp.StatusCode
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+
+		page, err = a.parser.ParseResponse(a.currentURL, resp.Header.Get("Content-Type"), string(body))
+		if err != nil {
+			tracing.Fail(span, err)
+			a.handleError(err, "parse_page")
+			return nil, "", 0, false
+		}
+		a.pages.put(a.currentURL, string(body), page)
+	}
+	return page, html, status, true
+}
+
+// oversizedPage stands in for a current page too large to load, so the agent
+// can decide where to go from it instead of failing on it
+func (a *RuntimeAgent) oversizedPage(err error) *models.StrippedPage {
+	log.Printf("[Agent %s] Not loading %s: %v", a.id, a.currentURL, err)
+	return utils.OversizedPage(a.currentURL, a.mission.MaxPageSizeBytes)
+}
+
+// startStep ends the previous loop iteration's span and starts the next
+func (a *RuntimeAgent) startStep(ctx context.Context) context.Context {
+	a.endStep()
+	ctx, a.step = tracer.Start(ctx, "step", trace.WithAttributes(tracing.URL.String(a.currentURL)))
+	return ctx
+}
+
+// endStep ends the span of the loop iteration in progress, if any
+func (a *RuntimeAgent) endStep() {
+	if a.step != nil {
+		a.step.End()
+		a.step = nil
+	}
+}
+
+// advanceStep moves a multi-step mission on to its next sub-goal. It reports
+// false once the final step (or a single-goal mission) is complete.
+func (a *RuntimeAgent) advanceStep() bool {
+	if len(a.mission.Steps) == 0 {
+		return false
+	}
+
+	a.stepsCompleted++
+	if a.stepsCompleted >= len(a.mission.Steps) {
+		return false
+	}
+
+	log.Printf("[Agent %s] Step %d/%d done, next: %s", a.id, a.stepsCompleted, len(a.mission.Steps), a.mission.Steps[a.stepsCompleted])
+	a.actionHistory = append(a.actionHistory, fmt.Sprintf("completed step %d: %s", a.stepsCompleted, a.mission.Steps[a.stepsCompleted-1]))
+	a.publish("agent_status", nil)
+	return true
+}
+
+// verifyCompletion asks the verifier whether a claimed completion holds on the
+// current page. A rejected claim is added to the history so the next decision
+// knows the goal is not yet met.
+func (a *RuntimeAgent) verifyCompletion(ctx context.Context, page *models.StrippedPage, decision *models.GeminiDecisionResponse) bool {
+	verification, err := a.gemini.VerifyGoal(ctx, a.mission, a.GetSnapshot(), page, decision.Reasoning)
+	if errors.Is(err, gemini.ErrCircuitOpen) {
+		a.pauseForGemini(ctx)
+		return false
+	}
+	if err != nil {
+		a.handleError(err, "verify_completion")
+		return false
+	}
+
+	a.send(models.Event{
+		Type:      "goal_verification",
+		Timestamp: time.Now(),
+		Data: models.VerificationEvent{
+			AgentID:      a.id,
+			MissionID:    a.mission.ID,
+			Achieved:     verification.Achieved,
+			Reasoning:    verification.Reasoning,
+			PromptTokens: verification.Metadata.PromptTokens,
+			OutputTokens: verification.Metadata.OutputTokens,
+			CostUSD:      verification.Metadata.CostUSD,
+		},
+	})
+
+	if !verification.Achieved {
+		log.Printf("[Agent %s] Completion claim rejected: %s", a.id, verification.Reasoning)
+		a.actionHistory = append(a.actionHistory, "completed (rejected by verifier: "+verification.Reasoning+")")
+		return false
+	}
+	return true
+}
+
 // handleError handles an error
 func (a *RuntimeAgent) handleError(err error, action string) {
+	a.fail(err, models.ActionLog{Action: action})
+}
+
+// handleDecisionError handles a failure to execute a decision, logging the
+// full decision so it can be replayed
+func (a *RuntimeAgent) handleDecisionError(err error, decision models.GeminiDecisionResponse) {
+	a.fail(err, models.ActionLog{
+		Action:            decision.Action,
+		Selector:          decision.Selector,
+		TextInput:         decision.TextInput,
+		Frame:             decision.Frame,
+		Reasoning:         decision.Reasoning,
+		ExpectedNextState: decision.ExpectedNextState,
+	})
+}
+
+// fail records a failed action and backs off
+func (a *RuntimeAgent) fail(err error, logEntry models.ActionLog) {
 	a.errorCount++
 	a.consecutiveErrors++
-	log.Printf("[Agent %s] Error during %s: %v", a.id, action, err)
+	if logEntry.Action == a.lastErrorAction {
+		a.sameActionErrors++
+	} else {
+		a.lastErrorAction, a.sameActionErrors = logEntry.Action, 1
+	}
+	ctx := a.traceCtx
+	if a.step != nil {
+		tracing.Fail(a.step, err)
+		ctx = trace.ContextWithSpan(ctx, a.step)
+	}
+	log.Printf("[Agent %s] Error during %s: %v%s", a.id, logEntry.Action, err, tracing.LogFields(ctx))
 
-	a.emitEvent(models.ActionLog{
-		Timestamp:    time.Now(),
-		AgentID:      a.id,
-		MissionID:    a.mission.ID,
-		Action:       action,
-		Result:       "failed",
-		ErrorMessage: err.Error(),
-		LatencyMS:    0,
-	})
-	
-	// Backoff
-	time.Sleep(time.Duration(a.consecutiveErrors) * time.Second)
+	logEntry.Timestamp = time.Now()
+	logEntry.AgentID = a.id
+	logEntry.MissionID = a.mission.ID
+	logEntry.Result = "failed"
+	logEntry.ErrorMessage = err.Error()
+	a.emitEvent(logEntry)
 	
-	if a.consecutiveErrors > 10 {
+	a.backOff(a.traceCtx)
+
+	if a.consecutiveErrors > a.maxConsecutiveErrors() {
 		a.status = "failed"
+		a.failureReason = a.consecutiveErrorReason(logEntry.Action, err)
+		log.Printf("[Agent %s] Giving up: %s", a.id, a.failureReason)
 	}
 }
 
+// backOff waits out the agent's current error streak, a second per error up
+// to maxErrorBackoff, with jitter so agents don't retry in lockstep. A stopped
+// agent stops waiting.
+func (a *RuntimeAgent) backOff(ctx context.Context) {
+	backoff := time.Duration(a.consecutiveErrors) * time.Second
+	if backoff > maxErrorBackoff {
+		backoff = maxErrorBackoff
+	}
+	jitter := time.Duration(a.rng.Int63n(int64(500 * time.Millisecond)))
+
+	timer := time.NewTimer(backoff + jitter)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// pauseForGemini parks the agent while the Gemini circuit breaker is open.
+// Waiting isn't an error, so it doesn't count toward the agent giving up.
+func (a *RuntimeAgent) pauseForGemini(ctx context.Context) {
+	if a.status != "paused" {
+		log.Printf("[Agent %s] Gemini circuit breaker open, pausing", a.id)
+		a.SetStatus("paused")
+	}
+
+	timer := time.NewTimer(geminiPauseInterval)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// actionTimeout bounds each executed action
+func (a *RuntimeAgent) actionTimeout() time.Duration {
+	if a.mission.ActionTimeoutSeconds > 0 {
+		return time.Duration(a.mission.ActionTimeoutSeconds) * time.Second
+	}
+	return utils.DefaultActionTimeout
+}
+
+// executeBrowserAction runs one browser action within the action timeout
+func (a *RuntimeAgent) executeBrowserAction(ctx context.Context, decision models.GeminiDecisionResponse) utils.ExecuteActionResult {
+	actionCtx, cancel := context.WithTimeout(ctx, a.actionTimeout())
+	defer cancel()
+	return a.browserExecutor.ExecuteAction(actionCtx, decision, a.currentURL)
+}
+
+// canFallBack reports whether a browser-mode agent may run in HTTP mode when
+// its browser can't start
+func (a *RuntimeAgent) canFallBack() bool {
+	return a.mission.ExecutionMode == models.ExecutionModeAuto || a.mission.BrowserFallback
+}
+
+// fallBackToHTTP switches the agent to HTTP mode, releasing its browser tab
+func (a *RuntimeAgent) fallBackToHTTP(reason string) {
+	log.Printf("[Agent %s] Falling back to HTTP mode: %s", a.id, reason)
+	if a.browserExecutor != nil {
+		a.browserExecutor.Close()
+		a.browserExecutor = nil
+	}
+	a.isBrowserMode = false
+}
+
+// executionMode is the mode the agent is actually running in
+func (a *RuntimeAgent) executionMode() models.ExecutionMode {
+	if a.crawl != nil {
+		return models.ExecutionModeCrawl
+	}
+	if a.isBrowserMode {
+		return models.ExecutionModeBrowser
+	}
+	return models.ExecutionModeHTTP
+}
+
+// maxConsecutiveErrors is how many errors in a row the agent tolerates
+func (a *RuntimeAgent) maxConsecutiveErrors() int {
+	if a.mission.MaxConsecutiveErrors > 0 {
+		return a.mission.MaxConsecutiveErrors
+	}
+	return defaultMaxConsecutiveErrors
+}
+
+// consecutiveErrorReason describes the error streak that made the agent give up
+func (a *RuntimeAgent) consecutiveErrorReason(action string, err error) string {
+	if a.sameActionErrors < a.consecutiveErrors {
+		return fmt.Sprintf("%d consecutive errors (last: %s: %v)", a.consecutiveErrors, action, err)
+	}
+	if action == "gemini_decision" {
+		return fmt.Sprintf("gemini unavailable: %d consecutive decision errors (last: %v)", a.consecutiveErrors, err)
+	}
+	return fmt.Sprintf("%d consecutive %s errors (last: %v)", a.consecutiveErrors, action, err)
+}
+
+// failWith ends the agent as failed for reason
+func (a *RuntimeAgent) failWith(reason string) {
+	a.failureReason = reason
+	a.SetStatus("failed")
+}
+
 // recordAction records a successful action
 func (a *RuntimeAgent) recordAction(decision models.GeminiDecisionResponse, latencyMS int64, newURL string) {
 	a.successCount++
 	a.consecutiveErrors = 0
+	a.lastErrorAction, a.sameActionErrors = "", 0
 	
 	actionDesc := decision.Action
 	if decision.Selector != "" {
 		actionDesc += fmt.Sprintf(" %s", decision.Selector)
 	}
+	if decision.Frame != "" {
+		actionDesc += " in " + decision.Frame
+	}
 	a.actionHistory = append(a.actionHistory, actionDesc)
 	
 	a.emitEvent(models.ActionLog{
@@ -267,27 +811,223 @@ func (a *RuntimeAgent) recordAction(decision models.GeminiDecisionResponse, late
 		Result:    "success",
 		LatencyMS: latencyMS,
 		NewURL:    newURL,
+		TextInput: decision.TextInput,
+		Frame:     decision.Frame,
+
+		Reasoning:         decision.Reasoning,
+		ExpectedNextState: decision.ExpectedNextState,
+	})
+}
+
+// recordRedirect records an action answered by a redirect that the mission's
+// redirect policy did not follow. The agent stays on its current page.
+func (a *RuntimeAgent) recordRedirect(decision models.GeminiDecisionResponse, latencyMS int64, result utils.ExecuteActionResult) {
+	a.successCount++
+	a.consecutiveErrors = 0
+	a.lastErrorAction, a.sameActionErrors = "", 0
+
+	actionDesc := decision.Action
+	if decision.Selector != "" {
+		actionDesc += fmt.Sprintf(" %s", decision.Selector)
+	}
+	if decision.Frame != "" {
+		actionDesc += " in " + decision.Frame
+	}
+	a.actionHistory = append(a.actionHistory, fmt.Sprintf("%s (redirected %d to %s, not followed)", actionDesc, result.StatusCode, result.RedirectURL))
+
+	a.emitEvent(models.ActionLog{
+		Timestamp:   time.Now(),
+		AgentID:     a.id,
+		MissionID:   a.mission.ID,
+		Action:      decision.Action,
+		Selector:    decision.Selector,
+		TextInput:   decision.TextInput,
+		Frame:       decision.Frame,
+		Result:      "redirected",
+		LatencyMS:   latencyMS,
+		StatusCode:  result.StatusCode,
+		RedirectURL: result.RedirectURL,
+
+		Reasoning:         decision.Reasoning,
+		ExpectedNextState: decision.ExpectedNextState,
+	})
+}
+
+// httpClientOptions maps a mission's redirect, proxy, TLS, page size and target auth settings onto client options
+func httpClientOptions(mission *models.Mission) (utils.HTTPClientOptions, error) {
+	tlsConfig, err := utils.ClientTLSConfig(mission.InsecureSkipVerify, mission.ClientCertFile, mission.ClientKeyFile)
+	if err != nil {
+		return utils.HTTPClientOptions{}, err
+	}
+	opts := utils.HTTPClientOptions{
+		FollowRedirects: mission.FollowRedirects == nil || *mission.FollowRedirects,
+		MaxRedirects:    mission.MaxRedirects,
+		Proxy:           proxyURL(mission),
+		TLSConfig:       tlsConfig,
+		MaxPageSize:     mission.MaxPageSizeBytes,
+		Language:        mission.Language,
+	}
+	opts.Authorization, opts.AuthOrigin = TargetAuthFor(mission)
+	return opts, nil
+}
+
+// TargetAuthFor returns the Authorization header of the mission's target auth
+// and the origin it may be sent to, or "" and nil without target auth
+func TargetAuthFor(mission *models.Mission) (string, *url.URL) {
+	if mission.TargetAuth == nil {
+		return "", nil
+	}
+	origin, err := url.Parse(mission.TargetURL)
+	if err != nil {
+		return "", nil
+	}
+	return mission.TargetAuth.Header(), origin
+}
+
+// proxyURL returns the mission's proxy; the setting was validated at creation
+func proxyURL(mission *models.Mission) *url.URL {
+	u, err := utils.ParseProxyURL(mission.Proxy)
+	if err != nil {
+		return nil
+	}
+	return u
+}
+
+// recordSkipped records a decision that was not carried out, e.g. because
+// robots.txt disallows its target. It is noted in the history so the model
+// picks something else, but does not count as an error.
+func (a *RuntimeAgent) recordSkipped(decision models.GeminiDecisionResponse, reason error) {
+	log.Printf("[Agent %s] Skipped %s: %v", a.id, decision.Action, reason)
+
+	actionDesc := decision.Action
+	if decision.Selector != "" {
+		actionDesc += fmt.Sprintf(" %s", decision.Selector)
+	}
+	if decision.Frame != "" {
+		actionDesc += " in " + decision.Frame
+	}
+	a.actionHistory = append(a.actionHistory, actionDesc+" (skipped: "+reason.Error()+")")
+
+	a.emitEvent(models.ActionLog{
+		Timestamp:    time.Now(),
+		AgentID:      a.id,
+		MissionID:    a.mission.ID,
+		Action:       decision.Action,
+		Selector:     decision.Selector,
+		TextInput:    decision.TextInput,
+		Frame:        decision.Frame,
+		Result:       "skipped",
+		ErrorMessage: reason.Error(),
+
+		Reasoning:         decision.Reasoning,
+		ExpectedNextState: decision.ExpectedNextState,
 	})
 }
 
-// emitEvent sends an event to the bus
+// emitEvent sends an action event to the bus
 func (a *RuntimeAgent) emitEvent(logEntry models.ActionLog) {
-	// Wrap in AgentEvent for frontend compatibility
+	a.publish("action", &logEntry)
+}
+
+// emitDecision reports how a decision was obtained (e.g. cache hit)
+func (a *RuntimeAgent) emitDecision(decision *models.GeminiDecisionResponse) {
+	a.send(models.Event{
+		Type:      "decision",
+		Timestamp: time.Now(),
+		Data: models.DecisionEvent{
+			AgentID:     a.id,
+			MissionID:   a.mission.ID,
+			Action:      decision.Action,
+			CacheLookup: decision.Metadata.CacheLookup,
+			CacheHit:    decision.Metadata.CacheHit,
+			Model:        decision.Metadata.Model,
+			PromptTokens: decision.Metadata.PromptTokens,
+			OutputTokens: decision.Metadata.OutputTokens,
+			CostUSD:      decision.Metadata.CostUSD,
+		},
+	})
+}
+
+// emitDiagnostics reports JavaScript errors and failed network requests the
+// page produced around an action
+func (a *RuntimeAgent) emitDiagnostics(action string, result utils.ExecuteActionResult) {
+	if len(result.JSErrors) > 0 {
+		a.emit("js_errors", models.JSErrorEvent{
+			AgentID:   a.id,
+			MissionID: a.mission.ID,
+			PageURL:   a.currentURL,
+			Action:    action,
+			Errors:    result.JSErrors,
+		})
+	}
+	if len(result.NetworkFailures) > 0 {
+		a.emit("network_failures", models.NetworkFailureEvent{
+			AgentID:   a.id,
+			MissionID: a.mission.ID,
+			PageURL:   a.currentURL,
+			Action:    action,
+			Failures:  result.NetworkFailures,
+		})
+	}
+}
+
+// emit sends an event to the bus
+func (a *RuntimeAgent) emit(eventType string, data any) {
+	a.send(models.Event{
+		Type:      eventType,
+		Timestamp: time.Now(),
+		Data:      data,
+	})
+}
+
+// emitThinking forwards a streamed decision chunk to the bus
+func (a *RuntimeAgent) emitThinking(chunk string, received int) {
+	a.send(models.Event{
+		Type:      "thinking",
+		Timestamp: time.Now(),
+		Data: models.ThinkingEvent{
+			AgentID:   a.id,
+			MissionID: a.mission.ID,
+			Chunk:     chunk,
+			Received:  received,
+		},
+	})
+}
+
+// Status returns the agent's current status
+func (a *RuntimeAgent) Status() string {
+	return a.status
+}
+
+// SetStatus updates the agent status and broadcasts the change
+func (a *RuntimeAgent) SetStatus(status string) {
+	a.status = status
+	a.publish("agent_status", nil)
+}
+
+// publish wraps the agent's current state in an AgentEvent and sends it to the bus
+func (a *RuntimeAgent) publish(eventType string, logEntry *models.ActionLog) {
 	agentEvent := models.AgentEvent{
-		AgentID:   a.id,
-		MissionID: a.mission.ID,
-		ActionLog: &logEntry,
+		AgentID:           a.id,
+		MissionID:         a.mission.ID,
+		Status:            a.status,
+		ActionLog:         logEntry,
+		CurrentURL:        a.currentURL,
+		SuccessCount:      a.successCount,
+		ErrorCount:        a.errorCount,
+		TotalLatencyMS:    a.totalLatency.Milliseconds(),
+		ConsecutiveErrors: a.consecutiveErrors,
+		StepsCompleted:    a.stepsCompleted,
+		FailureReason:     a.failureReason,
+		ExecutionMode:     a.executionMode(),
+		CriteriaMet:       a.criteriaMet,
 	}
 
-	select {
-	case a.eventBus <- models.Event{
-		Type:      "action",
+	a.send(models.Event{
+		Type:      eventType,
 		Timestamp: time.Now(),
 		Data:      agentEvent,
-	}:
-	default:
-		// Drop event if bus is full
-	}
+	})
 }
 
 // GetMetrics returns current metrics
@@ -304,6 +1044,12 @@ func (a *RuntimeAgent) GetMetrics() models.Agent {
 		ConsecutiveErrors: a.consecutiveErrors,
 		URLHistory:        a.urlHistory,
 		LastActionAt:      &a.lastActionAt,
+		StepsCompleted:    a.stepsCompleted,
+		ExplorationHint:   a.explorationHint,
+		FailureReason:     a.failureReason,
+		ExecutionMode:     a.executionMode(),
+		CriteriaMet:       a.criteriaMet,
+		UniqueURLsVisited: a.uniqueURLsVisited,
 	}
 }
 
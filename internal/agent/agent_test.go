@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"swarmtest/internal/gemini/geminitest"
+	"swarmtest/internal/models"
+	"swarmtest/internal/utils"
+)
+
+// runAgent runs an HTTP mode agent of mission, deciding with decide, until it
+// stops, and returns its final state and the events it emitted
+func runAgent(t *testing.T, mission *models.Mission, decide geminitest.DecideFunc) (models.Agent, []models.Event) {
+	t.Helper()
+	mission.ExecutionMode = models.ExecutionModeHTTP
+	if mission.MaxDurationSeconds == 0 {
+		mission.MaxDurationSeconds = 30
+	}
+	bus := make(chan models.Event, 1000)
+	a := NewAgent(mission.ID+"-agent-0", mission, geminitest.NewFakeGeminiClient(decide), utils.NewHTTPClientFactory,
+		utils.NewRateLimiter(100, 100), bus, NewDropCounter(), nil, nil, nil, nil, nil, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	a.Run(ctx)
+	close(bus)
+	var events []models.Event
+	for event := range bus {
+		events = append(events, event)
+	}
+	return a.GetMetrics(), events
+}
+
+func TestMaxConsecutiveErrors(t *testing.T) {
+	tests := []struct {
+		configured, want int
+	}{
+		{0, defaultMaxConsecutiveErrors},
+		{-1, defaultMaxConsecutiveErrors},
+		{3, 3},
+	}
+	for _, tt := range tests {
+		a := &RuntimeAgent{mission: &models.Mission{MissionOptions: models.MissionOptions{MaxConsecutiveErrors: tt.configured}}}
+		if got := a.maxConsecutiveErrors(); got != tt.want {
+			t.Errorf("max_consecutive_errors %d: maxConsecutiveErrors() = %d, want %d", tt.configured, got, tt.want)
+		}
+	}
+}
+
+func TestConsecutiveErrorReason(t *testing.T) {
+	errFetch := errors.New("connection refused")
+	tests := []struct {
+		name              string
+		consecutive, same int
+		action            string
+		want              string
+	}{
+		{"same fetch error", 11, 11, "fetch_page", "11 consecutive fetch_page errors (last: connection refused)"},
+		{"gemini unavailable", 11, 11, "gemini_decision", "gemini unavailable: 11 consecutive decision errors (last: connection refused)"},
+		{"mixed errors", 11, 4, "click", "11 consecutive errors (last: click: connection refused)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &RuntimeAgent{consecutiveErrors: tt.consecutive, sameActionErrors: tt.same}
+			if got := a.consecutiveErrorReason(tt.action, errFetch); got != tt.want {
+				t.Errorf("consecutiveErrorReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// A long error streak's backoff doesn't hold up stopping the agent
+func TestBackoffStopsWithAgent(t *testing.T) {
+	mission := &models.Mission{ID: "backoff", TargetURL: "http://example.com", MissionOptions: models.MissionOptions{MaxConsecutiveErrors: 1000}}
+	a := NewAgent("backoff-agent-0", mission, nil, utils.NewHTTPClientFactory, nil, make(chan models.Event, 10), NewDropCounter(), nil, nil, nil, nil, nil, 0)
+	a.consecutiveErrors = 500
+	ctx, cancel := context.WithCancel(context.Background())
+	a.traceCtx = ctx
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	a.fail(errors.New("connection refused"), models.ActionLog{Action: "fetch_page"})
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("fail took %s after the agent was stopped, want it to return promptly", elapsed)
+	}
+}
+
+func TestAgentFailureReasons(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Home</title></head><body><a href="/next">Next</a></body></html>`))
+	}))
+	t.Cleanup(site.Close)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	gaveUp := func(mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error) {
+		return geminitest.Fail("the goal is impossible here"), nil
+	}
+	unavailable := func(mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error) {
+		return nil, errors.New("503 model overloaded")
+	}
+
+	tests := []struct {
+		name    string
+		mission *models.Mission
+		decide  geminitest.DecideFunc
+		want    string // prefix of the failure reason
+	}{
+		{"model gave up", &models.Mission{ID: "gave-up", TargetURL: site.URL}, gaveUp, "model gave up: the goal is impossible here"},
+		// A threshold of 1 fails on the second error in a row, after 3s of backoff
+		{"gemini unavailable", &models.Mission{ID: "unavailable", TargetURL: site.URL, MissionOptions: models.MissionOptions{MaxConsecutiveErrors: 1}}, unavailable, "gemini unavailable: 2 consecutive decision errors"},
+		{"fetch errors", &models.Mission{ID: "down", TargetURL: down.URL, MissionOptions: models.MissionOptions{MaxConsecutiveErrors: 1}}, gaveUp, "2 consecutive fetch_page errors"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			state, _ := runAgent(t, tt.mission, tt.decide)
+			if state.Status != "failed" {
+				t.Fatalf("status = %q, want failed", state.Status)
+			}
+			if !strings.HasPrefix(state.FailureReason, tt.want) {
+				t.Errorf("failure reason = %q, want it to start %q", state.FailureReason, tt.want)
+			}
+		})
+	}
+}
+
+// A hung action costs the agent its timeout, then it carries on knowing why
+func TestAgentMovesOnAfterActionTimeout(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Home</title></head><body><a id="slow" href="/slow">Slow</a></body></html>`))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	})
+	site := httptest.NewServer(mux)
+	defer site.Close()
+
+	var timedOut string
+	decide := func(mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error) {
+		for _, entry := range agent.ActionHistory {
+			if strings.Contains(entry, "timed out") {
+				timedOut = entry
+				return geminitest.Complete("gave up on the slow page"), nil
+			}
+		}
+		return &models.GeminiDecisionResponse{Action: "click", Selector: "a#slow"}, nil
+	}
+	mission := &models.Mission{ID: "slow", TargetURL: site.URL, MissionOptions: models.MissionOptions{ActionTimeoutSeconds: 1}}
+
+	start := time.Now()
+	state, _ := runAgent(t, mission, decide)
+	if state.Status != "completed" {
+		t.Fatalf("status = %q (%s), want completed", state.Status, state.FailureReason)
+	}
+	if want := "click a#slow (timed out after 1s)"; timedOut != want {
+		t.Errorf("history entry = %q, want %q", timedOut, want)
+	}
+	if elapsed := time.Since(start); elapsed > 8*time.Second {
+		t.Errorf("agent took %s, want about its 1s action timeout", elapsed)
+	}
+}
@@ -0,0 +1,87 @@
+// Package agenttest provides a small multi-page site, and a scripted model
+// that walks through it, for driving agents end to end without a real target
+// or Gemini.
+package agenttest
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"swarmtest/internal/gemini/geminitest"
+	"swarmtest/internal/models"
+)
+
+// LoginGoal is a mission goal matching the site's flow
+const LoginGoal = "Log in and reach the dashboard"
+
+// sessionCookie marks a signed-in visitor
+const sessionCookie = "agenttest_session"
+
+// NewLoginSite serves a home page linking to a login form, which signs in any
+// non-empty username and redirects to a dashboard. The dashboard sends
+// visitors who aren't signed in back to the login form. Close it when done.
+func NewLoginSite() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		writePage(w, "Home", `<h1>Welcome</h1><p>Sign in to see your dashboard.</p><a id="login-link" href="/login">Log in</a>`)
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writePage(w, "Log in", `<h1>Log in</h1><form method="post" action="/login">`+
+				`<input id="username" name="username" type="text" placeholder="Username">`+
+				`<button id="submit" type="submit">Log in</button></form>`)
+		case http.MethodPost:
+			username := strings.TrimSpace(r.FormValue("username"))
+			if username == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				writePage(w, "Log in", `<h1>Log in</h1><p class="error">Username is required</p><a href="/login">Try again</a>`)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: username, Path: "/"})
+			http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/dashboard", func(w http.ResponseWriter, r *http.Request) {
+		session, err := r.Cookie(sessionCookie)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		writePage(w, "Dashboard", fmt.Sprintf(`<h1>Dashboard</h1><p>Signed in as %s</p><a href="/">Home</a>`, html.EscapeString(session.Value)))
+	})
+	return httptest.NewServer(mux)
+}
+
+func writePage(w http.ResponseWriter, title, body string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<!DOCTYPE html><html><head><title>%s</title></head><body>%s</body></html>", title, body)
+}
+
+// LoginFlow decides like a model working through NewLoginSite: it follows the
+// login link, types username into the form, and completes once the dashboard
+// shows, deciding off the elements parsed from each page. It gives up on a
+// page it doesn't recognise.
+func LoginFlow(username string) geminitest.DecideFunc {
+	return func(mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error) {
+		if page.Title == "Dashboard" {
+			return geminitest.Complete("the dashboard is showing"), nil
+		}
+		if input, ok := geminitest.FindElement(page, "input", "username"); ok {
+			return geminitest.Type(input, username), nil
+		}
+		if link, ok := geminitest.FindElement(page, "link", "log in"); ok {
+			return geminitest.Click(link), nil
+		}
+		return geminitest.Fail("no way to log in from " + page.URL), nil
+	}
+}
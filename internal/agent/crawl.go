@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"swarmtest/internal/models"
+	"swarmtest/internal/tracing"
+	"swarmtest/internal/utils"
+)
+
+// runCrawl replaces the decision loop in crawl mode: the agent takes pages
+// from the mission's shared frontier and fetches them, queueing the same-host
+// links it finds, until the crawl is done or the mission ends
+func (a *RuntimeAgent) runCrawl(ctx context.Context, client *http.Client) {
+	for {
+		if a.status == "failed" {
+			// fail hit the consecutive error threshold
+			a.SetStatus("failed")
+			return
+		}
+
+		target, ok := a.crawl.Next(ctx)
+		if !ok {
+			// Finishing the crawl also ends the mission, so check it before the context
+			select {
+			case <-a.crawl.Done():
+				log.Printf("[Agent %s] Crawl finished", a.id)
+				a.actionHistory = append(a.actionHistory, "completed (crawl finished)")
+				a.SetStatus("completed")
+			default:
+				a.SetStatus("stopped")
+			}
+			return
+		}
+
+		if err := a.limiter.Wait(ctx); err != nil {
+			a.SetStatus("stopped")
+			return
+		}
+		if _, ok := a.think(ctx); !ok {
+			a.SetStatus("stopped")
+			return
+		}
+
+		a.currentURL = target.URL
+		a.urlHistory = append(a.urlHistory, target.URL)
+		stepCtx := a.startStep(ctx)
+		visit := models.GeminiDecisionResponse{Action: "visit"}
+
+		reqCtx, cancel := context.WithTimeout(stepCtx, a.actionTimeout())
+		page, latency, err := a.fetchForCrawl(reqCtx, client, target.URL)
+		cancel()
+		if ctx.Err() != nil {
+			a.SetStatus("stopped")
+			return
+		}
+		a.totalLatency += latency
+		a.lastActionAt = time.Now()
+		a.crawl.Record(target, page.statusCode, err, page.links)
+
+		if errors.Is(err, utils.ErrDisallowedByRobots) {
+			a.recordSkipped(visit, err)
+			continue
+		}
+		if err != nil {
+			a.handleDecisionError(fmt.Errorf("crawl %s: %w", target.URL, err), visit)
+			continue
+		}
+		if page.statusCode >= http.StatusBadRequest {
+			a.reportHTTPError("crawl", target.URL, page.statusCode)
+		}
+		a.recordAction(visit, latency.Milliseconds(), target.URL)
+
+		if kind, blocked := utils.DetectBotWall(page.html, page.elements); blocked {
+			a.blockByBotWall(kind, page.statusCode)
+			return
+		}
+	}
+}
+
+// crawledPage is what fetching one page of a crawl found
+type crawledPage struct {
+	html       string
+	statusCode int
+	links      []string // distinct same-host links
+	elements   int      // interactive elements, for bot wall detection
+}
+
+// fetchForCrawl fetches pageURL and extracts its same-host links. The
+// latency is returned even when the fetch fails.
+func (a *RuntimeAgent) fetchForCrawl(ctx context.Context, client *http.Client, pageURL string) (crawledPage, time.Duration, error) {
+	ctx, span := tracer.Start(ctx, "fetch", trace.WithAttributes(tracing.URL.String(pageURL)))
+	defer span.End()
+
+	var result crawledPage
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return result, 0, err
+	}
+	req.Header.Set("User-Agent", "SwarmTest/1.0")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return result, time.Since(start), err
+	}
+	defer resp.Body.Close()
+	result.statusCode = resp.StatusCode
+	body, err := utils.ReadBody(resp)
+	latency := time.Since(start)
+	if err != nil {
+		return result, latency, err
+	}
+	result.html = string(body)
+
+	// Links resolve against where any redirects ended up
+	finalURL := resp.Request.URL.String()
+	page, err := utils.NewHTMLParser().ParseResponse(finalURL, resp.Header.Get("Content-Type"), result.html)
+	if err != nil {
+		return result, latency, err
+	}
+	var hrefs []string
+	for _, el := range page.InteractiveElements {
+		if el.Href != "" {
+			hrefs = append(hrefs, el.Href)
+		}
+	}
+	result.links = utils.SameSiteLinks(finalURL, hrefs)
+	result.elements = len(page.InteractiveElements)
+	return result, latency, nil
+}
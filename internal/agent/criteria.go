@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// meetsSuccessCriteria reports whether the page just fetched satisfies every
+// condition of the mission's success criteria. status is the page's response
+// status, 0 when unknown (browser mode, where status_code isn't allowed).
+func (a *RuntimeAgent) meetsSuccessCriteria(html string, status int) bool {
+	c := a.mission.SuccessCriteria
+	if c == nil {
+		return false
+	}
+
+	if c.StatusCode != 0 && status != c.StatusCode {
+		return false
+	}
+
+	if c.URLPattern != "" {
+		if a.criteriaURL == nil {
+			re, err := regexp.Compile(c.URLPattern)
+			if err != nil {
+				log.Printf("[Agent %s] Invalid success criteria url_pattern: %v", a.id, err)
+				return false
+			}
+			a.criteriaURL = re
+		}
+		if !a.criteriaURL.MatchString(a.currentURL) {
+			return false
+		}
+	}
+
+	if c.Selector == "" && c.Text == "" {
+		return true
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return false
+	}
+	if c.Selector != "" && doc.Find(c.Selector).Length() == 0 {
+		return false
+	}
+	if c.Text != "" && !strings.Contains(doc.Find("body").Text(), c.Text) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"swarmtest/internal/models"
+)
+
+// eventSendTimeout is how long an agent waits on a full event bus before
+// dropping the event. The wait slows agents down instead of losing their
+// action logs to a short burst.
+const eventSendTimeout = 250 * time.Millisecond
+
+// DropCounter counts the events each running mission lost to a full channel:
+// an agent's send to the bus, or the fan-out to the event logger, timing out.
+// A mission's count moves onto the mission when it finishes.
+type DropCounter struct {
+	mu        sync.Mutex
+	byMission map[string]int64
+}
+
+// NewDropCounter creates an empty DropCounter
+func NewDropCounter() *DropCounter {
+	return &DropCounter{byMission: make(map[string]int64)}
+}
+
+// Dropped is how many of the running mission's events were lost on their way
+// to the event logger; its metrics undercount by that many events
+func (c *DropCounter) Dropped(missionID string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.byMission[missionID]
+}
+
+// Count records a lost event of the mission and returns how many it has lost.
+// Events that aren't about a mission aren't counted.
+func (c *DropCounter) Count(missionID string) int64 {
+	if missionID == "" {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byMission[missionID]++
+	return c.byMission[missionID]
+}
+
+// Take removes the finished mission's count and returns it, for the mission
+// to keep
+func (c *DropCounter) Take(missionID string) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := c.byMission[missionID]
+	delete(c.byMission, missionID)
+	return n
+}
+
+// EventMissionID is the mission an event is about, or "" for one that isn't
+// about a mission. It is called on the fan-out's hot path, so it reads the
+// mission ID off the known event types rather than re-encoding the data.
+func EventMissionID(event models.Event) string {
+	switch data := event.Data.(type) {
+	case models.AgentEvent:
+		return data.MissionID
+	case *models.ActionLog:
+		return data.MissionID
+	case models.DecisionEvent:
+		return data.MissionID
+	case models.ThinkingEvent:
+		return data.MissionID
+	case models.VerificationEvent:
+		return data.MissionID
+	case models.JSErrorEvent:
+		return data.MissionID
+	case models.NetworkFailureEvent:
+		return data.MissionID
+	case models.ClientRedirectEvent:
+		return data.MissionID
+	case models.Finding:
+		return data.MissionID
+	case *models.AlertEvent:
+		return data.MissionID
+	case *models.SummaryEvent:
+		return data.MissionID
+	case map[string]string:
+		return data["mission_id"]
+	}
+	return ""
+}
+
+// send puts an event on the bus, waiting up to eventSendTimeout for room
+func (a *RuntimeAgent) send(event models.Event) {
+	select {
+	case a.eventBus <- event:
+		return
+	default:
+	}
+
+	timer := time.NewTimer(eventSendTimeout)
+	defer timer.Stop()
+	select {
+	case a.eventBus <- event:
+	case <-timer.C:
+		total := a.drops.Count(a.mission.ID)
+		log.Printf("[Agent %s] Event bus full, dropped %s event (%d dropped for mission %s)", a.id, event.Type, total, a.mission.ID)
+	}
+}
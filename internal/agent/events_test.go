@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"swarmtest/internal/models"
+)
+
+func TestEventMissionID(t *testing.T) {
+	tests := []struct {
+		name  string
+		event models.Event
+		want  string
+	}{
+		{"agent event", models.Event{Type: "decision", Data: models.DecisionEvent{AgentID: "m1-agent-0", MissionID: "m1"}}, "m1"},
+		{"pointer data", models.Event{Type: "action", Data: &models.ActionLog{MissionID: "m2"}}, "m2"},
+		{"finding", models.Event{Type: "finding", Data: models.Finding{MissionID: "m4"}}, "m4"},
+		{"map data", models.Event{Type: "mission_started", Data: map[string]string{"mission_id": "m3"}}, "m3"},
+		{"not about a mission", models.Event{Type: "summary_tick", Data: map[string]string{"message": "periodic_tick"}}, ""},
+		{"non-object data", models.Event{Type: "other", Data: "text"}, ""},
+		{"no data", models.Event{Type: "other"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EventMissionID(tt.event); got != tt.want {
+				t.Errorf("EventMissionID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDropCounterMovesOntoMission(t *testing.T) {
+	drops := NewDropCounter()
+	drops.Count("count-test")
+	if got := drops.Count("count-test"); got != 2 {
+		t.Errorf("Count() = %d, want 2", got)
+	}
+	if got := drops.Count(""); got != 0 {
+		t.Errorf("Count(\"\") = %d, want 0", got)
+	}
+
+	if got := drops.Take("count-test"); got != 2 {
+		t.Errorf("Take() = %d, want 2", got)
+	}
+	if got := drops.Dropped("count-test"); got != 0 {
+		t.Errorf("Dropped() after take = %d, want 0", got)
+	}
+	if _, kept := drops.byMission["count-test"]; kept {
+		t.Error("Take left the mission's entry in the map")
+	}
+}
+
+func TestSendCountsDropsOnFullBus(t *testing.T) {
+	bus := make(chan models.Event, 1)
+	bus <- models.Event{Type: "filler"}
+	drops := NewDropCounter()
+	a := &RuntimeAgent{id: "send-test-agent-0", mission: &models.Mission{ID: "send-test"}, eventBus: bus, drops: drops}
+
+	start := time.Now()
+	a.send(models.Event{Type: "action"})
+	if waited := time.Since(start); waited < eventSendTimeout {
+		t.Errorf("send gave up after %v, want it to wait %v", waited, eventSendTimeout)
+	}
+	if got := drops.Dropped("send-test"); got != 1 {
+		t.Errorf("Dropped() = %d, want 1", got)
+	}
+}
@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"swarmtest/internal/models"
+	"swarmtest/internal/utils"
+)
+
+// reportFinding emits a finding once per agent; the same problem seen again on
+// a later iteration (e.g. refetching a broken page) isn't reported twice
+func (a *RuntimeAgent) reportFinding(f models.Finding) {
+	key := fmt.Sprintf("%s|%s|%d", f.Type, f.RequestURL, f.StatusCode)
+	if a.reportedFindings == nil {
+		a.reportedFindings = make(map[string]bool)
+	}
+	if a.reportedFindings[key] {
+		return
+	}
+	a.reportedFindings[key] = true
+
+	f.Timestamp = time.Now()
+	f.MissionID = a.mission.ID
+	f.AgentID = a.id
+	if f.PageURL == "" {
+		f.PageURL = a.currentURL
+	}
+	a.emit("finding", f)
+}
+
+// reportHTTPError records an error response seen in HTTP mode
+func (a *RuntimeAgent) reportHTTPError(action, requestURL string, statusCode int) {
+	a.reportFinding(models.Finding{
+		Type:       "http_error",
+		Severity:   models.RequestSeverity(statusCode, "Document"),
+		Action:     action,
+		Message:    fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		RequestURL: requestURL,
+		StatusCode: statusCode,
+	})
+}
+
+// reportBrokenLink records a link that led to an error response, or to no
+// response at all (statusCode 0, with the fetch error)
+func (a *RuntimeAgent) reportBrokenLink(action, linkURL string, statusCode int, fetchErr error) {
+	message := fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode))
+	if statusCode == 0 && fetchErr != nil {
+		message = fetchErr.Error()
+	}
+	a.reportFinding(models.Finding{
+		Type:       "broken_link",
+		Severity:   models.RequestSeverity(statusCode, "Document"),
+		Action:     action,
+		Message:    message,
+		RequestURL: linkURL,
+		StatusCode: statusCode,
+	})
+}
+
+// reportActionFindings records the error responses an HTTP-mode action ran into,
+// whether it failed outright on a 5xx or landed on an error page. Failures
+// following a link are reported as broken links.
+func (a *RuntimeAgent) reportActionFindings(action string, result utils.ExecuteActionResult) {
+	statusCode, requestURL := result.StatusCode, result.NewURL
+	var statusErr *utils.HTTPStatusError
+	switch {
+	case errors.As(result.Error, &statusErr):
+		statusCode, requestURL = statusErr.StatusCode, statusErr.URL
+	case result.Error != nil:
+		// Other failures are only findings when a link couldn't be fetched at all
+		if result.LinkURL == "" || errors.Is(result.Error, utils.ErrDisallowedByRobots) ||
+			errors.Is(result.Error, context.Canceled) || errors.Is(result.Error, context.DeadlineExceeded) {
+			return
+		}
+		statusCode = 0
+	case statusCode < http.StatusBadRequest:
+		return
+	}
+
+	if result.LinkURL != "" {
+		a.reportBrokenLink(action, result.LinkURL, statusCode, result.Error)
+		return
+	}
+	a.reportHTTPError(action, requestURL, statusCode)
+}
+
+// reportPerformance records a page that took longer to load, or was larger,
+// than the mission's thresholds
+func (a *RuntimeAgent) reportPerformance(action, pageURL string, elapsed time.Duration, size int) {
+	slow := a.mission.SlowPageMS > 0 && elapsed.Milliseconds() > int64(a.mission.SlowPageMS)
+	large := a.mission.LargePageBytes > 0 && size > a.mission.LargePageBytes
+	if !slow && !large {
+		return
+	}
+
+	var problems []string
+	if slow {
+		problems = append(problems, fmt.Sprintf("took %s (over %dms)", elapsed.Round(time.Millisecond), a.mission.SlowPageMS))
+	}
+	if large {
+		problems = append(problems, fmt.Sprintf("is %d bytes (over %d)", size, a.mission.LargePageBytes))
+	}
+	a.reportFinding(models.Finding{
+		Type:          "performance",
+		Severity:      models.SeverityMedium,
+		Action:        action,
+		Message:       "page " + strings.Join(problems, " and "),
+		RequestURL:    pageURL,
+		DurationMS:    elapsed.Milliseconds(),
+		ContentLength: int64(size),
+	})
+}
+
+// blockByBotWall records that the target served a CAPTCHA or bot challenge
+// and ends the agent as blocked, since retrying won't get past it
+func (a *RuntimeAgent) blockByBotWall(kind string, statusCode int) {
+	a.reportFinding(models.Finding{
+		Type:       "bot_wall",
+		Severity:   models.SeverityHigh,
+		Action:     "fetch_page",
+		Message:    kind + " detected",
+		RequestURL: a.currentURL,
+		StatusCode: statusCode,
+	})
+	a.failureReason = fmt.Sprintf("blocked by %s at %s", kind, a.currentURL)
+	log.Printf("[Agent %s] %s", a.id, a.failureReason)
+	a.SetStatus("blocked")
+}
+
+// maxLinkChecksPerPage bounds the links an agent checks before each decision,
+// so link checking doesn't crowd out the mission goal
+const maxLinkChecksPerPage = 10
+
+// checkLinks requests the page's same-site links that no agent of the mission
+// has checked yet, reporting the broken ones. Each request waits on the
+// mission's rate limiter like any other.
+func (a *RuntimeAgent) checkLinks(ctx context.Context, client *http.Client, page *models.StrippedPage) {
+	var hrefs []string
+	for _, el := range page.InteractiveElements {
+		if el.Href != "" {
+			hrefs = append(hrefs, el.Href)
+		}
+	}
+
+	checked := 0
+	for _, link := range utils.SameSiteLinks(a.currentURL, hrefs) {
+		if checked >= maxLinkChecksPerPage {
+			return
+		}
+		if !a.links.Claim(link) {
+			continue
+		}
+		checked++
+
+		if err := a.limiter.Wait(ctx); err != nil {
+			return
+		}
+		status, err := utils.CheckLink(ctx, client, link)
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, utils.ErrDisallowedByRobots) {
+			continue
+		}
+		if err != nil || status >= http.StatusBadRequest {
+			a.reportBrokenLink("check_link", link, status, err)
+		}
+	}
+}
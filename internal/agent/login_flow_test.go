@@ -0,0 +1,109 @@
+package agent_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"swarmtest/internal/agent"
+	"swarmtest/internal/agent/agenttest"
+	"swarmtest/internal/gemini/geminitest"
+	"swarmtest/internal/models"
+	"swarmtest/internal/utils"
+)
+
+// runLoginFlow runs one HTTP mode agent through agenttest's login site,
+// signing in as username, and returns it with the actions it recorded
+func runLoginFlow(t *testing.T, username string) (*agent.RuntimeAgent, *geminitest.FakeGeminiClient, []models.ActionLog, string) {
+	t.Helper()
+	site := agenttest.NewLoginSite()
+	t.Cleanup(site.Close)
+
+	mission := &models.Mission{
+		ID:                 "login-flow",
+		TargetURL:          site.URL,
+		Goal:               agenttest.LoginGoal,
+		NumAgents:          1,
+		MaxDurationSeconds: 10,
+		RateLimitPerSecond: 100,
+		ExecutionMode:      models.ExecutionModeHTTP,
+	}
+	client := geminitest.NewFakeGeminiClient(agenttest.LoginFlow(username))
+	bus := make(chan models.Event, 100)
+	a := agent.NewAgent("login-flow-agent-0", mission, client, utils.NewHTTPClientFactory,
+		utils.NewRateLimiter(100, 100), bus, agent.NewDropCounter(), nil, nil, nil, nil, nil, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	a.Run(ctx)
+	close(bus)
+
+	var actions []models.ActionLog
+	for event := range bus {
+		if e, ok := event.Data.(models.AgentEvent); ok && event.Type == "action" && e.ActionLog != nil {
+			actions = append(actions, *e.ActionLog)
+		}
+	}
+	return a, client, actions, site.URL
+}
+
+func actionNames(actions []models.ActionLog) []string {
+	names := make([]string, 0, len(actions))
+	for _, action := range actions {
+		names = append(names, action.Action)
+	}
+	return names
+}
+
+// The agent visits the home page, follows the login link, types into the login
+// form (which submits it in HTTP mode) and completes on the dashboard
+func TestAgentLoginFlow(t *testing.T) {
+	a, client, actions, siteURL := runLoginFlow(t, "alice")
+
+	if status := a.Status(); status != "completed" {
+		t.Fatalf("agent status = %q, want completed; actions %v", status, actionNames(actions))
+	}
+
+	wantPages := []string{siteURL, siteURL + "/login", siteURL + "/dashboard"}
+	if got := client.Pages("login-flow-agent-0"); fmt.Sprint(got) != fmt.Sprint(wantPages) {
+		t.Errorf("pages decided on = %v, want %v", got, wantPages)
+	}
+
+	want := []struct {
+		action, selector, newURL string
+	}{
+		{"click", "a#login-link", siteURL + "/login"},
+		{"type", "input#username", siteURL + "/dashboard"},
+		{"completed", "", ""},
+	}
+	if len(actions) != len(want) {
+		t.Fatalf("recorded actions %v, want %d", actionNames(actions), len(want))
+	}
+	for i, w := range want {
+		got := actions[i]
+		if got.Action != w.action || got.Selector != w.selector || got.NewURL != w.newURL || got.Result != "success" {
+			t.Errorf("action %d = %s %q -> %q (%s), want %s %q -> %q (success)",
+				i, got.Action, got.Selector, got.NewURL, got.Result, w.action, w.selector, w.newURL)
+		}
+	}
+	if actions[1].TextInput != "alice" {
+		t.Errorf("typed %q, want alice", actions[1].TextInput)
+	}
+	if metrics := a.GetMetrics(); metrics.CurrentURL != siteURL+"/dashboard" {
+		t.Errorf("agent ended on %s, want the dashboard", metrics.CurrentURL)
+	}
+}
+
+// Signing in without a username is refused, leaving the agent on an error
+// page it can't log in from
+func TestAgentLoginFlowRejected(t *testing.T) {
+	a, _, actions, _ := runLoginFlow(t, "")
+
+	if status := a.Status(); status != "failed" {
+		t.Fatalf("agent status = %q, want failed; actions %v", status, actionNames(actions))
+	}
+	if got, want := fmt.Sprint(actionNames(actions)), "[click type failed]"; got != want {
+		t.Errorf("recorded actions %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"swarmtest/internal/models"
+)
+
+// UserAgentFor returns the User-Agent the agent at index sends: the mission's
+// pool is dealt out round-robin. Empty when the mission has no pool, leaving
+// the default in place.
+func UserAgentFor(mission *models.Mission, index int) string {
+	if len(mission.UserAgents) == 0 {
+		return ""
+	}
+	return mission.UserAgents[index%len(mission.UserAgents)]
+}
+
+// thinkTime is the pause before the agent's next action, drawn from the
+// mission's think time range with the agent's seeded random source
+func (a *RuntimeAgent) thinkTime() time.Duration {
+	lo, hi := a.mission.ThinkTimeMinMS, a.mission.ThinkTimeMaxMS
+	if hi <= 0 {
+		return 0
+	}
+	ms := lo
+	if hi > lo {
+		ms += a.rng.Intn(hi - lo + 1)
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// think pauses like a user reading the page before acting. It returns how long
+// it paused, and false if the agent was stopped meanwhile.
+func (a *RuntimeAgent) think(ctx context.Context) (time.Duration, bool) {
+	d := a.thinkTime()
+	if d == 0 {
+		return 0, true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return d, true
+	case <-ctx.Done():
+		return d, false
+	}
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"crypto/sha256"
+
+	"swarmtest/internal/models"
+)
+
+// pageCache holds the agent's last parsed page so loop iterations that didn't
+// act on the page (a rejected completion, a failed Gemini call) don't refetch
+// and re-parse it. An entry only matches the exact URL and DOM it was parsed
+// from, and is dropped whenever an action runs.
+type pageCache struct {
+	url  string
+	hash [sha256.Size]byte
+	page *models.StrippedPage
+
+	hits, misses int
+}
+
+// get returns the cached page if it was parsed from html at url
+func (c *pageCache) get(url, html string) (*models.StrippedPage, bool) {
+	if c.page != nil && c.url == url && c.hash == sha256.Sum256([]byte(html)) {
+		c.hits++
+		return c.page, true
+	}
+	c.misses++
+	return nil, false
+}
+
+// current returns the cached page for url without comparing the DOM. Only
+// valid in HTTP mode, where the page can't change without an action.
+func (c *pageCache) current(url string) (*models.StrippedPage, bool) {
+	if c.page != nil && c.url == url {
+		c.hits++
+		return c.page, true
+	}
+	c.misses++
+	return nil, false
+}
+
+// put caches page as the parse of html at url
+func (c *pageCache) put(url, html string, page *models.StrippedPage) {
+	c.url = url
+	c.hash = sha256.Sum256([]byte(html))
+	c.page = page
+}
+
+// invalidate drops the cached page; called after every executed action
+func (c *pageCache) invalidate() {
+	c.page = nil
+}
+
+// hitRate is the percentage of lookups served from the cache
+func (c *pageCache) hitRate() float64 {
+	total := c.hits + c.misses
+	if total == 0 {
+		return 0
+	}
+	return float64(c.hits) / float64(total) * 100
+}
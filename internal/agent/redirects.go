@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"swarmtest/internal/models"
+)
+
+// maxMetaRefreshes bounds a chain of meta refreshes followed in HTTP mode, so
+// pages refreshing to each other don't hold the agent
+const maxMetaRefreshes = 5
+
+// followMetaRefresh moves the agent on to where the page's meta refresh
+// points, as a browser would, reporting false when it stays put: the mission
+// doesn't follow redirects, the chain is too long, or it was stopped
+func (a *RuntimeAgent) followMetaRefresh(ctx context.Context, page *models.StrippedPage, refreshes int) bool {
+	followRedirects := a.mission.FollowRedirects == nil || *a.mission.FollowRedirects
+	if !followRedirects || refreshes >= maxMetaRefreshes {
+		if a.unfollowedRefresh != a.currentURL {
+			a.unfollowedRefresh = a.currentURL
+			log.Printf("[Agent %s] Not following meta refresh from %s to %s", a.id, a.currentURL, page.MetaRefresh)
+			a.emitClientRedirect(a.currentURL, page.MetaRefresh, false)
+		}
+		return false
+	}
+	// Each page fetched is a request against the mission's rate limit
+	if err := a.limiter.Wait(ctx); err != nil {
+		return false
+	}
+
+	a.emitClientRedirect(a.currentURL, page.MetaRefresh, true)
+	a.actionHistory = append(a.actionHistory, fmt.Sprintf("page redirected by meta refresh to %s", page.MetaRefresh))
+	a.currentURL = page.MetaRefresh
+	a.urlHistory = append(a.urlHistory, a.currentURL)
+	a.noteVisit()
+	return true
+}
+
+// emitClientRedirect reports a meta refresh from one page to another
+func (a *RuntimeAgent) emitClientRedirect(from, to string, followed bool) {
+	a.emit("client_redirect", models.ClientRedirectEvent{
+		AgentID:   a.id,
+		MissionID: a.mission.ID,
+		FromURL:   from,
+		ToURL:     to,
+		Followed:  followed,
+	})
+}
@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"swarmtest/internal/gemini/geminitest"
+	"swarmtest/internal/models"
+)
+
+// refreshSite serves a splash page that meta refreshes to /home, and two
+// pages that refresh to each other forever
+func refreshSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	page := func(head, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprintf(w, "<html><head><title>%s</title>%s</head><body>%s</body></html>", r.URL.Path, head, body)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", page(`<meta http-equiv="refresh" content="0; url=/home">`, "Loading..."))
+	mux.HandleFunc("/home", page("", `<a href="/about">About</a>`))
+	mux.HandleFunc("/ping", page(`<meta http-equiv="refresh" content="0; url=/pong">`, ""))
+	mux.HandleFunc("/pong", page(`<meta http-equiv="refresh" content="0; url=/ping">`, ""))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// clientRedirects are the client_redirect events among events
+func clientRedirects(events []models.Event) []models.ClientRedirectEvent {
+	var redirects []models.ClientRedirectEvent
+	for _, event := range events {
+		if r, ok := event.Data.(models.ClientRedirectEvent); ok && event.Type == "client_redirect" {
+			redirects = append(redirects, r)
+		}
+	}
+	return redirects
+}
+
+func TestAgentFollowsMetaRefresh(t *testing.T) {
+	site := refreshSite(t)
+	var seen []string
+	decide := func(mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error) {
+		seen = append(seen, page.URL)
+		return geminitest.Complete("reached " + page.Title), nil
+	}
+
+	state, events := runAgent(t, &models.Mission{ID: "refresh", TargetURL: site.URL + "/"}, decide)
+	if state.Status != "completed" {
+		t.Fatalf("status = %q (%s), want completed", state.Status, state.FailureReason)
+	}
+	// The model is never asked about the splash page
+	if len(seen) != 1 || seen[0] != site.URL+"/home" {
+		t.Errorf("decided on %v, want only %s/home", seen, site.URL)
+	}
+	redirects := clientRedirects(events)
+	if len(redirects) != 1 || redirects[0].FromURL != site.URL+"/" || redirects[0].ToURL != site.URL+"/home" || !redirects[0].Followed {
+		t.Errorf("client redirects = %+v, want one followed from / to /home", redirects)
+	}
+}
+
+func TestAgentMetaRefreshBounded(t *testing.T) {
+	site := refreshSite(t)
+	noRedirects := false
+	tests := []struct {
+		name      string
+		mission   *models.Mission
+		followed  int
+		wantStuck string // the page the agent decides on
+	}{
+		{"refresh loop", &models.Mission{ID: "loop", TargetURL: site.URL + "/ping"}, maxMetaRefreshes, "/pong"}, // five hops from /ping
+		{"redirects off", &models.Mission{ID: "off", TargetURL: site.URL + "/", MissionOptions: models.MissionOptions{FollowRedirects: &noRedirects}}, 0, "/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decidedOn string
+			decide := func(mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error) {
+				decidedOn = page.URL
+				return geminitest.Complete("done"), nil
+			}
+			_, events := runAgent(t, tt.mission, decide)
+			if decidedOn != site.URL+tt.wantStuck {
+				t.Errorf("decided on %s, want %s%s", decidedOn, site.URL, tt.wantStuck)
+			}
+
+			var followed, unfollowed int
+			for _, r := range clientRedirects(events) {
+				if r.Followed {
+					followed++
+				} else {
+					unfollowed++
+				}
+			}
+			if followed != tt.followed || unfollowed != 1 {
+				t.Errorf("%d followed and %d unfollowed refreshes reported, want %d and 1", followed, unfollowed, tt.followed)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"net/url"
+	"strings"
+
+	"swarmtest/internal/models"
+)
+
+// noteVisit records the agent's arrival at its current URL, counting it if
+// the agent hasn't been there before, and in the mission's shared visited set.
+// Arriving where another agent has already been is noted in the history, so
+// the next decision heads somewhere new.
+func (a *RuntimeAgent) noteVisit() {
+	if u := withoutFragment(a.currentURL); !a.uniqueURLs[u] {
+		a.uniqueURLs[u] = true
+		a.uniqueURLsVisited++
+	}
+	if a.visited == nil {
+		return
+	}
+	if a.visited.Visit(a.currentURL, a.id) && len(a.actionHistory) > 0 {
+		a.actionHistory[len(a.actionHistory)-1] += " (already visited by another agent, try something new)"
+	}
+}
+
+// pastURLLimit reports whether the agent has visited more distinct URLs than
+// the mission allows. At the limit the prompt already asked it to converge.
+func (a *RuntimeAgent) pastURLLimit() bool {
+	return a.mission.MaxUniqueURLsPerAgent > 0 && a.uniqueURLsVisited > a.mission.MaxUniqueURLsPerAgent
+}
+
+// withoutFragment drops a URL's #fragment, which doesn't make it a new page
+func withoutFragment(rawURL string) string {
+	if i := strings.IndexByte(rawURL, '#'); i >= 0 {
+		return rawURL[:i]
+	}
+	return rawURL
+}
+
+// markVisitedLinks flags the page's links that lead to pages another agent
+// already visited
+func (a *RuntimeAgent) markVisitedLinks(page *models.StrippedPage) {
+	if a.visited == nil {
+		return
+	}
+	base, err := url.Parse(a.currentURL)
+	if err != nil {
+		return
+	}
+	for i := range page.InteractiveElements {
+		el := &page.InteractiveElements[i]
+		el.Visited = false
+		if el.Href == "" {
+			continue
+		}
+		if u, err := base.Parse(el.Href); err == nil {
+			u.Fragment = ""
+			el.Visited = a.visited.VisitedByOther(u.String(), a.id)
+		}
+	}
+}
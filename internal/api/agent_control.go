@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// agentRegistry holds the cancel function of every running agent, per mission,
+// so single agents can be stopped without ending their mission
+type agentRegistry struct {
+	mu       sync.Mutex
+	missions map[string]map[string]context.CancelFunc
+}
+
+func newAgentRegistry() *agentRegistry {
+	return &agentRegistry{missions: make(map[string]map[string]context.CancelFunc)}
+}
+
+// add derives a cancellable context for an agent from its mission's context
+func (r *agentRegistry) add(ctx context.Context, missionID, agentID string) context.Context {
+	agentCtx, cancel := context.WithCancel(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	agents := r.missions[missionID]
+	if agents == nil {
+		agents = make(map[string]context.CancelFunc)
+		r.missions[missionID] = agents
+	}
+	agents[agentID] = cancel
+	return agentCtx
+}
+
+// remove releases an agent's context once it has finished
+func (r *agentRegistry) remove(missionID, agentID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cancel, ok := r.missions[missionID][agentID]; ok {
+		cancel()
+		delete(r.missions[missionID], agentID)
+		if len(r.missions[missionID]) == 0 {
+			delete(r.missions, missionID)
+		}
+	}
+}
+
+// running is how many of the mission's agents are running
+func (r *agentRegistry) running(missionID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.missions[missionID])
+}
+
+// stop cancels a running agent, reporting false if it isn't running
+func (r *agentRegistry) stop(missionID, agentID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cancel, ok := r.missions[missionID][agentID]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// handleStopAgent cancels one agent of a running mission; the agent finishes
+// its current step and ends in the "stopped" status
+func (api *RESTAPI) handleStopAgent(w http.ResponseWriter, r *http.Request, missionID, agentID string) {
+	if !api.agents.stop(missionID, agentID) {
+		if _, exists := api.store.GetAgent(r.Context(), missionID, agentID); exists {
+			http.Error(w, "Agent is not running", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Agent not found", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("Mission %s: stopping agent %s on request", missionID, agentID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"agent_id": agentID,
+		"status":   "stopping",
+	})
+}
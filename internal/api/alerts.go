@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"swarmtest/internal/models"
+)
+
+const (
+	// alertCheckInterval matches the event logger's metrics flush, so every
+	// check sees fresh totals
+	alertCheckInterval = 5 * time.Second
+	// alertSustainTicks is how many consecutive checks must breach the threshold
+	// before an alert fires, and stay below it before the alert re-arms
+	alertSustainTicks = 3
+	// minAlertAttempts ignores checks with too few attempts to give a meaningful rate
+	minAlertAttempts = 5
+)
+
+// errorRateMonitor tracks a mission's error rate between checks and decides
+// when a sustained breach should raise an alert
+type errorRateMonitor struct {
+	threshold float64
+
+	lastActions, lastErrors int
+	breaches, recoveries    int
+	alerting                bool
+}
+
+// observe takes the mission's cumulative totals and returns the error rate over
+// the last interval, and whether an alert should fire now
+func (m *errorRateMonitor) observe(totalActions, totalErrors int) (rate float64, fire bool) {
+	actions := totalActions - m.lastActions
+	errors := totalErrors - m.lastErrors
+	m.lastActions, m.lastErrors = totalActions, totalErrors
+
+	if actions+errors < minAlertAttempts {
+		return 0, false
+	}
+	rate = float64(errors) / float64(actions+errors) * 100
+
+	if rate < m.threshold {
+		m.breaches = 0
+		if m.alerting {
+			m.recoveries++
+			if m.recoveries >= alertSustainTicks {
+				m.alerting = false
+			}
+		}
+		return rate, false
+	}
+
+	m.recoveries = 0
+	m.breaches++
+	if m.alerting || m.breaches < alertSustainTicks {
+		return rate, false
+	}
+	m.alerting = true
+	return rate, true
+}
+
+// watchMission blocks until ctx is done, raising an alert whenever the
+// mission's error rate stays above its alert_error_rate_percent
+func (api *RESTAPI) watchMission(ctx context.Context, mission *models.Mission) {
+	if mission.AlertErrorRatePercent <= 0 {
+		<-ctx.Done()
+		return
+	}
+
+	monitor := &errorRateMonitor{threshold: mission.AlertErrorRatePercent}
+	if latest, ok := api.store.Get(ctx, mission.ID); ok {
+		// A resumed mission starts from its existing totals
+		monitor.lastActions, monitor.lastErrors = latest.TotalActions, latest.TotalErrors
+	}
+
+	ticker := time.NewTicker(alertCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			latest, ok := api.store.Get(ctx, mission.ID)
+			if !ok {
+				continue
+			}
+			if rate, fire := monitor.observe(latest.TotalActions, latest.TotalErrors); fire {
+				api.raiseAlert(latest, rate)
+			}
+		}
+	}
+}
+
+// budgetExhausted cancels a mission stopped by one of its budgets
+type budgetExhausted struct {
+	budget string
+}
+
+func (e budgetExhausted) Error() string {
+	return e.budget + " budget exhausted"
+}
+
+// StopMission stops a running mission because the event logger found one of
+// its budgets used up; the budget becomes the mission's stop reason
+func (api *RESTAPI) StopMission(missionID, budget string) {
+	api.liveMu.Lock()
+	defer api.liveMu.Unlock()
+	if cancel, ok := api.running[missionID]; ok {
+		cancel(budgetExhausted{budget: budget})
+	}
+}
+
+// stopReason is why the mission's context was cancelled early, or "" if it
+// ran its course
+func stopReason(ctx context.Context) string {
+	cause := context.Cause(ctx)
+	var budget budgetExhausted
+	switch {
+	case errors.As(cause, &budget):
+		return budget.budget
+	case errors.Is(cause, errDrained):
+		return stopReasonDrained
+	}
+	return ""
+}
+
+// raiseAlert broadcasts an alert event and posts it to the mission's webhook
+func (api *RESTAPI) raiseAlert(mission *models.Mission, rate float64) {
+	alert := &models.AlertEvent{
+		MissionID:        mission.ID,
+		Kind:             "error_rate",
+		ErrorRatePercent: rate,
+		ThresholdPercent: mission.AlertErrorRatePercent,
+		WindowSeconds:    int(alertSustainTicks * alertCheckInterval / time.Second),
+		Message: fmt.Sprintf("Error rate %.1f%% has been above %.1f%% for %d checks",
+			rate, mission.AlertErrorRatePercent, alertSustainTicks),
+	}
+	log.Printf("Mission %s: ALERT: %s", mission.ID, alert.Message)
+
+	select {
+	case api.eventBus <- models.Event{Type: "alert", Timestamp: time.Now(), Data: alert}:
+	default:
+		log.Printf("Mission %s: event bus full, alert not broadcast", mission.ID)
+	}
+
+	if mission.WebhookURL != "" {
+		go api.deliverWebhook(mission.ID, mission.WebhookURL, models.WebhookPayload{
+			Event:     "mission_alert",
+			MissionID: mission.ID,
+			Name:      mission.Name,
+			Status:    mission.Status,
+			Summary:   api.missionSummary(mission),
+			Alert:     alert,
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import "testing"
+
+func TestErrorRateMonitor(t *testing.T) {
+	// Each check adds actions and errors to the mission's running totals
+	type check struct {
+		actions, errors int
+		fire            bool
+	}
+	tests := []struct {
+		name   string
+		checks []check
+	}{
+		{"fires once the breach is sustained", []check{
+			{5, 5, false}, {5, 5, false}, {5, 5, true}, {5, 5, false},
+		}},
+		{"a dip resets the breach", []check{
+			{5, 5, false}, {5, 5, false}, {10, 0, false}, {5, 5, false}, {5, 5, false}, {5, 5, true},
+		}},
+		{"quiet checks are ignored", []check{
+			{5, 5, false}, {5, 5, false}, {1, 1, false}, {5, 5, true},
+		}},
+		{"re-arms after a sustained recovery", []check{
+			{5, 5, false}, {5, 5, false}, {5, 5, true},
+			{10, 0, false}, {10, 0, false}, {10, 0, false},
+			{5, 5, false}, {5, 5, false}, {5, 5, true},
+		}},
+		{"a brief recovery doesn't re-arm", []check{
+			{5, 5, false}, {5, 5, false}, {5, 5, true},
+			{10, 0, false}, {10, 0, false},
+			{5, 5, false}, {5, 5, false}, {5, 5, false},
+		}},
+		{"below the threshold", []check{
+			{9, 1, false}, {9, 1, false}, {9, 1, false}, {9, 1, false},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &errorRateMonitor{threshold: 20}
+			var actions, errors int
+			for i, c := range tt.checks {
+				actions, errors = actions+c.actions, errors+c.errors
+				if _, fire := m.observe(actions, errors); fire != c.fire {
+					t.Errorf("check %d: fire = %v, want %v", i+1, fire, c.fire)
+				}
+			}
+		})
+	}
+}
+
+func TestErrorRateMonitorRate(t *testing.T) {
+	m := &errorRateMonitor{threshold: 50, lastActions: 100, lastErrors: 10}
+	// A resumed mission's earlier totals don't count toward the rate
+	if rate, _ := m.observe(106, 14); rate != 40 {
+		t.Errorf("rate = %v, want 40 (4 errors in 10 attempts)", rate)
+	}
+	if rate, _ := m.observe(107, 15); rate != 0 {
+		t.Errorf("rate over too few attempts = %v, want 0", rate)
+	}
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIKeyQueryParam carries the API key on /ws and /api/events, since browsers
+// can't set headers on a WebSocket handshake or an EventSource request
+const APIKeyQueryParam = "access_token"
+
+// PublicPaths are served without an API key
+var PublicPaths = []string{"/api/health"}
+
+// APIKeyScope is what an API key may do
+type APIKeyScope string
+
+const (
+	// ScopeRead allows GET requests: viewing missions, logs, metrics and events
+	ScopeRead APIKeyScope = "read"
+	// ScopeWrite additionally allows launching, stopping and deleting missions
+	ScopeWrite APIKeyScope = "write"
+	// ScopeAdmin additionally allows the /api/admin/ endpoints, such as draining
+	// every mission
+	ScopeAdmin APIKeyScope = "admin"
+)
+
+// APIKey is a configured key and its scope
+type APIKey struct {
+	Key   string
+	Scope APIKeyScope
+}
+
+// ParseAPIKeys parses "key" or "key:scope" entries. A key without a scope
+// gets ScopeWrite, so keys configured before scopes existed keep working.
+func ParseAPIKeys(entries []string) ([]APIKey, error) {
+	keys := make([]APIKey, 0, len(entries))
+	for _, entry := range entries {
+		key, scope, hasScope := strings.Cut(entry, ":")
+		if key == "" {
+			return nil, fmt.Errorf("empty API key")
+		}
+		if !hasScope {
+			scope = string(ScopeWrite)
+		}
+		switch APIKeyScope(scope) {
+		case ScopeRead, ScopeWrite, ScopeAdmin:
+		default:
+			return nil, fmt.Errorf("API key has unknown scope %q (must be read, write or admin)", scope)
+		}
+		keys = append(keys, APIKey{Key: key, Scope: APIKeyScope(scope)})
+	}
+	return keys, nil
+}
+
+// requiredScope is the scope a request needs: admin for /api/admin/, reads
+// for GET, writes otherwise
+func requiredScope(r *http.Request) APIKeyScope {
+	if strings.HasPrefix(r.URL.Path, "/api/admin/") {
+		return ScopeAdmin
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return ScopeRead
+	}
+	return ScopeWrite
+}
+
+// allows reports whether a key with scope s may make a request needing required
+func (s APIKeyScope) allows(required APIKeyScope) bool {
+	switch s {
+	case ScopeAdmin:
+		return true
+	case ScopeWrite:
+		return required != ScopeAdmin
+	}
+	return s == required
+}
+
+// RequireAPIKey rejects requests to /api/* and /ws with 401 unless they carry
+// one of keys as "Authorization: Bearer <key>", and with 403 when the key's
+// scope doesn't cover the request. With no keys configured every request is
+// let through. CORS preflights are answered by the CORS middleware before
+// they reach it.
+func RequireAPIKey(keys []APIKey, next http.Handler) http.Handler {
+	if len(keys) == 0 {
+		return next
+	}
+
+	// Compare digests so the comparison time doesn't depend on key length
+	digests := make([][sha256.Size]byte, len(keys))
+	for i, key := range keys {
+		digests[i] = sha256.Sum256([]byte(key.Key))
+	}
+	public := make(map[string]bool, len(PublicPaths))
+	for _, path := range PublicPaths {
+		public[path] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		protected := strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == "/ws"
+		if !protected || public[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := bearerToken(r)
+		if token == "" && (r.URL.Path == "/ws" || r.URL.Path == "/api/events") {
+			token = r.URL.Query().Get(APIKeyQueryParam)
+		}
+		key, ok := matchAPIKey(keys, digests, token)
+		if token == "" || !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="swarmtest"`)
+			http.Error(w, "Missing or invalid API key", http.StatusUnauthorized)
+			return
+		}
+		if required := requiredScope(r); !key.Scope.allows(required) {
+			http.Error(w, fmt.Sprintf("API key lacks the %s scope", required), http.StatusForbidden)
+			return
+		}
+
+		digest := sha256.Sum256([]byte(key.Key))
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyIDKey{}, hex.EncodeToString(digest[:]))))
+	})
+}
+
+type apiKeyIDKey struct{}
+
+// authenticatedKeyID identifies the API key RequireAPIKey authenticated the
+// request with, by its SHA-256 so the key itself isn't kept; it is "" when
+// the request wasn't authenticated
+func authenticatedKeyID(r *http.Request) string {
+	id, _ := r.Context().Value(apiKeyIDKey{}).(string)
+	return id
+}
+
+// bearerToken returns the token from the Authorization header, if any
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// matchAPIKey finds the key matching token, checking every key so the time
+// taken doesn't reveal which one matched
+func matchAPIKey(keys []APIKey, digests [][sha256.Size]byte, token string) (APIKey, bool) {
+	digest := sha256.Sum256([]byte(token))
+	var found APIKey
+	matched := false
+	for i, d := range digests {
+		if subtle.ConstantTimeCompare(d[:], digest[:]) == 1 {
+			found, matched = keys[i], true
+		}
+	}
+	return found, matched
+}
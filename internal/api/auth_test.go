@@ -0,0 +1,126 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// authRequest sends a request through handler with the given Authorization
+// header, if any
+func authRequest(handler http.Handler, method, target, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRequireAPIKey(t *testing.T) {
+	handler := RequireAPIKey([]APIKey{{Key: "secret-key", Scope: ScopeWrite}}, okHandler())
+
+	tests := []struct {
+		name          string
+		method        string
+		target        string
+		authorization string
+		want          int
+	}{
+		{"valid bearer token", http.MethodGet, "/api/missions", "Bearer secret-key", http.StatusOK},
+		{"scheme is case-insensitive", http.MethodGet, "/api/missions", "bearer secret-key", http.StatusOK},
+		{"missing token", http.MethodGet, "/api/missions", "", http.StatusUnauthorized},
+		{"wrong token", http.MethodGet, "/api/missions", "Bearer other-key", http.StatusUnauthorized},
+		{"not a bearer token", http.MethodGet, "/api/missions", "Basic c2VjcmV0LWtleQ==", http.StatusUnauthorized},
+		{"scheme without token", http.MethodGet, "/api/missions", "Bearer", http.StatusUnauthorized},
+		{"empty bearer token", http.MethodGet, "/api/missions", "Bearer  ", http.StatusUnauthorized},
+		{"websocket without token", http.MethodGet, "/ws", "", http.StatusUnauthorized},
+		{"health is public", http.MethodGet, "/api/health", "", http.StatusOK},
+		{"outside the API", http.MethodGet, "/index.html", "", http.StatusOK},
+		{"query token on websocket", http.MethodGet, "/ws?access_token=secret-key", "", http.StatusOK},
+		{"query token on event stream", http.MethodGet, "/api/events?access_token=secret-key", "", http.StatusOK},
+		{"wrong query token on websocket", http.MethodGet, "/ws?access_token=other-key", "", http.StatusUnauthorized},
+		{"query token elsewhere", http.MethodGet, "/api/missions?access_token=secret-key", "", http.StatusUnauthorized},
+		{"query token on a mutation", http.MethodPost, "/api/missions?access_token=secret-key", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := authRequest(handler, tt.method, tt.target, tt.authorization)
+			if rec.Code != tt.want {
+				t.Fatalf("%s %s: status %d, want %d", tt.method, tt.target, rec.Code, tt.want)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("401 without a WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestRequireAPIKeyWithoutKeys(t *testing.T) {
+	handler := RequireAPIKey(nil, okHandler())
+
+	for _, target := range []string{"/api/missions", "/api/admin/drain", "/ws", "/api/health"} {
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodDelete} {
+			if rec := authRequest(handler, method, target, ""); rec.Code != http.StatusOK {
+				t.Errorf("%s %s without keys configured: status %d, want 200", method, target, rec.Code)
+			}
+		}
+	}
+}
+
+func TestRequireAPIKeyScopes(t *testing.T) {
+	handler := RequireAPIKey([]APIKey{
+		{Key: "read-key", Scope: ScopeRead},
+		{Key: "write-key", Scope: ScopeWrite},
+		{Key: "admin-key", Scope: ScopeAdmin},
+	}, okHandler())
+
+	tests := []struct {
+		key    string
+		method string
+		target string
+		want   int
+	}{
+		{"read-key", http.MethodGet, "/api/missions", http.StatusOK},
+		{"read-key", http.MethodHead, "/api/missions", http.StatusOK},
+		{"read-key", http.MethodPost, "/api/missions", http.StatusForbidden},
+		{"read-key", http.MethodDelete, "/api/missions/m1", http.StatusForbidden},
+		{"read-key", http.MethodPost, "/api/admin/drain", http.StatusForbidden},
+		{"write-key", http.MethodGet, "/api/missions", http.StatusOK},
+		{"write-key", http.MethodPost, "/api/missions", http.StatusOK},
+		{"write-key", http.MethodDelete, "/api/missions/m1", http.StatusOK},
+		{"write-key", http.MethodPost, "/api/admin/drain", http.StatusForbidden},
+		{"write-key", http.MethodGet, "/api/admin/drain", http.StatusForbidden},
+		{"admin-key", http.MethodPost, "/api/missions", http.StatusOK},
+		{"admin-key", http.MethodPost, "/api/admin/drain", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if rec := authRequest(handler, tt.method, tt.target, "Bearer "+tt.key); rec.Code != tt.want {
+			t.Errorf("%s: %s %s = %d, want %d", tt.key, tt.method, tt.target, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := ParseAPIKeys([]string{"legacy", "viewer:read", "ci:write", "ops:admin"})
+	if err != nil {
+		t.Fatalf("ParseAPIKeys: %v", err)
+	}
+	want := []APIKey{
+		{Key: "legacy", Scope: ScopeWrite},
+		{Key: "viewer", Scope: ScopeRead},
+		{Key: "ci", Scope: ScopeWrite},
+		{Key: "ops", Scope: ScopeAdmin},
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("ParseAPIKeys = %v, want %v", keys, want)
+	}
+
+	for _, entries := range [][]string{{"ops:root"}, {"ops:"}, {"ops:Admin"}, {":read"}} {
+		if keys, err := ParseAPIKeys(entries); err == nil {
+			t.Errorf("ParseAPIKeys(%q) = %v, want an error", entries, keys)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"swarmtest/internal/models"
+)
+
+// handleCompareMissions diffs two missions, e.g. two runs of the same scenario
+// either side of a deploy: GET /api/missions/compare?a={id}&b={id}
+func (api *RESTAPI) handleCompareMissions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idA, idB := r.URL.Query().Get("a"), r.URL.Query().Get("b")
+	if idA == "" || idB == "" {
+		http.Error(w, "Both a and b mission IDs are required", http.StatusBadRequest)
+		return
+	}
+
+	a, exists := api.store.Get(r.Context(), idA)
+	if !exists {
+		http.Error(w, "Mission not found: "+idA, http.StatusNotFound)
+		return
+	}
+	b, exists := api.store.Get(r.Context(), idB)
+	if !exists {
+		http.Error(w, "Mission not found: "+idB, http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(compareMissions(a, b))
+}
+
+// compareMissions builds the diff of b against a
+func compareMissions(a, b *models.Mission) *models.MissionComparison {
+	summaryA, summaryB := buildMissionSummary(a), buildMissionSummary(b)
+
+	return &models.MissionComparison{
+		A: comparedMission(a, summaryA),
+		B: comparedMission(b, summaryB),
+		Metrics: []models.MetricDiff{
+			metricDiff("completion_rate_percent", completionRate(a), completionRate(b), true),
+			metricDiff("pass_rate_percent", summaryA.PassRatePercent, summaryB.PassRatePercent, true),
+			metricDiff("error_rate_percent", summaryA.ErrorRatePercent, summaryB.ErrorRatePercent, false),
+			metricDiff("average_latency_ms", float64(summaryA.AverageLatencyMS), float64(summaryB.AverageLatencyMS), false),
+			metricDiff("unique_urls", float64(summaryA.UniqueURLs), float64(summaryB.UniqueURLs), true),
+			metricDiff("js_errors", float64(summaryA.JSErrors), float64(summaryB.JSErrors), false),
+			metricDiff("network_failures", float64(summaryA.NetworkFailures), float64(summaryB.NetworkFailures), false),
+		},
+		AgentChanges: agentOutcomeChanges(a, b),
+	}
+}
+
+func comparedMission(m *models.Mission, summary *models.SummaryEvent) models.ComparedMission {
+	return models.ComparedMission{
+		ID:        m.ID,
+		Name:      m.Name,
+		Status:    m.Status,
+		CreatedAt: m.CreatedAt,
+		Summary:   summary,
+	}
+}
+
+func metricDiff(name string, a, b float64, higherIsBetter bool) models.MetricDiff {
+	diff := models.MetricDiff{
+		Name:           name,
+		A:              a,
+		B:              b,
+		Delta:          b - a,
+		HigherIsBetter: higherIsBetter,
+	}
+	if a != 0 {
+		percent := (b - a) / a * 100
+		diff.DeltaPercent = &percent
+	}
+	if higherIsBetter {
+		diff.Regression = b < a
+	} else {
+		diff.Regression = b > a
+	}
+	return diff
+}
+
+// completionRate is the percentage of the mission's agents that completed
+func completionRate(m *models.Mission) float64 {
+	if m.NumAgents == 0 {
+		return 0
+	}
+	return float64(m.CompletedAgents) / float64(m.NumAgents) * 100
+}
+
+// agentOutcomeChanges pairs the missions' agents by index and reports the
+// positions whose final status differs. A position's outcome is its last
+// retry's, if its agent was retried.
+func agentOutcomeChanges(a, b *models.Mission) []models.AgentOutcomeChange {
+	byIndex := func(m *models.Mission) map[int]*models.Agent {
+		agents := make(map[int]*models.Agent, len(m.AgentMetrics))
+		for id, agent := range m.AgentMetrics {
+			i := agentIndex(id)
+			if prev := agents[i]; prev == nil || retryAttempt(id) > retryAttempt(prev.ID) {
+				agents[i] = agent
+			}
+		}
+		return agents
+	}
+	agentsA, agentsB := byIndex(a), byIndex(b)
+
+	indexes := make(map[int]bool, len(agentsA)+len(agentsB))
+	for i := range agentsA {
+		indexes[i] = true
+	}
+	for i := range agentsB {
+		indexes[i] = true
+	}
+
+	changes := []models.AgentOutcomeChange{}
+	for i := range indexes {
+		change := models.AgentOutcomeChange{AgentIndex: i}
+		if agent := agentsA[i]; agent != nil {
+			change.AgentA, change.StatusA, change.ErrorsA = agent.ID, agent.Status, agent.ErrorCount
+		}
+		if agent := agentsB[i]; agent != nil {
+			change.AgentB, change.StatusB, change.ErrorsB = agent.ID, agent.Status, agent.ErrorCount
+		}
+		if change.StatusA != change.StatusB {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].AgentIndex < changes[j].AgentIndex })
+	return changes
+}
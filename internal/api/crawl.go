@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"log"
+
+	"swarmtest/internal/models"
+	"swarmtest/internal/utils"
+)
+
+// startCrawl creates the shared frontier of a crawl mission, picking up from
+// its saved sitemap when the mission is resumed. The mission ends early, by
+// cancelling its context, once every reachable page has been crawled.
+func (api *RESTAPI) startCrawl(ctx context.Context, cancel context.CancelFunc, mission *models.Mission) *utils.Crawler {
+	crawl := utils.NewCrawler(mission.TargetURL, mission.CrawlStrategy, mission.CrawlMaxDepth, mission.CrawlMaxPages)
+	if saved, ok := api.store.GetSitemap(ctx, mission.ID); ok {
+		log.Printf("Mission %s: resuming crawl with %d pages already visited", mission.ID, len(saved.Pages))
+		crawl.Restore(saved)
+	}
+
+	api.liveMu.Lock()
+	api.crawls[mission.ID] = crawl
+	api.liveMu.Unlock()
+
+	go func() {
+		select {
+		case <-crawl.Done():
+			log.Printf("Mission %s: crawl finished", mission.ID)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return crawl
+}
+
+// finishCrawl saves the mission's sitemap and drops its live frontier
+func (api *RESTAPI) finishCrawl(missionID string, crawl *utils.Crawler) {
+	api.store.PutSitemap(context.Background(), crawl.Sitemap(missionID))
+
+	api.liveMu.Lock()
+	delete(api.crawls, missionID)
+	api.liveMu.Unlock()
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"swarmtest/internal/gemini"
+	"swarmtest/internal/models"
+	"swarmtest/internal/utils"
+)
+
+const (
+	// debugDecisionsPerMinute and debugDecisionsBurst limit POST
+	// /api/debug/decide server-wide
+	debugDecisionsPerMinute = 10
+	debugDecisionsBurst     = 3
+)
+
+// parseRequest is the body of POST /api/debug/parse: a page to fetch, or raw
+// HTML to parse as if it were served from URL
+type parseRequest struct {
+	URL  string `json:"url"`
+	HTML string `json:"html,omitempty"`
+	// ElementTypes limits the parse as the mission option of the same name does
+	ElementTypes []string `json:"element_types,omitempty"`
+}
+
+// decideRequest is the body of POST /api/debug/decide: a goal and the page to
+// decide on, given parsed or as for POST /api/debug/parse
+type decideRequest struct {
+	parseRequest
+	Page *models.StrippedPage `json:"page,omitempty"`
+
+	Goal                string                 `json:"goal"`
+	InitialSystemPrompt string                 `json:"initial_system_prompt,omitempty"`
+	ExecutionMode       models.ExecutionMode   `json:"execution_mode,omitempty"`
+	ActionHistory       []string               `json:"action_history,omitempty"`
+	MaxPromptTokens     int                    `json:"max_prompt_tokens,omitempty"`
+	PromptExamples      []models.PromptExample `json:"prompt_examples,omitempty"`
+}
+
+// decideResponse is the decision for a page with the prompt that produced it
+type decideResponse struct {
+	Prompt   string                         `json:"prompt"`
+	Decision *models.GeminiDecisionResponse `json:"decision"`
+	Usage    decisionUsage                  `json:"usage"`
+}
+
+// decisionUsage is what a decision cost
+type decisionUsage struct {
+	Model        string  `json:"model"`
+	PromptTokens int64   `json:"prompt_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"estimated_cost_usd"`
+	CacheHit     bool    `json:"cache_hit"`
+}
+
+// handleDebugParse returns the StrippedPage agents would decide from for a
+// URL or some HTML, without launching a mission: POST /api/debug/parse
+func (api *RESTAPI) handleDebugParse(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Room for a page of the largest size allowed, plus the JSON around it
+	r.Body = http.MaxBytesReader(w, r.Body, int64(2*api.DefaultMaxPageSize))
+	var req parseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateParseRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, status, err := api.debugPage(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+// handleDebugDecide asks Gemini for an agent's next action on a page and
+// returns the decision, the exact prompt sent and its token usage, without
+// executing anything: POST /api/debug/decide
+func (api *RESTAPI) handleDebugDecide(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, int64(2*api.DefaultMaxPageSize))
+	var req decideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateDecideRequest(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !allowRequest(w, api.debugDecisions) {
+		return
+	}
+
+	page := req.Page
+	if page == nil {
+		var status int
+		var err error
+		if page, status, err = api.debugPage(r.Context(), req.parseRequest); err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	mission := &models.Mission{
+		ID:                  "debug",
+		Goal:                req.Goal,
+		InitialSystemPrompt: req.InitialSystemPrompt,
+		ExecutionMode:       req.ExecutionMode,
+	}
+	mission.MaxPromptTokens = req.MaxPromptTokens
+	mission.PromptExamples = req.PromptExamples
+	agent := &models.Agent{
+		ID:            "debug-agent",
+		MissionID:     mission.ID,
+		CurrentURL:    page.URL,
+		ExecutionMode: req.ExecutionMode,
+		ActionHistory: req.ActionHistory,
+	}
+
+	decision, err := api.gemini.DecideNextAction(r.Context(), mission, agent, page)
+	if errors.Is(err, gemini.ErrCircuitOpen) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Gemini decision failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	json.NewEncoder(w).Encode(decideResponse{
+		Prompt:   gemini.BuildPrompt(mission, agent, page),
+		Decision: decision,
+		Usage: decisionUsage{
+			Model:        decision.Metadata.Model,
+			PromptTokens: decision.Metadata.PromptTokens,
+			OutputTokens: decision.Metadata.OutputTokens,
+			CostUSD:      decision.Metadata.CostUSD,
+			CacheHit:     decision.Metadata.CacheHit,
+		},
+	})
+}
+
+// debugPage parses the request's HTML, or fetches its URL the way an
+// HTTP-mode agent fetches its first page, following redirects. A failure comes
+// with the status to answer it with.
+func (api *RESTAPI) debugPage(ctx context.Context, req parseRequest) (*models.StrippedPage, int, error) {
+	parser := utils.NewHTMLParserFor(req.ElementTypes)
+	if req.HTML != "" {
+		page, err := parser.ParseHTMLString(req.URL, req.HTML)
+		if err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("failed to parse HTML: %w", err)
+		}
+		return page, 0, nil
+	}
+
+	client := utils.NewHTTPClientFactory(utils.HTTPClientOptions{FollowRedirects: true, MaxPageSize: api.DefaultMaxPageSize})
+	fetchReq, err := http.NewRequestWithContext(ctx, "GET", req.URL, nil)
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid url")
+	}
+	fetchReq.Header.Set("User-Agent", "SwarmTest/1.0")
+	resp, err := client.Do(fetchReq)
+	if err != nil {
+		return nil, http.StatusBadGateway, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	body, err := utils.ReadBody(resp)
+	resp.Body.Close()
+	if errors.Is(err, utils.ErrPageTooLarge) {
+		return utils.OversizedPage(req.URL, api.DefaultMaxPageSize), 0, nil
+	}
+	if err != nil {
+		return nil, http.StatusBadGateway, fmt.Errorf("failed to fetch page: %w", err)
+	}
+
+	page, err := parser.ParseResponse(resp.Request.URL.String(), resp.Header.Get("Content-Type"), string(body))
+	if err != nil {
+		return nil, http.StatusBadGateway, fmt.Errorf("failed to parse page: %w", err)
+	}
+	return page, 0, nil
+}
+
+// validateParseRequest checks the URL, which is required unless HTML is given,
+// and the element types
+func validateParseRequest(req *parseRequest) error {
+	req.URL = strings.TrimSpace(req.URL)
+	if req.URL == "" && req.HTML == "" {
+		return fmt.Errorf("url or html is required")
+	}
+	if req.URL != "" {
+		u, err := url.Parse(req.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an absolute http or https URL")
+		}
+	}
+	for _, t := range req.ElementTypes {
+		if !utils.ElementTypes[t] {
+			return fmt.Errorf("element_types must only contain link, button, input and form")
+		}
+	}
+	return nil
+}
+
+// validateDecideRequest checks the goal and either the page or what to parse
+func validateDecideRequest(req *decideRequest) error {
+	req.Goal = strings.TrimSpace(req.Goal)
+	if req.Goal == "" {
+		return fmt.Errorf("goal is required")
+	}
+	switch req.ExecutionMode {
+	case "", models.ExecutionModeHTTP, models.ExecutionModeBrowser:
+	default:
+		return fmt.Errorf("execution_mode must be http or browser")
+	}
+	if req.MaxPromptTokens < 0 {
+		return fmt.Errorf("max_prompt_tokens must not be negative")
+	}
+	if err := validatePromptExamples(req.PromptExamples); err != nil {
+		return err
+	}
+	if req.Page != nil {
+		return nil
+	}
+	return validateParseRequest(&req.parseRequest)
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"swarmtest/internal/models"
+)
+
+// stopReasonDrained is the stop reason of a mission stopped by a drain
+const stopReasonDrained = "drained"
+
+// errDrained cancels the missions stopped by a drain
+var errDrained = errors.New("missions drained by an operator")
+
+// drainResponse reports the state of the kill switch and, for a drain, the
+// missions it stopped
+type drainResponse struct {
+	Draining        bool     `json:"draining"`
+	StoppedMissions []string `json:"stopped_missions,omitempty"`
+	StoppedAgents   int      `json:"stopped_agents,omitempty"`
+}
+
+// trackRunning registers a running mission's cancel function, so a drain can
+// stop it, until the returned func is called. It reports false, registering
+// nothing, if missions are already drained: checked under liveMu, a drain
+// either sees the mission or the mission sees the drain.
+func (api *RESTAPI) trackRunning(missionID string, cancel context.CancelCauseFunc) (func(), bool) {
+	api.liveMu.Lock()
+	defer api.liveMu.Unlock()
+	if api.draining.Load() {
+		return nil, false
+	}
+	api.running[missionID] = cancel
+	return func() {
+		api.liveMu.Lock()
+		delete(api.running, missionID)
+		api.liveMu.Unlock()
+	}, true
+}
+
+// finishDrained finalizes a mission that was accepted but reached runMission
+// after a drain began, without launching its agents
+func (api *RESTAPI) finishDrained(mission *models.Mission, agents []*models.Agent) {
+	log.Printf("Mission %s drained before its agents launched", mission.ID)
+	if mission.AgentMetrics == nil {
+		mission.AgentMetrics = make(map[string]*models.Agent)
+	}
+	for _, state := range agents {
+		state.Status = "stopped"
+		mission.AgentMetrics[state.ID] = state
+		api.store.PutAgent(context.Background(), state)
+	}
+
+	mission.Status = "completed"
+	mission.StopReason = stopReasonDrained
+	completedAt := time.Now()
+	mission.CompletedAt = &completedAt
+	api.store.Put(context.Background(), mission)
+	api.emitMissionEvent("mission_completed", mission.ID)
+}
+
+// drain refuses new missions and stops every running one, returning the IDs
+// of the missions stopped and how many agents they had running
+func (api *RESTAPI) drain() ([]string, int) {
+	api.draining.Store(true)
+
+	api.liveMu.Lock()
+	defer api.liveMu.Unlock()
+	stopped := make([]string, 0, len(api.running))
+	agents := 0
+	for missionID, cancel := range api.running {
+		agents += api.agents.running(missionID)
+		cancel(errDrained)
+		stopped = append(stopped, missionID)
+	}
+	sort.Strings(stopped)
+	return stopped, agents
+}
+
+// refuseWhileDraining answers 503 and reports true while missions are drained
+func (api *RESTAPI) refuseWhileDraining(w http.ResponseWriter) bool {
+	if !api.draining.Load() {
+		return false
+	}
+	http.Error(w, "Missions are drained; new missions are refused until an operator re-enables them", http.StatusServiceUnavailable)
+	return true
+}
+
+// handleAdminDrain is the kill switch: POST stops every running mission and
+// refuses new ones, DELETE re-enables missions, and GET reports the state
+func (api *RESTAPI) handleAdminDrain(w http.ResponseWriter, r *http.Request) {
+	var resp drainResponse
+	switch r.Method {
+	case http.MethodGet:
+		resp.Draining = api.draining.Load()
+	case http.MethodPost:
+		resp.StoppedMissions, resp.StoppedAgents = api.drain()
+		resp.Draining = true
+		log.Printf("DRAIN: stopped %d missions (%d running agents); new missions are refused", len(resp.StoppedMissions), resp.StoppedAgents)
+	case http.MethodDelete:
+		api.draining.Store(false)
+		log.Printf("DRAIN: lifted; missions are accepted again")
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"swarmtest/internal/agent"
+)
+
+func TestDrainCancelsTrackedMissions(t *testing.T) {
+	api := NewRESTAPI(context.Background(), nil, nil, nil, agent.NewDropCounter(), 1)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	untrack, ok := api.trackRunning("m1", cancel)
+	if !ok {
+		t.Fatal("trackRunning refused a mission before any drain")
+	}
+	defer untrack()
+
+	stopped, _ := api.drain()
+	if len(stopped) != 1 || stopped[0] != "m1" {
+		t.Errorf("drain stopped %v, want [m1]", stopped)
+	}
+	if !errors.Is(context.Cause(ctx), errDrained) {
+		t.Errorf("mission context cause = %v, want errDrained", context.Cause(ctx))
+	}
+}
+
+func TestTrackRunningRefusedWhileDraining(t *testing.T) {
+	api := NewRESTAPI(context.Background(), nil, nil, nil, agent.NewDropCounter(), 1)
+	api.drain()
+
+	_, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	if _, ok := api.trackRunning("m1", cancel); ok {
+		t.Fatal("trackRunning accepted a mission while draining")
+	}
+	if len(api.running) != 0 {
+		t.Errorf("refused mission was registered: %v", api.running)
+	}
+}
+
+// Every mission racing a drain is either refused or stopped by it
+func TestTrackRunningRacesDrain(t *testing.T) {
+	api := NewRESTAPI(context.Background(), nil, nil, nil, agent.NewDropCounter(), 1)
+
+	const missions = 50
+	ctxs := make([]context.Context, missions)
+	tracked := make([]bool, missions)
+	var wg sync.WaitGroup
+	for i := 0; i < missions; i++ {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
+		ctxs[i] = ctx
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, tracked[i] = api.trackRunning(fmt.Sprintf("m%d", i), cancel)
+		}(i)
+	}
+	api.drain()
+	wg.Wait()
+
+	for i := 0; i < missions; i++ {
+		if tracked[i] && !errors.Is(context.Cause(ctxs[i]), errDrained) {
+			t.Errorf("mission m%d started but was not stopped by the drain", i)
+		}
+	}
+}
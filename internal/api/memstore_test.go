@@ -0,0 +1,217 @@
+package api
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"swarmtest/internal/models"
+	"swarmtest/internal/store"
+)
+
+// memStore is an in-memory store.MissionStore for tests. Like the database it
+// keeps agents apart from their mission and hands out copies, so callers
+// can't change stored state without saving it.
+type memStore struct {
+	mu        sync.Mutex
+	missions  map[string]models.Mission
+	agents    map[string]map[string]models.Agent
+	logs      map[string][]models.ActionLog
+	findings  []models.Finding
+	coverage  map[string]*models.Coverage
+	sitemaps  map[string]*models.Sitemap
+	templates []*models.MissionTemplate
+}
+
+var _ store.MissionStore = (*memStore)(nil)
+
+func newMemStore() *memStore {
+	return &memStore{
+		missions: make(map[string]models.Mission),
+		agents:   make(map[string]map[string]models.Agent),
+		logs:     make(map[string][]models.ActionLog),
+		coverage: make(map[string]*models.Coverage),
+		sitemaps: make(map[string]*models.Sitemap),
+	}
+}
+
+func (s *memStore) Put(ctx context.Context, mission *models.Mission) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := *mission
+	m.AgentMetrics = nil
+	m.RecentEvents = nil
+	s.missions[m.ID] = m
+}
+
+func (s *memStore) PutAgent(ctx context.Context, agent *models.Agent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.agents[agent.MissionID] == nil {
+		s.agents[agent.MissionID] = make(map[string]models.Agent)
+	}
+	s.agents[agent.MissionID][agent.ID] = *agent
+}
+
+func (s *memStore) Get(ctx context.Context, id string) (*models.Mission, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.missions[id]
+	if !ok {
+		return nil, false
+	}
+	m.AgentMetrics = make(map[string]*models.Agent)
+	for agentID, a := range s.agents[id] {
+		a := a
+		m.AgentMetrics[agentID] = &a
+	}
+	m.RecentEvents = append([]models.ActionLog(nil), s.logs[id]...)
+	return &m, true
+}
+
+func (s *memStore) List(ctx context.Context, opts store.ListOptions) ([]*models.Mission, int) {
+	s.mu.Lock()
+	ids := make([]string, 0, len(s.missions))
+	for id, m := range s.missions {
+		if opts.Status == "" || m.Status == opts.Status {
+			ids = append(ids, id)
+		}
+	}
+	s.mu.Unlock()
+	sort.Strings(ids)
+
+	opts = opts.Normalize()
+	missions := []*models.Mission{}
+	for i := opts.Offset; i < len(ids) && len(missions) < opts.Limit; i++ {
+		if m, ok := s.Get(ctx, ids[i]); ok {
+			missions = append(missions, m)
+		}
+	}
+	return missions, len(ids)
+}
+
+func (s *memStore) FindByIdempotencyKey(ctx context.Context, caller, key string, since time.Time) (*models.Mission, bool) {
+	s.mu.Lock()
+	var found string
+	for id, m := range s.missions {
+		if m.IdempotencyKey == key && m.IdempotencyCaller == caller && !m.CreatedAt.Before(since) {
+			found = id
+		}
+	}
+	s.mu.Unlock()
+	if found == "" {
+		return nil, false
+	}
+	return s.Get(ctx, found)
+}
+
+func (s *memStore) AddActionLog(ctx context.Context, log models.ActionLog, missionID string) {
+	s.AddActionLogs(ctx, []models.ActionLog{log}, missionID)
+}
+
+func (s *memStore) AddActionLogs(ctx context.Context, logs []models.ActionLog, missionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logs[missionID] = append(s.logs[missionID], logs...)
+}
+
+func (s *memStore) ListActionLogs(ctx context.Context, missionID string) []models.ActionLog {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.ActionLog(nil), s.logs[missionID]...)
+}
+
+func (s *memStore) PutCoverage(ctx context.Context, coverage *models.Coverage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.coverage[coverage.MissionID] = coverage
+}
+
+func (s *memStore) GetCoverage(ctx context.Context, missionID string) (*models.Coverage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.coverage[missionID]
+	return c, ok
+}
+
+func (s *memStore) PutSitemap(ctx context.Context, sitemap *models.Sitemap) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sitemaps[sitemap.MissionID] = sitemap
+}
+
+func (s *memStore) GetSitemap(ctx context.Context, missionID string) (*models.Sitemap, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sm, ok := s.sitemaps[missionID]
+	return sm, ok
+}
+
+func (s *memStore) AddFindings(ctx context.Context, findings []models.Finding) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.findings = append(s.findings, findings...)
+}
+
+func (s *memStore) ListFindings(ctx context.Context, missionID string, filter store.FindingFilter) ([]models.Finding, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var findings []models.Finding
+	for _, f := range s.findings {
+		if f.MissionID == missionID && (filter.Type == "" || f.Type == filter.Type) {
+			findings = append(findings, f)
+		}
+	}
+	return findings, len(findings)
+}
+
+func (s *memStore) GetAgent(ctx context.Context, missionID, agentID string) (*models.Agent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.agents[missionID][agentID]
+	return &a, ok
+}
+
+func (s *memStore) ListAgentActionLogs(ctx context.Context, missionID, agentID string) []models.ActionLog {
+	var logs []models.ActionLog
+	for _, l := range s.ListActionLogs(ctx, missionID) {
+		if l.AgentID == agentID {
+			logs = append(logs, l)
+		}
+	}
+	return logs
+}
+
+func (s *memStore) PerformanceOffenders(ctx context.Context, missionID string, limit int) (slowest, largest []models.Finding) {
+	return nil, nil
+}
+
+func (s *memStore) CreateTemplate(ctx context.Context, template *models.MissionTemplate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.templates {
+		if t.Name == template.Name {
+			return store.ErrTemplateExists
+		}
+	}
+	s.templates = append(s.templates, template)
+	return nil
+}
+
+func (s *memStore) GetTemplate(ctx context.Context, idOrName string) (*models.MissionTemplate, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.templates {
+		if t.ID == idOrName || t.Name == idOrName {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func (s *memStore) ListTemplates(ctx context.Context) []*models.MissionTemplate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*models.MissionTemplate(nil), s.templates...)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"swarmtest/internal/utils"
+)
+
+const (
+	// DefaultAPIRateLimitPerMinute is how many mutating requests a client may make per minute
+	DefaultAPIRateLimitPerMinute = 60
+	// DefaultAPIRateLimitBurst is how many mutating requests a client may make at once
+	DefaultAPIRateLimitBurst = 10
+
+	// apiLimiterIdleTTL is how long an idle client's limiter is kept
+	apiLimiterIdleTTL = 10 * time.Minute
+)
+
+// clientLimiter is one client's token bucket
+type clientLimiter struct {
+	limiter  *utils.RateLimiter
+	lastSeen time.Time
+}
+
+// apiRateLimiter tracks a token bucket per client
+type apiRateLimiter struct {
+	rate  float64 // tokens per second
+	burst int
+
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+// RateLimitMutations limits each client's POST, PUT and DELETE requests to
+// /api/* to perMinute, with bursts of up to burst. Clients are told apart by
+// the API key RequireAPIKey authenticated, or by IP without one. Requests over
+// the limit get 429 with a Retry-After header. Reads aren't limited;
+// perMinute <= 0 disables limiting.
+func RateLimitMutations(perMinute float64, burst int, next http.Handler) http.Handler {
+	if perMinute <= 0 {
+		return next
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	l := &apiRateLimiter{
+		rate:      perMinute / 60,
+		burst:     burst,
+		clients:   make(map[string]*clientLimiter),
+		lastSweep: time.Now(),
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || requiredScope(r) == ScopeRead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !allowRequest(w, l.get(clientKey(r))) {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowRequest takes a token from limiter, or answers 429 with a Retry-After
+// header and reports false when there is none
+func allowRequest(w http.ResponseWriter, limiter *utils.RateLimiter) bool {
+	if limiter.Allow() {
+		return true
+	}
+	retryAfter := int(math.Ceil(limiter.NextTokenIn().Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+	return false
+}
+
+// get returns the client's limiter, creating it on first use and dropping
+// limiters of clients idle longer than apiLimiterIdleTTL
+func (l *apiRateLimiter) get(key string) *utils.RateLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastSweep) > apiLimiterIdleTTL {
+		for k, c := range l.clients {
+			if now.Sub(c.lastSeen) > apiLimiterIdleTTL {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	c, ok := l.clients[key]
+	if !ok {
+		c = &clientLimiter{limiter: utils.NewRateLimiter(l.rate, l.burst)}
+		l.clients[key] = c
+	}
+	c.lastSeen = now
+	return c.limiter
+}
+
+// clientKey identifies the client by the API key it authenticated with,
+// falling back to its IP. An unchecked bearer token isn't trusted: a client
+// could send a new one with each request to dodge its limit.
+func clientKey(r *http.Request) string {
+	if id := authenticatedKeyID(r); id != "" {
+		return "key:" + id
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func post(handler http.Handler, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/missions", nil)
+	req.RemoteAddr = "203.0.113.7:40000"
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimitMutationsTightLoop(t *testing.T) {
+	const burst = 3
+	handler := RateLimitMutations(60, burst, okHandler())
+
+	for i := 0; i < burst; i++ {
+		if rec := post(handler, ""); rec.Code != http.StatusOK {
+			t.Fatalf("request %d within the burst: status %d, want 200", i+1, rec.Code)
+		}
+	}
+	rec := post(handler, "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over the burst: status %d, want 429", rec.Code)
+	}
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 1 {
+		t.Errorf("Retry-After = %q, want a positive number of seconds", rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestRateLimitMutationsIgnoresUnauthenticatedTokens(t *testing.T) {
+	handler := RateLimitMutations(60, 1, okHandler())
+
+	if rec := post(handler, "token-0"); rec.Code != http.StatusOK {
+		t.Fatalf("first request: status %d, want 200", rec.Code)
+	}
+	// Without RequireAPIKey the tokens are unchecked, so a new one per request
+	// mustn't get a new bucket
+	for i := 1; i <= 3; i++ {
+		if rec := post(handler, fmt.Sprintf("token-%d", i)); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("request with fresh token %d: status %d, want 429", i, rec.Code)
+		}
+	}
+}
+
+func TestRateLimitMutationsPerAuthenticatedKey(t *testing.T) {
+	keys := []APIKey{{Key: "key-a", Scope: ScopeWrite}, {Key: "key-b", Scope: ScopeWrite}}
+	handler := RequireAPIKey(keys, RateLimitMutations(60, 1, okHandler()))
+
+	if rec := post(handler, "key-a"); rec.Code != http.StatusOK {
+		t.Fatalf("key-a first request: status %d, want 200", rec.Code)
+	}
+	if rec := post(handler, "key-a"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("key-a second request: status %d, want 429", rec.Code)
+	}
+	// Same IP, but a different key has its own bucket
+	if rec := post(handler, "key-b"); rec.Code != http.StatusOK {
+		t.Errorf("key-b first request: status %d, want 200", rec.Code)
+	}
+	if rec := post(handler, "wrong-key"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("invalid key: status %d, want 401", rec.Code)
+	}
+}
+
+func TestRateLimitMutationsSkipsReads(t *testing.T) {
+	handler := RateLimitMutations(60, 1, okHandler())
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/missions", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("read %d: status %d, want 200", i+1, rec.Code)
+		}
+	}
+}
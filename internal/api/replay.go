@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"swarmtest/internal/gemini"
+	"swarmtest/internal/models"
+	"swarmtest/internal/utils"
+)
+
+// handleStartReplay launches a new mission that re-executes the recorded
+// decisions of mission sourceID against its target without calling Gemini
+func (api *RESTAPI) handleStartReplay(w http.ResponseWriter, r *http.Request, sourceID string) {
+	if api.refuseWhileDraining(w) {
+		return
+	}
+	source, exists := api.store.Get(r.Context(), sourceID)
+	if !exists {
+		http.Error(w, "Mission not found", http.StatusNotFound)
+		return
+	}
+
+	if source.ExecutionMode == models.ExecutionModeBrowser && !source.BrowserFallback && utils.SharedBrowserPool == nil {
+		http.Error(w, "Browser execution mode is not available (Chrome not found on server)", http.StatusBadRequest)
+		return
+	}
+
+	// Ceilings may have been lowered since the source ran
+	if err := api.validateMissionSize(source.NumAgents, source.RateLimitPerSecond); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recorded := gemini.DecisionsFromLogs(api.store.ListActionLogs(r.Context(), sourceID))
+	if len(recorded) == 0 {
+		http.Error(w, "Mission has no recorded decisions to replay", http.StatusBadRequest)
+		return
+	}
+
+	replay := &models.Mission{
+		ID:                  generateMissionID(),
+		Name:                fmt.Sprintf("Replay of %s", source.Name),
+		TargetURL:           source.TargetURL,
+		NumAgents:           source.NumAgents,
+		Goal:                source.Goal,
+		MaxDurationSeconds:  source.MaxDurationSeconds,
+		RateLimitPerSecond:  source.RateLimitPerSecond,
+		InitialSystemPrompt: source.InitialSystemPrompt,
+		ExecutionMode:       source.ExecutionMode,
+		ReplayOf:            source.ID,
+		Tags:                source.Tags,
+		MissionOptions:      source.MissionOptions,
+		Status:              "pending",
+		CreatedAt:           time.Now(),
+		AgentMetrics:        make(map[string]*models.Agent),
+		RecentEvents:        []models.ActionLog{},
+	}
+	// Recorded completions were already verified, and nothing is generated to stream
+	replay.VerifyCompletion = false
+	replay.StreamDecisions = false
+
+	// Agent i of the replay plays back agent i of the source, and its retries
+	// the source's retries
+	decisions := make(map[string][]models.GeminiDecisionResponse, len(recorded))
+	for agentID, seq := range recorded {
+		replayAgentID := fmt.Sprintf("%s-agent-%d", replay.ID, agentIndex(agentID))
+		if attempt := retryAttempt(agentID); attempt > 0 {
+			replayAgentID = retryAgentID(replay.ID, agentIndex(agentID), attempt)
+		}
+		decisions[replayAgentID] = seq
+	}
+
+	api.store.Put(r.Context(), replay)
+	log.Printf("Replaying mission %s as %s", source.ID, replay.ID)
+
+	api.goMission(func() { api.startMission(replay, gemini.NewRecordedGeminiClient(decisions)) })
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(models.CreateMissionResponse{
+		MissionID: replay.ID,
+	})
+}
+
+// handleReplayReport compares a replay mission's action outcomes with its source
+func (api *RESTAPI) handleReplayReport(w http.ResponseWriter, r *http.Request, replayID string) {
+	replay, exists := api.store.Get(r.Context(), replayID)
+	if !exists {
+		http.Error(w, "Mission not found", http.StatusNotFound)
+		return
+	}
+	if replay.ReplayOf == "" {
+		http.Error(w, "Mission is not a replay", http.StatusBadRequest)
+		return
+	}
+
+	report := compareReplay(
+		decisionLogsByAgent(api.store.ListActionLogs(r.Context(), replay.ReplayOf)),
+		decisionLogsByAgent(api.store.ListActionLogs(r.Context(), replay.ID)),
+	)
+	report.MissionID = replay.ID
+	report.SourceMissionID = replay.ReplayOf
+	report.Status = replay.Status
+
+	json.NewEncoder(w).Encode(report)
+}
+
+// decisionLogsByAgent groups the decision entries of an action log by agent index
+func decisionLogsByAgent(logs []models.ActionLog) map[int][]models.ActionLog {
+	byAgent := make(map[int][]models.ActionLog)
+	for _, l := range logs {
+		if gemini.IsDecisionAction(l.Action) {
+			i := agentIndex(l.AgentID)
+			byAgent[i] = append(byAgent[i], l)
+		}
+	}
+	return byAgent
+}
+
+// compareReplay pairs each recorded action with the replay's action at the same
+// step. Steps the replay took beyond the recording are ignored.
+func compareReplay(expected, actual map[int][]models.ActionLog) models.ReplayReport {
+	report := models.ReplayReport{Divergences: []models.ReplayDivergence{}}
+
+	for i, want := range expected {
+		got := actual[i]
+		for step, e := range want {
+			report.ComparedActions++
+
+			d := models.ReplayDivergence{
+				AgentID:        e.AgentID,
+				Step:           step + 1,
+				Action:         e.Action,
+				Selector:       e.Selector,
+				ExpectedResult: e.Result,
+				ExpectedURL:    e.NewURL,
+			}
+			if step >= len(got) {
+				d.ActualResult = "missing"
+				report.Divergences = append(report.Divergences, d)
+				continue
+			}
+
+			a := got[step]
+			if a.Result == e.Result && a.NewURL == e.NewURL {
+				continue
+			}
+			d.ActualResult = a.Result
+			d.ActualURL = a.NewURL
+			d.ErrorMessage = a.ErrorMessage
+			report.Divergences = append(report.Divergences, d)
+		}
+	}
+
+	sort.Slice(report.Divergences, func(i, j int) bool {
+		a, b := report.Divergences[i], report.Divergences[j]
+		if a.AgentID != b.AgentID {
+			return a.AgentID < b.AgentID
+		}
+		return a.Step < b.Step
+	})
+	return report
+}
@@ -2,17 +2,29 @@ package api
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"swarmtest/internal/agent"
 	"swarmtest/internal/gemini"
 	"swarmtest/internal/models"
 	"swarmtest/internal/store"
+	"swarmtest/internal/tracing"
 	"swarmtest/internal/utils"
 )
 
@@ -21,36 +33,117 @@ type RESTAPI struct {
 	store      store.MissionStore
 	gemini     gemini.GeminiClient
 	eventBus   chan models.Event
+	drops      *agent.DropCounter // events missions lost on their way to the event logger
 	rateLimits *utils.RateLimiterRegistry
+	robots     *utils.RobotsCache // shared by missions with respect_robots_txt
+	agents     *agentRegistry     // running agents, for stopping them one at a time
+
+	// liveMu guards the shared state of running missions: crawl frontiers, for
+	// live sitemaps, visited sets, for live coverage, and cancel functions, for
+	// draining and budgets
+	liveMu  sync.Mutex
+	crawls  map[string]*utils.Crawler
+	visited map[string]*utils.VisitedSet
+	running map[string]context.CancelCauseFunc
+
+	// draining is set by POST /api/admin/drain; no mission starts while it is
+	draining atomic.Bool
+
+	// debugDecisions limits POST /api/debug/decide across all clients, since
+	// each one spends Gemini quota
+	debugDecisions *utils.RateLimiter
+
+	// AllowInsecureTLS permits missions to set insecure_skip_verify
+	AllowInsecureTLS bool
+	// WebhookSecret, when set, signs webhook deliveries
+	WebhookSecret string
+	// DefaultRateLimitPerSecond applies to missions that don't set rate_limit_per_second
+	DefaultRateLimitPerSecond float64
+	// DefaultMaxPageSize applies to missions that don't set max_page_size_bytes
+	DefaultMaxPageSize int
+	// DefaultAlertErrorRatePercent applies to missions that don't set
+	// alert_error_rate_percent (0 = no alerts)
+	DefaultAlertErrorRatePercent float64
+	// MaxAgentsPerMission and MaxRateLimitPerSecond are the deployment's
+	// ceilings on num_agents and rate_limit_per_second (0 = the absolute limits)
+	MaxAgentsPerMission   int
+	MaxRateLimitPerSecond float64
+	// Events serves mission event streams; nil disables them
+	Events *WebSocketHub
+
+	// agentSlots bounds how many agents run at once across all missions
+	agentSlots chan struct{}
+
+	// idempotencyMu serializes keyed mission creation, so concurrent retries
+	// with one Idempotency-Key can't both create a mission
+	idempotencyMu sync.Mutex
+
+	// scheduleMu serializes launching and cancelling scheduled missions
+	scheduleMu sync.Mutex
+
+	// ctx is the parent of every mission's context; Shutdown cancels it and
+	// waits on missionsWG for the missions to wind down
+	ctx        context.Context
+	cancel     context.CancelFunc
+	missionsWG sync.WaitGroup
+
+	// webhooksWG tracks webhook deliveries, which outlive their missions
+	webhooksWG sync.WaitGroup
 }
 
-// NewRESTAPI creates a new REST API handler
-func NewRESTAPI(store store.MissionStore, gemini gemini.GeminiClient, eventBus chan models.Event) *RESTAPI {
+// tracer traces mission runs; each mission's span parents its agents'
+var tracer = tracing.Tracer("api")
+
+// DefaultMaxConcurrentAgents is used when no positive cap is configured
+const DefaultMaxConcurrentAgents = 50
+
+// DefaultRateLimitPerSecond is the request rate of missions that don't set one
+const DefaultRateLimitPerSecond = 2.0
+
+// NewRESTAPI creates a new REST API handler. Missions run under ctx and stop
+// when it is cancelled.
+func NewRESTAPI(ctx context.Context, store store.MissionStore, gemini gemini.GeminiClient, eventBus chan models.Event, drops *agent.DropCounter, maxConcurrentAgents int) *RESTAPI {
+	if maxConcurrentAgents <= 0 {
+		maxConcurrentAgents = DefaultMaxConcurrentAgents
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
 	return &RESTAPI{
+		ctx:        ctx,
+		cancel:     cancel,
 		store:      store,
 		gemini:     gemini,
 		eventBus:   eventBus,
+		drops:      drops,
 		rateLimits: utils.NewRateLimiterRegistry(),
+		robots:     utils.NewRobotsCache(),
+		agents:     newAgentRegistry(),
+		crawls:     make(map[string]*utils.Crawler),
+		visited:    make(map[string]*utils.VisitedSet),
+		running:    make(map[string]context.CancelCauseFunc),
+		agentSlots: make(chan struct{}, maxConcurrentAgents),
+
+		debugDecisions:            utils.NewRateLimiter(debugDecisionsPerMinute/60, debugDecisionsBurst),
+		DefaultRateLimitPerSecond: DefaultRateLimitPerSecond,
+		DefaultMaxPageSize:        utils.DefaultMaxPageSize,
 	}
 }
 
 // RegisterRoutes registers routes
 func (api *RESTAPI) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/missions", api.handleMissions)
+	mux.HandleFunc("/api/missions/compare", api.handleCompareMissions)
 	mux.HandleFunc("/api/missions/", api.handleMissionDetailOrActions)
+	mux.HandleFunc("/api/templates", api.handleTemplates)
+	mux.HandleFunc("/api/debug/parse", api.handleDebugParse)
+	mux.HandleFunc("/api/debug/decide", api.handleDebugDecide)
+	mux.HandleFunc("/api/admin/drain", api.handleAdminDrain)
 }
 
-func (api *RESTAPI) handleMissions(w http.ResponseWriter, r *http.Request) {
-	// CORS
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-	if r.Method == "OPTIONS" {
-		w.WriteHeader(http.StatusOK)
-		return
-	}
+// CORS headers and preflight requests are handled by the server's CORS middleware
 
+func (api *RESTAPI) handleMissions(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "POST" {
 		api.createMission(w, r)
 		return
@@ -65,31 +158,56 @@ func (api *RESTAPI) handleMissions(w http.ResponseWriter, r *http.Request) {
 }
 
 func (api *RESTAPI) handleMissionDetailOrActions(w http.ResponseWriter, r *http.Request) {
-	// CORS
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	missionID, resource := parseMissionPath(r.URL.Path)
+	if missionID == "" {
+		http.Error(w, "Invalid mission ID", http.StatusBadRequest)
+		return
+	}
 
-	if r.Method == "OPTIONS" {
-		w.WriteHeader(http.StatusOK)
+	if r.Method == "POST" {
+		if resource == "replay" {
+			api.handleStartReplay(w, r, missionID)
+			return
+		}
+		if resource == "cancel" {
+			api.handleCancelMission(w, r, missionID)
+			return
+		}
+		if rest, ok := strings.CutPrefix(resource, "agents/"); ok {
+			if agentID, ok := strings.CutSuffix(rest, "/stop"); ok && agentID != "" && !strings.Contains(agentID, "/") {
+				api.handleStopAgent(w, r, missionID, agentID)
+				return
+			}
+		}
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	if r.Method == "GET" {
-		missionID := extractMissionID(r.URL.Path)
-		if missionID == "" {
-			http.Error(w, "Invalid mission ID", http.StatusBadRequest)
+		if agentID, ok := strings.CutPrefix(resource, "agents/"); ok {
+			api.handleAgentTimeline(w, r, missionID, agentID)
 			return
 		}
-
-		// Check if it's an action logs request
-		if len(r.URL.Path) > len("/api/missions/"+missionID) && r.URL.Path[len("/api/missions/"+missionID):] == "/actions" {
+		switch resource {
+		case "":
+			api.handleMissionDetail(w, r, missionID)
+		case "actions":
 			api.handleMissionActionLogs(w, r, missionID)
-			return
+		case "metrics":
+			api.handleMissionMetrics(w, r, missionID)
+		case "replay":
+			api.handleReplayReport(w, r, missionID)
+		case "coverage":
+			api.handleMissionCoverage(w, r, missionID)
+		case "sitemap":
+			api.handleMissionSitemap(w, r, missionID)
+		case "findings":
+			api.handleMissionFindings(w, r, missionID)
+		case "stream":
+			api.handleMissionStream(w, r, missionID)
+		default:
+			http.NotFound(w, r)
 		}
-
-		// Otherwise, it's a mission detail request
-		api.handleMissionDetail(w, r)
 		return
 	}
 
@@ -97,7 +215,7 @@ func (api *RESTAPI) handleMissionDetailOrActions(w http.ResponseWriter, r *http.
 }
 
 func (api *RESTAPI) handleMissionActionLogs(w http.ResponseWriter, r *http.Request, missionID string) {
-	mission, exists := api.store.Get(missionID)
+	mission, exists := api.store.Get(r.Context(), missionID)
 	if !exists {
 		http.Error(w, "Mission not found", http.StatusNotFound)
 		return
@@ -106,71 +224,222 @@ func (api *RESTAPI) handleMissionActionLogs(w http.ResponseWriter, r *http.Reque
 	json.NewEncoder(w).Encode(mission.RecentEvents)
 }
 
-func (api *RESTAPI) handleMissionDetail(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	
-	if r.Method == "GET" {
-		id := extractMissionID(r.URL.Path)
-		if id == "" {
-			http.Error(w, "Invalid mission ID", http.StatusBadRequest)
-			return
-		}
+func (api *RESTAPI) handleMissionMetrics(w http.ResponseWriter, r *http.Request, missionID string) {
+	mission, exists := api.store.Get(r.Context(), missionID)
+	if !exists {
+		http.Error(w, "Mission not found", http.StatusNotFound)
+		return
+	}
 
-		mission, exists := api.store.Get(id)
-		if !exists {
+	summary := api.missionSummary(mission)
+	summary.SlowestPages, summary.LargestPages = api.store.PerformanceOffenders(r.Context(), missionID, maxPerformanceOffenders)
+	json.NewEncoder(w).Encode(summary)
+}
+
+func (api *RESTAPI) handleMissionCoverage(w http.ResponseWriter, r *http.Request, missionID string) {
+	coverage, ok := api.store.GetCoverage(r.Context(), missionID)
+	if !ok {
+		if _, exists := api.store.Get(r.Context(), missionID); !exists {
 			http.Error(w, "Mission not found", http.StatusNotFound)
 			return
 		}
-
-		// Calculate summary
-		activeAgents := 0
-		for _, agent := range mission.AgentMetrics {
-			if agent.Status == "running" {
-				activeAgents++
-			}
+		// Nothing explored yet
+		coverage = &models.Coverage{
+			MissionID: missionID,
+			URLs:      []string{},
+			Selectors: []string{},
+			Discovery: []models.CoveragePoint{},
 		}
+	}
 
-		total := mission.TotalActions + mission.TotalErrors
-		errorRate := 0.0
-		if total > 0 {
-			errorRate = float64(mission.TotalErrors) / float64(total) * 100
-		}
+	api.liveMu.Lock()
+	visited := api.visited[missionID]
+	api.liveMu.Unlock()
+	if visited != nil {
+		coverage.SharedVisited, coverage.SharedVisitedTruncated = visited.URLs()
+	}
 
-		agentStates := make([]models.Agent, 0, len(mission.AgentMetrics))
-		for _, a := range mission.AgentMetrics {
-			agentStates = append(agentStates, *a)
+	json.NewEncoder(w).Encode(coverage)
+}
+
+func (api *RESTAPI) handleMissionSitemap(w http.ResponseWriter, r *http.Request, missionID string) {
+	mission, exists := api.store.Get(r.Context(), missionID)
+	if !exists {
+		http.Error(w, "Mission not found", http.StatusNotFound)
+		return
+	}
+	if mission.ExecutionMode != models.ExecutionModeCrawl {
+		http.Error(w, "Sitemaps are only built by crawl mode missions", http.StatusBadRequest)
+		return
+	}
+
+	api.liveMu.Lock()
+	crawl := api.crawls[missionID]
+	api.liveMu.Unlock()
+
+	var sitemap *models.Sitemap
+	if crawl != nil {
+		sitemap = crawl.Sitemap(missionID)
+	} else if saved, ok := api.store.GetSitemap(r.Context(), missionID); ok {
+		sitemap = saved
+	} else {
+		// Nothing crawled yet
+		sitemap = &models.Sitemap{MissionID: missionID, Pages: []models.SitemapPage{}}
+	}
+
+	json.NewEncoder(w).Encode(sitemap)
+}
+
+func (api *RESTAPI) handleAgentTimeline(w http.ResponseWriter, r *http.Request, missionID, agentID string) {
+	mission, exists := api.store.Get(r.Context(), missionID)
+	if !exists {
+		http.Error(w, "Mission not found", http.StatusNotFound)
+		return
+	}
+	agent, exists := api.store.GetAgent(r.Context(), missionID, agentID)
+	if !exists {
+		http.Error(w, "Agent not found", http.StatusNotFound)
+		return
+	}
+
+	logs := api.store.ListAgentActionLogs(r.Context(), missionID, agentID)
+	if logs == nil {
+		logs = []models.ActionLog{}
+	}
+	// Histories aren't persisted with the agent, so rebuild them from its log
+	agent.ActionHistory, agent.URLHistory = agentHistories(mission.TargetURL, logs)
+
+	json.NewEncoder(w).Encode(models.AgentTimelineResponse{
+		Agent:      agent,
+		ActionLogs: logs,
+	})
+}
+
+// agentHistories reconstructs an agent's action and URL histories from its
+// action logs, in the same shape the running agent keeps them
+func agentHistories(startURL string, logs []models.ActionLog) (actions, urls []string) {
+	actions = []string{}
+	urls = []string{startURL}
+	for _, l := range logs {
+		desc := l.Action
+		if l.Selector != "" {
+			desc += " " + l.Selector
+		}
+		switch l.Result {
+		case "failed":
+			desc += " (failed: " + l.ErrorMessage + ")"
+		case "skipped":
+			desc += " (skipped: " + l.ErrorMessage + ")"
+		case "redirected":
+			desc += fmt.Sprintf(" (redirected %d to %s, not followed)", l.StatusCode, l.RedirectURL)
 		}
+		actions = append(actions, desc)
 
-		resp := models.MissionStatusResponse{
-			Mission: mission,
-			AgentStates: agentStates,
-			Summary: &models.SummaryEvent{
-				MissionID:        mission.ID,
-				TotalAgents:      mission.NumAgents,
-				ActiveAgents:     activeAgents,
-				CompletedAgents:  mission.CompletedAgents,
-				FailedAgents:     mission.FailedAgents,
-				TotalActions:     mission.TotalActions,
-				TotalErrors:      mission.TotalErrors,
-				AverageLatencyMS: mission.AverageLatencyMS,
-				ErrorRatePercent: errorRate,
-			},
+		if l.NewURL != "" && l.NewURL != urls[len(urls)-1] {
+			urls = append(urls, l.NewURL)
 		}
+	}
+	return actions, urls
+}
+
+func (api *RESTAPI) handleMissionFindings(w http.ResponseWriter, r *http.Request, missionID string) {
+	filter, err := parseFindingFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, exists := api.store.Get(r.Context(), missionID); !exists {
+		http.Error(w, "Mission not found", http.StatusNotFound)
+		return
+	}
+
+	filter = filter.Normalize()
+	findings, total := api.store.ListFindings(r.Context(), missionID, filter)
+	if findings == nil {
+		findings = []models.Finding{}
+	}
+
+	json.NewEncoder(w).Encode(models.ListFindingsResponse{
+		Findings: findings,
+		Total:    total,
+		Limit:    filter.Limit,
+		Offset:   filter.Offset,
+	})
+}
 
-		json.NewEncoder(w).Encode(resp)
+func (api *RESTAPI) handleMissionDetail(w http.ResponseWriter, r *http.Request, id string) {
+	mission, exists := api.store.Get(r.Context(), id)
+	if !exists {
+		http.Error(w, "Mission not found", http.StatusNotFound)
 		return
 	}
+
+	agentStates := make([]models.Agent, 0, len(mission.AgentMetrics))
+	for _, a := range mission.AgentMetrics {
+		agentStates = append(agentStates, *a)
+	}
+
+	resp := models.MissionStatusResponse{
+		Mission:     mission,
+		AgentStates: agentStates,
+		Summary:     api.missionSummary(mission),
+	}
+
+	json.NewEncoder(w).Encode(resp)
 }
 
 func (api *RESTAPI) createMission(w http.ResponseWriter, r *http.Request) {
+	if api.refuseWhileDraining(w) {
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
 	var req models.CreateMissionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.Unmarshal(body, &req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	if req.TemplateID != "" {
+		if status, err := api.applyTemplate(r, body, &req); err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		http.Error(w, fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength), http.StatusBadRequest)
+		return
+	}
+	// Keys are scoped to the caller, and a key only replays the request it
+	// was first sent with
+	caller := authenticatedKeyID(r)
+	bodyHash := ""
+	if idempotencyKey != "" {
+		sum := sha256.Sum256(body)
+		bodyHash = hex.EncodeToString(sum[:])
 
-	missionID := generateMissionID()
+		// Held until the new mission is saved
+		api.idempotencyMu.Lock()
+		defer api.idempotencyMu.Unlock()
 
+		if existing, ok := api.store.FindByIdempotencyKey(r.Context(), caller, idempotencyKey, time.Now().Add(-idempotencyKeyTTL)); ok {
+			if existing.IdempotencyBodyHash != bodyHash {
+				http.Error(w, "Idempotency-Key was already used with a different request body", http.StatusUnprocessableEntity)
+				return
+			}
+			log.Printf("Idempotency-Key replayed, returning existing mission %s", existing.ID)
+			w.Header().Set("Idempotent-Replayed", "true")
+			json.NewEncoder(w).Encode(models.CreateMissionResponse{
+				MissionID: existing.ID,
+			})
+			return
+		}
+	}
 
 	// Sanitize URL
 	targetURL := req.TargetURL
@@ -188,22 +457,73 @@ func (api *RESTAPI) createMission(w http.ResponseWriter, r *http.Request) {
 
 	// Validate browser pool is available if browser mode is requested
 	// Validate execution mode
-	if req.ExecutionMode != "" && req.ExecutionMode != models.ExecutionModeHTTP && req.ExecutionMode != models.ExecutionModeBrowser {
+	if req.ExecutionMode != "" && req.ExecutionMode != models.ExecutionModeHTTP && req.ExecutionMode != models.ExecutionModeBrowser && req.ExecutionMode != models.ExecutionModeAuto && req.ExecutionMode != models.ExecutionModeCrawl {
 		http.Error(w, "Invalid execution mode", http.StatusBadRequest)
 		return
 	}
 	if req.ExecutionMode == "" {
 		req.ExecutionMode = models.ExecutionModeHTTP
 	}
+	if req.RateLimitPerSecond == 0 {
+		req.RateLimitPerSecond = api.DefaultRateLimitPerSecond
+	}
+	if req.MaxPageSizeBytes == 0 {
+		req.MaxPageSizeBytes = api.DefaultMaxPageSize
+	}
+	if req.AlertErrorRatePercent == 0 {
+		req.AlertErrorRatePercent = api.DefaultAlertErrorRatePercent
+	}
+	if err := api.validateMissionSize(req.NumAgents, req.RateLimitPerSecond); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	
-	// Check if browser mode is requested but not available
-	if req.ExecutionMode == models.ExecutionModeBrowser && utils.SharedBrowserPool == nil {
+	// Check if browser mode is requested but not available; auto mode and
+	// browser_fallback missions run in HTTP mode instead
+	if req.ExecutionMode == models.ExecutionModeBrowser && !req.BrowserFallback && utils.SharedBrowserPool == nil {
 		http.Error(w, "Browser execution mode is not available (Chrome not found on server)", http.StatusBadRequest)
 		return
 	}
 
+	if err := validateMissionOptions(req.MissionOptions); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.RampUpSeconds > 0 && req.RampUpSeconds >= req.MaxDurationSeconds {
+		http.Error(w, "ramp_up_seconds must be shorter than max_duration_seconds", http.StatusBadRequest)
+		return
+	}
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.InsecureSkipVerify && !api.AllowInsecureTLS {
+		http.Error(w, "insecure_skip_verify is disabled on this server (set ALLOW_INSECURE_TLS to enable)", http.StatusBadRequest)
+		return
+	}
+	// Crawl agents follow links instead of pursuing a goal
+	if req.ExecutionMode == models.ExecutionModeCrawl && (req.SuccessCriteria != nil || len(req.Steps) > 0 || req.VerifyCompletion) {
+		http.Error(w, "success_criteria, steps and verify_completion are not supported in crawl execution mode", http.StatusBadRequest)
+		return
+	}
+	if req.ClientCertFile != "" && req.ExecutionMode != models.ExecutionModeHTTP && req.ExecutionMode != models.ExecutionModeCrawl {
+		http.Error(w, "client certificates are only supported in http execution mode", http.StatusBadRequest)
+		return
+	}
+	// Browser mode doesn't see the page's response status
+	if req.SuccessCriteria != nil && req.SuccessCriteria.StatusCode != 0 && req.ExecutionMode != models.ExecutionModeHTTP {
+		http.Error(w, "success_criteria.status_code is only supported in http execution mode", http.StatusBadRequest)
+		return
+	}
+	nextRunAt, err := nextRun(req.MissionOptions, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	mission := &models.Mission{
-		ID:                  "mission-" + uuid.New().String()[:8],
+		ID:                  generateMissionID(),
 		Name:                req.Name,
 		TargetURL:           req.TargetURL,
 		NumAgents:           req.NumAgents,
@@ -212,6 +532,12 @@ func (api *RESTAPI) createMission(w http.ResponseWriter, r *http.Request) {
 		RateLimitPerSecond:  req.RateLimitPerSecond,
 		InitialSystemPrompt: req.InitialSystemPrompt,
 		ExecutionMode:       req.ExecutionMode,
+		Tags:                tags,
+		IdempotencyKey:      idempotencyKey,
+		IdempotencyCaller:   caller,
+		IdempotencyBodyHash: bodyHash,
+		TemplateID:          req.TemplateID,
+		MissionOptions:      req.MissionOptions,
 		Status:              "pending",
 		CreatedAt:           time.Now(),
 		TotalActions:        0,
@@ -220,98 +546,785 @@ func (api *RESTAPI) createMission(w http.ResponseWriter, r *http.Request) {
 		RecentEvents:       []models.ActionLog{},
 	}
 
-	api.store.Put(mission)
+	if mission.Seed == 0 {
+		// Record a seed for every mission so any run can be replayed; kept
+		// below 2^53 so JavaScript clients read it back exactly
+		mission.Seed = time.Now().UnixNano()&(1<<53-1) | 1
+	}
+
+	// Scheduled missions are left to the scheduler
+	if nextRunAt != nil {
+		mission.Status = "scheduled"
+		mission.NextRunAt = nextRunAt
+		api.store.Put(r.Context(), mission)
+		log.Printf("Scheduled mission %s for %s", mission.ID, nextRunAt.Format(time.RFC3339))
+		json.NewEncoder(w).Encode(models.CreateMissionResponse{
+			MissionID: mission.ID,
+		})
+		return
+	}
+
+	api.store.Put(r.Context(), mission)
 
 	// Start mission asynchronously
-	go api.startMission(mission)
+	api.goMission(func() { api.startMission(mission, api.gemini) })
 
 	json.NewEncoder(w).Encode(models.CreateMissionResponse{
-		MissionID: missionID,
+		MissionID: mission.ID,
 	})
 }
 
 func (api *RESTAPI) listMissions(w http.ResponseWriter, r *http.Request) {
-	missions := api.store.List()
-	json.NewEncoder(w).Encode(map[string][]*models.Mission{
-		"missions": missions,
-	})
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts = opts.Normalize()
+
+	missions, total := api.store.List(r.Context(), opts)
+
+	resp := models.ListMissionsResponse{
+		Missions: missions,
+		Total:    total,
+		Limit:    opts.Limit,
+		Offset:   opts.Offset,
+	}
+	if next := opts.Offset + len(missions); next < total {
+		resp.NextOffset = &next
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+const (
+	// Absolute bounds of num_agents and rate_limit_per_second; a deployment
+	// may set lower ceilings
+	maxNumAgents          = 1000
+	maxRateLimitPerSecond = 1000
+	// maxMissionSteps bounds the number of sub-goals in a multi-step mission
+	maxMissionSteps = 20
+	// Few-shot example bounds
+	maxPromptExamples       = 10
+	maxPromptExampleContext = 2000
+	// Mission tag bounds
+	maxMissionTags = 20
+	maxTagLength   = 50
+	// maxRedirectsLimit bounds max_redirects
+	maxRedirectsLimit = 50
+	// maxConsecutiveErrorsLimit bounds max_consecutive_errors
+	maxConsecutiveErrorsLimit = 1000
+	// maxActionTimeoutSeconds bounds action_timeout_seconds
+	maxActionTimeoutSeconds = 600
+	// Crawl mode bounds
+	maxCrawlDepth = 20
+	maxCrawlPages = 10000
+	// maxPerformanceOffenders is how many of the slowest and of the largest
+	// pages the metrics endpoint lists
+	maxPerformanceOffenders = 10
+	// maxThinkTimeMS bounds think_time_max_ms
+	maxThinkTimeMS = 60000
+	// maxUserAgents bounds the user_agents pool
+	maxUserAgents = 100
+	// idempotencyKeyTTL is how long an Idempotency-Key maps to its mission
+	idempotencyKeyTTL = 24 * time.Hour
+	// maxIdempotencyKeyLength bounds the Idempotency-Key header
+	maxIdempotencyKeyLength = 255
+	// Viewport bounds for browser mode (up to 8K)
+	minViewportSize   = 200
+	maxViewportWidth  = 7680
+	maxViewportHeight = 4320
+)
+
+// tagPattern is the allowed form of a mission tag, e.g. "checkout-flow"
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// normalizeTags lower-cases, trims and de-duplicates tags, rejecting malformed ones
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) > maxMissionTags {
+		return nil, fmt.Errorf("at most %d tags are allowed", maxMissionTags)
+	}
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if len(tag) > maxTagLength || !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q: use up to %d lowercase letters, digits, '-', '_' or '.'", tag, maxTagLength)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+// validateMissionSize checks a mission's agent count and request rate against
+// the absolute limits and the deployment's ceilings
+func (api *RESTAPI) validateMissionSize(numAgents int, rateLimit float64) error {
+	if numAgents < 1 || numAgents > maxNumAgents {
+		return fmt.Errorf("num_agents must be between 1 and %d", maxNumAgents)
+	}
+	if api.MaxAgentsPerMission > 0 && numAgents > api.MaxAgentsPerMission {
+		return fmt.Errorf("num_agents must be at most %d on this server", api.MaxAgentsPerMission)
+	}
+	if rateLimit <= 0 || rateLimit > maxRateLimitPerSecond {
+		return fmt.Errorf("rate_limit_per_second must be between 0 and %d", maxRateLimitPerSecond)
+	}
+	if api.MaxRateLimitPerSecond > 0 && rateLimit > api.MaxRateLimitPerSecond {
+		return fmt.Errorf("rate_limit_per_second must be at most %g on this server", api.MaxRateLimitPerSecond)
+	}
+	return nil
+}
+
+// validatePromptExamples checks few-shot examples: each describes a situation
+// and decides with an action agents can take
+func validatePromptExamples(examples []models.PromptExample) error {
+	if len(examples) > maxPromptExamples {
+		return fmt.Errorf("at most %d prompt_examples are allowed", maxPromptExamples)
+	}
+	for i, example := range examples {
+		if strings.TrimSpace(example.Context) == "" || len(example.Context) > maxPromptExampleContext {
+			return fmt.Errorf("prompt_examples[%d].context must be 1-%d characters", i, maxPromptExampleContext)
+		}
+		if !gemini.IsDecisionAction(example.Decision.Action) {
+			return fmt.Errorf("prompt_examples[%d].decision.action %q is not an action agents can take", i, example.Decision.Action)
+		}
+	}
+	return nil
+}
+
+// validateMissionOptions checks the optional per-mission settings
+func validateMissionOptions(opts models.MissionOptions) error {
+	if opts.MaxPromptTokens < 0 {
+		return fmt.Errorf("max_prompt_tokens must not be negative")
+	}
+	if opts.ActionTimeoutSeconds < 0 || opts.ActionTimeoutSeconds > maxActionTimeoutSeconds {
+		return fmt.Errorf("action_timeout_seconds must be between 0 and %d", maxActionTimeoutSeconds)
+	}
+	if opts.RampUpSeconds < 0 {
+		return fmt.Errorf("ramp_up_seconds must not be negative")
+	}
+	if opts.MaxConsecutiveErrors < 0 || opts.MaxConsecutiveErrors > maxConsecutiveErrorsLimit {
+		return fmt.Errorf("max_consecutive_errors must be between 0 and %d", maxConsecutiveErrorsLimit)
+	}
+	if opts.RetryFailedAgents < 0 || opts.RetryFailedAgents > maxRetryFailedAgents {
+		return fmt.Errorf("retry_failed_agents must be between 0 and %d", maxRetryFailedAgents)
+	}
+	if opts.Temperature != nil && (*opts.Temperature < 0 || *opts.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2")
+	}
+	if opts.TopP != nil && (*opts.TopP < 0 || *opts.TopP > 1) {
+		return fmt.Errorf("top_p must be between 0 and 1")
+	}
+	if opts.MaxOutputTokens < 0 {
+		return fmt.Errorf("max_output_tokens must not be negative")
+	}
+	if opts.MaxRedirects < 0 || opts.MaxRedirects > maxRedirectsLimit {
+		return fmt.Errorf("max_redirects must be between 0 and %d", maxRedirectsLimit)
+	}
+	if (opts.ViewportWidth == 0) != (opts.ViewportHeight == 0) {
+		return fmt.Errorf("viewport_width and viewport_height must be set together")
+	}
+	if opts.ViewportWidth != 0 {
+		if opts.ViewportWidth < minViewportSize || opts.ViewportWidth > maxViewportWidth {
+			return fmt.Errorf("viewport_width must be between %d and %d", minViewportSize, maxViewportWidth)
+		}
+		if opts.ViewportHeight < minViewportSize || opts.ViewportHeight > maxViewportHeight {
+			return fmt.Errorf("viewport_height must be between %d and %d", minViewportSize, maxViewportHeight)
+		}
+	}
+	if opts.Device != "" {
+		if _, ok := utils.DeviceProfiles[opts.Device]; !ok {
+			return fmt.Errorf("unknown device: %s", opts.Device)
+		}
+		if opts.ViewportWidth != 0 {
+			return fmt.Errorf("device already sets the viewport; omit viewport_width and viewport_height")
+		}
+	}
+	if _, err := utils.ParseProxyURL(opts.Proxy); err != nil {
+		return err
+	}
+	if opts.CrawlMaxDepth < 0 || opts.CrawlMaxDepth > maxCrawlDepth {
+		return fmt.Errorf("crawl_max_depth must be between 0 and %d", maxCrawlDepth)
+	}
+	if opts.CrawlMaxPages < 0 || opts.CrawlMaxPages > maxCrawlPages {
+		return fmt.Errorf("crawl_max_pages must be between 0 and %d", maxCrawlPages)
+	}
+	switch opts.CrawlStrategy {
+	case "", utils.CrawlBFS, utils.CrawlDFS:
+	default:
+		return fmt.Errorf("crawl_strategy must be bfs or dfs")
+	}
+	if opts.ThinkTimeMinMS < 0 || opts.ThinkTimeMaxMS < 0 {
+		return fmt.Errorf("think_time_min_ms and think_time_max_ms must not be negative")
+	}
+	if opts.ThinkTimeMinMS > opts.ThinkTimeMaxMS {
+		return fmt.Errorf("think_time_min_ms must not exceed think_time_max_ms")
+	}
+	if opts.ThinkTimeMaxMS > maxThinkTimeMS {
+		return fmt.Errorf("think_time_max_ms must be at most %d", maxThinkTimeMS)
+	}
+	if len(opts.UserAgents) > maxUserAgents {
+		return fmt.Errorf("at most %d user_agents are allowed", maxUserAgents)
+	}
+	for i, ua := range opts.UserAgents {
+		if strings.TrimSpace(ua) == "" || strings.ContainsAny(ua, "\r\n") {
+			return fmt.Errorf("user_agents[%d] must be a non-empty single line", i)
+		}
+	}
+	if opts.Language != "" && !utils.ValidLanguageTag(opts.Language) {
+		return fmt.Errorf("language must be a language tag such as fr or ja-JP")
+	}
+	if opts.Timezone != "" {
+		if _, err := time.LoadLocation(opts.Timezone); err != nil || opts.Timezone == "Local" {
+			return fmt.Errorf("timezone must be an IANA time zone such as Europe/Paris")
+		}
+	}
+	if opts.ScheduledAt != nil && opts.Cron != "" {
+		return fmt.Errorf("scheduled_at and cron are mutually exclusive")
+	}
+	if opts.MaxPageSizeBytes < 0 || opts.MaxPageSizeBytes > utils.MaxPageSizeLimit {
+		return fmt.Errorf("max_page_size_bytes must be between 0 and %d", utils.MaxPageSizeLimit)
+	}
+	for _, t := range opts.ElementTypes {
+		if !utils.ElementTypes[t] {
+			return fmt.Errorf("element_types must only contain link, button, input and form")
+		}
+	}
+	if opts.MaxTotalActions < 0 || opts.MaxCostUSD < 0 {
+		return fmt.Errorf("max_total_actions and max_cost_usd must not be negative")
+	}
+	if opts.MaxUniqueURLsPerAgent < 0 {
+		return fmt.Errorf("max_unique_urls_per_agent must not be negative")
+	}
+	if opts.SlowPageMS < 0 || opts.LargePageBytes < 0 {
+		return fmt.Errorf("slow_page_ms and large_page_bytes must not be negative")
+	}
+	if opts.AlertErrorRatePercent < 0 || opts.AlertErrorRatePercent > 100 {
+		return fmt.Errorf("alert_error_rate_percent must be between 0 and 100")
+	}
+	if opts.FormContentType != "" && !utils.FormEncodings[opts.FormContentType] {
+		return fmt.Errorf("form_content_type must be application/x-www-form-urlencoded, multipart/form-data or application/json")
+	}
+	if opts.WebhookURL != "" {
+		u, err := url.Parse(opts.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook_url must be an absolute http or https URL")
+		}
+	}
+	if auth := opts.TargetAuth; auth != nil {
+		if (auth.Username == "") == (auth.BearerToken == "") {
+			return fmt.Errorf("target_auth must set either username and password, or bearer_token")
+		}
+		if strings.Contains(auth.Username, ":") {
+			return fmt.Errorf("target_auth.username must not contain ':'")
+		}
+		if auth.Password != "" && auth.BearerToken != "" {
+			return fmt.Errorf("target_auth.password is only used with username")
+		}
+		if strings.ContainsAny(auth.Username+auth.Password+auth.BearerToken, "\r\n") {
+			return fmt.Errorf("target_auth values must be single lines")
+		}
+	}
+	if (opts.ClientCertFile == "") != (opts.ClientKeyFile == "") {
+		return fmt.Errorf("client_cert_file and client_key_file must be set together")
+	}
+	if _, err := utils.ClientTLSConfig(false, opts.ClientCertFile, opts.ClientKeyFile); err != nil {
+		return err
+	}
+	if len(opts.Steps) > maxMissionSteps {
+		return fmt.Errorf("at most %d steps are allowed", maxMissionSteps)
+	}
+	for i, step := range opts.Steps {
+		if strings.TrimSpace(step) == "" {
+			return fmt.Errorf("step %d is empty", i+1)
+		}
+	}
+	if err := validatePromptExamples(opts.PromptExamples); err != nil {
+		return err
+	}
+	if c := opts.SuccessCriteria; c != nil {
+		if c.URLPattern == "" && c.Selector == "" && c.Text == "" && c.StatusCode == 0 {
+			return fmt.Errorf("success_criteria must set at least one of url_pattern, selector, text or status_code")
+		}
+		if _, err := regexp.Compile(c.URLPattern); err != nil {
+			return fmt.Errorf("success_criteria.url_pattern is not a valid regular expression: %v", err)
+		}
+		if c.StatusCode != 0 && (c.StatusCode < 100 || c.StatusCode > 599) {
+			return fmt.Errorf("success_criteria.status_code must be between 100 and 599")
+		}
+	}
+	return nil
+}
+
+// filterableStatuses is the allow-list for the list-missions status filter
+var filterableStatuses = map[string]bool{
+	"pending":     true,
+	"running":     true,
+	"completed":   true,
+	"failed":      true,
+	"interrupted": true,
+	"scheduled":   true,
+	"cancelled":   true,
+}
+
+// parseFindingFilter reads type, severity, agent_id, status, limit and offset query
+// parameters. status is an exact code (500) or a class (5xx).
+func parseFindingFilter(query url.Values) (store.FindingFilter, error) {
+	var filter store.FindingFilter
+
+	switch v := query.Get("type"); v {
+	case "", "js_error", "network", "http_error", "broken_link", "bot_wall":
+		filter.Type = v
+	default:
+		return filter, fmt.Errorf("invalid type: %s", v)
+	}
+
+	switch v := query.Get("severity"); v {
+	case "", models.SeverityHigh, models.SeverityMedium, models.SeverityLow:
+		filter.Severity = v
+	default:
+		return filter, fmt.Errorf("invalid severity: %s", v)
+	}
+
+	filter.AgentID = query.Get("agent_id")
+
+	if v := query.Get("status"); v != "" {
+		if class, ok := strings.CutSuffix(strings.ToLower(v), "xx"); ok {
+			n, err := strconv.Atoi(class)
+			if err != nil || n < 1 || n > 5 {
+				return filter, fmt.Errorf("invalid status: %s", v)
+			}
+			filter.MinStatus, filter.MaxStatus = n*100, n*100+99
+		} else {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 100 || n > 599 {
+				return filter, fmt.Errorf("invalid status: %s", v)
+			}
+			filter.MinStatus, filter.MaxStatus = n, n
+		}
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return filter, fmt.Errorf("invalid limit: %s", v)
+		}
+		filter.Limit = limit
+	}
+
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return filter, fmt.Errorf("invalid offset: %s", v)
+		}
+		filter.Offset = offset
+	}
+
+	return filter, nil
+}
+
+// parseListOptions reads limit, offset, status, tag and sort query params
+func parseListOptions(query url.Values) (store.ListOptions, error) {
+	var opts store.ListOptions
+
+	if v := query.Get("status"); v != "" {
+		if !filterableStatuses[v] {
+			return opts, fmt.Errorf("invalid status: %s", v)
+		}
+		opts.Status = v
+	}
+
+	if values := query["tag"]; len(values) > 0 {
+		tags, err := normalizeTags(values)
+		if err != nil {
+			return opts, err
+		}
+		opts.Tags = tags
+	}
+
+	if v := query.Get("sort"); v != "" {
+		field, asc, err := store.ParseSort(v)
+		if err != nil {
+			return opts, err
+		}
+		opts.SortField = field
+		opts.SortAsc = asc
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return opts, fmt.Errorf("invalid limit: %s", v)
+		}
+		opts.Limit = limit
+	}
+
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return opts, fmt.Errorf("invalid offset: %s", v)
+		}
+		opts.Offset = offset
+	}
+
+	return opts, nil
 }
 
-func (api *RESTAPI) startMission(mission *models.Mission) {
+// startMission runs a mission with the given decision client
+func (api *RESTAPI) startMission(mission *models.Mission, client gemini.GeminiClient) {
 	log.Printf("Starting mission %s with %d agents (mode: %s)", mission.ID, mission.NumAgents, mission.ExecutionMode)
 
 	mission.Status = "running"
 	now := time.Now()
 	mission.StartedAt = &now
-	api.store.Put(mission)
+	api.store.Put(context.Background(), mission)
+	api.emitMissionEvent("mission_started", mission.ID)
+
+	api.runMission(mission, client, time.Duration(mission.MaxDurationSeconds)*time.Second, newAgentStates(mission))
+}
+
+// resumeMission relaunches the unfinished agents of a mission that was running
+// when the server stopped, for whatever remains of its duration
+func (api *RESTAPI) resumeMission(mission *models.Mission, remaining time.Duration) {
+	var agents []*models.Agent
+	if len(mission.AgentMetrics) == 0 {
+		// Interrupted before any agents were persisted
+		agents = newAgentStates(mission)
+	}
+	for _, a := range mission.AgentMetrics {
+		// Agents stopped on request stay stopped
+		if a.Status != "completed" && a.Status != "failed" && a.Status != "blocked" && a.Status != "stopped" {
+			agents = append(agents, a)
+		}
+	}
+
+	log.Printf("Resuming mission %s with %d agents (%s remaining)", mission.ID, len(agents), remaining.Round(time.Second))
+	api.emitMissionEvent("mission_started", mission.ID)
+
+	api.runMission(mission, api.gemini, remaining, agents)
+}
+
+// newAgentStates builds the initial state for each of a mission's agents
+func newAgentStates(mission *models.Mission) []*models.Agent {
+	agents := make([]*models.Agent, 0, mission.NumAgents)
+	for i := 0; i < mission.NumAgents; i++ {
+		agents = append(agents, &models.Agent{
+			ID:        fmt.Sprintf("%s-agent-%d", mission.ID, i),
+			MissionID: mission.ID,
+			Status:    "initialized",
+		})
+	}
+	return agents
+}
+
+// rampUpDelay is how long until an agent's launch slot in the mission's ramp-up:
+// agent i of n starts i/n of the way through the window. Slots are measured
+// from the mission's start, so a resumed mission doesn't ramp up again.
+func rampUpDelay(mission *models.Mission, agentID string) time.Duration {
+	if mission.RampUpSeconds <= 0 || mission.NumAgents <= 1 || mission.StartedAt == nil {
+		return 0
+	}
+	window := time.Duration(mission.RampUpSeconds) * time.Second
+	offset := window * time.Duration(agentIndex(agentID)) / time.Duration(mission.NumAgents)
+	return time.Until(mission.StartedAt.Add(offset))
+}
+
+// agentIndex recovers an agent's position from its "<mission>-agent-<i>" ID so
+// resumed agents keep the same random source. Retries share their slot's index.
+func agentIndex(agentID string) int {
+	if i := strings.LastIndex(agentID, retrySeparator); i >= 0 {
+		agentID = agentID[:i]
+	}
+	i := strings.LastIndex(agentID, "-agent-")
+	if i < 0 {
+		return 0
+	}
+	n, _ := strconv.Atoi(agentID[i+len("-agent-"):])
+	return n
+}
+
+// runMission runs the given agents until the duration elapses, then finalizes the mission
+func (api *RESTAPI) runMission(mission *models.Mission, client gemini.GeminiClient, duration time.Duration, agents []*models.Agent) {
+	if mission.AgentMetrics == nil {
+		mission.AgentMetrics = make(map[string]*models.Agent)
+	}
+
+	ctx, cancel := context.WithTimeout(api.ctx, duration)
+	defer cancel()
+	ctx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+	untrack, ok := api.trackRunning(mission.ID, stop)
+	if !ok {
+		api.finishDrained(mission, agents)
+		return
+	}
+	defer untrack()
 
 	// Create rate limiter
 	limiter := api.rateLimits.Get(mission.ID, mission.RateLimitPerSecond)
+	ctx, span := tracer.Start(ctx, "mission", trace.WithAttributes(
+		tracing.MissionID.String(mission.ID),
+		attribute.String("swarmtest.execution_mode", string(mission.ExecutionMode)),
+		attribute.Int("swarmtest.agents", len(agents)),
+	))
+	defer span.End()
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(mission.MaxDurationSeconds)*time.Second)
-	defer cancel()
+	var robots *utils.RobotsCache
+	if mission.RespectRobotsTxt {
+		robots = api.robots
+		// Load the target's rules once up front rather than on every agent's first request
+		if !robots.Allowed(ctx, mission.TargetURL) {
+			log.Printf("Mission %s: robots.txt disallows target %s", mission.ID, mission.TargetURL)
+		}
+	}
 
-	for i := 0; i < mission.NumAgents; i++ {
-		agentID := fmt.Sprintf("%s-agent-%d", mission.ID, i)
+	if mission.RampUpSeconds > 0 {
+		log.Printf("Mission %s: ramping up %d agents over %ds", mission.ID, mission.NumAgents, mission.RampUpSeconds)
+	}
 
+	var links *utils.LinkChecker
+	if mission.CheckLinks {
+		links = utils.NewLinkChecker()
+	}
+
+	proxy, _ := utils.ParseProxyURL(mission.Proxy)
+	if proxy != nil {
+		log.Printf("Mission %s: routing agents through proxy %s", mission.ID, proxy.Redacted())
+	}
+	if mission.InsecureSkipVerify {
+		log.Printf("WARNING: Mission %s: TLS certificate verification is DISABLED for %s", mission.ID, mission.TargetURL)
+	}
+
+	var visited *utils.VisitedSet
+	if mission.ShareVisitedURLs && mission.ExecutionMode != models.ExecutionModeCrawl {
+		visited = utils.NewVisitedSet()
+		api.liveMu.Lock()
+		api.visited[mission.ID] = visited
+		api.liveMu.Unlock()
+		defer func() {
+			api.liveMu.Lock()
+			delete(api.visited, mission.ID)
+			api.liveMu.Unlock()
+		}()
+	}
+
+	// The final save moves the mission's dropped event count onto it; this also
+	// clears a count left by a shutdown or by drops after the final save
+	defer api.drops.Take(mission.ID)
+
+	var crawl *utils.Crawler
+	if mission.ExecutionMode == models.ExecutionModeCrawl {
+		crawl = api.startCrawl(ctx, cancel, mission)
+		defer api.finishCrawl(mission.ID, crawl)
+	}
+
+	authorization, authOrigin := agent.TargetAuthFor(mission)
+	// Guards mission.AgentMetrics, which retries add to while agents run
+	var metricsMu sync.Mutex
+	launch := func(state *models.Agent) *agent.RuntimeAgent {
 		// Create browser executor if in browser mode
 		var browserExecutor *utils.BrowserExecutor
-		if mission.ExecutionMode == models.ExecutionModeBrowser && utils.SharedBrowserPool != nil {
-			browserExecutor = utils.NewBrowserExecutor(utils.SharedBrowserPool)
+		if (mission.ExecutionMode == models.ExecutionModeBrowser || mission.ExecutionMode == models.ExecutionModeAuto) && utils.SharedBrowserPool != nil {
+			browserExecutor = utils.NewBrowserExecutor(utils.SharedBrowserPool, utils.BrowserOptions{
+				ViewportWidth:    mission.ViewportWidth,
+				ViewportHeight:   mission.ViewportHeight,
+				Device:           mission.Device,
+				Proxy:            proxy,
+				IgnoreCertErrors: mission.InsecureSkipVerify,
+				UserAgent:        agent.UserAgentFor(mission, agentIndex(state.ID)),
+				Language:         mission.Language,
+				Timezone:         mission.Timezone,
+				MaxPageSize:      mission.MaxPageSizeBytes,
+				Authorization:    authorization,
+				AuthOrigin:       authOrigin,
+			})
 		}
 
 		runtimeAgent := agent.NewAgent(
-			agentID,
+			state.ID,
 			mission,
-			api.gemini,
+			client,
 			utils.NewHTTPClientFactory,
 			limiter,
 			api.eventBus,
+			api.drops,
 			browserExecutor,
+			robots,
+			links,
+			visited,
+			crawl,
+			agentIndex(state.ID),
 		)
+		runtimeAgent.Restore(state)
 
 		// Initialize agent metric in mission
-		mission.AgentMetrics[agentID] = &models.Agent{
-			ID:        agentID,
-			MissionID: mission.ID,
-			Status:    "initialized",
-		}
+		state.Status = "queued"
+		metricsMu.Lock()
+		mission.AgentMetrics[state.ID] = state
+		metricsMu.Unlock()
 
 		// Persist agent to database immediately to satisfy foreign key constraint
-		api.store.PutAgent(mission.AgentMetrics[agentID])
+		api.store.PutAgent(ctx, state)
+		return runtimeAgent
+	}
 
-		go func(a *agent.RuntimeAgent) {
-			a.Run(ctx)
-		}(runtimeAgent)
+	var agentsWG sync.WaitGroup
+	for _, state := range agents {
+		runtimeAgent := launch(state)
+		agentsWG.Add(1)
+		go func(id string, runtimeAgent *agent.RuntimeAgent, delay time.Duration) {
+			defer agentsWG.Done()
+			for {
+				api.runAgent(api.agents.add(ctx, mission.ID, id), mission.ID, id, runtimeAgent, delay)
+				next, ok := api.nextAttempt(ctx, mission, id, runtimeAgent)
+				if !ok {
+					return
+				}
+				id, runtimeAgent, delay = next.ID, launch(next), 0
+			}
+		}(state.ID, runtimeAgent, rampUpDelay(mission, state.ID))
 	}
 
-	// Wait for context done (mission timeout) or completion logic?
-	// For now, we just let agents run until timeout.
+	// Agents run until the mission times out, is drained or a budget runs
+	// out; meanwhile watch for error rate alerts
+	api.watchMission(ctx, mission)
 
-	<-ctx.Done()
+	// Let agents finish their current step so their final state is recorded
+	agentsWG.Wait()
+
+	if api.shuttingDown() {
+		// Left running so the next process resumes or reaps it
+		log.Printf("Mission %s interrupted by server shutdown", mission.ID)
+		api.rateLimits.Remove(mission.ID)
+		return
+	}
 
 	log.Printf("Mission %s finished (timeout or completed)", mission.ID)
+
+	// Reload so the final save doesn't clobber metrics the event logger has flushed
+	// (the mission context has expired by now)
+	if latest, ok := api.store.Get(context.Background(), mission.ID); ok {
+		mission = latest
+	}
 	mission.Status = "completed"
+	mission.StopReason = stopReason(ctx)
+	mission.DroppedEvents += api.drops.Take(mission.ID)
 	completedAt := time.Now()
 	mission.CompletedAt = &completedAt
 
 	// Final save
-	api.store.Put(mission)
+	api.store.Put(context.Background(), mission)
+	api.emitMissionEvent("mission_completed", mission.ID)
 
 	// Clean up rate limiter
 	api.rateLimits.Remove(mission.ID)
 }
 
-// extractMissionID extracts mission ID from URL path
-func extractMissionID(path string) string {
-	// Path format: /api/missions/{id}
-	// Trim prefix /api/missions/
-	// This is cleaner than splitting
-	prefix := "/api/missions/"
-	if len(path) > len(prefix) && path[:len(prefix)] == prefix {
-		return path[len(prefix):]
+// runAgent waits for a free agent slot, then runs the agent to completion.
+// Agents still queued when the mission ends, or stopped while queued, are
+// marked stopped without running. Agents cut short by a server shutdown are
+// marked interrupted instead, so a resumed mission relaunches them.
+func (api *RESTAPI) runAgent(ctx context.Context, missionID, agentID string, a *agent.RuntimeAgent, delay time.Duration) {
+	defer api.agents.remove(missionID, agentID)
+	defer func() {
+		if api.shuttingDown() && a.Status() == "stopped" {
+			a.SetStatus("interrupted")
+		}
+	}()
+	a.SetStatus("queued")
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			a.SetStatus("stopped")
+			return
+		}
+	}
+
+	select {
+	case api.agentSlots <- struct{}{}:
+	case <-ctx.Done():
+		a.SetStatus("stopped")
+		return
+	}
+	defer func() { <-api.agentSlots }()
+
+	a.Run(ctx)
+}
+
+// ReconcileInterruptedMissions handles missions left in "running" by a previous
+// process. With resume set, missions with time remaining are relaunched from
+// their agents' last known URLs; all others are marked "interrupted".
+func (api *RESTAPI) ReconcileInterruptedMissions(ctx context.Context, resume bool) {
+	var stale []*models.Mission
+	for offset := 0; ; offset += store.MaxListLimit {
+		page, total := api.store.List(ctx, store.ListOptions{
+			Status: "running",
+			Limit:  store.MaxListLimit,
+			Offset: offset,
+		})
+		stale = append(stale, page...)
+		if len(page) == 0 || offset+len(page) >= total {
+			break
+		}
+	}
+
+	for _, summary := range stale {
+		mission, ok := api.store.Get(ctx, summary.ID)
+		if !ok {
+			continue
+		}
+
+		remaining := time.Duration(mission.MaxDurationSeconds) * time.Second
+		if mission.StartedAt != nil {
+			remaining -= time.Since(*mission.StartedAt)
+		}
+
+		// Replays can't resume: their position in the recording is lost
+		if resume && remaining > 0 && mission.ReplayOf == "" {
+			api.goMission(func() { api.resumeMission(mission, remaining) })
+			continue
+		}
+
+		log.Printf("Marking stale mission %s as interrupted", mission.ID)
+		mission.Status = "interrupted"
+		completedAt := time.Now()
+		mission.CompletedAt = &completedAt
+		for _, a := range mission.AgentMetrics {
+			if a.Status != "completed" && a.Status != "failed" && a.Status != "blocked" && a.Status != "stopped" {
+				a.Status = "interrupted"
+			}
+		}
+		// Nothing is left for the event logger to flush
+		api.store.Put(ctx, mission)
+		api.goWebhook(mission.ID)
+	}
+}
+
+// emitMissionEvent publishes a mission lifecycle event. Unlike agent action events
+// this send blocks: the fan-out loop drains the bus continuously and these events
+// drive the event logger's metric initialization and final flush.
+func (api *RESTAPI) emitMissionEvent(eventType, missionID string) {
+	api.eventBus <- models.Event{
+		Type:      eventType,
+		Timestamp: time.Now(),
+		Data:      map[string]string{"mission_id": missionID},
+	}
+}
+
+// parseMissionPath splits /api/missions/{id}[/{resource}] into its parts
+func parseMissionPath(path string) (id, resource string) {
+	rest, ok := strings.CutPrefix(path, "/api/missions/")
+	if !ok {
+		return "", ""
 	}
-	return ""
+	id, resource, _ = strings.Cut(strings.Trim(rest, "/"), "/")
+	return id, resource
 }
 
 // generateMissionID generates a unique mission ID
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"swarmtest/internal/agent"
+	"swarmtest/internal/agent/agenttest"
+	"swarmtest/internal/gemini/geminitest"
+	"swarmtest/internal/models"
+	"swarmtest/internal/services"
+	"swarmtest/internal/store"
+)
+
+func TestParseListOptions(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    store.ListOptions
+		wantErr bool
+	}{
+		{query: "", want: store.ListOptions{}},
+		{query: "status=running", want: store.ListOptions{Status: "running"}},
+		{query: "status=completed&sort=created_at:desc", want: store.ListOptions{Status: "completed", SortField: "created_at"}},
+		{query: "status=failed&sort=name:asc", want: store.ListOptions{Status: "failed", SortField: "name", SortAsc: true}},
+		{query: "sort=total_errors", want: store.ListOptions{SortField: "total_errors"}},
+		{query: "sort=started_at:asc&limit=10&offset=20", want: store.ListOptions{SortField: "started_at", SortAsc: true, Limit: 10, Offset: 20}},
+		{query: "status=scheduled&tag=Smoke&tag=nightly", want: store.ListOptions{Status: "scheduled", Tags: []string{"smoke", "nightly"}}},
+		{query: "status=bogus", wantErr: true},
+		{query: "sort=goal:asc", wantErr: true},
+		{query: "sort=created_at:up", wantErr: true},
+		{query: "limit=0", wantErr: true},
+		{query: "offset=-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := parseListOptions(values)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseListOptions(%q) = %+v, want an error", tt.query, got)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseListOptions(%q) = %+v, %v, want %+v", tt.query, got, err, tt.want)
+			}
+		})
+	}
+}
+
+// startLoginMission runs a mission of one or more agents against the login
+// site to completion, with the event logger consuming its events and
+// webhooks sent as in the server. It returns the stored mission once the
+// logger has flushed it, and the types of the events on the bus in order.
+func startLoginMission(t *testing.T, mission *models.Mission) (*memStore, []string) {
+	t.Helper()
+	site := agenttest.NewLoginSite()
+	t.Cleanup(site.Close)
+	mission.TargetURL = site.URL
+	mission.Goal = agenttest.LoginGoal
+	mission.ExecutionMode = models.ExecutionModeHTTP
+	mission.RateLimitPerSecond = 100
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	st := newMemStore()
+	bus := make(chan models.Event, 1000)
+	loggerBus := make(chan models.Event, 1000)
+	webhookBus := make(chan models.Event, 1000)
+	flushed := make(chan struct{})
+	var mu sync.Mutex
+	var types []string
+	go func() {
+		for event := range bus {
+			mu.Lock()
+			types = append(types, event.Type)
+			mu.Unlock()
+			loggerBus <- event
+			webhookBus <- event
+			if event.Type == "mission_flushed" {
+				close(flushed)
+			}
+		}
+	}()
+	api := NewRESTAPI(ctx, st, nil, bus, agent.NewDropCounter(), 1)
+	go services.NewEventLogger(st, loggerBus, bus, api).Run(ctx)
+	// Webhooks are sent after the mission's events stop
+	webhookCtx, stopWebhooks := context.WithCancel(context.Background())
+	t.Cleanup(stopWebhooks)
+	go api.RunWebhooks(webhookCtx, webhookBus)
+
+	st.Put(ctx, mission)
+	api.startMission(mission, geminitest.NewFakeGeminiClient(agenttest.LoginFlow("alice")))
+
+	// The logger flushes a finished mission's metrics on mission_completed,
+	// well before its periodic flush
+	select {
+	case <-flushed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("mission metrics were not flushed after mission_completed")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return st, append([]string(nil), types...)
+}
+
+func TestMissionLifecycleEvents(t *testing.T) {
+	st, types := startLoginMission(t, &models.Mission{ID: "lifecycle", NumAgents: 1, MaxDurationSeconds: 1})
+
+	if n := len(types); n < 3 || types[0] != "mission_started" || types[n-2] != "mission_completed" || types[n-1] != "mission_flushed" {
+		t.Fatalf("events %v, want mission_started first and mission_completed then mission_flushed last", types)
+	}
+	mission, _ := st.Get(context.Background(), "lifecycle")
+	if mission.Status != "completed" || mission.StartedAt == nil || mission.CompletedAt == nil {
+		t.Errorf("mission status %q started %v completed %v, want completed with both times", mission.Status, mission.StartedAt, mission.CompletedAt)
+	}
+	if a := mission.AgentMetrics["lifecycle-agent-0"]; a == nil || a.Status != "completed" {
+		t.Errorf("agent state %+v, want completed", a)
+	}
+}
+
+// The webhook is sent once the mission's final metrics are in the store
+func TestWebhookSentAfterFinalFlush(t *testing.T) {
+	payloads := make(chan models.WebhookPayload, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload models.WebhookPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		payloads <- payload
+	}))
+	defer receiver.Close()
+
+	mission := &models.Mission{ID: "webhook", NumAgents: 1, MaxDurationSeconds: 1}
+	mission.WebhookURL = receiver.URL
+	startLoginMission(t, mission)
+
+	select {
+	case payload := <-payloads:
+		if payload.Event != "mission_finished" || payload.Summary == nil || payload.Summary.TotalActions == 0 {
+			t.Errorf("payload %+v, want mission_finished with the final actions", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+}
+
+// The event logger stops a mission as soon as its budget runs out, well within
+// its duration
+func TestBudgetStopsMission(t *testing.T) {
+	mission := &models.Mission{ID: "budget", NumAgents: 1, MaxDurationSeconds: 30}
+	mission.MaxTotalActions = 1
+	start := time.Now()
+	st, _ := startLoginMission(t, mission)
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("mission ran %s, want it stopped right after its first action", elapsed)
+	}
+	got, _ := st.Get(context.Background(), "budget")
+	if got.StopReason != services.StopReasonMaxTotalActions {
+		t.Errorf("stop reason = %q, want %q", got.StopReason, services.StopReasonMaxTotalActions)
+	}
+}
+
+func TestIdempotencyKeyScopedToCallerAndBody(t *testing.T) {
+	st := newMemStore()
+	api := NewRESTAPI(context.Background(), st, nil, nil, agent.NewDropCounter(), 1)
+	// Scheduled, so creating the mission doesn't start it
+	at := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	body := fmt.Sprintf(`{"target_url":"https://example.com","goal":"Look around","num_agents":1,"max_duration_seconds":60,"scheduled_at":%q}`, at)
+	create := func(caller, body string) (*httptest.ResponseRecorder, string) {
+		r := httptest.NewRequest(http.MethodPost, "/api/missions", strings.NewReader(body))
+		r.Header.Set("Idempotency-Key", "retry-1")
+		r = r.WithContext(context.WithValue(r.Context(), apiKeyIDKey{}, caller))
+		w := httptest.NewRecorder()
+		api.createMission(w, r)
+		var resp models.CreateMissionResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w, resp.MissionID
+	}
+
+	w, first := create("alice", body)
+	if w.Code != http.StatusOK || first == "" {
+		t.Fatalf("create = %d, want 200 with a mission ID", w.Code)
+	}
+	if w, id := create("alice", body); id != first || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry returned mission %q, want the replayed %q", id, first)
+	}
+	if w, id := create("bob", body); w.Code != http.StatusOK || id == first {
+		t.Errorf("another caller's key returned mission %q (%d), want a new mission", id, w.Code)
+	}
+	changed := strings.Replace(body, "Look around", "Buy something", 1)
+	if w, _ := create("alice", changed); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key with a different body = %d, want 422", w.Code)
+	}
+}
+
+func TestMissionActionLogsIncludeReasoning(t *testing.T) {
+	st := newMemStore()
+	st.Put(context.Background(), &models.Mission{ID: "m1", Status: "completed"})
+	st.AddActionLog(context.Background(), models.ActionLog{
+		AgentID:           "m1-agent-0",
+		Action:            "click",
+		Selector:          "#login",
+		Result:            "success",
+		Reasoning:         "The goal needs a signed-in user",
+		ExpectedNextState: "Login form",
+	}, "m1")
+	api := NewRESTAPI(context.Background(), st, nil, nil, agent.NewDropCounter(), 1)
+
+	w := httptest.NewRecorder()
+	api.handleMissionDetailOrActions(w, httptest.NewRequest(http.MethodGet, "/api/missions/m1/actions", nil))
+	var logs []map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&logs); err != nil || len(logs) != 1 {
+		t.Fatalf("GET /actions = %d, %v, %d logs, want 1", w.Code, err, len(logs))
+	}
+	if logs[0]["reasoning"] != "The goal needs a signed-in user" || logs[0]["expected_next_state"] != "Login form" {
+		t.Errorf("log = %v, want its reasoning and expected_next_state", logs[0])
+	}
+}
+
+func TestValidateElementTypes(t *testing.T) {
+	tests := []struct {
+		types   []string
+		wantErr bool
+	}{
+		{nil, false},
+		{[]string{"link"}, false},
+		{[]string{"link", "button", "input", "form"}, false},
+		{[]string{"anchor"}, true},
+		{[]string{"input", "file_input"}, true},
+	}
+	for _, tt := range tests {
+		err := validateMissionOptions(models.MissionOptions{ElementTypes: tt.types})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("element_types %v: error = %v, want error %v", tt.types, err, tt.wantErr)
+		}
+	}
+}
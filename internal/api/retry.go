@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"swarmtest/internal/agent"
+	"swarmtest/internal/models"
+)
+
+const (
+	// maxRetryFailedAgents bounds retry_failed_agents
+	maxRetryFailedAgents = 10
+	// minAgentRetryTime is the least mission time left worth relaunching a
+	// failed agent for
+	minAgentRetryTime = 5 * time.Second
+	// retrySeparator joins an agent slot's ID and its retry number:
+	// "<mission>-agent-<i>-retry-<n>"
+	retrySeparator = "-retry-"
+)
+
+// retryAttempt is which retry of its slot an agent is, 0 for the original
+func retryAttempt(agentID string) int {
+	i := strings.LastIndex(agentID, retrySeparator)
+	if i < 0 {
+		return 0
+	}
+	n, _ := strconv.Atoi(agentID[i+len(retrySeparator):])
+	return n
+}
+
+// retryAgentID names the attempt'th replacement for the agent in slot index
+func retryAgentID(missionID string, index, attempt int) string {
+	return fmt.Sprintf("%s-agent-%d%s%d", missionID, index, retrySeparator, attempt)
+}
+
+// nextAttempt returns the state for a fresh agent replacing one that just
+// finished, or false if it isn't to be replaced: it didn't fail, its slot has
+// used its retry_failed_agents, or the mission is out of time or budget
+func (api *RESTAPI) nextAttempt(ctx context.Context, mission *models.Mission, agentID string, a *agent.RuntimeAgent) (*models.Agent, bool) {
+	attempt := retryAttempt(agentID)
+	if a.Status() != "failed" || attempt >= mission.RetryFailedAgents {
+		return nil, false
+	}
+	// A budget running out cancels ctx too
+	if ctx.Err() != nil || api.shuttingDown() {
+		return nil, false
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < minAgentRetryTime {
+		return nil, false
+	}
+
+	state := &models.Agent{
+		ID:        retryAgentID(mission.ID, agentIndex(agentID), attempt+1),
+		MissionID: mission.ID,
+		Status:    "initialized",
+		RetryOf:   agentID,
+	}
+	log.Printf("Mission %s: agent %s failed, relaunching it as %s (retry %d of %d)",
+		mission.ID, agentID, state.ID, attempt+1, mission.RetryFailedAgents)
+	return state, true
+}
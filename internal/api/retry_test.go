@@ -0,0 +1,40 @@
+package api
+
+import "testing"
+
+func TestRetryAgentIDs(t *testing.T) {
+	tests := []struct {
+		agentID     string
+		wantAttempt int
+		wantIndex   int
+	}{
+		{"m1-agent-0", 0, 0},
+		{"m1-agent-7", 0, 7},
+		{"m1-agent-3-retry-1", 1, 3},
+		{"m1-agent-12-retry-10", 10, 12},
+		// Mission IDs may themselves contain the separators
+		{"load-agent-test-agent-2-retry-4", 4, 2},
+		{"m1-agent-2-retry-x", 0, 2},
+		{"agent", 0, 0},
+	}
+	for _, tt := range tests {
+		if got := retryAttempt(tt.agentID); got != tt.wantAttempt {
+			t.Errorf("retryAttempt(%q) = %d, want %d", tt.agentID, got, tt.wantAttempt)
+		}
+		if got := agentIndex(tt.agentID); got != tt.wantIndex {
+			t.Errorf("agentIndex(%q) = %d, want %d", tt.agentID, got, tt.wantIndex)
+		}
+	}
+}
+
+func TestRetryAgentIDRoundTrip(t *testing.T) {
+	for attempt := 1; attempt <= maxRetryFailedAgents; attempt++ {
+		id := retryAgentID("m1", 5, attempt)
+		if retryAttempt(id) != attempt || agentIndex(id) != 5 {
+			t.Errorf("%s parses as attempt %d of slot %d, want %d of 5", id, retryAttempt(id), agentIndex(id), attempt)
+		}
+	}
+	if id := retryAgentID("m1", 5, 2); id != "m1-agent-5-retry-2" {
+		t.Errorf("retryAgentID() = %q, want m1-agent-5-retry-2", id)
+	}
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"swarmtest/internal/models"
+	"swarmtest/internal/utils"
+)
+
+// nextRun is when a scheduled mission first launches, or nil if it isn't scheduled
+func nextRun(opts models.MissionOptions, now time.Time) (*time.Time, error) {
+	if opts.ScheduledAt != nil {
+		if !opts.ScheduledAt.After(now) {
+			return nil, fmt.Errorf("scheduled_at must be in the future")
+		}
+		at := *opts.ScheduledAt
+		return &at, nil
+	}
+	if opts.Cron != "" {
+		schedule, err := utils.ParseCron(opts.Cron)
+		if err != nil {
+			return nil, err
+		}
+		next := schedule.Next(now)
+		return &next, nil
+	}
+	return nil, nil
+}
+
+// LaunchScheduled starts a scheduled mission that has come due. A one-shot
+// mission runs itself; a cron mission stays scheduled for its next time and
+// launches a new run of itself.
+// Nothing launches while missions are drained, and runMission stops a launch
+// that races a drain; due missions launch once the drain is lifted.
+func (api *RESTAPI) LaunchScheduled(ctx context.Context, scheduled *models.Mission) {
+	if api.shuttingDown() || api.draining.Load() {
+		return
+	}
+	api.scheduleMu.Lock()
+	defer api.scheduleMu.Unlock()
+
+	// Re-read it, in case it was cancelled since it was listed
+	mission, ok := api.store.Get(ctx, scheduled.ID)
+	if !ok || mission.Status != "scheduled" {
+		return
+	}
+
+	if mission.Cron == "" {
+		log.Printf("Launching scheduled mission %s", mission.ID)
+		// Saved before unlocking, so it can no longer be cancelled or relaunched
+		mission.Status = "pending"
+		mission.NextRunAt = nil
+		api.store.Put(ctx, mission)
+		api.goMission(func() { api.startMission(mission, api.gemini) })
+		return
+	}
+
+	schedule, err := utils.ParseCron(mission.Cron)
+	if err != nil {
+		log.Printf("Mission %s has an invalid cron schedule, not launching it: %v", mission.ID, err)
+		return
+	}
+	next := schedule.Next(time.Now())
+	mission.NextRunAt = &next
+	api.store.Put(ctx, mission)
+
+	run := scheduledRun(mission)
+	api.store.Put(ctx, run)
+	log.Printf("Launching mission %s as a run of cron mission %s; next run at %s", run.ID, mission.ID, next.Format(time.RFC3339))
+	api.goMission(func() { api.startMission(run, api.gemini) })
+}
+
+// scheduledRun is a new mission carrying out one run of a cron mission
+func scheduledRun(cron *models.Mission) *models.Mission {
+	run := &models.Mission{
+		ID:                  generateMissionID(),
+		Name:                cron.Name,
+		TargetURL:           cron.TargetURL,
+		NumAgents:           cron.NumAgents,
+		Goal:                cron.Goal,
+		MaxDurationSeconds:  cron.MaxDurationSeconds,
+		RateLimitPerSecond:  cron.RateLimitPerSecond,
+		InitialSystemPrompt: cron.InitialSystemPrompt,
+		ExecutionMode:       cron.ExecutionMode,
+		ScheduledFrom:       cron.ID,
+		Tags:                cron.Tags,
+		MissionOptions:      cron.MissionOptions,
+		Status:              "pending",
+		CreatedAt:           time.Now(),
+		AgentMetrics:        make(map[string]*models.Agent),
+		RecentEvents:        []models.ActionLog{},
+	}
+	run.Cron = ""
+	run.ScheduledAt = nil
+	return run
+}
+
+// handleCancelMission cancels a mission that is scheduled and hasn't started;
+// a cron mission launches no further runs
+func (api *RESTAPI) handleCancelMission(w http.ResponseWriter, r *http.Request, missionID string) {
+	api.scheduleMu.Lock()
+	defer api.scheduleMu.Unlock()
+
+	mission, exists := api.store.Get(r.Context(), missionID)
+	if !exists {
+		http.Error(w, "Mission not found", http.StatusNotFound)
+		return
+	}
+	if mission.Status != "scheduled" {
+		http.Error(w, "Only scheduled missions can be cancelled", http.StatusConflict)
+		return
+	}
+
+	mission.Status = "cancelled"
+	mission.NextRunAt = nil
+	now := time.Now()
+	mission.CompletedAt = &now
+	api.store.Put(r.Context(), mission)
+
+	log.Printf("Cancelled scheduled mission %s", missionID)
+	json.NewEncoder(w).Encode(map[string]string{
+		"mission_id": missionID,
+		"status":     mission.Status,
+	})
+}
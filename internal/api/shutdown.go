@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"log"
+)
+
+// goMission runs fn, a mission's lifetime, in its own goroutine and tracks it
+// so Shutdown can wait for it
+func (api *RESTAPI) goMission(fn func()) {
+	api.missionsWG.Add(1)
+	go func() {
+		defer api.missionsWG.Done()
+		fn()
+	}()
+}
+
+// shuttingDown reports whether Shutdown has been called
+func (api *RESTAPI) shuttingDown() bool {
+	return api.ctx.Err() != nil
+}
+
+// Shutdown stops every running mission and waits, until ctx expires, for their
+// agents to stop and their final state to be put on the event bus. Missions
+// stopped this way stay "running" with their unfinished agents "interrupted",
+// so the next process resumes or reaps them like after a crash.
+func (api *RESTAPI) Shutdown(ctx context.Context) error {
+	api.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		api.missionsWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("All missions stopped")
+		return nil
+	case <-ctx.Done():
+		log.Println("Timed out waiting for missions to stop")
+		return ctx.Err()
+	}
+}
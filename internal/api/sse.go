@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// sseHeartbeatInterval is how often an idle Server-Sent Events connection
+	// gets a comment, so proxies don't close it
+	sseHeartbeatInterval = 15 * time.Second
+	// sseRetryMS is how long EventSource waits before reconnecting
+	sseRetryMS = 3000
+)
+
+// ServeEvents streams the hub's events as Server-Sent Events, each as a data
+// line holding the same JSON as a WebSocket message. A mission query parameter
+// limits the stream to that mission's events.
+func ServeEvents(hub *WebSocketHub, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stream := hub.subscribe(r.URL.Query().Get("mission"))
+	defer hub.unsubscribe(stream)
+
+	rc := http.NewResponseController(w)
+	// The connection stays open past the server's write timeout
+	rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Stops nginx buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetryMS)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case event, ok := <-stream.send:
+			if !ok {
+				return // dropped for falling behind; EventSource reconnects
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", event.data); err != nil {
+				return
+			}
+
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// streamSendBuffer is how many events a stream queues before it is
+	// considered too slow and closed
+	streamSendBuffer = 256
+	// streamStatusInterval is how often a stream checks whether its mission
+	// ended without a mission_completed event (cancelled or interrupted)
+	streamStatusInterval = 5 * time.Second
+)
+
+// eventStream receives the hub's events for one mission, or for all of them
+// when missionID is empty
+type eventStream struct {
+	missionID string
+	send      chan streamedEvent
+}
+
+// streamedEvent is an event as broadcast, with its type for the stream to act on
+type streamedEvent struct {
+	eventType string
+	data      []byte
+}
+
+// subscribe starts streaming the mission's events; "" streams every event
+func (h *WebSocketHub) subscribe(missionID string) *eventStream {
+	s := &eventStream{missionID: missionID, send: make(chan streamedEvent, streamSendBuffer)}
+	h.mu.Lock()
+	h.streams[s] = true
+	h.mu.Unlock()
+	return s
+}
+
+// unsubscribe stops a stream; closing its channel tells its reader it ended
+func (h *WebSocketHub) unsubscribe(s *eventStream) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.streams[s] {
+		delete(h.streams, s)
+		close(s.send)
+	}
+}
+
+// eventMissionID is the mission a marshalled event belongs to, or "" for one
+// that isn't about a mission. Every mission event carries it in data.mission_id.
+func eventMissionID(data []byte) string {
+	var probe struct {
+		Data struct {
+			MissionID string `json:"mission_id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return ""
+	}
+	return probe.Data.MissionID
+}
+
+// wantsMission reports whether a subscriber limited to the mission wanted (all
+// missions if empty) receives an event of the mission given
+func wantsMission(wanted, missionID string) bool {
+	return wanted == "" || wanted == missionID
+}
+
+// sendToStreams queues a marshalled event for the streams that want it. The
+// caller holds h.mu for reading.
+func (h *WebSocketHub) sendToStreams(eventType string, data []byte, missionID string) {
+	for s := range h.streams {
+		if !wantsMission(s.missionID, missionID) {
+			continue
+		}
+		select {
+		case s.send <- streamedEvent{eventType: eventType, data: data}:
+		default:
+			log.Printf("[WebSocketHub] Event stream is too slow, closing it")
+			go h.unsubscribe(s)
+		}
+	}
+}
+
+// isTerminalStatus reports whether a mission with this status has finished
+func isTerminalStatus(status string) bool {
+	return status == "completed" || status == "cancelled" || status == "interrupted"
+}
+
+// handleMissionStream streams the mission's events as newline-delimited JSON,
+// one event per line as on the WebSocket, until the mission finishes or the
+// client disconnects
+func (api *RESTAPI) handleMissionStream(w http.ResponseWriter, r *http.Request, missionID string) {
+	if api.Events == nil {
+		http.Error(w, "Event streaming is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Subscribed before the status check, so no event falls between the two
+	stream := api.Events.subscribe(missionID)
+	defer api.Events.unsubscribe(stream)
+
+	mission, exists := api.store.Get(r.Context(), missionID)
+	if !exists {
+		http.Error(w, "Mission not found", http.StatusNotFound)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// The stream lasts as long as the mission, past the server's write timeout
+	rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if isTerminalStatus(mission.Status) {
+		return
+	}
+	rc.Flush()
+
+	ticker := time.NewTicker(streamStatusInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case event, ok := <-stream.send:
+			if !ok {
+				return // dropped for falling behind
+			}
+			// data is shared with the hub's other receivers, so it isn't appended to
+			if _, err := w.Write(event.data); err != nil {
+				return
+			}
+			if _, err := w.Write([]byte("\n")); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+			if event.eventType == "mission_completed" {
+				return
+			}
+
+		case <-ticker.C:
+			if latest, ok := api.store.Get(r.Context(), missionID); !ok || isTerminalStatus(latest.Status) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"swarmtest/internal/models"
+	"swarmtest/internal/store"
+)
+
+// maxTemplateNameLength bounds template names
+const maxTemplateNameLength = 100
+
+func (api *RESTAPI) handleTemplates(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		api.createTemplate(w, r)
+	case "GET":
+		json.NewEncoder(w).Encode(map[string]any{
+			"templates": api.store.ListTemplates(r.Context()),
+		})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (api *RESTAPI) createTemplate(w http.ResponseWriter, r *http.Request) {
+	var template models.MissionTemplate
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	template.Name = strings.TrimSpace(template.Name)
+	if err := validateTemplate(&template); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tags, err := normalizeTags(template.Tags)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	template.Tags = tags
+	template.ID = "template-" + uuid.New().String()[:8]
+	template.CreatedAt = time.Now()
+
+	if err := api.store.CreateTemplate(r.Context(), &template); err != nil {
+		if errors.Is(err, store.ErrTemplateExists) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("Error creating template %s: %v", template.Name, err)
+		http.Error(w, "Failed to save template", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Created template %s (%s)", template.ID, template.Name)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(template)
+}
+
+// validateTemplate checks a template's settings. Settings that only make sense
+// for one target or one run can't be saved; the rest are checked again with
+// the target when a mission is created from the template.
+func validateTemplate(t *models.MissionTemplate) error {
+	if t.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(t.Name) > maxTemplateNameLength {
+		return fmt.Errorf("name must be at most %d characters", maxTemplateNameLength)
+	}
+	switch t.ExecutionMode {
+	case "", models.ExecutionModeHTTP, models.ExecutionModeBrowser, models.ExecutionModeAuto, models.ExecutionModeCrawl:
+	default:
+		return fmt.Errorf("invalid execution mode")
+	}
+	if t.NumAgents < 0 || t.MaxDurationSeconds < 0 || t.RateLimitPerSecond < 0 {
+		return fmt.Errorf("num_agents, max_duration_seconds and rate_limit_per_second must not be negative")
+	}
+	if t.TargetAuth != nil {
+		return fmt.Errorf("target_auth belongs to a target and can't be saved in a template")
+	}
+	if t.ScheduledAt != nil {
+		return fmt.Errorf("scheduled_at can't be saved in a template")
+	}
+	return validateMissionOptions(t.MissionOptions)
+}
+
+// applyTemplate fills req from the template it names, with the fields set in
+// the request body overriding the template's
+func (api *RESTAPI) applyTemplate(r *http.Request, body []byte, req *models.CreateMissionRequest) (int, error) {
+	template, ok := api.store.GetTemplate(r.Context(), req.TemplateID)
+	if !ok {
+		return http.StatusBadRequest, fmt.Errorf("template not found: %s", req.TemplateID)
+	}
+
+	base, err := json.Marshal(models.CreateMissionRequest{
+		Name:                template.Name,
+		NumAgents:           template.NumAgents,
+		Goal:                template.Goal,
+		MaxDurationSeconds:  template.MaxDurationSeconds,
+		RateLimitPerSecond:  template.RateLimitPerSecond,
+		InitialSystemPrompt: template.InitialSystemPrompt,
+		ExecutionMode:       template.ExecutionMode,
+		Tags:                template.Tags,
+		MissionOptions:      template.MissionOptions,
+	})
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("encode template: %w", err)
+	}
+
+	var merged, overrides map[string]json.RawMessage
+	if err := json.Unmarshal(base, &merged); err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("encode template: %w", err)
+	}
+	if err := json.Unmarshal(body, &overrides); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("Invalid request body")
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	mergedBody, err := json.Marshal(merged)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("encode template: %w", err)
+	}
+
+	*req = models.CreateMissionRequest{}
+	if err := json.Unmarshal(mergedBody, req); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("Invalid request body")
+	}
+	req.TemplateID = template.ID
+	return 0, nil
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"swarmtest/internal/models"
+)
+
+const (
+	// WebhookSignatureHeader carries the hex HMAC-SHA256 of the body when a secret is configured
+	WebhookSignatureHeader = "X-SwarmTest-Signature"
+
+	webhookAttempts = 3
+	webhookTimeout  = 10 * time.Second
+)
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// RunWebhooks consumes events until ctx is cancelled, posting each finished
+// mission's final summary to its webhook once the event logger has flushed
+// the mission's final metrics
+func (api *RESTAPI) RunWebhooks(ctx context.Context, events <-chan models.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			if event.Type != "mission_flushed" {
+				continue
+			}
+			if data, ok := event.Data.(map[string]string); ok && data["mission_id"] != "" {
+				api.goWebhook(data["mission_id"])
+			}
+		}
+	}
+}
+
+// goWebhook notifies the mission's webhook in its own goroutine, tracked so
+// WaitWebhooks can wait for it
+func (api *RESTAPI) goWebhook(missionID string) {
+	api.webhooksWG.Add(1)
+	go func() {
+		defer api.webhooksWG.Done()
+		api.notifyWebhook(missionID)
+	}()
+}
+
+// WaitWebhooks waits, until ctx expires, for webhook deliveries in flight
+func (api *RESTAPI) WaitWebhooks(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		api.webhooksWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Println("Timed out waiting for webhook deliveries")
+	}
+}
+
+// notifyWebhook posts the mission's final summary to its webhook URL, if any
+func (api *RESTAPI) notifyWebhook(missionID string) {
+	ctx := context.Background()
+	mission, ok := api.store.Get(ctx, missionID)
+	if !ok || mission.WebhookURL == "" {
+		return
+	}
+
+	api.deliverWebhook(mission.ID, mission.WebhookURL, models.WebhookPayload{
+		Event:       "mission_finished",
+		MissionID:   mission.ID,
+		Name:        mission.Name,
+		Status:      mission.Status,
+		CompletedAt: mission.CompletedAt,
+		Summary:     api.missionSummary(mission),
+	})
+}
+
+// deliverWebhook posts payload to webhookURL, retrying transient failures
+func (api *RESTAPI) deliverWebhook(missionID, webhookURL string, payload models.WebhookPayload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Mission %s: failed to encode webhook payload: %v", missionID, err)
+		return
+	}
+
+	ctx := context.Background()
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		retry, err := api.postWebhook(ctx, webhookURL, body)
+		if err == nil {
+			log.Printf("Mission %s: %s webhook delivered", missionID, payload.Event)
+			return
+		}
+		log.Printf("Mission %s: webhook attempt %d/%d failed: %v", missionID, attempt, webhookAttempts, err)
+		if !retry {
+			return
+		}
+		if attempt < webhookAttempts {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}
+}
+
+// postWebhook sends one delivery. retry reports whether the failure is worth
+// retrying: network errors, 429 and 5xx are; other 4xx responses are not.
+func (api *RESTAPI) postWebhook(ctx context.Context, webhookURL string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SwarmTest/1.0")
+	if api.WebhookSecret != "" {
+		req.Header.Set(WebhookSignatureHeader, "sha256="+signWebhook(api.WebhookSecret, body))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+// signWebhook returns the hex HMAC-SHA256 of body under secret
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,31 +14,82 @@ import (
 	"swarmtest/internal/store"
 )
 
-// WebSocketUpgrader upgrades HTTP to WebSocket
+const (
+	// Time allowed to write a message to the peer
+	writeWait = 10 * time.Second
+	// Time allowed to read the next pong message from the peer
+	pongWait = 60 * time.Second
+	// Send pings at this period; must be less than pongWait
+	pingPeriod = (pongWait * 9) / 10
+	// Clients only send control frames, so inbound messages can stay small
+	maxMessageSize = 512
+	// Outbound messages queued per client before it is considered too slow
+	clientSendBuffer = 256
+)
+
+// DefaultAllowedOrigin matches the dashboard origin the CORS middleware defaults to
+const DefaultAllowedOrigin = "http://localhost:3000"
+
+// WebSocketUpgrader upgrades HTTP to WebSocket. Disallowed origins are rejected
+// by Upgrade with 403 Forbidden.
 var WebSocketUpgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins for MVP
-	},
+	CheckOrigin:     CheckOriginAllowlist([]string{DefaultAllowedOrigin}, false),
+}
+
+// CheckOriginAllowlist returns a CheckOrigin func accepting only the given origins.
+// allowAll disables the check entirely and is meant for local development only.
+// Requests without an Origin header come from non-browser clients and are allowed.
+func CheckOriginAllowlist(origins []string, allowAll bool) func(r *http.Request) bool {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowed[strings.TrimRight(origin, "/")] = true
+	}
+
+	return func(r *http.Request) bool {
+		if allowAll {
+			return true
+		}
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		if allowed[origin] {
+			return true
+		}
+		log.Printf("[WebSocket] Rejected connection from origin %q", origin)
+		return false
+	}
+}
+
+// wsClient is a single connection with its own outbound queue. All writes go
+// through writePump so the connection only ever has one writer.
+type wsClient struct {
+	conn *websocket.Conn
+	send chan []byte
+	// missionID limits the client to one mission's events; empty is all events
+	missionID string
 }
 
 // WebSocketHub manages WebSocket connections and broadcasts events
 type WebSocketHub struct {
-	connections map[*websocket.Conn]bool
-	mu          sync.RWMutex
-	eventBus    <-chan models.Event
-	register    chan *websocket.Conn
-	unregister  chan *websocket.Conn
+	clients    map[*wsClient]bool
+	streams    map[*eventStream]bool
+	mu         sync.RWMutex
+	eventBus   <-chan models.Event
+	register   chan *wsClient
+	unregister chan *wsClient
 }
 
 // NewWebSocketHub creates a new WebSocket hub
 func NewWebSocketHub(eventBus <-chan models.Event) *WebSocketHub {
 	return &WebSocketHub{
-		connections: make(map[*websocket.Conn]bool),
-		eventBus:    eventBus,
-		register:    make(chan *websocket.Conn),
-		unregister:  make(chan *websocket.Conn),
+		clients:    make(map[*wsClient]bool),
+		streams:    make(map[*eventStream]bool),
+		eventBus:   eventBus,
+		register:   make(chan *wsClient),
+		unregister: make(chan *wsClient),
 	}
 }
 
@@ -53,20 +105,23 @@ func (h *WebSocketHub) Run(ctx context.Context) {
 			log.Println("[WebSocketHub] Context cancelled, shutting down")
 			return
 
-		case conn := <-h.register:
+		case client := <-h.register:
 			h.mu.Lock()
-			h.connections[conn] = true
+			h.clients[client] = true
+			total := len(h.clients)
 			h.mu.Unlock()
-			log.Printf("[WebSocketHub] Client connected (total: %d)", len(h.connections))
+			log.Printf("[WebSocketHub] Client connected (total: %d)", total)
 
-		case conn := <-h.unregister:
+		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, ok := h.connections[conn]; ok {
-				delete(h.connections, conn)
-				conn.Close()
+			if _, ok := h.clients[client]; ok {
+				delete(h.clients, client)
+				// Closing send tells writePump to send a close frame and close the connection
+				close(client.send)
 			}
+			total := len(h.clients)
 			h.mu.Unlock()
-			log.Printf("[WebSocketHub] Client disconnected (total: %d)", len(h.connections))
+			log.Printf("[WebSocketHub] Client disconnected (total: %d)", total)
 
 		case event := <-h.eventBus:
 			h.broadcast(event)
@@ -74,12 +129,12 @@ func (h *WebSocketHub) Run(ctx context.Context) {
 	}
 }
 
-// broadcast sends an event to all connected clients
+// broadcast queues an event for all connected clients
 func (h *WebSocketHub) broadcast(event models.Event) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	if len(h.connections) == 0 {
+	if len(h.clients) == 0 && len(h.streams) == 0 {
 		return
 	}
 
@@ -90,16 +145,22 @@ func (h *WebSocketHub) broadcast(event models.Event) {
 		return
 	}
 
-	// Send to all connections
-	for conn := range h.connections {
-		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
-		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
-			log.Printf("[WebSocketHub] Failed to send to client: %v", err)
-			go func() {
-				h.unregister <- conn
-			}()
+	// Queue for all clients; a client whose queue is full is too slow and is dropped
+	missionID := eventMissionID(data)
+	for client := range h.clients {
+		if !wantsMission(client.missionID, missionID) {
+			continue
+		}
+		select {
+		case client.send <- data:
+		default:
+			log.Printf("[WebSocketHub] Client send queue full, disconnecting")
+			go func(c *wsClient) {
+				h.unregister <- c
+			}(client)
 		}
 	}
+	h.sendToStreams(event.Type, data, missionID)
 }
 
 // runSummaryBroadcaster sends periodic summary events
@@ -121,7 +182,8 @@ func (h *WebSocketHub) runSummaryBroadcaster(ctx context.Context) {
 	}
 }
 
-// ServeWebSocket handles a new WebSocket connection
+// ServeWebSocket handles a new WebSocket connection. A mission query
+// parameter limits it to that mission's events.
 func ServeWebSocket(hub *WebSocketHub, w http.ResponseWriter, r *http.Request) {
 	conn, err := WebSocketUpgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -129,26 +191,72 @@ func ServeWebSocket(hub *WebSocketHub, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	client := &wsClient{
+		conn:      conn,
+		send:      make(chan []byte, clientSendBuffer),
+		missionID: r.URL.Query().Get("mission"),
+	}
+
 	// Register connection
-	hub.register <- conn
-
-	// Start read pump to handle disconnects
-	go func() {
-		defer func() {
-			hub.unregister <- conn
-		}()
-
-		// Keep reading to detect disconnects
-		for {
-			_, _, err := conn.ReadMessage()
-			if err != nil {
-				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-					log.Printf("[WebSocket] Unexpected close: %v", err)
-				}
-				break
+	hub.register <- client
+
+	go client.writePump()
+	go client.readPump(hub)
+}
+
+// readPump reads from the connection to process pongs and detect disconnects.
+// The read deadline is extended on every pong, so a peer that stops answering
+// pings (e.g. a laptop that went to sleep) is dropped after pongWait.
+func (c *wsClient) readPump(hub *WebSocketHub) {
+	defer func() {
+		hub.unregister <- c
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				log.Printf("[WebSocket] Unexpected close: %v", err)
 			}
+			return
 		}
+	}
+}
+
+// writePump writes queued messages and periodic pings to the connection
+func (c *wsClient) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
 	}()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				// The hub closed the channel
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				log.Printf("[WebSocketHub] Failed to send to client: %v", err)
+				return
+			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
 }
 
 // MissionSummaryBroadcaster broadcasts mission-specific summaries
@@ -191,35 +299,73 @@ func (b *MissionSummaryBroadcaster) Stop() {
 
 // broadcastSummary broadcasts a summary event
 func (b *MissionSummaryBroadcaster) broadcastSummary() {
-	mission, ok := b.store.Get(b.mission.ID)
+	mission, ok := b.store.Get(context.Background(), b.mission.ID)
 	if !ok {
 		return
 	}
 
-	// Calculate metrics
-	activeAgents := 0
+	summary := buildMissionSummary(mission)
+
+	b.hub.broadcast(models.Event{
+		Type:      "summary",
+		Timestamp: time.Now(),
+		Data:      summary,
+	})
+}
+
+// buildMissionSummary derives the summary view of a mission's metrics
+func buildMissionSummary(mission *models.Mission) *models.SummaryEvent {
+	activeAgents, passedAgents, retriedAgents := 0, 0, 0
 	for _, agent := range mission.AgentMetrics {
 		if agent.Status == "running" {
 			activeAgents++
 		}
+		if agent.RetryOf != "" {
+			retriedAgents++
+		}
+		if agent.CriteriaMet {
+			passedAgents++
+		}
+	}
+	passRate := 0.0
+	if mission.NumAgents > 0 {
+		passRate = float64(passedAgents) / float64(mission.NumAgents) * 100
 	}
 
-	summary := models.SummaryEvent{
-		MissionID:        mission.ID,
-		TotalAgents:      mission.NumAgents,
-		ActiveAgents:     activeAgents,
-		CompletedAgents:  mission.CompletedAgents,
-		FailedAgents:     mission.FailedAgents,
-		TotalActions:     mission.TotalActions,
-		AverageLatencyMS: mission.AverageLatencyMS,
-		ErrorRatePercent: calculateErrorRate(mission),
+	return &models.SummaryEvent{
+		MissionID:           mission.ID,
+		TotalAgents:         mission.NumAgents,
+		ActiveAgents:        activeAgents,
+		CompletedAgents:     mission.CompletedAgents,
+		FailedAgents:        mission.FailedAgents,
+		TotalActions:        mission.TotalActions,
+		TotalErrors:         mission.TotalErrors,
+		AverageLatencyMS:    mission.AverageLatencyMS,
+		ErrorRatePercent:    calculateErrorRate(mission),
+		CacheHitRatePercent: calculateCacheHitRate(mission),
+		TotalPromptTokens:   mission.TotalPromptTokens,
+		TotalOutputTokens:   mission.TotalOutputTokens,
+		EstimatedCostUSD:    mission.EstimatedCostUSD,
+		ClaimedCompletions:  mission.ClaimedCompletions,
+		VerifiedCompletions: mission.VerifiedCompletions,
+		UniqueURLs:          mission.UniqueURLs,
+		PathOverlapPercent:  calculatePathOverlap(mission),
+		JSErrors:            mission.JSErrors,
+		NetworkFailures:     mission.NetworkFailures,
+		DroppedEvents:       mission.DroppedEvents,
+		RetriedAgents:       retriedAgents,
+		PassedAgents:        passedAgents,
+		PassRatePercent:     passRate,
+		StopReason:          mission.StopReason,
 	}
+}
 
-	b.hub.broadcast(models.Event{
-		Type:      "summary",
-		Timestamp: time.Now(),
-		Data:      summary,
-	})
+// missionSummary is the mission's summary, counting the events it has lost
+// so far if it is still running
+func (api *RESTAPI) missionSummary(mission *models.Mission) *models.SummaryEvent {
+	summary := buildMissionSummary(mission)
+	summary.DroppedEvents += api.drops.Dropped(mission.ID)
+	return summary
 }
 
 // calculateErrorRate calculates the error rate percentage
@@ -230,3 +376,21 @@ func calculateErrorRate(mission *models.Mission) float64 {
 	}
 	return float64(mission.TotalErrors) / float64(total) * 100
 }
+
+// calculatePathOverlap is the share of agents' URL visits that repeated a URL
+// another agent had already covered: 0 when every agent took its own path
+func calculatePathOverlap(mission *models.Mission) float64 {
+	if mission.AgentURLVisits == 0 {
+		return 0
+	}
+	return float64(mission.AgentURLVisits-mission.UniqueURLs) / float64(mission.AgentURLVisits) * 100
+}
+
+// calculateCacheHitRate calculates the decision cache hit rate percentage
+func calculateCacheHitRate(mission *models.Mission) float64 {
+	total := mission.DecisionCacheHits + mission.DecisionCacheMisses
+	if total == 0 {
+		return 0
+	}
+	return float64(mission.DecisionCacheHits) / float64(total) * 100
+}
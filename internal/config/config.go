@@ -0,0 +1,375 @@
+// Package config loads the server's configuration
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"swarmtest/internal/api"
+	"swarmtest/internal/gemini"
+	"swarmtest/internal/store"
+	"swarmtest/internal/utils"
+)
+
+// FileEnv names the environment variable pointing at an optional JSON config file
+const FileEnv = "CONFIG_FILE"
+
+// Gemini backends
+const (
+	BackendGeminiAPI = "gemini_api"
+	BackendVertexAI  = "vertex_ai"
+)
+
+// Config is the server's configuration. Each field can be set in the JSON
+// file named by CONFIG_FILE, using the field's json name, or by its
+// environment variable (see applyEnv), which takes precedence.
+type Config struct {
+	Port                int `json:"port"`
+	ReadTimeoutSeconds  int `json:"read_timeout_seconds"`
+	WriteTimeoutSeconds int `json:"write_timeout_seconds"`
+	IdleTimeoutSeconds  int `json:"idle_timeout_seconds"`
+
+	DatabaseURL string `json:"supabase_db_url"`
+
+	// GeminiBackend selects the public Gemini API, authenticated by
+	// GeminiAPIKey, or Vertex AI in GoogleCloudProject/GoogleCloudLocation,
+	// authenticated by GoogleCredentialsFile or Application Default Credentials
+	GeminiBackend         string `json:"gemini_backend"`
+	GeminiAPIKey          string `json:"gemini_api_key"`
+	GoogleCloudProject    string `json:"google_cloud_project"`
+	GoogleCloudLocation   string `json:"google_cloud_location"`
+	GoogleCredentialsFile string `json:"google_credentials_file"`
+	GeminiModel           string `json:"gemini_model"`
+	// GeminiPricing overrides the price table used for cost estimates
+	GeminiPricing json.RawMessage `json:"gemini_pricing,omitempty"`
+	// The circuit breaker opens after GeminiBreakerThreshold failed calls
+	// within GeminiBreakerWindowSeconds, for GeminiBreakerCooldownSeconds
+	GeminiBreakerThreshold       int `json:"gemini_breaker_threshold"`
+	GeminiBreakerWindowSeconds   int `json:"gemini_breaker_window_seconds"`
+	GeminiBreakerCooldownSeconds int `json:"gemini_breaker_cooldown_seconds"`
+
+	CORSAllowedOrigins    []string `json:"cors_allowed_origins"`
+	WSAllowedOrigins      []string `json:"ws_allowed_origins"`
+	WSAllowAllOrigins     bool     `json:"ws_allow_all_origins"`
+	APIKeys               []string `json:"api_keys"`
+	APIRateLimitPerMinute int      `json:"api_rate_limit_per_minute"`
+	APIRateLimitBurst     int      `json:"api_rate_limit_burst"`
+
+	BrowserHeadless bool `json:"browser_headless"`
+	BrowserWarmTabs int  `json:"browser_warm_tabs"`
+
+	MaxConcurrentAgents       int     `json:"max_concurrent_agents"`
+	DefaultRateLimitPerSecond float64 `json:"default_rate_limit_per_second"`
+	MaxAgentsPerMission       int     `json:"max_agents_per_mission"`
+	MaxRateLimitPerSecond     float64 `json:"max_rate_limit_per_second"`
+	MaxPageSizeBytes          int     `json:"max_page_size_bytes"`
+	RecentEventsLimit         int     `json:"recent_events_limit"`
+	AllowInsecureTLS          bool    `json:"allow_insecure_tls"`
+	ResumeInterruptedMissions bool    `json:"resume_interrupted_missions"`
+	UploadFixturesDir         string  `json:"upload_fixtures_dir"`
+	WebhookSecret             string  `json:"webhook_secret"`
+
+	// OTLPEndpoint, when set, exports traces over OTLP/HTTP, e.g. http://localhost:4318
+	OTLPEndpoint    string `json:"otlp_endpoint"`
+	OTELServiceName string `json:"otel_service_name"`
+
+	SlackWebhookURL            string `json:"slack_webhook_url"`
+	SlackAlertErrorRatePercent int    `json:"slack_alert_error_rate_percent"`
+	DashboardURL               string `json:"dashboard_url"`
+}
+
+// Default returns the configuration used for anything not set
+func Default() *Config {
+	return &Config{
+		Port:                         8080,
+		ReadTimeoutSeconds:           15,
+		WriteTimeoutSeconds:          15,
+		IdleTimeoutSeconds:           60,
+		GeminiBackend:                BackendGeminiAPI,
+		GeminiModel:                  gemini.DefaultModel,
+		GeminiBreakerThreshold:       gemini.DefaultBreakerThreshold,
+		GeminiBreakerWindowSeconds:   int(gemini.DefaultBreakerWindow / time.Second),
+		GeminiBreakerCooldownSeconds: int(gemini.DefaultBreakerCooldown / time.Second),
+		CORSAllowedOrigins:           []string{"http://localhost:3000", "http://localhost:3001"}, // the dashboard's dev ports
+		WSAllowedOrigins:             []string{api.DefaultAllowedOrigin},
+		APIRateLimitPerMinute:        api.DefaultAPIRateLimitPerMinute,
+		APIRateLimitBurst:            api.DefaultAPIRateLimitBurst,
+		BrowserHeadless:              true,
+		BrowserWarmTabs:              utils.DefaultBrowserWarmTabs,
+		MaxConcurrentAgents:          api.DefaultMaxConcurrentAgents,
+		DefaultRateLimitPerSecond:    api.DefaultRateLimitPerSecond,
+		MaxPageSizeBytes:             utils.DefaultMaxPageSize,
+		RecentEventsLimit:            store.DefaultRecentEvents,
+		SlackAlertErrorRatePercent:   20,
+		OTELServiceName:              "swarmtest",
+	}
+}
+
+// Load builds the configuration from the defaults, the CONFIG_FILE JSON file
+// if set, then the environment, and validates the result
+func Load() (*Config, error) {
+	cfg := Default()
+
+	if path := os.Getenv(FileEnv); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", FileEnv, err)
+		}
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("parse %s %s: %w", FileEnv, path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyEnv overrides fields with the environment variables that are set
+func (c *Config) applyEnv() error {
+	e := envReader{}
+	e.int("PORT", &c.Port)
+	e.int("READ_TIMEOUT_SECONDS", &c.ReadTimeoutSeconds)
+	e.int("WRITE_TIMEOUT_SECONDS", &c.WriteTimeoutSeconds)
+	e.int("IDLE_TIMEOUT_SECONDS", &c.IdleTimeoutSeconds)
+	e.string("SUPABASE_DB_URL", &c.DatabaseURL)
+	e.string("GEMINI_BACKEND", &c.GeminiBackend)
+	e.string("GEMINI_API_KEY", &c.GeminiAPIKey)
+	e.string("GOOGLE_CLOUD_PROJECT", &c.GoogleCloudProject)
+	e.string("GOOGLE_CLOUD_LOCATION", &c.GoogleCloudLocation)
+	e.string("GOOGLE_CREDENTIALS_FILE", &c.GoogleCredentialsFile)
+	e.string("GEMINI_MODEL", &c.GeminiModel)
+	if raw := os.Getenv("GEMINI_PRICING"); raw != "" {
+		c.GeminiPricing = json.RawMessage(raw)
+	}
+	e.int("GEMINI_BREAKER_THRESHOLD", &c.GeminiBreakerThreshold)
+	e.int("GEMINI_BREAKER_WINDOW_SECONDS", &c.GeminiBreakerWindowSeconds)
+	e.int("GEMINI_BREAKER_COOLDOWN_SECONDS", &c.GeminiBreakerCooldownSeconds)
+	e.list("CORS_ALLOWED_ORIGINS", &c.CORSAllowedOrigins)
+	e.list("WS_ALLOWED_ORIGINS", &c.WSAllowedOrigins)
+	e.bool("WS_ALLOW_ALL_ORIGINS", &c.WSAllowAllOrigins)
+	e.list("API_KEYS", &c.APIKeys)
+	e.int("API_RATE_LIMIT_PER_MINUTE", &c.APIRateLimitPerMinute)
+	e.int("API_RATE_LIMIT_BURST", &c.APIRateLimitBurst)
+	e.bool("BROWSER_HEADLESS", &c.BrowserHeadless)
+	e.int("BROWSER_WARM_TABS", &c.BrowserWarmTabs)
+	e.int("MAX_CONCURRENT_AGENTS", &c.MaxConcurrentAgents)
+	e.float("DEFAULT_RATE_LIMIT_PER_SECOND", &c.DefaultRateLimitPerSecond)
+	e.int("MAX_AGENTS_PER_MISSION", &c.MaxAgentsPerMission)
+	e.float("MAX_RATE_LIMIT_PER_SECOND", &c.MaxRateLimitPerSecond)
+	e.int("MAX_PAGE_SIZE_BYTES", &c.MaxPageSizeBytes)
+	e.int("RECENT_EVENTS_LIMIT", &c.RecentEventsLimit)
+	e.bool("ALLOW_INSECURE_TLS", &c.AllowInsecureTLS)
+	e.bool("RESUME_INTERRUPTED_MISSIONS", &c.ResumeInterruptedMissions)
+	e.string("UPLOAD_FIXTURES_DIR", &c.UploadFixturesDir)
+	e.string("WEBHOOK_SECRET", &c.WebhookSecret)
+	e.string("SLACK_WEBHOOK_URL", &c.SlackWebhookURL)
+	e.int("SLACK_ALERT_ERROR_RATE_PERCENT", &c.SlackAlertErrorRatePercent)
+	e.string("DASHBOARD_URL", &c.DashboardURL)
+	e.string("OTEL_EXPORTER_OTLP_ENDPOINT", &c.OTLPEndpoint)
+	e.string("OTEL_SERVICE_NAME", &c.OTELServiceName)
+	return errors.Join(e.errs...)
+}
+
+// Validate reports every missing or out-of-range setting
+func (c *Config) Validate() error {
+	var errs []error
+	if c.DatabaseURL == "" {
+		errs = append(errs, errors.New("SUPABASE_DB_URL (supabase_db_url) is required"))
+	}
+	switch c.GeminiBackend {
+	case BackendGeminiAPI:
+		if c.GeminiAPIKey == "" {
+			errs = append(errs, errors.New("GEMINI_API_KEY (gemini_api_key) is required"))
+		}
+	case BackendVertexAI:
+		if c.GoogleCloudProject == "" {
+			errs = append(errs, errors.New("GOOGLE_CLOUD_PROJECT (google_cloud_project) is required for the vertex_ai backend"))
+		}
+		if c.GoogleCloudLocation == "" {
+			errs = append(errs, errors.New("GOOGLE_CLOUD_LOCATION (google_cloud_location) is required for the vertex_ai backend"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("GEMINI_BACKEND (gemini_backend) must be %s or %s, got %q", BackendGeminiAPI, BackendVertexAI, c.GeminiBackend))
+	}
+	if c.GeminiModel == "" {
+		errs = append(errs, errors.New("GEMINI_MODEL (gemini_model) must not be empty"))
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT (port) must be between 1 and 65535, got %d", c.Port))
+	}
+	for _, setting := range []struct {
+		name  string
+		value int
+	}{
+		{"READ_TIMEOUT_SECONDS (read_timeout_seconds)", c.ReadTimeoutSeconds},
+		{"WRITE_TIMEOUT_SECONDS (write_timeout_seconds)", c.WriteTimeoutSeconds},
+		{"IDLE_TIMEOUT_SECONDS (idle_timeout_seconds)", c.IdleTimeoutSeconds},
+		{"GEMINI_BREAKER_THRESHOLD (gemini_breaker_threshold)", c.GeminiBreakerThreshold},
+		{"GEMINI_BREAKER_WINDOW_SECONDS (gemini_breaker_window_seconds)", c.GeminiBreakerWindowSeconds},
+		{"GEMINI_BREAKER_COOLDOWN_SECONDS (gemini_breaker_cooldown_seconds)", c.GeminiBreakerCooldownSeconds},
+	} {
+		if setting.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %d", setting.name, setting.value))
+		}
+	}
+	if c.APIRateLimitPerMinute < 0 {
+		errs = append(errs, fmt.Errorf("API_RATE_LIMIT_PER_MINUTE (api_rate_limit_per_minute) must not be negative, got %d", c.APIRateLimitPerMinute))
+	}
+	if c.BrowserWarmTabs < 0 || c.BrowserWarmTabs > utils.MaxBrowserWarmTabs {
+		errs = append(errs, fmt.Errorf("BROWSER_WARM_TABS (browser_warm_tabs) must be between 0 and %d, got %d", utils.MaxBrowserWarmTabs, c.BrowserWarmTabs))
+	}
+	if c.MaxConcurrentAgents < 0 {
+		errs = append(errs, fmt.Errorf("MAX_CONCURRENT_AGENTS (max_concurrent_agents) must not be negative, got %d", c.MaxConcurrentAgents))
+	}
+	if c.DefaultRateLimitPerSecond <= 0 || c.DefaultRateLimitPerSecond > 1000 {
+		errs = append(errs, fmt.Errorf("DEFAULT_RATE_LIMIT_PER_SECOND (default_rate_limit_per_second) must be in (0, 1000], got %g", c.DefaultRateLimitPerSecond))
+	}
+	if c.MaxAgentsPerMission < 0 || c.MaxAgentsPerMission > 1000 {
+		errs = append(errs, fmt.Errorf("MAX_AGENTS_PER_MISSION (max_agents_per_mission) must be between 0 and 1000, got %d", c.MaxAgentsPerMission))
+	}
+	if c.MaxRateLimitPerSecond < 0 || c.MaxRateLimitPerSecond > 1000 {
+		errs = append(errs, fmt.Errorf("MAX_RATE_LIMIT_PER_SECOND (max_rate_limit_per_second) must be between 0 and 1000, got %g", c.MaxRateLimitPerSecond))
+	} else if c.MaxRateLimitPerSecond > 0 && c.DefaultRateLimitPerSecond > c.MaxRateLimitPerSecond {
+		errs = append(errs, fmt.Errorf("DEFAULT_RATE_LIMIT_PER_SECOND (default_rate_limit_per_second) must not exceed MAX_RATE_LIMIT_PER_SECOND, got %g > %g", c.DefaultRateLimitPerSecond, c.MaxRateLimitPerSecond))
+	}
+	if c.MaxPageSizeBytes <= 0 || c.MaxPageSizeBytes > utils.MaxPageSizeLimit {
+		errs = append(errs, fmt.Errorf("MAX_PAGE_SIZE_BYTES (max_page_size_bytes) must be in (0, %d], got %d", utils.MaxPageSizeLimit, c.MaxPageSizeBytes))
+	}
+	if c.RecentEventsLimit <= 0 || c.RecentEventsLimit > store.MaxRecentEvents {
+		errs = append(errs, fmt.Errorf("RECENT_EVENTS_LIMIT (recent_events_limit) must be in (0, %d], got %d", store.MaxRecentEvents, c.RecentEventsLimit))
+	}
+	if c.SlackAlertErrorRatePercent < 0 || c.SlackAlertErrorRatePercent > 100 {
+		errs = append(errs, fmt.Errorf("SLACK_ALERT_ERROR_RATE_PERCENT (slack_alert_error_rate_percent) must be between 0 and 100, got %d", c.SlackAlertErrorRatePercent))
+	}
+	if _, err := api.ParseAPIKeys(c.APIKeys); err != nil {
+		errs = append(errs, fmt.Errorf("API_KEYS (api_keys): %w", err))
+	}
+	if c.OTLPEndpoint != "" {
+		u, err := url.Parse(c.OTLPEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("OTEL_EXPORTER_OTLP_ENDPOINT (otlp_endpoint) must be an http or https URL, got %q", c.OTLPEndpoint))
+		}
+		if c.OTELServiceName == "" {
+			errs = append(errs, errors.New("OTEL_SERVICE_NAME (otel_service_name) must not be empty"))
+		}
+	}
+	if len(c.GeminiPricing) > 0 {
+		if _, err := gemini.ParsePriceTable(string(c.GeminiPricing)); err != nil {
+			errs = append(errs, fmt.Errorf("GEMINI_PRICING (gemini_pricing): %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Addr is the address the server listens on
+func (c *Config) Addr() string {
+	return fmt.Sprintf(":%d", c.Port)
+}
+
+// ReadTimeout is the HTTP server's read timeout
+func (c *Config) ReadTimeout() time.Duration {
+	return time.Duration(c.ReadTimeoutSeconds) * time.Second
+}
+
+// WriteTimeout is the HTTP server's write timeout
+func (c *Config) WriteTimeout() time.Duration {
+	return time.Duration(c.WriteTimeoutSeconds) * time.Second
+}
+
+// GeminiBreakerWindow is the window the circuit breaker counts failures in
+func (c *Config) GeminiBreakerWindow() time.Duration {
+	return time.Duration(c.GeminiBreakerWindowSeconds) * time.Second
+}
+
+// GeminiBreakerCooldown is how long the circuit breaker stays open
+func (c *Config) GeminiBreakerCooldown() time.Duration {
+	return time.Duration(c.GeminiBreakerCooldownSeconds) * time.Second
+}
+
+// IdleTimeout is how long idle keep-alive connections stay open
+func (c *Config) IdleTimeout() time.Duration {
+	return time.Duration(c.IdleTimeoutSeconds) * time.Second
+}
+
+// envReader parses environment variables into config fields, collecting
+// errors so they can all be reported at once. Unset variables leave the
+// field unchanged.
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) string(key string, dst *string) {
+	if raw := os.Getenv(key); raw != "" {
+		*dst = raw
+	}
+}
+
+func (e *envReader) int(key string, dst *int) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s=%q is not an integer", key, raw))
+		return
+	}
+	*dst = v
+}
+
+func (e *envReader) float(key string, dst *float64) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s=%q is not a number", key, raw))
+		return
+	}
+	*dst = v
+}
+
+func (e *envReader) bool(key string, dst *bool) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return
+	}
+	switch strings.ToLower(raw) {
+	case "1", "true", "yes", "on":
+		*dst = true
+	case "0", "false", "no", "off":
+		*dst = false
+	default:
+		e.errs = append(e.errs, fmt.Errorf("%s=%q is not a boolean", key, raw))
+	}
+}
+
+// list parses a comma-separated variable
+func (e *envReader) list(key string, dst *[]string) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return
+	}
+
+	var values []string
+	for _, v := range strings.Split(raw, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	if len(values) > 0 {
+		*dst = values
+	}
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"swarmtest/internal/store"
+)
+
+func TestRecentEventsLimit(t *testing.T) {
+	tests := []struct {
+		env     string // RECENT_EVENTS_LIMIT, "" for unset
+		want    int
+		wantErr bool
+	}{
+		{"", store.DefaultRecentEvents, false},
+		{"1", 1, false},
+		{strconv.Itoa(store.MaxRecentEvents), store.MaxRecentEvents, false},
+		{"0", 0, true},
+		{"-5", -5, true},
+		{strconv.Itoa(store.MaxRecentEvents + 1), store.MaxRecentEvents + 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv("RECENT_EVENTS_LIMIT", tt.env)
+			}
+			cfg := Default()
+			cfg.DatabaseURL = "postgres://localhost/swarmtest"
+			cfg.GeminiAPIKey = "test-key"
+			if err := cfg.applyEnv(); err != nil {
+				t.Fatal(err)
+			}
+			if cfg.RecentEventsLimit != tt.want {
+				t.Errorf("RecentEventsLimit = %d, want %d", cfg.RecentEventsLimit, tt.want)
+			}
+			err := cfg.Validate()
+			if tt.wantErr != (err != nil && strings.Contains(err.Error(), "RECENT_EVENTS_LIMIT")) {
+				t.Errorf("Validate() = %v, want a RECENT_EVENTS_LIMIT error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"swarmtest/internal/models"
+)
+
+const (
+	DefaultBreakerThreshold = 20
+	DefaultBreakerWindow    = 30 * time.Second
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned without calling Gemini while the breaker is open
+var ErrCircuitOpen = errors.New("gemini circuit breaker is open")
+
+// BreakerState is the state of a CircuitBreaker
+type BreakerState string
+
+const (
+	// BreakerClosed passes every call through
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen fails every call fast until the cooldown ends
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single probe call through; its outcome closes or reopens the breaker
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// BreakerStats is a snapshot of a CircuitBreaker, for health reporting
+type BreakerStats struct {
+	State          BreakerState `json:"state"`
+	RecentFailures int          `json:"recent_failures"`
+	OpenedAt       *time.Time   `json:"opened_at,omitempty"`
+	TimesOpened    int          `json:"times_opened"`
+}
+
+// CircuitBreaker wraps a GeminiClient shared by every agent. After threshold
+// failed calls within window it opens, failing calls with ErrCircuitOpen so
+// agents stop piling retries onto a degraded backend. After cooldown a single
+// probe is let through: success closes the breaker, failure reopens it.
+type CircuitBreaker struct {
+	inner     GeminiClient
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+
+	mu          sync.Mutex
+	state       BreakerState
+	failures    []time.Time // failures within the window, oldest first
+	openedAt    time.Time
+	probing     bool
+	timesOpened int
+}
+
+// NewCircuitBreaker creates a breaker around inner
+func NewCircuitBreaker(inner GeminiClient, threshold int, window, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = DefaultBreakerThreshold
+	}
+	if window <= 0 {
+		window = DefaultBreakerWindow
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultBreakerCooldown
+	}
+
+	return &CircuitBreaker{
+		inner:     inner,
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+		state:     BreakerClosed,
+	}
+}
+
+func (b *CircuitBreaker) DecideNextAction(ctx context.Context, mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	decision, err := b.inner.DecideNextAction(ctx, mission, agent, page)
+	b.record(ctx, err)
+	return decision, err
+}
+
+func (b *CircuitBreaker) VerifyGoal(ctx context.Context, mission *models.Mission, agent *models.Agent, page *models.StrippedPage, claim string) (*models.GoalVerification, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	verification, err := b.inner.VerifyGoal(ctx, mission, agent, page, claim)
+	b.record(ctx, err)
+	return verification, err
+}
+
+// allow reports whether a call may go through, moving an open breaker whose
+// cooldown has passed to half-open for a probe
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		// One probe at a time
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record updates the breaker with a call's outcome. Calls abandoned by their
+// caller say nothing about Gemini's health and aren't counted.
+func (b *CircuitBreaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err != nil && ctx.Err() != nil {
+		if b.state == BreakerHalfOpen {
+			b.probing = false
+		}
+		return
+	}
+
+	if b.state == BreakerHalfOpen {
+		b.probing = false
+		if err != nil {
+			b.open()
+			return
+		}
+		b.state = BreakerClosed
+		b.failures = nil
+		return
+	}
+
+	if err == nil {
+		return
+	}
+
+	now := time.Now()
+	b.failures = append(b.pruneFailures(now), now)
+	if b.state == BreakerClosed && len(b.failures) >= b.threshold {
+		b.open()
+	}
+}
+
+func (b *CircuitBreaker) open() {
+	b.state = BreakerOpen
+	b.openedAt = time.Now()
+	b.timesOpened++
+}
+
+// pruneFailures drops failures older than the window
+func (b *CircuitBreaker) pruneFailures(now time.Time) []time.Time {
+	i := 0
+	for i < len(b.failures) && now.Sub(b.failures[i]) > b.window {
+		i++
+	}
+	return b.failures[i:]
+}
+
+// Stats returns the breaker's current state
+func (b *CircuitBreaker) Stats() BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = b.pruneFailures(time.Now())
+	stats := BreakerStats{
+		State:          b.state,
+		RecentFailures: len(b.failures),
+		TimesOpened:    b.timesOpened,
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		stats.OpenedAt = &openedAt
+	}
+	return stats
+}
+
+// Cooldown is how long the breaker stays open before probing
+func (b *CircuitBreaker) Cooldown() time.Duration {
+	return b.cooldown
+}
@@ -0,0 +1,168 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"swarmtest/internal/gemini/geminitest"
+	"swarmtest/internal/models"
+)
+
+var errUnavailable = errors.New("503 unavailable")
+
+// flakyClient fails its decisions while failing is set
+type flakyClient struct {
+	*geminitest.FakeGeminiClient
+	mu      sync.Mutex
+	failing bool
+}
+
+func newFlakyClient() *flakyClient {
+	c := &flakyClient{failing: true}
+	c.FakeGeminiClient = geminitest.NewFakeGeminiClient(func(mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.failing {
+			return nil, errUnavailable
+		}
+		return geminitest.Complete("done"), nil
+	})
+	return c
+}
+
+func (c *flakyClient) setFailing(failing bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failing = failing
+}
+
+var breakerAgent = &models.Agent{ID: "m1-agent-0"}
+
+// callBreaker asks b for a decision and returns its error
+func callBreaker(b *CircuitBreaker) error {
+	_, err := b.DecideNextAction(context.Background(), &models.Mission{}, breakerAgent, &models.StrippedPage{})
+	return err
+}
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	inner := newFlakyClient()
+	b := NewCircuitBreaker(inner, 3, time.Minute, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if err := callBreaker(b); !errors.Is(err, errUnavailable) {
+			t.Fatalf("call %d: error = %v, want the backend's", i+1, err)
+		}
+	}
+	if stats := b.Stats(); stats.State != BreakerOpen || stats.TimesOpened != 1 || stats.OpenedAt == nil {
+		t.Fatalf("stats = %+v, want opened once", stats)
+	}
+	// Open, calls fail fast without reaching Gemini
+	if err := callBreaker(b); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("error while open = %v, want ErrCircuitOpen", err)
+	}
+	if _, err := b.VerifyGoal(context.Background(), &models.Mission{}, breakerAgent, &models.StrippedPage{}, "done"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("VerifyGoal error while open = %v, want ErrCircuitOpen", err)
+	}
+	if calls := inner.Decisions(breakerAgent.ID); calls != 3 {
+		t.Errorf("backend called %d times, want 3", calls)
+	}
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	b := NewCircuitBreaker(newFlakyClient(), 3, 50*time.Millisecond, time.Minute)
+	callBreaker(b)
+	callBreaker(b)
+	time.Sleep(80 * time.Millisecond)
+	// The first two failures have left the window
+	callBreaker(b)
+	if stats := b.Stats(); stats.State != BreakerClosed || stats.RecentFailures != 1 {
+		t.Errorf("stats = %+v, want closed with 1 recent failure", stats)
+	}
+}
+
+func TestCircuitBreakerProbe(t *testing.T) {
+	tests := []struct {
+		name        string
+		probeFails  bool
+		wantState   BreakerState
+		wantOpened  int
+		wantNextErr error
+	}{
+		{"success closes", false, BreakerClosed, 1, nil},
+		{"failure reopens", true, BreakerOpen, 2, ErrCircuitOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := newFlakyClient()
+			b := NewCircuitBreaker(inner, 1, time.Minute, 50*time.Millisecond)
+			callBreaker(b)
+			if b.Stats().State != BreakerOpen {
+				t.Fatal("breaker didn't open")
+			}
+
+			time.Sleep(80 * time.Millisecond)
+			inner.setFailing(tt.probeFails)
+			callBreaker(b)
+			if stats := b.Stats(); stats.State != tt.wantState || stats.TimesOpened != tt.wantOpened {
+				t.Errorf("after the probe: %+v, want %s, opened %d times", stats, tt.wantState, tt.wantOpened)
+			}
+			if err := callBreaker(b); !errors.Is(err, tt.wantNextErr) {
+				t.Errorf("next call error = %v, want %v", err, tt.wantNextErr)
+			}
+		})
+	}
+}
+
+// While half-open, only one probe is in flight; other calls fail fast
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	release := make(chan struct{})
+	probing := make(chan struct{})
+	first := true
+	inner := geminitest.NewFakeGeminiClient(func(mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error) {
+		if first {
+			first = false
+			return nil, errUnavailable
+		}
+		close(probing)
+		<-release
+		return geminitest.Complete("done"), nil
+	})
+	b := NewCircuitBreaker(inner, 1, time.Minute, time.Millisecond)
+	callBreaker(b)
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan error)
+	go func() { done <- callBreaker(b) }()
+	<-probing
+	if stats := b.Stats(); stats.State != BreakerHalfOpen {
+		t.Errorf("state during the probe = %s, want half_open", stats.State)
+	}
+	if err := callBreaker(b); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("call during the probe: error = %v, want ErrCircuitOpen", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("probe error = %v", err)
+	}
+	if stats := b.Stats(); stats.State != BreakerClosed {
+		t.Errorf("state after the probe = %s, want closed", stats.State)
+	}
+}
+
+// Calls their caller gave up on say nothing about Gemini's health
+func TestCircuitBreakerIgnoresCancelledCalls(t *testing.T) {
+	b := NewCircuitBreaker(newFlakyClient(), 1, time.Minute, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 3; i++ {
+		if _, err := b.DecideNextAction(ctx, &models.Mission{}, breakerAgent, &models.StrippedPage{}); !errors.Is(err, context.Canceled) {
+			t.Fatalf("error = %v, want context.Canceled", err)
+		}
+	}
+	if stats := b.Stats(); stats.State != BreakerClosed || stats.RecentFailures != 0 {
+		t.Errorf("stats = %+v, want closed without failures", stats)
+	}
+}
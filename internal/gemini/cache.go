@@ -0,0 +1,141 @@
+package gemini
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"swarmtest/internal/models"
+)
+
+const (
+	DefaultDecisionCacheTTL        = 60 * time.Second
+	DefaultDecisionCacheMaxEntries = 1000
+)
+
+// CachingClient wraps a GeminiClient with a bounded, TTL-based decision cache.
+// Only missions with EnableDecisionCache set consult the cache.
+type CachingClient struct {
+	inner      GeminiClient
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // front = most recently used
+}
+
+type cacheEntry struct {
+	key       string
+	decision  models.GeminiDecisionResponse
+	expiresAt time.Time
+}
+
+// NewCachingClient creates a caching decorator around inner
+func NewCachingClient(inner GeminiClient, ttl time.Duration, maxEntries int) *CachingClient {
+	if ttl <= 0 {
+		ttl = DefaultDecisionCacheTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultDecisionCacheMaxEntries
+	}
+
+	return &CachingClient{
+		inner:      inner,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+func (c *CachingClient) DecideNextAction(ctx context.Context, mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error) {
+	if !mission.EnableDecisionCache {
+		return c.inner.DecideNextAction(ctx, mission, agent, page)
+	}
+
+	key := decisionCacheKey(mission, agent, page)
+	if decision, ok := c.get(key); ok {
+		decision.Metadata = models.DecisionMetadata{CacheLookup: true, CacheHit: true}
+		return &decision, nil
+	}
+
+	decision, err := c.inner.DecideNextAction(ctx, mission, agent, page)
+	if err != nil {
+		return nil, err
+	}
+
+	c.put(key, *decision)
+	decision.Metadata.CacheLookup = true
+	return decision, nil
+}
+
+// VerifyGoal is never cached; verification must look at the live page
+func (c *CachingClient) VerifyGoal(ctx context.Context, mission *models.Mission, agent *models.Agent, page *models.StrippedPage, claim string) (*models.GoalVerification, error) {
+	return c.inner.VerifyGoal(ctx, mission, agent, page, claim)
+}
+
+// get returns a copy of a live cache entry
+func (c *CachingClient) get(key string) (models.GeminiDecisionResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return models.GeminiDecisionResponse{}, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return models.GeminiDecisionResponse{}, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.decision, true
+}
+
+// put stores a decision, evicting the least recently used entry when full
+func (c *CachingClient) put(key string, decision models.GeminiDecisionResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.decision = decision
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, decision: decision, expiresAt: expiresAt})
+
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// decisionCacheKey hashes everything that shapes the prompt: the agent's hint and current goal, the
+// mission's examples, the page structure and the agent's recent actions
+func decisionCacheKey(mission *models.Mission, agent *models.Agent, page *models.StrippedPage) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%s\x00", mission.InitialSystemPrompt, agent.ExplorationHint, currentGoal(mission, agent), page.URL, page.Title)
+	if len(mission.PromptExamples) > 0 {
+		examples, _ := json.Marshal(mission.PromptExamples)
+		fmt.Fprintf(h, "%s\x00", examples)
+	}
+	for _, el := range page.InteractiveElements {
+		fmt.Fprintf(h, "%s|%s|%s|%s|%s\x00", el.Type, el.Selector, el.Text, el.Href, el.Frame)
+	}
+	fmt.Fprintf(h, "%s", formatHistory(agent.ActionHistory))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -4,53 +4,223 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"strings"
+	"sync/atomic"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/genai"
 	"swarmtest/internal/models"
+	"swarmtest/internal/tracing"
 )
 
 // GeminiClient provides AI-powered decision making for agents
 type GeminiClient interface {
 	DecideNextAction(ctx context.Context, mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error)
+	VerifyGoal(ctx context.Context, mission *models.Mission, agent *models.Agent, page *models.StrippedPage, claim string) (*models.GoalVerification, error)
 }
 
+var tracer = tracing.Tracer("gemini")
+
+// DefaultModel is the model used for agent decisions unless configured otherwise
+const DefaultModel = "gemini-3-flash-preview"
+
 // GeminiService implements GeminiClient
 type GeminiService struct {
 	client *genai.Client
+	prices PriceTable
+	model  string
+
+	// noSchema is set once the model rejects a response schema; decisions are
+	// then requested as plain JSON and parsed leniently
+	noSchema atomic.Bool
 }
 
-func NewGeminiService(client *genai.Client) *GeminiService {
-	return &GeminiService{client: client}
+// NewGeminiService creates a client deciding with model, or DefaultModel when empty
+func NewGeminiService(client *genai.Client, prices PriceTable, model string) *GeminiService {
+	if prices == nil {
+		prices = DefaultPriceTable
+	}
+	if model == "" {
+		model = DefaultModel
+	}
+	return &GeminiService{client: client, prices: prices, model: model}
 }
 
 func (s *GeminiService) DecideNextAction(ctx context.Context, mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error) {
 	// Construct prompt
-	prompt := buildPrompt(mission, agent, page)
-
-	// Call Gemini
-	// Fix type mismatches: val creates *T. GenAI expects specific types.
-	// Looking at error: cannot use val(0.2) (type *float64) as *float32.
-	// So Temperature is *float32.
-	// MaxOutputTokens is int32 (error said int32).
-	
-	temp := float32(0.2)
-	maxTokens := int32(8192)
-	
-	resp, err := s.client.Models.GenerateContent(ctx, "gemini-3-flash-preview", genai.Text(prompt), &genai.GenerateContentConfig{
-		Temperature:     &temp,
-		MaxOutputTokens: maxTokens, 
-		ResponseMIMEType: "application/json", 
-	})
+	prompt := BuildPrompt(mission, agent, page)
+
+	// Call Gemini, constrained to the decision schema when the model supports it
+	config := generationConfig(mission)
+	if !s.noSchema.Load() {
+		config.ResponseSchema = decisionSchema(agent.ExecutionMode == models.ExecutionModeBrowser)
+	}
+
+	responseText, usage, err := s.call(ctx, prompt, config, mission.StreamDecisions)
+	if err != nil && config.ResponseSchema != nil && isSchemaUnsupported(err) {
+		log.Printf("[Gemini] Model %s does not support response schemas, falling back to plain JSON: %v", s.model, err)
+		s.noSchema.Store(true)
+		config.ResponseSchema = nil
+		responseText, usage, err = s.call(ctx, prompt, config, mission.StreamDecisions)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	decision, err := parseDecision(responseText)
+	if err != nil {
+		return nil, err
+	}
+
+	decision.Metadata.Model = s.model
+	if usage != nil {
+		decision.Metadata.PromptTokens = int64(usage.PromptTokenCount)
+		decision.Metadata.OutputTokens = int64(usage.CandidatesTokenCount) + int64(usage.ThoughtsTokenCount)
+		decision.Metadata.CostUSD = s.prices.EstimateCost(s.model, decision.Metadata.PromptTokens, decision.Metadata.OutputTokens)
+	}
+
+	return decision, nil
+}
+
+// VerifyGoal asks Gemini whether the goal is actually achieved on the given page
+func (s *GeminiService) VerifyGoal(ctx context.Context, mission *models.Mission, agent *models.Agent, page *models.StrippedPage, claim string) (*models.GoalVerification, error) {
+	prompt := buildVerificationPrompt(mission, agent, page, claim)
+
+	// Verification should be as deterministic as possible regardless of mission settings
+	temp := float32(0)
+	config := &genai.GenerateContentConfig{
+		Temperature:      &temp,
+		MaxOutputTokens:  defaultMaxOutputTokens,
+		ResponseMIMEType: "application/json",
+	}
+
+	responseText, usage, err := s.call(ctx, prompt, config, false)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := extractJSON(responseText)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call Gemini: %v", err)
+		return nil, fmt.Errorf("failed to parse verification response: %v. Response: %s", err, responseText)
+	}
+	var verification models.GoalVerification
+	if err := json.Unmarshal([]byte(raw), &verification); err != nil {
+		return nil, fmt.Errorf("failed to parse verification response: %v. Response: %s", err, responseText)
+	}
+
+	verification.Metadata.Model = s.model
+	if usage != nil {
+		verification.Metadata.PromptTokens = int64(usage.PromptTokenCount)
+		verification.Metadata.OutputTokens = int64(usage.CandidatesTokenCount) + int64(usage.ThoughtsTokenCount)
+		verification.Metadata.CostUSD = s.prices.EstimateCost(s.model, verification.Metadata.PromptTokens, verification.Metadata.OutputTokens)
+	}
+
+	return &verification, nil
+}
+
+const (
+	defaultTemperature     = float32(0.2)
+	defaultMaxOutputTokens = int32(8192)
+)
+
+// generationConfig applies the mission's generation parameters over the defaults
+func generationConfig(mission *models.Mission) *genai.GenerateContentConfig {
+	temp := defaultTemperature
+	if mission.Temperature != nil {
+		temp = *mission.Temperature
+	}
+
+	maxTokens := defaultMaxOutputTokens
+	if mission.MaxOutputTokens > 0 {
+		maxTokens = mission.MaxOutputTokens
 	}
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("empty response from Gemini")
+	return &genai.GenerateContentConfig{
+		Temperature:      &temp,
+		TopP:             mission.TopP,
+		MaxOutputTokens:  maxTokens,
+		ResponseMIMEType: "application/json",
+	}
+}
+
+// call generates a response, streamed or not, in a span recording the model
+// and token usage
+func (s *GeminiService) call(ctx context.Context, prompt string, config *genai.GenerateContentConfig, stream bool) (string, *genai.GenerateContentResponseUsageMetadata, error) {
+	ctx, span := tracer.Start(ctx, "gemini.generate_content", trace.WithAttributes(
+		attribute.String("gen_ai.request.model", s.model),
+		attribute.Bool("swarmtest.gemini.stream", stream),
+	))
+
+	var text string
+	var usage *genai.GenerateContentResponseUsageMetadata
+	var err error
+	if stream {
+		text, usage, err = s.generateStream(ctx, prompt, config)
+	} else {
+		text, usage, err = s.generate(ctx, prompt, config)
+	}
+	if usage != nil {
+		span.SetAttributes(
+			attribute.Int("gen_ai.usage.input_tokens", int(usage.PromptTokenCount)),
+			attribute.Int("gen_ai.usage.output_tokens", int(usage.CandidatesTokenCount)+int(usage.ThoughtsTokenCount)),
+		)
+	}
+	tracing.End(span, err)
+	return text, usage, err
+}
+
+// generate makes a single blocking GenerateContent call
+func (s *GeminiService) generate(ctx context.Context, prompt string, config *genai.GenerateContentConfig) (string, *genai.GenerateContentResponseUsageMetadata, error) {
+	resp, err := s.client.Models.GenerateContent(ctx, s.model, genai.Text(prompt), config)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to call Gemini: %w", err)
+	}
+
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return "", nil, fmt.Errorf("empty response from Gemini")
+	}
+
+	return candidateText(resp), resp.UsageMetadata, nil
+}
+
+// generateStream assembles the response from GenerateContentStream, reporting
+// each chunk to the context's ProgressFunc. Partial JSON is never parsed; the
+// caller parses the complete text once the stream ends.
+func (s *GeminiService) generateStream(ctx context.Context, prompt string, config *genai.GenerateContentConfig) (string, *genai.GenerateContentResponseUsageMetadata, error) {
+	progress := progressFromContext(ctx)
+
+	var text strings.Builder
+	var usage *genai.GenerateContentResponseUsageMetadata
+	for resp, err := range s.client.Models.GenerateContentStream(ctx, s.model, genai.Text(prompt), config) {
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to stream from Gemini: %w", err)
+		}
+
+		chunk := candidateText(resp)
+		text.WriteString(chunk)
+		if resp.UsageMetadata != nil {
+			// Usage is cumulative; the last chunk carries the totals
+			usage = resp.UsageMetadata
+		}
+		if progress != nil && chunk != "" {
+			progress(chunk, text.Len())
+		}
+	}
+
+	if text.Len() == 0 {
+		return "", nil, fmt.Errorf("empty response from Gemini")
+	}
+	return text.String(), usage, nil
+}
+
+// candidateText concatenates the text parts of the first candidate
+func candidateText(resp *genai.GenerateContentResponse) string {
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return ""
 	}
 
-	// Parse Logic
 	var responseText string
 	for _, part := range resp.Candidates[0].Content.Parts {
 		// part is *genai.Part struct.
@@ -58,67 +228,90 @@ func (s *GeminiService) DecideNextAction(ctx context.Context, mission *models.Mi
 			responseText += part.Text
 		}
 	}
-	
-	// Clean markdown json if present
-	responseText = strings.TrimSpace(responseText)
-	if strings.HasPrefix(responseText, "```json") {
-		responseText = strings.TrimPrefix(responseText, "```json")
-		responseText = strings.TrimSuffix(responseText, "```")
-	} else if strings.HasPrefix(responseText, "```") {
-		responseText = strings.TrimPrefix(responseText, "```")
-		responseText = strings.TrimSuffix(responseText, "```")
+	return responseText
+}
+
+// parseDecision decodes the model's JSON decision. Schema-constrained output is
+// plain JSON; fenced or prose-wrapped JSON from models without schemas is
+// extracted first.
+func parseDecision(responseText string) (*models.GeminiDecisionResponse, error) {
+	raw, err := extractJSON(responseText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Gemini response: %v. Response: %s", err, responseText)
 	}
 
 	var decision models.GeminiDecisionResponse
-	if err := json.Unmarshal([]byte(responseText), &decision); err != nil {
+	if err := json.Unmarshal([]byte(raw), &decision); err != nil {
 		return nil, fmt.Errorf("failed to parse Gemini response: %v. Response: %s", err, responseText)
 	}
-
 	return &decision, nil
 }
 
-func buildPrompt(mission *models.Mission, agent *models.Agent, page *models.StrippedPage) string {
-	
-	elementsJSON, _ := json.MarshalIndent(page.InteractiveElements, "", "  ")
-
-	systemPrompt := mission.InitialSystemPrompt
-	if systemPrompt == "" {
-		systemPrompt = "You are an AI agent."
+// extractJSON returns the first balanced JSON object in a response, skipping
+// any markdown fence, prose around it (braces in the prose included), or
+// objects after it. Braces inside strings are ignored.
+func extractJSON(responseText string) (string, error) {
+	err := fmt.Errorf("no JSON object in response")
+	for offset := 0; ; {
+		i := strings.IndexByte(responseText[offset:], '{')
+		if i < 0 {
+			return "", err
+		}
+		start := offset + i
+		object, ok := balancedObject(responseText[start:])
+		if !ok {
+			err = fmt.Errorf("unterminated JSON object in response")
+		} else if json.Valid([]byte(object)) {
+			return object, nil
+		}
+		offset = start + 1
 	}
+}
 
-	return fmt.Sprintf(`%s
-
-Current Goal: %s
-Current URL: %s
-
-Page Content:
-%s
-
-Interactable Elements:
-%s
-
-Agent History (Last 5 actions):
-%s
-
-Instructions:
-1. Analyze the page and history.
-2. Decide the next best action to assume to achieve the goal.
-3. If the goal is achieved, return action="completed".
-4. If stuck or error, return action="failed" or try "go_back".
-5. Respond strictly in JSON format matching this schema:
-{
-  "reasoning": "Reasoning ...",
-  "action": "click" | "type" | "wait" | "go_back" | "visit" | "completed" | "failed",
-  "selector": "css_selector",
-  "text_input": "text to type (optional)"
+// balancedObject returns the prefix of s, which starts with '{', up to the
+// brace that closes it
+func balancedObject(s string) (string, bool) {
+	depth := 0
+	inString, escaped := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return s[:i+1], true
+			}
+		}
+	}
+	return "", false
 }
-`, systemPrompt, mission.Goal, agent.CurrentURL, page.TextContent, string(elementsJSON), formatHistory(agent.ActionHistory))
+
+// BuildPrompt renders the decision prompt DecideNextAction sends for the page,
+// trimmed to the mission's max_prompt_tokens
+func BuildPrompt(mission *models.Mission, agent *models.Agent, page *models.StrippedPage) string {
+	maxTokens := mission.MaxPromptTokens
+	if maxTokens <= 0 {
+		maxTokens = DefaultMaxPromptTokens
+	}
+	return newPromptContext(mission, agent, page).fit(maxTokens)
 }
 
 func formatHistory(history []string) string {
 	start := 0
-	if len(history) > 5 {
-		start = len(history) - 5
+	if len(history) > historyWindow {
+		start = len(history) - historyWindow
 	}
 	return strings.Join(history[start:], "\n")
 }
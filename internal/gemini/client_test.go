@@ -0,0 +1,45 @@
+package gemini
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractJSON(t *testing.T) {
+	const decision = `{"action": "click", "selector": "a#next"}`
+	tests := []struct {
+		name     string
+		response string
+		want     string
+		wantErr  string
+	}{
+		{"bare", decision, decision, ""},
+		{"json fence", "```json\n" + decision + "\n```", decision, ""},
+		{"plain fence", "```\n" + decision + "\n```", decision, ""},
+		{"prose around", "Sure! Here is my decision:\n" + decision + "\nLet me know if you need more.", decision, ""},
+		{"prose with braces", "Skipping the {cookie banner} first. " + decision, decision, ""},
+		{"unclosed brace in prose", "The {menu is collapsed. " + decision, decision, ""},
+		{"first of several", decision + "\n" + `{"action": "completed"}`, decision, ""},
+		{"nested", `{"action": "type", "metadata": {"model": "x", "tags": [{"a": 1}]}} trailing`, `{"action": "type", "metadata": {"model": "x", "tags": [{"a": 1}]}}`, ""},
+		{"braces in strings", `{"reasoning": "the } closes { nothing", "selector": "div.{x}"}`, `{"reasoning": "the } closes { nothing", "selector": "div.{x}"}`, ""},
+		{"escaped quotes", `{"reasoning": "click \"Next }\" now"} done`, `{"reasoning": "click \"Next }\" now"}`, ""},
+		{"no object", "I can't decide on this page.", "", "no JSON object"},
+		{"only prose braces", "Use {the menu}.", "", "no JSON object"},
+		{"empty", "", "", "no JSON object"},
+		{"truncated", `{"action": "click", "selector": "a#ne`, "", "unterminated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractJSON(tt.response)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("extractJSON() = %q, %v, want an error saying %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("extractJSON() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
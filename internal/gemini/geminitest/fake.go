@@ -0,0 +1,142 @@
+// Package geminitest provides a scripted Gemini client for exercising agents
+// and missions without calling the model.
+package geminitest
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"swarmtest/internal/models"
+)
+
+// FakeModel is reported as the model for decisions from a FakeGeminiClient
+const FakeModel = "fake"
+
+// DecideFunc picks an agent's next action from the page it is on. Returning
+// an error makes the decision fail as a Gemini call would.
+type DecideFunc func(mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error)
+
+// VerifyFunc judges a completion claim
+type VerifyFunc func(mission *models.Mission, agent *models.Agent, page *models.StrippedPage, claim string) (*models.GoalVerification, error)
+
+// FakeGeminiClient implements gemini.GeminiClient with decisions computed by
+// a DecideFunc, so tests can act on the elements actually parsed from a page
+// (e.g. click the login link if there is one). It is safe for concurrent use
+// by a mission's agents.
+type FakeGeminiClient struct {
+	decide DecideFunc
+	// Verify judges completion claims; nil accepts every claim
+	Verify VerifyFunc
+
+	mu    sync.Mutex
+	pages map[string][]string // URLs decided on, by agent ID
+}
+
+// NewFakeGeminiClient creates a client deciding with decide
+func NewFakeGeminiClient(decide DecideFunc) *FakeGeminiClient {
+	return &FakeGeminiClient{
+		decide: decide,
+		pages:  make(map[string][]string),
+	}
+}
+
+// DecideNextAction returns decide's decision for the page. An agent given no
+// decision (nil) is told to complete.
+func (c *FakeGeminiClient) DecideNextAction(ctx context.Context, mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.pages[agent.ID] = append(c.pages[agent.ID], page.URL)
+	c.mu.Unlock()
+
+	decision, err := c.decide(mission, agent, page)
+	if err != nil {
+		return nil, err
+	}
+	if decision == nil {
+		decision = Complete("no decision scripted")
+	}
+	decision.Metadata = models.DecisionMetadata{Model: FakeModel}
+	return decision, nil
+}
+
+// VerifyGoal judges the claim with Verify, accepting it when Verify is nil
+func (c *FakeGeminiClient) VerifyGoal(ctx context.Context, mission *models.Mission, agent *models.Agent, page *models.StrippedPage, claim string) (*models.GoalVerification, error) {
+	if c.Verify == nil {
+		return &models.GoalVerification{
+			Achieved:  true,
+			Reasoning: "accepted by fake verifier",
+			Metadata:  models.DecisionMetadata{Model: FakeModel},
+		}, nil
+	}
+	verification, err := c.Verify(mission, agent, page, claim)
+	if err != nil {
+		return nil, err
+	}
+	verification.Metadata = models.DecisionMetadata{Model: FakeModel}
+	return verification, nil
+}
+
+// Pages lists the URLs the agent was on for each of its decisions, in order
+func (c *FakeGeminiClient) Pages(agentID string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.pages[agentID]...)
+}
+
+// Decisions is the number of decisions made for the agent
+func (c *FakeGeminiClient) Decisions(agentID string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pages[agentID])
+}
+
+// FindElement returns the first enabled element on the page of the given type
+// ("" for any) whose text, name, placeholder or href contains text, ignoring case
+func FindElement(page *models.StrippedPage, elementType, text string) (models.Element, bool) {
+	text = strings.ToLower(text)
+	for _, el := range page.InteractiveElements {
+		if el.Disabled || (elementType != "" && el.Type != elementType) {
+			continue
+		}
+		for _, s := range []string{el.Text, el.Name, el.Placeholder, el.Href} {
+			if strings.Contains(strings.ToLower(s), text) {
+				return el, true
+			}
+		}
+	}
+	return models.Element{}, false
+}
+
+// Click is a decision to click el
+func Click(el models.Element) *models.GeminiDecisionResponse {
+	return &models.GeminiDecisionResponse{
+		Reasoning: "scripted click on " + el.Selector,
+		Action:    "click",
+		Selector:  el.Selector,
+		Frame:     el.Frame,
+	}
+}
+
+// Type is a decision to type text into el
+func Type(el models.Element, text string) *models.GeminiDecisionResponse {
+	return &models.GeminiDecisionResponse{
+		Reasoning: "scripted typing into " + el.Selector,
+		Action:    "type",
+		Selector:  el.Selector,
+		TextInput: text,
+		Frame:     el.Frame,
+	}
+}
+
+// Complete is a decision claiming the goal was achieved
+func Complete(reasoning string) *models.GeminiDecisionResponse {
+	return &models.GeminiDecisionResponse{Reasoning: reasoning, Action: "completed"}
+}
+
+// Fail is a decision giving up on the goal
+func Fail(reasoning string) *models.GeminiDecisionResponse {
+	return &models.GeminiDecisionResponse{Reasoning: reasoning, Action: "failed"}
+}
@@ -0,0 +1,192 @@
+package geminitest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"swarmtest/internal/gemini/geminitest"
+	"swarmtest/internal/models"
+	"swarmtest/internal/utils"
+)
+
+const loginPage = `<html><head><title>Log in</title></head><body>
+<a id="home" href="/">Home</a>
+<form method="post" action="/login">
+<input id="username" name="username" type="text" placeholder="Username">
+<input id="code" name="code" type="text" disabled>
+<button id="submit" type="submit">Log in</button>
+</form></body></html>`
+
+// parse parses a page the way agents do, so decisions act on real elements
+func parse(url, content string) *models.StrippedPage {
+	page, err := utils.NewHTMLParser().ParseHTMLString(url, content)
+	if err != nil {
+		panic(err)
+	}
+	return page
+}
+
+var (
+	mission = &models.Mission{ID: "m1", Goal: "Log in"}
+	agent   = &models.Agent{ID: "m1-agent-0", MissionID: "m1"}
+)
+
+// A decision navigates by clicking a link found on the page
+func ExampleFakeGeminiClient_navigation() {
+	client := geminitest.NewFakeGeminiClient(func(mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error) {
+		if link, ok := geminitest.FindElement(page, "link", "log in"); ok {
+			return geminitest.Click(link), nil
+		}
+		return geminitest.Fail("no login link"), nil
+	})
+
+	page := parse("http://example.test/", `<html><head><title>Home</title></head><body><a id="login-link" href="/login">Log in</a></body></html>`)
+	decision, _ := client.DecideNextAction(context.Background(), mission, agent, page)
+	fmt.Println(decision.Action, decision.Selector)
+	// Output: click a#login-link
+}
+
+// A decision completes once the page shows the goal was reached
+func ExampleComplete() {
+	client := geminitest.NewFakeGeminiClient(func(mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error) {
+		if page.Title == "Dashboard" {
+			return geminitest.Complete("the dashboard is showing"), nil
+		}
+		return nil, nil
+	})
+
+	page := parse("http://example.test/dashboard", `<html><head><title>Dashboard</title></head><body><h1>Dashboard</h1></body></html>`)
+	decision, _ := client.DecideNextAction(context.Background(), mission, agent, page)
+	fmt.Println(decision.Action, decision.Metadata.Model)
+	// Output: completed fake
+}
+
+// A decision gives up on a page with no way forward
+func ExampleFail() {
+	client := geminitest.NewFakeGeminiClient(func(mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error) {
+		if _, ok := geminitest.FindElement(page, "input", "username"); !ok {
+			return geminitest.Fail("no login form on " + page.URL), nil
+		}
+		return nil, nil
+	})
+
+	page := parse("http://example.test/about", `<html><head><title>About</title></head><body><p>About us</p></body></html>`)
+	decision, _ := client.DecideNextAction(context.Background(), mission, agent, page)
+	fmt.Println(decision.Action+":", decision.Reasoning)
+	// Output: failed: no login form on http://example.test/about
+}
+
+func TestFindElement(t *testing.T) {
+	page := parse("http://example.test/login", loginPage)
+	tests := []struct {
+		name        string
+		elementType string
+		text        string
+		want        string // selector, "" for none
+	}{
+		{"by text", "link", "home", "a#home"},
+		{"ignores case", "button", "LOG IN", "button#submit"},
+		{"by name", "input", "username", "input#username"},
+		{"by placeholder", "", "Username", "input#username"},
+		{"by href", "link", "/", "a#home"},
+		{"type filters", "link", "log in", ""},
+		{"skips disabled", "input", "code", ""},
+		{"no match", "", "missing", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			el, ok := geminitest.FindElement(page, tt.elementType, tt.text)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("FindElement() = %s, want none", el.Selector)
+				}
+				return
+			}
+			if !ok || el.Selector != tt.want {
+				t.Errorf("FindElement() = %q, %v, want %q", el.Selector, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestFakeGeminiClientDecisions(t *testing.T) {
+	client := geminitest.NewFakeGeminiClient(func(mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error) {
+		if input, ok := geminitest.FindElement(page, "input", "username"); ok {
+			return geminitest.Type(input, "alice"), nil
+		}
+		return nil, nil
+	})
+	ctx := context.Background()
+
+	decision, err := client.DecideNextAction(ctx, mission, agent, parse("http://example.test/login", loginPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.Action != "type" || decision.Selector != "input#username" || decision.TextInput != "alice" {
+		t.Errorf("decision = %s %s %q, want type input#username \"alice\"", decision.Action, decision.Selector, decision.TextInput)
+	}
+
+	// No decision scripted for the page: the agent is told to complete
+	decision, err = client.DecideNextAction(ctx, mission, agent, parse("http://example.test/", "<html><body></body></html>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.Action != "completed" {
+		t.Errorf("unscripted decision = %s, want completed", decision.Action)
+	}
+
+	want := []string{"http://example.test/login", "http://example.test/"}
+	if got := client.Pages(agent.ID); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Pages() = %v, want %v", got, want)
+	}
+	if got := client.Decisions(agent.ID); got != 2 {
+		t.Errorf("Decisions() = %d, want 2", got)
+	}
+	if got := client.Decisions("other"); got != 0 {
+		t.Errorf("Decisions() of an agent without any = %d, want 0", got)
+	}
+}
+
+func TestFakeGeminiClientErrors(t *testing.T) {
+	errModel := errors.New("model unavailable")
+	client := geminitest.NewFakeGeminiClient(func(mission *models.Mission, agent *models.Agent, page *models.StrippedPage) (*models.GeminiDecisionResponse, error) {
+		return nil, errModel
+	})
+	page := parse("http://example.test/login", loginPage)
+
+	if _, err := client.DecideNextAction(context.Background(), mission, agent, page); !errors.Is(err, errModel) {
+		t.Errorf("DecideNextAction() error = %v, want %v", err, errModel)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.DecideNextAction(ctx, mission, agent, page); !errors.Is(err, context.Canceled) {
+		t.Errorf("DecideNextAction() on a cancelled context error = %v, want context.Canceled", err)
+	}
+	if got := client.Decisions(agent.ID); got != 1 {
+		t.Errorf("Decisions() = %d, want 1: a cancelled call isn't a decision", got)
+	}
+}
+
+func TestFakeGeminiClientVerifyGoal(t *testing.T) {
+	client := geminitest.NewFakeGeminiClient(nil)
+	page := parse("http://example.test/login", loginPage)
+
+	verification, err := client.VerifyGoal(context.Background(), mission, agent, page, "logged in")
+	if err != nil || !verification.Achieved {
+		t.Fatalf("VerifyGoal() without Verify = %+v, %v, want achieved", verification, err)
+	}
+
+	client.Verify = func(mission *models.Mission, agent *models.Agent, page *models.StrippedPage, claim string) (*models.GoalVerification, error) {
+		return &models.GoalVerification{Achieved: page.Title == "Dashboard", Reasoning: "still on " + page.Title}, nil
+	}
+	verification, err = client.VerifyGoal(context.Background(), mission, agent, page, "logged in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verification.Achieved || verification.Metadata.Model != geminitest.FakeModel {
+		t.Errorf("VerifyGoal() = %+v, want rejected by the fake model", verification)
+	}
+}
@@ -0,0 +1,40 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ModelPrice is the USD price per million tokens for a model
+type ModelPrice struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+}
+
+// PriceTable maps model names to prices
+type PriceTable map[string]ModelPrice
+
+// DefaultPriceTable holds list prices at the time of writing. Override it with
+// ParsePriceTable when prices change or a different model is used.
+var DefaultPriceTable = PriceTable{
+	"gemini-3-flash-preview": {InputPerMillion: 0.50, OutputPerMillion: 3.00},
+}
+
+// ParsePriceTable decodes a JSON object of the form
+// {"model": {"input_per_million": 0.5, "output_per_million": 3}}
+func ParsePriceTable(raw string) (PriceTable, error) {
+	table := PriceTable{}
+	if err := json.Unmarshal([]byte(raw), &table); err != nil {
+		return nil, fmt.Errorf("invalid price table: %w", err)
+	}
+	return table, nil
+}
+
+// EstimateCost returns the estimated USD cost of a call; unknown models cost 0
+func (t PriceTable) EstimateCost(model string, promptTokens, outputTokens int64) float64 {
+	price, ok := t[model]
+	if !ok {
+		return 0
+	}
+	return float64(promptTokens)*price.InputPerMillion/1e6 + float64(outputTokens)*price.OutputPerMillion/1e6
+}
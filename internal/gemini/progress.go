@@ -0,0 +1,19 @@
+package gemini
+
+import "context"
+
+// ProgressFunc receives each streamed chunk of a decision along with the total
+// number of bytes received so far
+type ProgressFunc func(chunk string, received int)
+
+type progressKey struct{}
+
+// WithProgress attaches a ProgressFunc that streaming calls report to
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func progressFromContext(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
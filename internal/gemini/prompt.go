@@ -0,0 +1,304 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"swarmtest/internal/models"
+)
+
+const (
+	// DefaultMaxPromptTokens is the prompt budget when a mission doesn't set one
+	DefaultMaxPromptTokens = 32000
+	// charsPerToken is a rough estimate that avoids a tokenizer round-trip per call
+	charsPerToken = 4
+	// historyWindow is how many recent actions are shown to the model
+	historyWindow = 5
+	// minTextContent is the shortest page excerpt worth keeping before dropping it entirely
+	minTextContent = 200
+)
+
+// promptContext holds the trimmable parts of a decision prompt
+type promptContext struct {
+	systemPrompt string
+	examples     []models.PromptExample
+	goal         string
+	currentURL   string
+	textContent  string
+	elements     []models.Element
+	history      []string
+	browserMode  bool // enables the key and hover actions
+	sharedVisits bool // elements may be marked visited by other agents
+	// urlLimit is the mission's max_unique_urls_per_agent once the agent has
+	// reached it, telling it to stop opening new pages; 0 otherwise
+	urlLimit int
+}
+
+func newPromptContext(mission *models.Mission, agent *models.Agent, page *models.StrippedPage) *promptContext {
+	systemPrompt := mission.InitialSystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = "You are an AI agent."
+	}
+	if agent.ExplorationHint != "" {
+		systemPrompt += "\n" + agent.ExplorationHint
+	}
+
+	history := agent.ActionHistory
+	if len(history) > historyWindow {
+		history = history[len(history)-historyWindow:]
+	}
+
+	urlLimit := 0
+	if mission.MaxUniqueURLsPerAgent > 0 && agent.UniqueURLsVisited >= mission.MaxUniqueURLsPerAgent {
+		urlLimit = mission.MaxUniqueURLsPerAgent
+	}
+
+	return &promptContext{
+		systemPrompt: systemPrompt,
+		examples:     mission.PromptExamples,
+		goal:         currentGoal(mission, agent),
+		currentURL:   agent.CurrentURL,
+		textContent:  page.TextContent,
+		elements:     page.InteractiveElements,
+		history:      history,
+		browserMode:  agent.ExecutionMode == models.ExecutionModeBrowser,
+		sharedVisits: mission.ShareVisitedURLs,
+		urlLimit:     urlLimit,
+	}
+}
+
+func (p *promptContext) render() string {
+	elementsJSON, _ := json.MarshalIndent(p.elements, "", "  ")
+
+	actions := `"` + strings.Join(httpDecisionActions, `" | "`) + `"`
+	keyInstruction, schemaStep := "", 6
+	if p.browserMode {
+		actions = `"` + strings.Join(browserDecisionActions, `" | "`) + `"`
+		keyInstruction = "6. To press a key (e.g. submit a search with Enter, close a modal with Escape) use action=\"key\" with text_input one of Enter, Escape, Tab, Backspace, Space, ArrowDown, ArrowUp, ArrowLeft, ArrowRight; selector optionally focuses an element first. To open a menu that only appears on hover, use action=\"hover\" on its trigger before clicking the revealed item. Copy selectors exactly; those containing \" >>> \" reach inside web components. For an element with a \"frame\", also return that frame; check its frame_origin before entering anything into a frame from another site, such as a third-party payment or login widget.\n"
+		schemaStep = 7
+	}
+	visitedInstruction := ""
+	if p.sharedVisits {
+		visitedInstruction = " Links marked \"visited\": true lead to pages another agent already covered; prefer unvisited links unless the goal needs that page."
+	}
+	if p.urlLimit > 0 {
+		visitedInstruction += fmt.Sprintf(" You have visited %d distinct pages, the most this mission allows, and will be stopped on reaching a new one: stay on the pages you have seen and finish the goal there, returning \"completed\" or \"failed\".", p.urlLimit)
+	}
+
+	return fmt.Sprintf(`%s
+%s
+Current Goal: %s
+Current URL: %s
+
+Page Content:
+%s
+
+Interactable Elements:
+%s
+
+Agent History (Last %d actions):
+%s
+
+Instructions:
+1. Analyze the page and history.
+2. Decide the next best action to assume to achieve the goal.
+3. If the goal is achieved, return action="completed".
+4. If stuck or error, return action="failed" or try "go_back".
+5. To fill a "file_input" element use action="upload"; text_input may name a test fixture file, or be left empty for a generated file. To wait for content that is still loading, use action="wait_for" with the selector of the element you expect; it fails if the element doesn't appear in time.%s
+%s%d. Respond strictly in JSON format matching this schema:
+{
+  "reasoning": "Reasoning ...",
+  "action": %s,
+  "selector": "css_selector",
+  "text_input": "text to type, fixture file name for upload, or key name (optional)",
+  "frame": "frame of the selected element, if it has one (optional)"
+}
+`, p.systemPrompt, renderExamples(p.examples), p.goal, p.currentURL, p.textContent, string(elementsJSON), len(p.history), strings.Join(p.history, "\n"), visitedInstruction, keyInstruction, schemaStep, actions)
+}
+
+// renderExamples lists the mission's few-shot examples, each with its decision
+// as the JSON the model should answer with, or "" without any
+func renderExamples(examples []models.PromptExample) string {
+	if len(examples) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\nExamples of good decisions:\n")
+	for i, example := range examples {
+		decision, _ := json.Marshal(example.Decision)
+		fmt.Fprintf(&b, "%d. Situation: %s\n   Decision: %s\n", i+1, example.Context, decision)
+	}
+	return b.String()
+}
+
+// fit renders the prompt, trimming it until it fits maxTokens. Context is given
+// up least-important first: page text, then few-shot examples (last first),
+// then older history, then lower-priority elements. The goal, instructions and
+// most recent action are always kept.
+func (p *promptContext) fit(maxTokens int) string {
+	prompt := p.render()
+	if estimateTokens(prompt) <= maxTokens {
+		return prompt
+	}
+	originalTokens := estimateTokens(prompt)
+
+	fits := func() bool {
+		prompt = p.render()
+		return estimateTokens(prompt) <= maxTokens
+	}
+
+	for p.textContent != "" {
+		if len(p.textContent) > minTextContent {
+			p.textContent = cutAtRune(p.textContent, len(p.textContent)/2) + "..."
+		} else {
+			p.textContent = ""
+		}
+		if fits() {
+			return p.logTrim(prompt, originalTokens, maxTokens)
+		}
+	}
+
+	for len(p.examples) > 0 {
+		p.examples = p.examples[:len(p.examples)-1]
+		if fits() {
+			return p.logTrim(prompt, originalTokens, maxTokens)
+		}
+	}
+
+	for len(p.history) > 1 {
+		p.history = p.history[1:]
+		if fits() {
+			return p.logTrim(prompt, originalTokens, maxTokens)
+		}
+	}
+
+	// Dropping elements one render at a time is quadratic on a page with
+	// thousands of them, so drop as many as the overflow needs at once
+	maxChars := (maxTokens+1)*charsPerToken - 1
+	for len(p.elements) > 0 {
+		p.elements = dropElements(p.elements, len(prompt)-maxChars)
+		if fits() {
+			return p.logTrim(prompt, originalTokens, maxTokens)
+		}
+	}
+
+	log.Printf("[Gemini] Prompt still ~%d tokens after trimming (budget %d)", estimateTokens(prompt), maxTokens)
+	return prompt
+}
+
+func (p *promptContext) logTrim(prompt string, originalTokens, maxTokens int) string {
+	log.Printf("[Gemini] Trimmed prompt from ~%d to ~%d tokens (budget %d): %d chars of page text, %d examples, %d history entries, %d elements kept",
+		originalTokens, estimateTokens(prompt), maxTokens, len(p.textContent), len(p.examples), len(p.history), len(p.elements))
+	return prompt
+}
+
+// elementPriority ranks element types by how useful they are for choosing an action
+func elementPriority(el models.Element) int {
+	switch el.Type {
+	case "form":
+		return 0 // submission happens via its inputs and buttons
+	case "link":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// dropElements removes elements, lowest priority first and the last of a
+// priority first, until they render at least excess bytes shorter. It always
+// removes at least one.
+func dropElements(elements []models.Element, excess int) []models.Element {
+	order := make([]int, len(elements))
+	for i := range order {
+		order[i] = len(elements) - 1 - i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return elementPriority(elements[order[a]]) < elementPriority(elements[order[b]])
+	})
+
+	drop := make([]bool, len(elements))
+	for n, freed := 0, 0; n < len(order) && (n == 0 || freed < excess); n++ {
+		drop[order[n]] = true
+		freed += renderedElementSize(elements[order[n]])
+	}
+
+	trimmed := make([]models.Element, 0, len(elements))
+	for i, el := range elements {
+		if !drop[i] {
+			trimmed = append(trimmed, el)
+		}
+	}
+	return trimmed
+}
+
+// renderedElementSize is how many bytes el takes up in the prompt's indented
+// elements array, separator included
+func renderedElementSize(el models.Element) int {
+	b, _ := json.MarshalIndent(el, "  ", "  ")
+	return len(b) + len(",\n  ")
+}
+
+// cutAtRune cuts s to at most n bytes, backing off to the start of a rune so
+// a multibyte character is never split
+func cutAtRune(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func estimateTokens(s string) int {
+	return len(s) / charsPerToken
+}
+
+// currentGoal is the goal the agent is working on: the overall goal, or for a
+// multi-step mission only the current sub-goal, framed by the overall goal
+func currentGoal(mission *models.Mission, agent *models.Agent) string {
+	if len(mission.Steps) == 0 {
+		return mission.Goal
+	}
+
+	step := agent.StepsCompleted
+	if step >= len(mission.Steps) {
+		step = len(mission.Steps) - 1
+	}
+	return fmt.Sprintf("%s (step %d of %d; overall mission: %s)", mission.Steps[step], step+1, len(mission.Steps), mission.Goal)
+}
+
+// buildVerificationPrompt asks an independent judgement of a claimed completion
+func buildVerificationPrompt(mission *models.Mission, agent *models.Agent, page *models.StrippedPage, claim string) string {
+	p := newPromptContext(mission, agent, page)
+	text := p.textContent
+	if len(text) > 8000 {
+		text = cutAtRune(text, 8000) + "..."
+	}
+
+	return fmt.Sprintf(`You are verifying the work of a web testing agent. Be skeptical: agents often claim success prematurely.
+
+Goal: %s
+Final URL: %s
+Page Title: %s
+
+Final Page Content:
+%s
+
+Agent History (Last %d actions):
+%s
+
+Agent's claim: %s
+
+Based only on the final page and history, is the goal actually achieved?
+Respond strictly in JSON format matching this schema:
+{
+  "achieved": true | false,
+  "reasoning": "why"
+}
+`, p.goal, p.currentURL, page.Title, text, len(p.history), strings.Join(p.history, "\n"), claim)
+}
@@ -0,0 +1,187 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"swarmtest/internal/models"
+)
+
+// promptFixture is a content-heavy page: long text, a long history and
+// elements of every priority
+func promptFixture() (*models.Mission, *models.Agent, *models.StrippedPage) {
+	mission := &models.Mission{Goal: "Buy the blue widget"}
+	agent := &models.Agent{ID: "m1-agent-0", CurrentURL: "https://shop.test/widgets"}
+	for i := 0; i < 8; i++ {
+		agent.ActionHistory = append(agent.ActionHistory, fmt.Sprintf("history entry %d", i))
+	}
+	page := &models.StrippedPage{
+		URL:         "https://shop.test/widgets",
+		TextContent: strings.Repeat("Widgets of every colour and size. ", 2000),
+		InteractiveElements: []models.Element{
+			{ID: "elem_a", Type: "form", Selector: "form#search", Text: "GET /search"},
+			{ID: "elem_b", Type: "link", Selector: "a#about", Text: "About us"},
+			{ID: "elem_c", Type: "button", Selector: "button#add-to-cart", Text: "Add to cart"},
+			{ID: "elem_d", Type: "input", Selector: "input#quantity", Name: "quantity"},
+		},
+	}
+	return mission, agent, page
+}
+
+func TestBuildPromptFitsBudget(t *testing.T) {
+	mission, agent, page := promptFixture()
+	untrimmed := estimateTokens(newPromptContext(mission, agent, page).render())
+
+	for _, budget := range []int{untrimmed * 2, untrimmed / 2, 2000, 1000} {
+		t.Run(fmt.Sprint(budget), func(t *testing.T) {
+			mission.MaxPromptTokens = budget
+			prompt := BuildPrompt(mission, agent, page)
+			if tokens := estimateTokens(prompt); tokens > budget {
+				t.Errorf("prompt is ~%d tokens, over the budget of %d", tokens, budget)
+			}
+			// The most important context survives any trim
+			for _, want := range []string{"Current Goal: Buy the blue widget", "button#add-to-cart", "input#quantity", "history entry 7", "Respond strictly in JSON"} {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("trimmed prompt lost %q", want)
+				}
+			}
+		})
+	}
+}
+
+func TestFitTrimOrder(t *testing.T) {
+	mission, agent, page := promptFixture()
+	mission.PromptExamples = []models.PromptExample{{Context: "A cart page", Decision: models.GeminiDecisionResponse{Action: "click", Selector: "#checkout"}}}
+	full := newPromptContext(mission, agent, page)
+	fullText, fullHistory := full.textContent, len(full.history)
+
+	noText := newPromptContext(mission, agent, page)
+	noText.textContent = ""
+	withoutText := estimateTokens(noText.render())
+	noText.examples = nil
+	noText.history = noText.history[len(noText.history)-1:]
+	contextOnly := estimateTokens(noText.render())
+
+	tests := []struct {
+		name         string
+		budget       int
+		wantText     bool // some page text is kept
+		wantExamples int
+		wantHistory  int
+		wantElements int
+	}{
+		{"fits untrimmed", estimateTokens(full.render()), true, 1, fullHistory, 4},
+		{"page text goes first", withoutText, false, 1, fullHistory, 4},
+		{"then examples and older history", contextOnly, false, 0, 1, 4},
+		{"then forms and links", contextOnly - 40, false, 0, 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPromptContext(mission, agent, page)
+			p.fit(tt.budget)
+			if hasText := p.textContent != ""; hasText != tt.wantText {
+				t.Errorf("page text kept = %v, want %v", hasText, tt.wantText)
+			}
+			if tt.wantText && p.textContent != fullText {
+				t.Errorf("page text was trimmed though the prompt fit")
+			}
+			if len(p.examples) != tt.wantExamples || len(p.history) != tt.wantHistory || len(p.elements) != tt.wantElements {
+				t.Errorf("kept %d examples, %d history entries, %d elements; want %d, %d, %d",
+					len(p.examples), len(p.history), len(p.elements), tt.wantExamples, tt.wantHistory, tt.wantElements)
+			}
+			for _, el := range p.elements {
+				if tt.wantElements < 4 && (el.Type == "form" || el.Type == "link") {
+					t.Errorf("kept %s %s over actionable elements", el.Type, el.Selector)
+				}
+			}
+		})
+	}
+}
+
+func TestDropElements(t *testing.T) {
+	el := func(typ, selector string) models.Element { return models.Element{Type: typ, Selector: selector} }
+	tests := []struct {
+		name     string
+		elements []models.Element
+		excess   int
+		want     string // selectors left, in order
+	}{
+		{"form first", []models.Element{el("button", "b"), el("form", "f"), el("link", "l")}, 0, "b l"},
+		{"then links, last first", []models.Element{el("link", "l1"), el("input", "i"), el("link", "l2")}, 0, "l1 i"},
+		{"then the last of the rest", []models.Element{el("button", "b"), el("input", "i")}, 0, "b"},
+		{"single", []models.Element{el("link", "l")}, 0, ""},
+		{"as many as the excess needs", []models.Element{el("button", "b"), el("link", "l1"), el("form", "f"), el("link", "l2")},
+			renderedElementSize(el("form", "f")) + renderedElementSize(el("link", "l2")) + 1, "b"},
+		{"all of them", []models.Element{el("button", "b"), el("link", "l")}, 1 << 20, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var left []string
+			for _, e := range dropElements(tt.elements, tt.excess) {
+				left = append(left, e.Selector)
+			}
+			if got := strings.Join(left, " "); got != tt.want {
+				t.Errorf("left %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Each element's size is exactly what it adds to the rendered array
+func TestRenderedElementSize(t *testing.T) {
+	_, _, page := promptFixture()
+	all, _ := json.MarshalIndent(page.InteractiveElements, "", "  ")
+	fewer, _ := json.MarshalIndent(page.InteractiveElements[1:], "", "  ")
+	if got, want := renderedElementSize(page.InteractiveElements[0]), len(all)-len(fewer); got != want {
+		t.Errorf("renderedElementSize() = %d, want %d", got, want)
+	}
+}
+
+func TestCutAtRune(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 3, "hel"},
+		{"日本語", 3, "日"},
+		{"日本語", 4, "日"},
+		{"日本語", 5, "日"},
+		{"日本語", 6, "日本"},
+		{"a日", 2, "a"},
+		{"日", 1, ""},
+	}
+	for _, tt := range tests {
+		if got := cutAtRune(tt.s, tt.n); got != tt.want {
+			t.Errorf("cutAtRune(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+// Trimmed page text never ends in a split multibyte character
+func TestTrimmedPromptsAreValidUTF8(t *testing.T) {
+	mission, agent, page := promptFixture()
+	// An odd number of 3-byte runes, so halving and the 8000-byte cut both
+	// land inside one
+	page.TextContent = strings.Repeat("日", 8001)
+
+	full := estimateTokens(newPromptContext(mission, agent, page).render())
+	for _, budget := range []int{full * 3 / 4, full / 3} {
+		p := newPromptContext(mission, agent, page)
+		prompt := p.fit(budget)
+		if p.textContent == "" {
+			t.Fatalf("budget %d dropped all page text, want it halved", budget)
+		}
+		if !utf8.ValidString(prompt) {
+			t.Errorf("prompt trimmed to budget %d is not valid UTF-8", budget)
+		}
+	}
+
+	if prompt := buildVerificationPrompt(mission, agent, page, "done"); !utf8.ValidString(prompt) {
+		t.Error("verification prompt is not valid UTF-8")
+	}
+}
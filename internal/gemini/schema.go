@@ -0,0 +1,47 @@
+package gemini
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// httpDecisionActions are the actions an HTTP-mode agent can be told to take
+var httpDecisionActions = []string{"click", "type", "upload", "wait", "wait_for", "go_back", "visit", "completed", "failed"}
+
+// browserDecisionActions adds the actions only a real browser can perform
+var browserDecisionActions = []string{"click", "type", "upload", "key", "hover", "wait", "wait_for", "go_back", "visit", "completed", "failed"}
+
+// decisionSchema constrains the model's output to a GeminiDecisionResponse
+// taking one of the actions available in the agent's mode
+func decisionSchema(browserMode bool) *genai.Schema {
+	actions := httpDecisionActions
+	if browserMode {
+		actions = browserDecisionActions
+	}
+	return &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"reasoning":  {Type: genai.TypeString},
+			"action":     {Type: genai.TypeString, Enum: actions},
+			"selector":   {Type: genai.TypeString, Description: "CSS selector of the element to act on"},
+			"text_input": {Type: genai.TypeString, Description: "Text to type, fixture file name for upload, or key name"},
+			"frame":      {Type: genai.TypeString, Description: "Frame of the selected element, if it has one"},
+		},
+		Required:         []string{"reasoning", "action"},
+		PropertyOrdering: []string{"reasoning", "action", "selector", "text_input", "frame"},
+	}
+}
+
+// isSchemaUnsupported reports whether Gemini rejected a request for its
+// response schema, as models without structured output do
+func isSchemaUnsupported(err error) bool {
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusBadRequest {
+		return false
+	}
+	message := strings.ToLower(apiErr.Message)
+	return strings.Contains(message, "schema")
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -11,6 +14,10 @@ type ExecutionMode string
 const (
 	ExecutionModeHTTP    ExecutionMode = "http"
 	ExecutionModeBrowser ExecutionMode = "browser"
+	// ExecutionModeAuto runs agents in browser mode when Chrome is available, else in HTTP mode
+	ExecutionModeAuto ExecutionMode = "auto"
+	// ExecutionModeCrawl follows every same-host link over HTTP without asking Gemini, mapping the site
+	ExecutionModeCrawl ExecutionMode = "crawl"
 )
 
 // Mission represents a test mission configuration
@@ -28,6 +35,16 @@ type Mission struct {
 	CreatedAt            time.Time      `json:"created_at"`
 	StartedAt            *time.Time     `json:"started_at,omitempty"`
 	CompletedAt          *time.Time     `json:"completed_at,omitempty"`
+	ReplayOf             string         `json:"replay_of,omitempty"` // source mission when this is a replay
+	ScheduledFrom        string         `json:"scheduled_from,omitempty"` // cron mission this run was launched by
+	TemplateID           string         `json:"template_id,omitempty"`    // template the mission was created from
+	NextRunAt            *time.Time     `json:"next_run_at,omitempty"`    // when a scheduled mission next launches
+	StopReason           string         `json:"stop_reason,omitempty"`    // budget that stopped the mission early
+	Tags                 []string       `json:"tags"`
+	IdempotencyKey       string         `json:"-"` // client-supplied key the mission was created with
+	IdempotencyCaller    string         `json:"-"` // API key ID that sent it; keys are scoped per caller
+	IdempotencyBodyHash  string         `json:"-"` // SHA-256 of the request body sent with it
+	MissionOptions
 
 	// Runtime metrics
 	TotalActions        int                `json:"total_actions"`
@@ -35,10 +52,158 @@ type Mission struct {
 	AverageLatencyMS    int64              `json:"average_latency_ms"`
 	CompletedAgents     int                `json:"completed_agents"`
 	FailedAgents        int                `json:"failed_agents"`
+	DecisionCacheHits   int                `json:"decision_cache_hits"`
+	DecisionCacheMisses int                `json:"decision_cache_misses"`
+	TotalPromptTokens   int64              `json:"total_prompt_tokens"`
+	TotalOutputTokens   int64              `json:"total_output_tokens"`
+	EstimatedCostUSD    float64            `json:"estimated_cost_usd"`
+	ClaimedCompletions  int                `json:"claimed_completions"`
+	VerifiedCompletions int                `json:"verified_completions"`
+	UniqueURLs          int                `json:"unique_urls"`      // distinct URLs visited by the swarm
+	AgentURLVisits      int                `json:"agent_url_visits"` // distinct URLs summed over agents
+	JSErrors            int                `json:"js_errors"`        // browser mode console errors and exceptions
+	NetworkFailures     int                `json:"network_failures"` // browser mode failed or 4xx/5xx requests
+	DroppedEvents       int64              `json:"dropped_events"`   // events lost before the event logger, once finished
 	RecentEvents        []ActionLog        `json:"recent_events"`
 	AgentMetrics        map[string]*Agent  `json:"agent_metrics"`
 }
 
+// MissionOptions holds optional per-mission behaviour settings. It is embedded in
+// both Mission and CreateMissionRequest, so its fields appear at the top level of
+// the JSON API, and is persisted as a single JSON column.
+type MissionOptions struct {
+	// EnableDecisionCache reuses recent Gemini decisions for identical page+goal contexts
+	EnableDecisionCache bool `json:"enable_decision_cache,omitempty"`
+	// MaxPromptTokens caps the estimated prompt size; larger prompts are trimmed (0 = server default)
+	MaxPromptTokens int `json:"max_prompt_tokens,omitempty"`
+	// PromptExamples are shown to the model ahead of each decision prompt, as
+	// ideal decisions for typical situations on the target
+	PromptExamples []PromptExample `json:"prompt_examples,omitempty"`
+	// ActionTimeoutSeconds bounds each executed action (0 = 30s)
+	ActionTimeoutSeconds int `json:"action_timeout_seconds,omitempty"`
+	// RampUpSeconds staggers agent launches linearly over this window (0 = all at once)
+	RampUpSeconds int `json:"ramp_up_seconds,omitempty"`
+	// MaxConsecutiveErrors is the error streak an agent gives up after (0 = 10)
+	MaxConsecutiveErrors int `json:"max_consecutive_errors,omitempty"`
+	// RetryFailedAgents is how many times each agent slot is relaunched with a
+	// fresh agent when its agent fails (0 = never)
+	RetryFailedAgents int `json:"retry_failed_agents,omitempty"`
+	// StreamDecisions streams Gemini output and emits "thinking" progress events
+	StreamDecisions bool `json:"stream_decisions,omitempty"`
+
+	// Gemini generation parameters; nil/zero uses the defaults (temperature 0.2, max 8192 output tokens)
+	Temperature     *float32 `json:"temperature,omitempty"`       // 0-2; low is deterministic, high explores
+	TopP            *float32 `json:"top_p,omitempty"`             // 0-1
+	MaxOutputTokens int32    `json:"max_output_tokens,omitempty"`
+
+	// VerifyCompletion re-checks "completed" claims against the final page before accepting them
+	VerifyCompletion bool `json:"verify_completion,omitempty"`
+
+	// Steps splits the goal into ordered sub-goals; agents work on one at a time
+	Steps []string `json:"steps,omitempty"`
+
+	// SuccessCriteria, when set, decides when an agent has passed: it is checked
+	// on every page and overrides the model's own completion claims
+	SuccessCriteria *SuccessCriteria `json:"success_criteria,omitempty"`
+
+	// ThinkTimeMinMS and ThinkTimeMaxMS pause each agent for a random time in
+	// this range before every action, like a user reading the page (0 = no pause)
+	ThinkTimeMinMS int `json:"think_time_min_ms,omitempty"`
+	ThinkTimeMaxMS int `json:"think_time_max_ms,omitempty"`
+	// UserAgents is dealt out round-robin, one User-Agent per agent, instead of SwarmTest/1.0
+	UserAgents []string `json:"user_agents,omitempty"`
+	// Language is a BCP 47 tag agents send as their Accept-Language, and the
+	// browser's locale in browser mode (default the server's)
+	Language string `json:"language,omitempty"`
+	// Timezone is an IANA time zone the browser reports in browser mode (default the server's)
+	Timezone string `json:"timezone,omitempty"`
+
+	// Seed drives each agent's random source (agent i uses Seed+i); assigned at creation when unset
+	Seed int64 `json:"seed,omitempty"`
+
+	// DiversifyAgents gives each agent a persona and asks it to avoid the paths others take
+	DiversifyAgents bool `json:"diversify_agents,omitempty"`
+
+	// RespectRobotsTxt skips navigations the target's robots.txt disallows
+	RespectRobotsTxt bool `json:"respect_robots_txt,omitempty"`
+	// BrowserFallback runs browser-mode agents in HTTP mode when their browser
+	// can't start, instead of failing them
+	BrowserFallback bool `json:"browser_fallback,omitempty"`
+	// ShareVisitedURLs shares a visited set among the mission's agents, steering
+	// each away from pages another agent already covered
+	ShareVisitedURLs bool `json:"share_visited_urls,omitempty"`
+	// CheckLinks has agents request the same-site links they find, reporting
+	// broken ones as findings
+	CheckLinks bool `json:"check_links,omitempty"`
+
+	// Crawl mode bounds: link depth from the target (0 = 3), pages visited (0 = 500),
+	// and traversal order, "bfs" (default) or "dfs"
+	CrawlMaxDepth int    `json:"crawl_max_depth,omitempty"`
+	CrawlMaxPages int    `json:"crawl_max_pages,omitempty"`
+	CrawlStrategy string `json:"crawl_strategy,omitempty"`
+
+	// FollowRedirects (default true) and MaxRedirects (default 10) control HTTP-mode redirects.
+	// Unfollowed redirects are logged with their status and target instead.
+	FollowRedirects *bool `json:"follow_redirects,omitempty"`
+	MaxRedirects    int   `json:"max_redirects,omitempty"`
+
+	// Browser mode emulation: a fixed viewport, or a named device profile
+	ViewportWidth  int    `json:"viewport_width,omitempty"`
+	ViewportHeight int    `json:"viewport_height,omitempty"`
+	Device         string `json:"device,omitempty"`
+
+	// Proxy routes the agents' traffic (HTTP and browser mode) through an http, https or socks5 proxy
+	Proxy string `json:"proxy,omitempty"`
+
+	// InsecureSkipVerify accepts invalid TLS certificates (self-signed staging hosts).
+	// The server must opt in with ALLOW_INSECURE_TLS.
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+	// ClientCertFile and ClientKeyFile are server-side PEM paths for mutual TLS (HTTP mode only)
+	ClientCertFile string `json:"client_cert_file,omitempty"`
+	ClientKeyFile  string `json:"client_key_file,omitempty"`
+	// TargetAuth authenticates every request to the target's origin (HTTP and browser mode)
+	TargetAuth *TargetAuth `json:"target_auth,omitempty"`
+
+	// ScheduledAt launches the mission once at that time instead of right away.
+	// Cron instead launches a new run of it each time the UTC five-field
+	// expression fires, until it is cancelled.
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+	Cron        string     `json:"cron,omitempty"`
+
+	// WebhookURL receives a POST with the final summary when the mission finishes
+	WebhookURL string `json:"webhook_url,omitempty"`
+
+	// AlertErrorRatePercent raises an "alert" event (and webhook) when the error
+	// rate stays at or above it for several consecutive checks; 0 disables alerts
+	AlertErrorRatePercent float64 `json:"alert_error_rate_percent,omitempty"`
+
+	// FormContentType makes HTTP mode encode every POST form this way instead of
+	// following its enctype, e.g. application/json for JS-driven endpoints
+	FormContentType string `json:"form_content_type,omitempty"`
+
+	// MaxPageSizeBytes caps each response body and browser DOM an agent reads;
+	// the server's default applies when unset
+	MaxPageSizeBytes int `json:"max_page_size_bytes,omitempty"`
+
+	// MaxTotalActions and MaxCostUSD stop the whole mission once its agents have
+	// attempted that many actions or spent that much on Gemini (0 = unlimited)
+	MaxTotalActions int     `json:"max_total_actions,omitempty"`
+	MaxCostUSD      float64 `json:"max_cost_usd,omitempty"`
+
+	// MaxUniqueURLsPerAgent bounds the distinct URLs each agent visits: at the
+	// limit it is told to converge, past it it completes (0 = unlimited)
+	MaxUniqueURLsPerAgent int `json:"max_unique_urls_per_agent,omitempty"`
+
+	// SlowPageMS and LargePageBytes raise a "performance" finding for a page
+	// that took longer to load or was larger than this (0 = no finding)
+	SlowPageMS     int `json:"slow_page_ms,omitempty"`
+	LargePageBytes int `json:"large_page_bytes,omitempty"`
+
+	// ElementTypes limits the elements agents are shown to these types (link,
+	// button, input, form); empty shows them all
+	ElementTypes []string `json:"element_types,omitempty"`
+}
+
 // Agent represents a single testing agent
 type Agent struct {
 	ID              string         `json:"id"`
@@ -52,6 +217,13 @@ type Agent struct {
 	ConsecutiveErrors int          `json:"consecutive_errors"`
 	URLHistory      []string       `json:"url_history"`
 	LastActionAt    *time.Time     `json:"last_action_at,omitempty"`
+	StepsCompleted  int            `json:"steps_completed"` // sub-goals done; also the index of the current step
+	ExplorationHint string         `json:"exploration_hint,omitempty"`
+	FailureReason   string         `json:"failure_reason,omitempty"` // why a failed or blocked agent gave up
+	ExecutionMode   ExecutionMode  `json:"execution_mode,omitempty"` // mode the agent actually ran in
+	CriteriaMet     bool           `json:"criteria_met,omitempty"`   // completed by meeting the mission's success criteria
+	UniqueURLsVisited int          `json:"unique_urls_visited"`
+	RetryOf         string         `json:"retry_of,omitempty"` // failed agent this one replaced
 }
 
 // ActionLog represents a single action performed by an agent
@@ -65,6 +237,13 @@ type ActionLog struct {
 	LatencyMS     int64     `json:"latency_ms"`
 	ErrorMessage  string    `json:"error_message,omitempty"`
 	NewURL        string    `json:"new_url,omitempty"`
+	TextInput     string    `json:"text_input,omitempty"`
+	Frame         string    `json:"frame,omitempty"` // iframe the selector was in
+	StatusCode    int       `json:"status_code,omitempty"`
+	RedirectURL   string    `json:"redirect_url,omitempty"` // target of a redirect that was not followed
+	// Reasoning and ExpectedNextState are the model's explanation of the decision behind the action
+	Reasoning         string `json:"reasoning,omitempty"`
+	ExpectedNextState string `json:"expected_next_state,omitempty"`
 }
 
 // StrippedPage represents a simplified view of a web page
@@ -72,7 +251,11 @@ type StrippedPage struct {
 	URL                  string    `json:"url"`
 	Title                string    `json:"title"`
 	Description          string    `json:"description"`
+	ContentType          string    `json:"content_type,omitempty"` // set only for non-HTML responses, which have no elements
 	TextContent          string    `json:"text_content"`
+	// MetaRefresh is where a <meta http-equiv="refresh"> on the page sends the
+	// visitor, as an absolute URL
+	MetaRefresh          string    `json:"meta_refresh,omitempty"`
 	InteractiveElements  []Element `json:"interactive_elements"`
 	Timestamp            time.Time `json:"timestamp"`
 }
@@ -87,6 +270,13 @@ type Element struct {
 	Name        string `json:"name,omitempty"`
 	Placeholder string `json:"placeholder,omitempty"`
 	InputType   string `json:"input_type,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+	ReadOnly    bool   `json:"readonly,omitempty"`
+	Visited     bool   `json:"visited,omitempty"` // link target already covered by another agent (share_visited_urls)
+	// Frame labels the iframe holding the element (browser mode), which actions
+	// on it must name; FrameOrigin is where that frame's document is from
+	Frame       string `json:"frame,omitempty"`
+	FrameOrigin string `json:"frame_origin,omitempty"`
 }
 
 // GeminiDecisionRequest is the request sent to Gemini for action decision
@@ -104,7 +294,59 @@ type GeminiDecisionResponse struct {
 	Action             string `json:"action"` // click, type, wait, go_back
 	Selector           string `json:"selector,omitempty"`
 	TextInput          string `json:"text_input,omitempty"`
+	Frame              string `json:"frame,omitempty"` // iframe of the selected element
 	ExpectedNextState  string `json:"expected_next_state,omitempty"`
+
+	Metadata DecisionMetadata `json:"-"`
+}
+
+// PromptExample is a few-shot example for decision prompts: a situation
+// described in words, and the decision the model should make in it
+type PromptExample struct {
+	Context  string                 `json:"context"`
+	Decision GeminiDecisionResponse `json:"decision"`
+}
+
+// DecisionMetadata describes how a decision was produced; it is not part of the model output
+type DecisionMetadata struct {
+	CacheLookup  bool // the decision cache was consulted
+	CacheHit     bool // the decision was served from the cache
+	Model        string
+	PromptTokens int64
+	OutputTokens int64   // candidate plus thinking tokens
+	CostUSD      float64 // estimated from the configured price table
+}
+
+// GoalVerification is the verifier's judgement of a claimed completion
+type GoalVerification struct {
+	Achieved  bool   `json:"achieved"`
+	Reasoning string `json:"reasoning"`
+
+	Metadata DecisionMetadata `json:"-"`
+}
+
+// VerificationEvent is emitted when a claimed completion is verified
+type VerificationEvent struct {
+	AgentID      string  `json:"agent_id"`
+	MissionID    string  `json:"mission_id"`
+	Achieved     bool    `json:"achieved"`
+	Reasoning    string  `json:"reasoning"`
+	PromptTokens int64   `json:"prompt_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// DecisionEvent is emitted each time an agent obtains a decision
+type DecisionEvent struct {
+	AgentID     string `json:"agent_id"`
+	MissionID   string `json:"mission_id"`
+	Action      string `json:"action"`
+	CacheLookup bool   `json:"cache_lookup"`
+	CacheHit    bool   `json:"cache_hit"`
+	Model        string  `json:"model,omitempty"`
+	PromptTokens int64   `json:"prompt_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
 }
 
 // CreateMissionRequest is the request body for creating a mission
@@ -117,6 +359,28 @@ type CreateMissionRequest struct {
 	RateLimitPerSecond   float64       `json:"rate_limit_per_second"`
 	InitialSystemPrompt  string        `json:"initial_system_prompt"`
 	ExecutionMode        ExecutionMode `json:"execution_mode"` // defaults to "http"
+	Tags                 []string      `json:"tags,omitempty"`
+	// TemplateID names a saved template, by ID or name, whose settings the
+	// mission starts from; fields set in the request override them
+	TemplateID string `json:"template_id,omitempty"`
+	MissionOptions
+}
+
+// MissionTemplate is a named, reusable mission configuration without a
+// target; missions are created from it with just a target_url
+type MissionTemplate struct {
+	ID                  string        `json:"id"`
+	Name                string        `json:"name"`
+	Description         string        `json:"description,omitempty"`
+	CreatedAt           time.Time     `json:"created_at"`
+	NumAgents           int           `json:"num_agents,omitempty"`
+	Goal                string        `json:"goal,omitempty"`
+	MaxDurationSeconds  int           `json:"max_duration_seconds,omitempty"`
+	RateLimitPerSecond  float64       `json:"rate_limit_per_second,omitempty"`
+	InitialSystemPrompt string        `json:"initial_system_prompt,omitempty"`
+	ExecutionMode       ExecutionMode `json:"execution_mode,omitempty"`
+	Tags                []string      `json:"tags,omitempty"`
+	MissionOptions
 }
 
 // CreateMissionResponse is the response when creating a mission
@@ -124,6 +388,219 @@ type CreateMissionResponse struct {
 	MissionID string `json:"mission_id"`
 }
 
+// ReplayDivergence is a recorded action whose outcome differed when replayed
+type ReplayDivergence struct {
+	AgentID        string `json:"agent_id"`
+	Step           int    `json:"step"`
+	Action         string `json:"action"`
+	Selector       string `json:"selector,omitempty"`
+	ExpectedResult string `json:"expected_result"`
+	ActualResult   string `json:"actual_result"` // "missing" if the replay never reached this step
+	ExpectedURL    string `json:"expected_url,omitempty"`
+	ActualURL      string `json:"actual_url,omitempty"`
+	ErrorMessage   string `json:"error_message,omitempty"`
+}
+
+// ReplayReport compares a replay mission's outcomes with those of its source
+type ReplayReport struct {
+	MissionID       string             `json:"mission_id"`
+	SourceMissionID string             `json:"source_mission_id"`
+	Status          string             `json:"status"`
+	ComparedActions int                `json:"compared_actions"`
+	Divergences     []ReplayDivergence `json:"divergences"`
+}
+
+// MissionComparison diffs two missions' key metrics and per-agent outcomes;
+// deltas are B minus A
+type MissionComparison struct {
+	A            ComparedMission      `json:"a"`
+	B            ComparedMission      `json:"b"`
+	Metrics      []MetricDiff         `json:"metrics"`
+	AgentChanges []AgentOutcomeChange `json:"agent_changes"`
+}
+
+// ComparedMission identifies one side of a comparison
+type ComparedMission struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Status    string        `json:"status"`
+	CreatedAt time.Time     `json:"created_at"`
+	Summary   *SummaryEvent `json:"summary"`
+}
+
+// MetricDiff is one metric's value in both missions
+type MetricDiff struct {
+	Name           string   `json:"name"`
+	A              float64  `json:"a"`
+	B              float64  `json:"b"`
+	Delta          float64  `json:"delta"`
+	DeltaPercent   *float64 `json:"delta_percent,omitempty"` // omitted when A is zero
+	HigherIsBetter bool     `json:"higher_is_better"`
+	Regression     bool     `json:"regression"` // B is worse than A
+}
+
+// AgentOutcomeChange is an agent position whose final status differs between
+// the missions; agents are paired by index
+type AgentOutcomeChange struct {
+	AgentIndex int    `json:"agent_index"`
+	AgentA     string `json:"agent_a,omitempty"`
+	AgentB     string `json:"agent_b,omitempty"`
+	StatusA    string `json:"status_a,omitempty"` // empty when the agent only exists in B
+	StatusB    string `json:"status_b,omitempty"` // empty when the agent only exists in A
+	ErrorsA    int    `json:"errors_a"`
+	ErrorsB    int    `json:"errors_b"`
+}
+
+// JSError is a console error or uncaught exception reported by a page in browser mode
+type JSError struct {
+	Kind      string `json:"kind"` // "console" or "exception"
+	Message   string `json:"message"`
+	SourceURL string `json:"source_url,omitempty"`
+}
+
+// JSErrorEvent reports the JavaScript errors seen while an agent performed an action
+type JSErrorEvent struct {
+	AgentID   string    `json:"agent_id"`
+	MissionID string    `json:"mission_id"`
+	PageURL   string    `json:"page_url"`
+	Action    string    `json:"action"`
+	Errors    []JSError `json:"errors"`
+}
+
+// NetworkFailure is a request made by a page that failed or returned a 4xx/5xx status
+type NetworkFailure struct {
+	Method       string `json:"method,omitempty"`
+	URL          string `json:"url"`
+	ResourceType string `json:"resource_type,omitempty"` // e.g. XHR, Fetch, Document
+	StatusCode   int    `json:"status_code,omitempty"`   // 0 when no response was received
+	ErrorText    string `json:"error_text,omitempty"`
+}
+
+// NetworkFailureEvent reports the failed requests seen while an agent performed an action
+type NetworkFailureEvent struct {
+	AgentID   string           `json:"agent_id"`
+	MissionID string           `json:"mission_id"`
+	PageURL   string           `json:"page_url"`
+	Action    string           `json:"action"`
+	Failures  []NetworkFailure `json:"failures"`
+}
+
+// ClientRedirectEvent reports an HTTP-mode page whose meta refresh sends the
+// agent on to another URL. Browser mode follows such redirects, and script
+// ones, like any visitor's browser.
+type ClientRedirectEvent struct {
+	AgentID   string `json:"agent_id"`
+	MissionID string `json:"mission_id"`
+	FromURL   string `json:"from_url"`
+	ToURL     string `json:"to_url"`
+	// Followed is false for a meta refresh the agent didn't follow: the mission
+	// doesn't follow redirects, or the page is too many refreshes in
+	Followed bool `json:"followed"`
+}
+
+// Finding severities, most severe first
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+)
+
+// Finding is a persisted problem observed by an agent: a JavaScript error, a
+// failed request in browser mode, or an error response in HTTP mode
+type Finding struct {
+	Timestamp  time.Time `json:"timestamp"`
+	MissionID  string    `json:"mission_id"`
+	AgentID    string    `json:"agent_id"`
+	Type       string    `json:"type"` // "js_error", "network", "http_error", "broken_link", "bot_wall" or "performance"
+	Severity   string    `json:"severity"`
+	PageURL    string    `json:"page_url"`
+	Action     string    `json:"action"`
+	Message    string    `json:"message"`
+	RequestURL string    `json:"request_url,omitempty"`
+	Method     string    `json:"method,omitempty"`
+	StatusCode int       `json:"status_code,omitempty"`
+	// DurationMS and ContentLength are the load time and size of the page a
+	// performance finding is about
+	DurationMS    int64 `json:"duration_ms,omitempty"`
+	ContentLength int64 `json:"content_length,omitempty"`
+}
+
+// RequestSeverity rates a failed request: no response or a server error is
+// high; a client error is medium for pages and API calls and low for assets
+// such as images and fonts
+func RequestSeverity(statusCode int, resourceType string) string {
+	if statusCode == 0 || statusCode >= 500 {
+		return SeverityHigh
+	}
+	switch resourceType {
+	case "", "Document", "XHR", "Fetch":
+		return SeverityMedium
+	}
+	return SeverityLow
+}
+
+// JSErrorSeverity rates a JavaScript error: uncaught exceptions are high,
+// console errors medium
+func JSErrorSeverity(kind string) string {
+	if kind == "exception" {
+		return SeverityHigh
+	}
+	return SeverityMedium
+}
+
+// AgentTimelineResponse is one agent's metrics and everything it did, oldest first
+type AgentTimelineResponse struct {
+	Agent      *Agent      `json:"agent"`
+	ActionLogs []ActionLog `json:"action_logs"`
+}
+
+// ListFindingsResponse is a single page of findings
+type ListFindingsResponse struct {
+	Findings []Finding `json:"findings"`
+	Total    int       `json:"total"`
+	Limit    int       `json:"limit"`
+	Offset   int       `json:"offset"`
+}
+
+// Coverage is what the swarm has collectively explored of a mission's target
+type Coverage struct {
+	MissionID       string          `json:"mission_id"`
+	UniqueURLs      int             `json:"unique_urls"`
+	UniqueSelectors int             `json:"unique_selectors"`
+	URLs            []string        `json:"urls"`
+	Selectors       []string        `json:"selectors"`  // selectors of successful interactions
+	Discovery       []CoveragePoint `json:"discovery"`  // pages discovered over time
+	Truncated       bool            `json:"truncated"`  // tracking limits were hit; later finds are not counted
+	UpdatedAt       time.Time       `json:"updated_at"`
+
+	// SharedVisited is the live visited set of a running share_visited_urls mission
+	SharedVisited          []string `json:"shared_visited,omitempty"`
+	SharedVisitedTruncated bool     `json:"shared_visited_truncated,omitempty"`
+}
+
+// Sitemap is the URL graph a crawl mission mapped
+type Sitemap struct {
+	MissionID string        `json:"mission_id"`
+	Pages     []SitemapPage `json:"pages"`     // in visit order
+	Truncated bool          `json:"truncated"` // crawl_max_pages was reached; further links were not crawled
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// SitemapPage is one crawled page and the same-host links found on it
+type SitemapPage struct {
+	URL        string   `json:"url"`
+	Depth      int      `json:"depth"` // links away from the target URL
+	StatusCode int      `json:"status_code,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	Links      []string `json:"links"`
+}
+
+// CoveragePoint is the number of unique URLs discovered by a point in time
+type CoveragePoint struct {
+	At         time.Time `json:"at"`
+	UniqueURLs int       `json:"unique_urls"`
+}
+
 // Event represents any event that can be broadcast via WebSocket
 type Event struct {
 	Type      string    `json:"type"`
@@ -131,12 +608,82 @@ type Event struct {
 	Data      any       `json:"data"`
 }
 
+// ThinkingEvent reports a streamed chunk of an in-progress decision
+type ThinkingEvent struct {
+	AgentID   string `json:"agent_id"`
+	MissionID string `json:"mission_id"`
+	Chunk     string `json:"chunk"`
+	Received  int    `json:"received_bytes"`
+}
+
+// TargetAuth is HTTP Basic credentials or a bearer token for a protected
+// target. Its secrets never leave the server: they encode as RedactedSecret.
+type TargetAuth struct {
+	Username    string `json:"username,omitempty"`
+	Password    string `json:"password,omitempty"`
+	BearerToken string `json:"bearer_token,omitempty"`
+}
+
+// RedactedSecret replaces credentials in API responses, events and logs
+const RedactedSecret = "[REDACTED]"
+
+// Redacted returns a copy of the credentials with the secrets replaced
+func (t TargetAuth) Redacted() TargetAuth {
+	if t.Password != "" {
+		t.Password = RedactedSecret
+	}
+	if t.BearerToken != "" {
+		t.BearerToken = RedactedSecret
+	}
+	return t
+}
+
+// MarshalJSON encodes the credentials redacted
+func (t TargetAuth) MarshalJSON() ([]byte, error) {
+	type plain TargetAuth
+	return json.Marshal(plain(t.Redacted()))
+}
+
+// String formats the credentials redacted, so logging a mission can't leak them
+func (t TargetAuth) String() string {
+	r := t.Redacted()
+	return fmt.Sprintf("{Username:%s Password:%s BearerToken:%s}", r.Username, r.Password, r.BearerToken)
+}
+
+// Header is the Authorization header value the credentials send
+func (t TargetAuth) Header() string {
+	if t.BearerToken != "" {
+		return "Bearer " + t.BearerToken
+	}
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(t.Username+":"+t.Password))
+}
+
+// SuccessCriteria is the expected outcome of a mission. An agent passes once
+// its current page meets every condition that is set.
+type SuccessCriteria struct {
+	URLPattern string `json:"url_pattern,omitempty"` // regexp the page URL must match
+	Selector   string `json:"selector,omitempty"`    // CSS selector that must match an element
+	Text       string `json:"text,omitempty"`        // text the page body must contain
+	StatusCode int    `json:"status_code,omitempty"` // status the page must be served with (HTTP mode only)
+}
+
 // AgentEvent is an event specific to an agent
 type AgentEvent struct {
-	AgentID  string     `json:"agent_id"`
-	MissionID string    `json:"mission_id"`
-	Status   string     `json:"status"`
+	AgentID   string     `json:"agent_id"`
+	MissionID string     `json:"mission_id"`
+	Status    string     `json:"status"`
 	ActionLog *ActionLog `json:"action_log,omitempty"`
+
+	// Cumulative agent state at the time of the event, persisted by the event logger
+	CurrentURL        string        `json:"current_url,omitempty"`
+	SuccessCount      int           `json:"success_count"`
+	ErrorCount        int           `json:"error_count"`
+	TotalLatencyMS    int64         `json:"total_latency_ms"`
+	ConsecutiveErrors int           `json:"consecutive_errors"`
+	StepsCompleted    int           `json:"steps_completed"`
+	FailureReason     string        `json:"failure_reason,omitempty"`
+	ExecutionMode     ExecutionMode `json:"execution_mode,omitempty"`
+	CriteriaMet       bool          `json:"criteria_met,omitempty"`
 }
 
 // SummaryEvent is a periodic summary of mission progress
@@ -150,6 +697,62 @@ type SummaryEvent struct {
 	TotalErrors      int     `json:"total_errors"`
 	AverageLatencyMS int64   `json:"average_latency_ms"`
 	ErrorRatePercent float64 `json:"error_rate_percent"`
+	CacheHitRatePercent float64 `json:"cache_hit_rate_percent"`
+	TotalPromptTokens   int64   `json:"total_prompt_tokens"`
+	TotalOutputTokens   int64   `json:"total_output_tokens"`
+	EstimatedCostUSD    float64 `json:"estimated_cost_usd"`
+	ClaimedCompletions  int     `json:"claimed_completions"`
+	VerifiedCompletions int     `json:"verified_completions"`
+	UniqueURLs          int     `json:"unique_urls"`
+	PathOverlapPercent  float64 `json:"path_overlap_percent"`
+	JSErrors            int     `json:"js_errors"`
+	NetworkFailures     int     `json:"network_failures"`
+	// DroppedEvents counts the mission's events lost before they reached the
+	// event logger; the other metrics undercount while it isn't 0
+	DroppedEvents int64 `json:"dropped_events"`
+	// RetriedAgents counts agents launched to replace failed ones; TotalAgents
+	// counts the original agents only
+	RetriedAgents int `json:"retried_agents"`
+	// Agents that met the mission's success criteria, and their share of all agents
+	PassedAgents    int     `json:"passed_agents"`
+	PassRatePercent float64 `json:"pass_rate_percent"`
+	// StopReason names the budget that stopped the mission, if one did
+	StopReason string `json:"stop_reason,omitempty"`
+
+	// SlowestPages and LargestPages are the mission's worst performance
+	// findings, reported by the metrics endpoint only
+	SlowestPages []Finding `json:"slowest_pages,omitempty"`
+	LargestPages []Finding `json:"largest_pages,omitempty"`
+}
+
+// WebhookPayload is posted to a mission's webhook URL when it finishes
+type WebhookPayload struct {
+	Event       string        `json:"event"`
+	MissionID   string        `json:"mission_id"`
+	Name        string        `json:"name"`
+	Status      string        `json:"status"`
+	CompletedAt *time.Time    `json:"completed_at,omitempty"`
+	Summary     *SummaryEvent `json:"summary"`
+	Alert       *AlertEvent   `json:"alert,omitempty"` // set for mission_alert
+}
+
+// AlertEvent is broadcast when a mission's error rate stays above its alert threshold
+type AlertEvent struct {
+	MissionID        string  `json:"mission_id"`
+	Kind             string  `json:"kind"` // "error_rate"
+	ErrorRatePercent float64 `json:"error_rate_percent"`
+	ThresholdPercent float64 `json:"threshold_percent"`
+	WindowSeconds    int     `json:"window_seconds"`
+	Message          string  `json:"message"`
+}
+
+// ListMissionsResponse is a single page of missions
+type ListMissionsResponse struct {
+	Missions   []*Mission `json:"missions"`
+	Total      int        `json:"total"`
+	Limit      int        `json:"limit"`
+	Offset     int        `json:"offset"`
+	NextOffset *int       `json:"next_offset,omitempty"` // nil on the last page
 }
 
 // MissionStatusResponse is the response for mission status
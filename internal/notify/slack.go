@@ -0,0 +1,182 @@
+// Package notify posts mission notifications to chat tools, driven by events
+// from the bus so mission execution knows nothing about them
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"swarmtest/internal/models"
+	"swarmtest/internal/store"
+)
+
+const slackTimeout = 10 * time.Second
+
+// SlackNotifier posts a summary to a Slack incoming webhook when a mission
+// finishes, and each error rate alert a running mission raises
+type SlackNotifier struct {
+	webhookURL   string
+	dashboardURL string
+	store        store.MissionStore
+	events       <-chan models.Event
+	client       *http.Client
+}
+
+// NewSlackNotifier creates a notifier consuming events
+func NewSlackNotifier(webhookURL, dashboardURL string, store store.MissionStore, events <-chan models.Event) *SlackNotifier {
+	return &SlackNotifier{
+		webhookURL:   webhookURL,
+		dashboardURL: dashboardURL,
+		store:        store,
+		events:       events,
+		client:       &http.Client{Timeout: slackTimeout},
+	}
+}
+
+// Run consumes events until ctx is cancelled or the channel is closed
+func (n *SlackNotifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-n.events:
+			if !ok {
+				return
+			}
+			n.handleEvent(event)
+		}
+	}
+}
+
+// handleEvent posts alerts and, on mission_flushed, once the event logger has
+// saved a finished mission's final metrics, its summary; all other events are
+// ignored
+func (n *SlackNotifier) handleEvent(event models.Event) {
+	switch event.Type {
+	case "alert":
+		if alert, ok := event.Data.(*models.AlertEvent); ok {
+			go n.notifyAlert(alert)
+		}
+	case "mission_flushed":
+		if data, ok := event.Data.(map[string]string); ok && data["mission_id"] != "" {
+			go n.notifyCompleted(data["mission_id"])
+		}
+	}
+}
+
+// notifyCompleted posts the final summary of a finished mission
+func (n *SlackNotifier) notifyCompleted(missionID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), slackTimeout)
+	defer cancel()
+
+	mission, ok := n.store.Get(ctx, missionID)
+	if !ok {
+		return
+	}
+
+	icon, outcome := completionOutcome(mission)
+	text := fmt.Sprintf("%s Mission %s %s: %d/%d agents succeeded, %.1f%% error rate, $%.2f spent",
+		icon, n.missionLink(mission), outcome, mission.CompletedAgents, mission.NumAgents,
+		errorRate(mission), mission.EstimatedCostUSD)
+	n.post(ctx, mission.ID, text)
+}
+
+// completionOutcome is the icon and wording of a finished mission's summary:
+// only a mission that ran its course is marked a success
+func completionOutcome(mission *models.Mission) (icon, outcome string) {
+	switch {
+	case mission.StopReason == "drained":
+		return ":octagonal_sign:", "was drained by an operator"
+	case mission.StopReason != "":
+		return ":money_with_wings:", fmt.Sprintf("was stopped by its %s budget", mission.StopReason)
+	case mission.Status != "completed":
+		return ":warning:", mission.Status
+	}
+	return ":white_check_mark:", "completed"
+}
+
+// notifyAlert posts an error rate alert raised by a running mission
+func (n *SlackNotifier) notifyAlert(alert *models.AlertEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), slackTimeout)
+	defer cancel()
+
+	mission, ok := n.store.Get(ctx, alert.MissionID)
+	if !ok {
+		return
+	}
+
+	text := fmt.Sprintf(":warning: Mission %s is at %.1f%% error rate (threshold %.0f%%), sustained for %ds",
+		n.missionLink(mission), alert.ErrorRatePercent, alert.ThresholdPercent, alert.WindowSeconds)
+	n.post(ctx, mission.ID, text)
+}
+
+// missionLink formats the mission name as a Slack link to it in the dashboard
+func (n *SlackNotifier) missionLink(mission *models.Mission) string {
+	name := mission.Name
+	if name == "" {
+		name = mission.ID
+	}
+	if n.dashboardURL == "" {
+		return "*" + slackEscape(name) + "*"
+	}
+	link := n.dashboardURL + "/dashboard?mission=" + url.QueryEscape(mission.ID)
+	return fmt.Sprintf("<%s|*%s*>", link, slackEscape(name))
+}
+
+// post sends a message to the webhook. Failures are logged, not retried.
+func (n *SlackNotifier) post(ctx context.Context, missionID, text string) {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", n.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("[Slack] Failed to build request for mission %s: %v", missionID, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		log.Printf("[Slack] Failed to notify for mission %s: %v", missionID, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("[Slack] Notification for mission %s rejected: status %d", missionID, resp.StatusCode)
+	}
+}
+
+// errorRate is the share of a mission's attempts that failed, in percent
+func errorRate(mission *models.Mission) float64 {
+	total := mission.TotalActions + mission.TotalErrors
+	if total == 0 {
+		return 0
+	}
+	return float64(mission.TotalErrors) / float64(total) * 100
+}
+
+// slackEscape escapes the characters Slack treats as markup in message text
+func slackEscape(s string) string {
+	var b bytes.Buffer
+	for _, r := range s {
+		switch r {
+		case '&':
+			b.WriteString("&amp;")
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
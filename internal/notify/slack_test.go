@@ -0,0 +1,31 @@
+package notify
+
+import (
+	"testing"
+
+	"swarmtest/internal/models"
+)
+
+func TestCompletionOutcome(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      string
+		stopReason  string
+		wantIcon    string
+		wantOutcome string
+	}{
+		{"ran its course", "completed", "", ":white_check_mark:", "completed"},
+		{"drained", "completed", "drained", ":octagonal_sign:", "was drained by an operator"},
+		{"action budget", "completed", "max_total_actions", ":money_with_wings:", "was stopped by its max_total_actions budget"},
+		{"cost budget", "completed", "max_cost_usd", ":money_with_wings:", "was stopped by its max_cost_usd budget"},
+		{"interrupted", "interrupted", "", ":warning:", "interrupted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			icon, outcome := completionOutcome(&models.Mission{Status: tt.status, StopReason: tt.stopReason})
+			if icon != tt.wantIcon || outcome != tt.wantOutcome {
+				t.Errorf("completionOutcome() = %q, %q, want %q, %q", icon, outcome, tt.wantIcon, tt.wantOutcome)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"log"
+)
+
+// Stop reasons of a mission stopped by one of its budgets
+const (
+	StopReasonMaxTotalActions = "max_total_actions"
+	StopReasonMaxCost         = "max_cost_usd"
+)
+
+// MissionStopper stops a running mission early, recording why
+type MissionStopper interface {
+	StopMission(missionID, reason string)
+}
+
+// missionBudget tracks a running mission's totals against its budgets as the
+// events arrive, so the mission is stopped the moment one runs out rather than
+// on the next flush
+type missionBudget struct {
+	maxActions int
+	maxCost    float64
+
+	// attempts and costUSD include what earlier processes recorded, for a
+	// resumed mission
+	attempts int
+	costUSD  float64
+	stopped  bool
+}
+
+// exhausted names the budget the totals have used up, or "" if none has.
+// Failed actions count toward max_total_actions too.
+func (b *missionBudget) exhausted() string {
+	if b.maxActions > 0 && b.attempts >= b.maxActions {
+		return StopReasonMaxTotalActions
+	}
+	if b.maxCost > 0 && b.costUSD >= b.maxCost {
+		return StopReasonMaxCost
+	}
+	return ""
+}
+
+// loadBudget starts tracking the mission's budgets, if it has any, from its
+// recorded totals
+func (e *EventLogger) loadBudget(ctx context.Context, missionID string) {
+	mission, ok := e.store.Get(ctx, missionID)
+	if !ok || mission.MaxTotalActions <= 0 && mission.MaxCostUSD <= 0 {
+		return
+	}
+
+	e.budgetMu.Lock()
+	defer e.budgetMu.Unlock()
+	e.budgets[missionID] = &missionBudget{
+		maxActions: mission.MaxTotalActions,
+		maxCost:    mission.MaxCostUSD,
+		attempts:   mission.TotalActions + mission.TotalErrors,
+		costUSD:    mission.EstimatedCostUSD,
+	}
+}
+
+// chargeBudget adds attempted actions and Gemini spend to the mission's
+// totals, stopping the mission once they use up one of its budgets
+func (e *EventLogger) chargeBudget(missionID string, attempts int, costUSD float64) {
+	e.budgetMu.Lock()
+	b := e.budgets[missionID]
+	if b == nil || b.stopped {
+		e.budgetMu.Unlock()
+		return
+	}
+	b.attempts += attempts
+	b.costUSD += costUSD
+	reason := b.exhausted()
+	b.stopped = reason != ""
+	total, cost := b.attempts, b.costUSD
+	e.budgetMu.Unlock()
+
+	if reason != "" {
+		log.Printf("[EventLogger] Mission %s: %s budget exhausted (%d actions, $%.4f), stopping", missionID, reason, total, cost)
+		e.stopper.StopMission(missionID, reason)
+	}
+}
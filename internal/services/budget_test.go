@@ -0,0 +1,66 @@
+package services
+
+import "testing"
+
+func TestMissionBudgetExhausted(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxActions int
+		maxCost    float64
+		attempts   int
+		cost       float64
+		want       string
+	}{
+		{"no budgets", 0, 0, 2000, 99, ""},
+		{"under the action budget", 100, 0, 99, 0, ""},
+		{"at the action budget", 100, 0, 100, 0, StopReasonMaxTotalActions},
+		{"over the action budget", 100, 0, 150, 0, StopReasonMaxTotalActions},
+		{"under the cost budget", 0, 0.50, 10, 0.49, ""},
+		{"at the cost budget", 0, 0.50, 10, 0.50, StopReasonMaxCost},
+		{"actions are checked first", 10, 0.50, 10, 1, StopReasonMaxTotalActions},
+		{"cost with actions left", 100, 0.50, 10, 1, StopReasonMaxCost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &missionBudget{maxActions: tt.maxActions, maxCost: tt.maxCost, attempts: tt.attempts, costUSD: tt.cost}
+			if got := b.exhausted(); got != tt.want {
+				t.Errorf("exhausted() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// stopRecorder records the missions it is asked to stop
+type stopRecorder struct {
+	stopped map[string]string
+}
+
+func (r *stopRecorder) StopMission(missionID, reason string) {
+	r.stopped[missionID] = reason
+}
+
+func TestChargeBudgetStopsOnce(t *testing.T) {
+	stopper := &stopRecorder{stopped: make(map[string]string)}
+	e := NewEventLogger(nil, nil, nil, stopper)
+	// A resumed mission starts from the totals it had already recorded
+	e.budgets["m1"] = &missionBudget{maxActions: 10, maxCost: 1, attempts: 8, costUSD: 0.25}
+
+	e.chargeBudget("m1", 1, 0.25)
+	if _, ok := stopper.stopped["m1"]; ok {
+		t.Fatal("mission stopped with its budgets left")
+	}
+	e.chargeBudget("m1", 1, 0)
+	if got := stopper.stopped["m1"]; got != StopReasonMaxTotalActions {
+		t.Fatalf("stop reason = %q, want %q", got, StopReasonMaxTotalActions)
+	}
+
+	delete(stopper.stopped, "m1")
+	e.chargeBudget("m1", 1, 1)
+	if _, ok := stopper.stopped["m1"]; ok {
+		t.Error("a stopped mission was stopped again")
+	}
+	e.chargeBudget("no-budget", 100, 100)
+	if len(stopper.stopped) != 0 {
+		t.Errorf("missions without budgets were stopped: %v", stopper.stopped)
+	}
+}
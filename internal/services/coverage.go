@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"swarmtest/internal/models"
+)
+
+const (
+	// maxTrackedURLs and maxTrackedSelectors bound each mission's coverage
+	// tracker so crawling a huge site can't exhaust memory
+	maxTrackedURLs      = 10000
+	maxTrackedSelectors = 10000
+	// maxDiscoveryPoints bounds the pages-over-time series; older points are
+	// thinned out when it fills up
+	maxDiscoveryPoints = 500
+)
+
+// coverageTracker accumulates what a mission's agents have collectively explored
+type coverageTracker struct {
+	agentsByURL map[string]map[string]struct{} // URL -> agents that reached it
+	agentVisits int                            // distinct (agent, URL) pairs
+	selectors   map[string]struct{}
+	discovery   []models.CoveragePoint
+	truncated   bool
+	dirty       bool
+}
+
+func newCoverageTracker() *coverageTracker {
+	return &coverageTracker{
+		agentsByURL: make(map[string]map[string]struct{}),
+		selectors:   make(map[string]struct{}),
+	}
+}
+
+// tracker returns the mission's tracker, creating it on first use. Callers hold coverageMu.
+func (e *EventLogger) tracker(missionID string) *coverageTracker {
+	t := e.coverage[missionID]
+	if t == nil {
+		t = newCoverageTracker()
+		e.coverage[missionID] = t
+	}
+	return t
+}
+
+// trackURL records that an agent reached a URL
+func (e *EventLogger) trackURL(missionID, agentID, url string) {
+	e.coverageMu.Lock()
+	defer e.coverageMu.Unlock()
+
+	t := e.tracker(missionID)
+	agents, seen := t.agentsByURL[url]
+	if !seen {
+		if len(t.agentsByURL) >= maxTrackedURLs {
+			t.truncated = true
+			return
+		}
+		agents = make(map[string]struct{})
+		t.agentsByURL[url] = agents
+		t.dirty = true
+	}
+	if _, visited := agents[agentID]; !visited {
+		agents[agentID] = struct{}{}
+		t.agentVisits++
+	}
+}
+
+// trackSelector records a selector an agent interacted with successfully
+func (e *EventLogger) trackSelector(missionID, selector string) {
+	e.coverageMu.Lock()
+	defer e.coverageMu.Unlock()
+
+	t := e.tracker(missionID)
+	if _, seen := t.selectors[selector]; seen {
+		return
+	}
+	if len(t.selectors) >= maxTrackedSelectors {
+		t.truncated = true
+		return
+	}
+	t.selectors[selector] = struct{}{}
+	t.dirty = true
+}
+
+// pathTotals returns the unique URL and agent visit counts tracked for a mission
+func (e *EventLogger) pathTotals(missionID string) (uniqueURLs, agentVisits int, ok bool) {
+	e.coverageMu.Lock()
+	defer e.coverageMu.Unlock()
+
+	t := e.coverage[missionID]
+	if t == nil {
+		return 0, 0, false
+	}
+	return len(t.agentsByURL), t.agentVisits, true
+}
+
+// loadCoverage seeds a mission's tracker from its last saved snapshot so a
+// resumed mission keeps what was discovered before the restart. Which agent
+// reached which URL is not persisted, so path overlap restarts from zero.
+func (e *EventLogger) loadCoverage(ctx context.Context, missionID string) {
+	saved, ok := e.store.GetCoverage(ctx, missionID)
+	if !ok {
+		return
+	}
+
+	e.coverageMu.Lock()
+	defer e.coverageMu.Unlock()
+
+	t := newCoverageTracker()
+	for _, url := range saved.URLs {
+		t.agentsByURL[url] = make(map[string]struct{})
+	}
+	for _, selector := range saved.Selectors {
+		t.selectors[selector] = struct{}{}
+	}
+	t.discovery = saved.Discovery
+	t.truncated = saved.Truncated
+	e.coverage[missionID] = t
+}
+
+// flushCoverage saves a snapshot of the mission's coverage if anything new was found
+func (e *EventLogger) flushCoverage(ctx context.Context, missionID string) {
+	snapshot := e.coverageSnapshot(missionID)
+	if snapshot != nil {
+		e.store.PutCoverage(ctx, snapshot)
+	}
+}
+
+// coverageSnapshot appends a discovery point and copies the tracker, or returns
+// nil when nothing changed since the last snapshot
+func (e *EventLogger) coverageSnapshot(missionID string) *models.Coverage {
+	e.coverageMu.Lock()
+	defer e.coverageMu.Unlock()
+
+	t := e.coverage[missionID]
+	if t == nil || !t.dirty {
+		return nil
+	}
+	t.dirty = false
+
+	now := time.Now()
+	t.discovery = append(t.discovery, models.CoveragePoint{At: now, UniqueURLs: len(t.agentsByURL)})
+	if len(t.discovery) > maxDiscoveryPoints {
+		t.discovery = thinPoints(t.discovery)
+	}
+
+	urls := make([]string, 0, len(t.agentsByURL))
+	for url := range t.agentsByURL {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	selectors := make([]string, 0, len(t.selectors))
+	for selector := range t.selectors {
+		selectors = append(selectors, selector)
+	}
+	sort.Strings(selectors)
+
+	return &models.Coverage{
+		MissionID:       missionID,
+		UniqueURLs:      len(urls),
+		UniqueSelectors: len(selectors),
+		URLs:            urls,
+		Selectors:       selectors,
+		Discovery:       append([]models.CoveragePoint(nil), t.discovery...),
+		Truncated:       t.truncated,
+		UpdatedAt:       now,
+	}
+}
+
+// thinPoints halves a series by dropping every other point, always keeping the latest
+func thinPoints(points []models.CoveragePoint) []models.CoveragePoint {
+	thinned := make([]models.CoveragePoint, 0, len(points)/2+1)
+	for i := 0; i < len(points)-1; i += 2 {
+		thinned = append(thinned, points[i])
+	}
+	return append(thinned, points[len(points)-1])
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"strings"
 	"sync"
@@ -12,8 +13,10 @@ import (
 )
 
 const (
-	flushInterval   = 5 * time.Second
+	flushInterval    = 5 * time.Second
 	agentIDSeparator = "-agent-"
+	// maxBufferedLogs triggers an early flush so a busy mission can't grow the buffer unbounded
+	maxBufferedLogs = 1000
 )
 
 // EventLogger consumes events from the event bus and persists them to the store.
@@ -23,6 +26,32 @@ type EventLogger struct {
 	eventBus       <-chan models.Event
 	mu             sync.RWMutex
 	missionMetrics map[string]*missionMetrics
+
+	logMu      sync.Mutex
+	logBuffer  map[string][]models.ActionLog
+	bufferSize int
+
+	// Latest reported state per agent, persisted on each flush so that an
+	// interrupted mission can be resumed from the agents' last known URLs
+	agentMu     sync.Mutex
+	agentStates map[string]*models.Agent
+
+	// Findings (JS errors, failed requests) awaiting the next batched insert
+	findingMu     sync.Mutex
+	findingBuffer []models.Finding
+
+	// Collective URL and selector coverage per mission
+	coverageMu sync.Mutex
+	coverage   map[string]*coverageTracker
+
+	// Running totals of missions with budgets, and what stops them
+	budgetMu sync.Mutex
+	budgets  map[string]*missionBudget
+	stopper  MissionStopper
+
+	// publish is the bus the logger announces each finished mission's final
+	// flush on, as a mission_flushed event
+	publish chan<- models.Event
 }
 
 type missionMetrics struct {
@@ -30,14 +59,30 @@ type missionMetrics struct {
 	totalErrors  int
 	totalLatency int64
 	actionCount  int
+	cacheHits    int
+	cacheMisses  int
+	promptTokens int64
+	outputTokens int64
+	costUSD      float64
+
+	claimedCompletions  int
+	verifiedCompletions int
+	jsErrors            int
+	networkFailures     int
 }
 
 // NewEventLogger creates a new event logger
-func NewEventLogger(store store.MissionStore, eventBus <-chan models.Event) *EventLogger {
+func NewEventLogger(store store.MissionStore, eventBus <-chan models.Event, publish chan<- models.Event, stopper MissionStopper) *EventLogger {
 	return &EventLogger{
 		store:          store,
 		eventBus:       eventBus,
+		publish:        publish,
 		missionMetrics: make(map[string]*missionMetrics),
+		logBuffer:      make(map[string][]models.ActionLog),
+		agentStates:    make(map[string]*models.Agent),
+		coverage:       make(map[string]*coverageTracker),
+		budgets:        make(map[string]*missionBudget),
+		stopper:        stopper,
 	}
 }
 
@@ -52,55 +97,406 @@ func (e *EventLogger) Run(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			log.Println("[EventLogger] Context cancelled, flushing final metrics")
-			e.flushAllMetrics()
+			// The run context is already cancelled; detach so the final writes can complete
+			flushCtx := context.WithoutCancel(ctx)
+			e.drainEventBus(flushCtx)
+			e.flushActionLogs(flushCtx)
+			e.flushFindings(flushCtx, "")
+			e.flushAgentStates(flushCtx, "")
+			e.flushAllMetrics(flushCtx)
 			return
 
 		case event := <-e.eventBus:
-			e.handleEvent(event)
+			e.handleEvent(ctx, event)
 
 		case <-ticker.C:
-			e.flushAllMetrics()
+			e.flushActionLogs(ctx)
+			e.flushFindings(ctx, "")
+			e.flushAgentStates(ctx, "")
+			e.flushAllMetrics(ctx)
+		}
+	}
+}
+
+// drainEventBus handles any events already queued so they are not lost on shutdown
+func (e *EventLogger) drainEventBus(ctx context.Context) {
+	for {
+		select {
+		case event := <-e.eventBus:
+			e.handleEvent(ctx, event)
+		default:
+			return
 		}
 	}
 }
 
 // handleEvent processes a single event
-func (e *EventLogger) handleEvent(event models.Event) {
+func (e *EventLogger) handleEvent(ctx context.Context, event models.Event) {
 	switch event.Type {
 	case "action":
-		e.handleActionEvent(event)
+		e.handleActionEvent(ctx, event)
+	case "agent_status":
+		e.handleAgentStatusEvent(event)
+	case "decision":
+		e.handleDecisionEvent(event)
+	case "goal_verification":
+		e.handleVerificationEvent(event)
+	case "js_errors":
+		e.handleJSErrorEvent(ctx, event)
+	case "network_failures":
+		e.handleNetworkFailureEvent(ctx, event)
+	case "finding":
+		e.handleFindingEvent(ctx, event)
 	case "mission_started":
-		e.handleMissionLifecycleEvent(event, true)
+		e.handleMissionLifecycleEvent(ctx, event, true)
 	case "mission_completed":
-		e.handleMissionLifecycleEvent(event, false)
+		e.handleMissionLifecycleEvent(ctx, event, false)
 	}
 }
 
 // handleActionEvent processes agent action events
-func (e *EventLogger) handleActionEvent(event models.Event) {
+func (e *EventLogger) handleActionEvent(ctx context.Context, event models.Event) {
 	agentEvent, ok := event.Data.(models.AgentEvent)
 	if !ok {
 		log.Printf("[EventLogger] Invalid action event data: %T", event.Data)
 		return
 	}
 
+	missionID := agentEvent.MissionID
+	if missionID == "" {
+		missionID = extractMissionID(agentEvent.AgentID)
+	}
+
+	if missionID == "" {
+		log.Printf("[EventLogger] Could not extract mission ID from agent ID: %s", agentEvent.AgentID)
+		return
+	}
+
+	e.trackAgentState(missionID, agentEvent, event.Timestamp)
+
 	if agentEvent.ActionLog == nil {
 		return
 	}
 	actionLog := *agentEvent.ActionLog
 
+	e.bufferActionLog(ctx, missionID, actionLog)
+	e.updateMetrics(missionID, actionLog)
+	if actionLog.Result == "success" && actionLog.Selector != "" {
+		e.trackSelector(missionID, actionLog.Selector)
+	}
+}
+
+// handleAgentStatusEvent records agent status transitions
+func (e *EventLogger) handleAgentStatusEvent(event models.Event) {
+	agentEvent, ok := event.Data.(models.AgentEvent)
+	if !ok {
+		log.Printf("[EventLogger] Invalid agent status event data: %T", event.Data)
+		return
+	}
+
 	missionID := agentEvent.MissionID
 	if missionID == "" {
 		missionID = extractMissionID(agentEvent.AgentID)
 	}
+	if missionID == "" {
+		return
+	}
+
+	e.trackAgentState(missionID, agentEvent, event.Timestamp)
+}
+
+// handleDecisionEvent accumulates decision cache and token usage statistics
+func (e *EventLogger) handleDecisionEvent(event models.Event) {
+	decision, ok := event.Data.(models.DecisionEvent)
+	if !ok {
+		log.Printf("[EventLogger] Invalid decision event data: %T", event.Data)
+		return
+	}
 
+	missionID := decision.MissionID
+	if missionID == "" {
+		missionID = extractMissionID(decision.AgentID)
+	}
 	if missionID == "" {
-		log.Printf("[EventLogger] Could not extract mission ID from agent ID: %s", agentEvent.AgentID)
 		return
 	}
 
-	e.store.AddActionLog(actionLog, missionID)
-	e.updateMetrics(missionID, actionLog)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.missionMetrics[missionID] == nil {
+		e.missionMetrics[missionID] = &missionMetrics{}
+	}
+	metrics := e.missionMetrics[missionID]
+
+	metrics.promptTokens += decision.PromptTokens
+	metrics.outputTokens += decision.OutputTokens
+	metrics.costUSD += decision.CostUSD
+
+	if decision.CacheLookup {
+		if decision.CacheHit {
+			metrics.cacheHits++
+		} else {
+			metrics.cacheMisses++
+		}
+	}
+	e.chargeBudget(missionID, 0, decision.CostUSD)
+}
+
+// handleVerificationEvent counts claimed versus verified completions
+func (e *EventLogger) handleVerificationEvent(event models.Event) {
+	verification, ok := event.Data.(models.VerificationEvent)
+	if !ok {
+		log.Printf("[EventLogger] Invalid verification event data: %T", event.Data)
+		return
+	}
+
+	missionID := verification.MissionID
+	if missionID == "" {
+		missionID = extractMissionID(verification.AgentID)
+	}
+	if missionID == "" {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.missionMetrics[missionID] == nil {
+		e.missionMetrics[missionID] = &missionMetrics{}
+	}
+	metrics := e.missionMetrics[missionID]
+
+	metrics.promptTokens += verification.PromptTokens
+	metrics.outputTokens += verification.OutputTokens
+	metrics.costUSD += verification.CostUSD
+	metrics.claimedCompletions++
+	if verification.Achieved {
+		metrics.verifiedCompletions++
+	}
+	e.chargeBudget(missionID, 0, verification.CostUSD)
+}
+
+// handleJSErrorEvent counts and records JavaScript errors seen in browser mode
+func (e *EventLogger) handleJSErrorEvent(ctx context.Context, event models.Event) {
+	jsEvent, ok := event.Data.(models.JSErrorEvent)
+	if !ok {
+		log.Printf("[EventLogger] Invalid js_errors event data: %T", event.Data)
+		return
+	}
+
+	missionID := jsEvent.MissionID
+	if missionID == "" {
+		missionID = extractMissionID(jsEvent.AgentID)
+	}
+	if missionID == "" {
+		return
+	}
+
+	findings := make([]models.Finding, 0, len(jsEvent.Errors))
+	for _, jsErr := range jsEvent.Errors {
+		findings = append(findings, models.Finding{
+			Timestamp:  event.Timestamp,
+			MissionID:  missionID,
+			AgentID:    jsEvent.AgentID,
+			Type:       "js_error",
+			Severity:   models.JSErrorSeverity(jsErr.Kind),
+			PageURL:    jsEvent.PageURL,
+			Action:     jsEvent.Action,
+			Message:    jsErr.Message,
+			RequestURL: jsErr.SourceURL,
+		})
+	}
+	e.bufferFindings(ctx, findings)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.missionMetrics[missionID] == nil {
+		e.missionMetrics[missionID] = &missionMetrics{}
+	}
+	e.missionMetrics[missionID].jsErrors += len(jsEvent.Errors)
+}
+
+// handleNetworkFailureEvent counts and records failed requests seen in browser mode
+func (e *EventLogger) handleNetworkFailureEvent(ctx context.Context, event models.Event) {
+	netEvent, ok := event.Data.(models.NetworkFailureEvent)
+	if !ok {
+		log.Printf("[EventLogger] Invalid network_failures event data: %T", event.Data)
+		return
+	}
+
+	missionID := netEvent.MissionID
+	if missionID == "" {
+		missionID = extractMissionID(netEvent.AgentID)
+	}
+	if missionID == "" {
+		return
+	}
+
+	findings := make([]models.Finding, 0, len(netEvent.Failures))
+	for _, failure := range netEvent.Failures {
+		message := failure.ErrorText
+		if failure.StatusCode > 0 {
+			message = fmt.Sprintf("%d %s", failure.StatusCode, failure.ErrorText)
+		}
+		findings = append(findings, models.Finding{
+			Timestamp:  event.Timestamp,
+			MissionID:  missionID,
+			AgentID:    netEvent.AgentID,
+			Type:       "network",
+			Severity:   models.RequestSeverity(failure.StatusCode, failure.ResourceType),
+			PageURL:    netEvent.PageURL,
+			Action:     netEvent.Action,
+			Message:    strings.TrimSpace(message),
+			RequestURL: failure.URL,
+			Method:     failure.Method,
+			StatusCode: failure.StatusCode,
+		})
+	}
+	e.bufferFindings(ctx, findings)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.missionMetrics[missionID] == nil {
+		e.missionMetrics[missionID] = &missionMetrics{}
+	}
+	e.missionMetrics[missionID].networkFailures += len(netEvent.Failures)
+}
+
+// handleFindingEvent records a finding an agent reported directly
+func (e *EventLogger) handleFindingEvent(ctx context.Context, event models.Event) {
+	finding, ok := event.Data.(models.Finding)
+	if !ok {
+		log.Printf("[EventLogger] Invalid finding event data: %T", event.Data)
+		return
+	}
+	if finding.MissionID == "" {
+		finding.MissionID = extractMissionID(finding.AgentID)
+	}
+	if finding.MissionID == "" {
+		return
+	}
+	e.bufferFindings(ctx, []models.Finding{finding})
+}
+
+// bufferFindings queues findings for the next batched insert
+func (e *EventLogger) bufferFindings(ctx context.Context, findings []models.Finding) {
+	e.findingMu.Lock()
+	e.findingBuffer = append(e.findingBuffer, findings...)
+	full := len(e.findingBuffer) >= maxBufferedLogs
+	e.findingMu.Unlock()
+
+	if full {
+		e.flushFindings(ctx, "")
+	}
+}
+
+// flushFindings writes buffered findings. An empty missionID flushes all missions.
+func (e *EventLogger) flushFindings(ctx context.Context, missionID string) {
+	e.findingMu.Lock()
+	var pending, kept []models.Finding
+	for _, f := range e.findingBuffer {
+		if missionID == "" || f.MissionID == missionID {
+			pending = append(pending, f)
+		} else {
+			kept = append(kept, f)
+		}
+	}
+	e.findingBuffer = kept
+	e.findingMu.Unlock()
+
+	if len(pending) > 0 {
+		e.store.AddFindings(ctx, pending)
+	}
+}
+
+// trackAgentState keeps the latest cumulative state reported by an agent
+func (e *EventLogger) trackAgentState(missionID string, agentEvent models.AgentEvent, at time.Time) {
+	currentURL := agentEvent.CurrentURL
+	if agentEvent.ActionLog != nil && agentEvent.ActionLog.NewURL != "" {
+		currentURL = agentEvent.ActionLog.NewURL
+	}
+
+	if currentURL != "" {
+		e.trackURL(missionID, agentEvent.AgentID, currentURL)
+	}
+
+	e.agentMu.Lock()
+	defer e.agentMu.Unlock()
+
+	e.agentStates[agentEvent.AgentID] = &models.Agent{
+		ID:                agentEvent.AgentID,
+		MissionID:         missionID,
+		Status:            agentEvent.Status,
+		CurrentURL:        currentURL,
+		ErrorCount:        agentEvent.ErrorCount,
+		SuccessCount:      agentEvent.SuccessCount,
+		TotalLatencyMS:    agentEvent.TotalLatencyMS,
+		ConsecutiveErrors: agentEvent.ConsecutiveErrors,
+		StepsCompleted:    agentEvent.StepsCompleted,
+		FailureReason:     agentEvent.FailureReason,
+		ExecutionMode:     agentEvent.ExecutionMode,
+		CriteriaMet:       agentEvent.CriteriaMet,
+		LastActionAt:      &at,
+	}
+}
+
+// flushAgentStates persists tracked agent states. An empty missionID flushes all missions.
+func (e *EventLogger) flushAgentStates(ctx context.Context, missionID string) {
+	e.agentMu.Lock()
+	pending := make([]*models.Agent, 0, len(e.agentStates))
+	for id, agent := range e.agentStates {
+		if missionID == "" || agent.MissionID == missionID {
+			pending = append(pending, agent)
+			delete(e.agentStates, id)
+		}
+	}
+	e.agentMu.Unlock()
+
+	for _, agent := range pending {
+		e.store.PutAgent(ctx, agent)
+	}
+}
+
+// bufferActionLog queues a log for the next batched insert
+func (e *EventLogger) bufferActionLog(ctx context.Context, missionID string, actionLog models.ActionLog) {
+	e.logMu.Lock()
+	e.logBuffer[missionID] = append(e.logBuffer[missionID], actionLog)
+	e.bufferSize++
+	full := e.bufferSize >= maxBufferedLogs
+	e.logMu.Unlock()
+
+	if full {
+		e.flushActionLogs(ctx)
+	}
+}
+
+// flushActionLogs writes all buffered logs to the store, one batch per mission
+func (e *EventLogger) flushActionLogs(ctx context.Context) {
+	e.logMu.Lock()
+	buffer := e.logBuffer
+	e.logBuffer = make(map[string][]models.ActionLog)
+	e.bufferSize = 0
+	e.logMu.Unlock()
+
+	for missionID, logs := range buffer {
+		e.store.AddActionLogs(ctx, logs, missionID)
+	}
+}
+
+// flushMissionActionLogs writes buffered logs for a single mission
+func (e *EventLogger) flushMissionActionLogs(ctx context.Context, missionID string) {
+	e.logMu.Lock()
+	logs := e.logBuffer[missionID]
+	delete(e.logBuffer, missionID)
+	e.bufferSize -= len(logs)
+	e.logMu.Unlock()
+
+	if len(logs) > 0 {
+		e.store.AddActionLogs(ctx, logs, missionID)
+	}
 }
 
 // updateMetrics updates in-memory metrics for a mission
@@ -113,17 +509,22 @@ func (e *EventLogger) updateMetrics(missionID string, actionLog models.ActionLog
 	}
 	
 	metrics := e.missionMetrics[missionID]
-	if actionLog.Result == "success" {
+	switch actionLog.Result {
+	case "success", "redirected":
 		metrics.totalActions++
 		metrics.totalLatency += actionLog.LatencyMS
 		metrics.actionCount++
-	} else {
+	case "skipped":
+		// Deliberately not performed; neither an action nor an error
+		return
+	default:
 		metrics.totalErrors++
 	}
+	e.chargeBudget(missionID, 1, 0)
 }
 
 // handleMissionLifecycleEvent handles mission start and completion events (DRY principle)
-func (e *EventLogger) handleMissionLifecycleEvent(event models.Event, isStart bool) {
+func (e *EventLogger) handleMissionLifecycleEvent(ctx context.Context, event models.Event, isStart bool) {
 	data, ok := event.Data.(map[string]string)
 	if !ok {
 		return
@@ -138,21 +539,45 @@ func (e *EventLogger) handleMissionLifecycleEvent(event models.Event, isStart bo
 		e.mu.Lock()
 		e.missionMetrics[missionID] = &missionMetrics{}
 		e.mu.Unlock()
+		e.loadCoverage(ctx, missionID)
+		e.loadBudget(ctx, missionID)
 		log.Printf("[EventLogger] Initialized metrics for mission %s", missionID)
 	} else {
-		e.flushMissionMetrics(missionID)
+		e.flushMissionActionLogs(ctx, missionID)
+		e.flushFindings(ctx, missionID)
+		e.flushAgentStates(ctx, missionID)
+		e.flushMissionMetrics(ctx, missionID)
 		e.mu.Lock()
 		delete(e.missionMetrics, missionID)
 		e.mu.Unlock()
+		e.coverageMu.Lock()
+		delete(e.coverage, missionID)
+		e.coverageMu.Unlock()
+		e.budgetMu.Lock()
+		delete(e.budgets, missionID)
+		e.budgetMu.Unlock()
 		log.Printf("[EventLogger] Flushed final metrics for mission %s", missionID)
+		e.announceFlushed(missionID)
+	}
+}
+
+// announceFlushed tells consumers of the bus, such as webhooks and chat
+// notifications, that the finished mission's final metrics are in the store.
+// The send blocks like the other lifecycle events; the fan-out stops waiting
+// on a full logger channel, so it can't deadlock with the logger.
+func (e *EventLogger) announceFlushed(missionID string) {
+	e.publish <- models.Event{
+		Type:      "mission_flushed",
+		Timestamp: time.Now(),
+		Data:      map[string]string{"mission_id": missionID},
 	}
 }
 
 // flushAllMetrics flushes all mission metrics to the database
-func (e *EventLogger) flushAllMetrics() {
+func (e *EventLogger) flushAllMetrics(ctx context.Context) {
 	missionIDs := e.getMissionIDs()
 	for _, missionID := range missionIDs {
-		e.flushMissionMetrics(missionID)
+		e.flushMissionMetrics(ctx, missionID)
 	}
 }
 
@@ -169,19 +594,26 @@ func (e *EventLogger) getMissionIDs() []string {
 }
 
 // flushMissionMetrics flushes metrics for a specific mission
-func (e *EventLogger) flushMissionMetrics(missionID string) {
+func (e *EventLogger) flushMissionMetrics(ctx context.Context, missionID string) {
 	metrics := e.getMetricsCopy(missionID)
 	if metrics == nil {
 		return
 	}
 
-	mission, exists := e.store.Get(missionID)
+	mission, exists := e.store.Get(ctx, missionID)
 	if !exists {
 		return
 	}
 
 	e.updateMission(mission, metrics)
-	e.store.Put(mission)
+	// Path totals are cumulative for this process; never regress what an
+	// earlier process already recorded
+	if unique, visits, ok := e.pathTotals(missionID); ok && visits > mission.AgentURLVisits {
+		mission.UniqueURLs = unique
+		mission.AgentURLVisits = visits
+	}
+	e.store.Put(ctx, mission)
+	e.flushCoverage(ctx, missionID)
 	e.resetMetrics(missionID)
 }
 
@@ -197,6 +629,15 @@ func (e *EventLogger) getMetricsCopy(missionID string) *missionMetrics {
 func (e *EventLogger) updateMission(mission *models.Mission, metrics *missionMetrics) {
 	mission.TotalActions += metrics.totalActions
 	mission.TotalErrors += metrics.totalErrors
+	mission.DecisionCacheHits += metrics.cacheHits
+	mission.DecisionCacheMisses += metrics.cacheMisses
+	mission.TotalPromptTokens += metrics.promptTokens
+	mission.TotalOutputTokens += metrics.outputTokens
+	mission.EstimatedCostUSD += metrics.costUSD
+	mission.ClaimedCompletions += metrics.claimedCompletions
+	mission.VerifiedCompletions += metrics.verifiedCompletions
+	mission.JSErrors += metrics.jsErrors
+	mission.NetworkFailures += metrics.networkFailures
 
 	if metrics.actionCount > 0 {
 		avgLatency := metrics.totalLatency / int64(metrics.actionCount)
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"swarmtest/internal/models"
+	"swarmtest/internal/store"
+)
+
+// SchedulerInterval is how often the scheduler looks for missions that are due
+const SchedulerInterval = 15 * time.Second
+
+// MissionLauncher starts a scheduled mission that has come due
+type MissionLauncher interface {
+	LaunchScheduled(ctx context.Context, mission *models.Mission)
+}
+
+// Scheduler launches missions in the "scheduled" status once their next run
+// time passes. Schedules live in the store, so they survive restarts; a run
+// that came due while the server was down launches on the first check.
+type Scheduler struct {
+	store    store.MissionStore
+	launcher MissionLauncher
+	interval time.Duration
+}
+
+// NewScheduler creates a scheduler that checks the store every SchedulerInterval
+func NewScheduler(store store.MissionStore, launcher MissionLauncher) *Scheduler {
+	return &Scheduler{
+		store:    store,
+		launcher: launcher,
+		interval: SchedulerInterval,
+	}
+}
+
+// Run checks for due missions until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	log.Println("[Scheduler] Starting mission scheduler")
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.launchDue(ctx)
+		select {
+		case <-ctx.Done():
+			log.Println("[Scheduler] Stopping mission scheduler")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// launchDue launches every scheduled mission whose next run time has passed
+func (s *Scheduler) launchDue(ctx context.Context) {
+	now := time.Now()
+	for offset := 0; ; offset += store.MaxListLimit {
+		page, total := s.store.List(ctx, store.ListOptions{
+			Status: "scheduled",
+			Limit:  store.MaxListLimit,
+			Offset: offset,
+		})
+		for _, mission := range page {
+			if ctx.Err() != nil {
+				return
+			}
+			if mission.NextRunAt != nil && !mission.NextRunAt.After(now) {
+				s.launcher.LaunchScheduled(ctx, mission)
+			}
+		}
+		if len(page) == 0 || offset+len(page) >= total {
+			return
+		}
+	}
+}
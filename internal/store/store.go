@@ -1,12 +1,135 @@
 package store
 
-import "swarmtest/internal/models"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"swarmtest/internal/models"
+)
 
 // MissionStore interface
 type MissionStore interface {
-	Put(mission *models.Mission)
-	PutAgent(agent *models.Agent)
-	Get(id string) (*models.Mission, bool)
-	List() []*models.Mission
-	AddActionLog(log models.ActionLog, missionID string)
+	Put(ctx context.Context, mission *models.Mission)
+	PutAgent(ctx context.Context, agent *models.Agent)
+	Get(ctx context.Context, id string) (*models.Mission, bool)
+	List(ctx context.Context, opts ListOptions) ([]*models.Mission, int)
+	FindByIdempotencyKey(ctx context.Context, caller, key string, since time.Time) (*models.Mission, bool)
+	AddActionLog(ctx context.Context, log models.ActionLog, missionID string)
+	AddActionLogs(ctx context.Context, logs []models.ActionLog, missionID string)
+	ListActionLogs(ctx context.Context, missionID string) []models.ActionLog
+	GetAgent(ctx context.Context, missionID, agentID string) (*models.Agent, bool)
+	ListAgentActionLogs(ctx context.Context, missionID, agentID string) []models.ActionLog
+	PutCoverage(ctx context.Context, coverage *models.Coverage)
+	GetCoverage(ctx context.Context, missionID string) (*models.Coverage, bool)
+	PutSitemap(ctx context.Context, sitemap *models.Sitemap)
+	GetSitemap(ctx context.Context, missionID string) (*models.Sitemap, bool)
+	AddFindings(ctx context.Context, findings []models.Finding)
+	ListFindings(ctx context.Context, missionID string, filter FindingFilter) ([]models.Finding, int)
+	// PerformanceOffenders lists the mission's performance findings with the
+	// longest load times and with the largest pages, worst first
+	PerformanceOffenders(ctx context.Context, missionID string, limit int) (slowest, largest []models.Finding)
+	CreateTemplate(ctx context.Context, template *models.MissionTemplate) error
+	GetTemplate(ctx context.Context, idOrName string) (*models.MissionTemplate, bool)
+	ListTemplates(ctx context.Context) []*models.MissionTemplate
+}
+
+// ErrTemplateExists is returned creating a template whose name is taken
+var ErrTemplateExists = errors.New("a template with this name already exists")
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 200
+)
+
+// sortColumns is the allow-list of sortable fields and their SQL columns
+var sortColumns = map[string]string{
+	"created_at":    "created_at",
+	"started_at":    "started_at",
+	"completed_at":  "completed_at",
+	"name":          "name",
+	"status":        "status",
+	"total_actions": "total_actions",
+	"total_errors":  "total_errors",
+}
+
+// ListOptions controls filtering, sorting and paging of List results
+type ListOptions struct {
+	Limit     int
+	Offset    int
+	Status    string   // empty matches all statuses
+	Tags      []string // missions must carry every tag; empty matches all
+	SortField string   // one of sortColumns; defaults to created_at
+	SortAsc   bool     // defaults to descending
+}
+
+// FindingFilter narrows and pages a mission's findings
+type FindingFilter struct {
+	Limit     int
+	Offset    int
+	Type      string // "js_error", "network", "http_error", "broken_link", "bot_wall" or "performance"; empty matches all
+	Severity  string // "high", "medium" or "low"; empty matches all
+	AgentID   string
+	MinStatus int // inclusive HTTP status range; 0 leaves that side open
+	MaxStatus int
+}
+
+// Normalize clamps the filter's paging to valid bounds
+func (f FindingFilter) Normalize() FindingFilter {
+	if f.Limit <= 0 {
+		f.Limit = DefaultListLimit
+	}
+	if f.Limit > MaxListLimit {
+		f.Limit = MaxListLimit
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+	return f
+}
+
+// ParseSort parses a "field:direction" sort spec, e.g. "created_at:desc"
+func ParseSort(spec string) (field string, asc bool, err error) {
+	field, dir, _ := strings.Cut(spec, ":")
+	if _, ok := sortColumns[field]; !ok {
+		return "", false, fmt.Errorf("invalid sort field: %s", field)
+	}
+
+	switch strings.ToLower(dir) {
+	case "", "desc":
+		return field, false, nil
+	case "asc":
+		return field, true, nil
+	default:
+		return "", false, fmt.Errorf("invalid sort direction: %s", dir)
+	}
+}
+
+// Normalize clamps the options to valid bounds
+func (o ListOptions) Normalize() ListOptions {
+	if o.Limit <= 0 {
+		o.Limit = DefaultListLimit
+	}
+	if o.Limit > MaxListLimit {
+		o.Limit = MaxListLimit
+	}
+	if o.Offset < 0 {
+		o.Offset = 0
+	}
+	if _, ok := sortColumns[o.SortField]; !ok {
+		o.SortField = "created_at"
+		o.SortAsc = false
+	}
+	return o
+}
+
+// orderByClause renders the ORDER BY for the options; id breaks ties so paging is stable
+func (o ListOptions) orderByClause() string {
+	dir := "DESC"
+	if o.SortAsc {
+		dir = "ASC"
+	}
+	return fmt.Sprintf("ORDER BY %s %s NULLS LAST, id %s", sortColumns[o.SortField], dir, dir)
 }
@@ -0,0 +1,80 @@
+package store
+
+import "testing"
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		spec    string
+		field   string
+		asc     bool
+		wantErr bool
+	}{
+		{spec: "created_at", field: "created_at"},
+		{spec: "created_at:desc", field: "created_at"},
+		{spec: "created_at:asc", field: "created_at", asc: true},
+		{spec: "name:ASC", field: "name", asc: true},
+		{spec: "started_at:desc", field: "started_at"},
+		{spec: "completed_at:asc", field: "completed_at", asc: true},
+		{spec: "status:desc", field: "status"},
+		{spec: "total_actions:asc", field: "total_actions", asc: true},
+		{spec: "total_errors:desc", field: "total_errors"},
+		{spec: "goal:asc", wantErr: true},
+		{spec: "created_at:sideways", wantErr: true},
+		{spec: "created_at; DROP TABLE missions", wantErr: true},
+		{spec: ":asc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			field, asc, err := ParseSort(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseSort(%q) = %q, %v, want an error", tt.spec, field, asc)
+				}
+				return
+			}
+			if err != nil || field != tt.field || asc != tt.asc {
+				t.Errorf("ParseSort(%q) = %q, %v, %v, want %q, %v", tt.spec, field, asc, err, tt.field, tt.asc)
+			}
+		})
+	}
+}
+
+func TestListOptionsOrderBy(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ListOptions
+		want string
+	}{
+		{"default", ListOptions{}, "ORDER BY created_at DESC NULLS LAST, id DESC"},
+		{"ascending", ListOptions{SortField: "created_at", SortAsc: true}, "ORDER BY created_at ASC NULLS LAST, id ASC"},
+		{"other field", ListOptions{SortField: "total_errors"}, "ORDER BY total_errors DESC NULLS LAST, id DESC"},
+		{"unknown field falls back", ListOptions{SortField: "goal", SortAsc: true}, "ORDER BY created_at DESC NULLS LAST, id DESC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.Normalize().orderByClause(); got != tt.want {
+				t.Errorf("orderByClause() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListOptionsNormalize(t *testing.T) {
+	tests := []struct {
+		name               string
+		opts               ListOptions
+		wantLimit, wantOff int
+	}{
+		{"defaults", ListOptions{}, DefaultListLimit, 0},
+		{"kept", ListOptions{Limit: 10, Offset: 20}, 10, 20},
+		{"clamped", ListOptions{Limit: MaxListLimit + 1, Offset: -5}, MaxListLimit, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.opts.Normalize()
+			if got.Limit != tt.wantLimit || got.Offset != tt.wantOff {
+				t.Errorf("Normalize() limit %d offset %d, want %d and %d", got.Limit, got.Offset, tt.wantLimit, tt.wantOff)
+			}
+		})
+	}
+}
@@ -1,50 +1,162 @@
 package store
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"log"
-	
+	"strings"
+	"time"
+
 	"swarmtest/internal/models"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
+// defaultQueryTimeout bounds every database operation so a stalled connection
+// can't hang the event logger or HTTP handlers
+const defaultQueryTimeout = 5 * time.Second
+
+// DefaultRecentEvents is how many of a mission's latest action logs Get loads
+// into RecentEvents
+const DefaultRecentEvents = 20
+
+// MaxRecentEvents bounds RecentEventsLimit
+const MaxRecentEvents = 1000
+
 // SupabaseStore implements the Store interface using Supabase Postgres
 type SupabaseStore struct {
-	db *sql.DB
+	db           *sql.DB
+	queryTimeout time.Duration
+
+	// RecentEventsLimit is how many action logs a fetched mission carries in
+	// RecentEvents; the rest stay in the database
+	RecentEventsLimit int
 }
 
 func NewSupabaseStore(db *sql.DB) *SupabaseStore {
-	return &SupabaseStore{db: db}
+	return &SupabaseStore{db: db, queryTimeout: defaultQueryTimeout, RecentEventsLimit: DefaultRecentEvents}
 }
 
-func (s *SupabaseStore) Put(mission *models.Mission) {
-	query := `
-		INSERT INTO missions (
-			id, name, target_url, num_agents, goal, max_duration_seconds, 
-			rate_limit_per_second, initial_system_prompt, status, created_at, 
-			started_at, completed_at, total_actions, total_errors, 
-			average_latency_ms, completed_agents, failed_agents
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17
-		)
-		ON CONFLICT (id) DO UPDATE SET
-			status = EXCLUDED.status,
-			started_at = EXCLUDED.started_at,
-			completed_at = EXCLUDED.completed_at,
-			total_actions = EXCLUDED.total_actions,
-			total_errors = EXCLUDED.total_errors,
-			average_latency_ms = EXCLUDED.average_latency_ms,
-			completed_agents = EXCLUDED.completed_agents,
-			failed_agents = EXCLUDED.failed_agents;
-	`
+// schemaMigrations are idempotent DDL statements applied at startup for columns
+// added after the initial schema
+var schemaMigrations = []string{
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS execution_mode TEXT NOT NULL DEFAULT 'http'`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS options JSONB NOT NULL DEFAULT '{}'`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS decision_cache_hits INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS decision_cache_misses INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS total_prompt_tokens BIGINT NOT NULL DEFAULT 0`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS total_output_tokens BIGINT NOT NULL DEFAULT 0`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS estimated_cost_usd DOUBLE PRECISION NOT NULL DEFAULT 0`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS claimed_completions INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS verified_completions INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE agents ADD COLUMN IF NOT EXISTS steps_completed INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS replay_of TEXT`,
+	`ALTER TABLE action_logs ADD COLUMN IF NOT EXISTS text_input TEXT`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS unique_urls INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS agent_url_visits INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS js_errors INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE action_logs ADD COLUMN IF NOT EXISTS status_code INTEGER`,
+	`ALTER TABLE action_logs ADD COLUMN IF NOT EXISTS redirect_url TEXT`,
+	`ALTER TABLE action_logs ADD COLUMN IF NOT EXISTS reasoning TEXT`,
+	`ALTER TABLE action_logs ADD COLUMN IF NOT EXISTS expected_next_state TEXT`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS network_failures INTEGER NOT NULL DEFAULT 0`,
+	`CREATE TABLE IF NOT EXISTS findings (
+		id BIGSERIAL PRIMARY KEY,
+		mission_id TEXT NOT NULL REFERENCES missions(id) ON DELETE CASCADE,
+		agent_id TEXT NOT NULL,
+		timestamp TIMESTAMPTZ NOT NULL,
+		type TEXT NOT NULL,
+		page_url TEXT,
+		action TEXT,
+		message TEXT,
+		request_url TEXT,
+		method TEXT,
+		status_code INTEGER
+	)`,
+	`CREATE INDEX IF NOT EXISTS findings_mission_id_idx ON findings (mission_id, type)`,
+	`CREATE TABLE IF NOT EXISTS mission_coverage (
+		mission_id TEXT PRIMARY KEY REFERENCES missions(id) ON DELETE CASCADE,
+		data JSONB NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS action_logs_agent_id_idx ON action_logs (mission_id, agent_id)`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '[]'`,
+	`ALTER TABLE findings ADD COLUMN IF NOT EXISTS severity TEXT NOT NULL DEFAULT 'medium'`,
+	`ALTER TABLE agents ADD COLUMN IF NOT EXISTS failure_reason TEXT`,
+	`ALTER TABLE agents ADD COLUMN IF NOT EXISTS execution_mode TEXT`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS idempotency_key TEXT`,
+	`ALTER TABLE agents ADD COLUMN IF NOT EXISTS criteria_met BOOLEAN NOT NULL DEFAULT false`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS target_auth JSONB`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS scheduled_from TEXT`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS next_run_at TIMESTAMPTZ`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS template_id TEXT`,
+	`CREATE INDEX IF NOT EXISTS missions_idempotency_key_idx ON missions (idempotency_key, created_at) WHERE idempotency_key IS NOT NULL`,
+	`CREATE INDEX IF NOT EXISTS missions_tags_idx ON missions USING GIN (tags)`,
+	`CREATE TABLE IF NOT EXISTS mission_sitemaps (
+		mission_id TEXT PRIMARY KEY REFERENCES missions(id) ON DELETE CASCADE,
+		data JSONB NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS mission_templates (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE,
+		data JSONB NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL
+	)`,
+	`ALTER TABLE action_logs ADD COLUMN IF NOT EXISTS frame TEXT`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS stop_reason TEXT`,
+	`ALTER TABLE agents ADD COLUMN IF NOT EXISTS unique_urls_visited INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE findings ADD COLUMN IF NOT EXISTS duration_ms BIGINT`,
+	`ALTER TABLE findings ADD COLUMN IF NOT EXISTS content_length BIGINT`,
+	`ALTER TABLE agents ADD COLUMN IF NOT EXISTS retry_of TEXT`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS dropped_events BIGINT NOT NULL DEFAULT 0`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS idempotency_caller TEXT`,
+	`ALTER TABLE missions ADD COLUMN IF NOT EXISTS idempotency_body_hash TEXT`,
+}
 
-	_, err := s.db.Exec(query,
-		mission.ID, mission.Name, mission.TargetURL, mission.NumAgents, mission.Goal,
-		mission.MaxDurationSeconds, mission.RateLimitPerSecond, mission.InitialSystemPrompt,
-		mission.Status, mission.CreatedAt, mission.StartedAt, mission.CompletedAt,
-		mission.TotalActions, mission.TotalErrors, mission.AverageLatencyMS,
-		mission.CompletedAgents, mission.FailedAgents,
-	)
+// Migrate applies schemaMigrations
+func (s *SupabaseStore) Migrate(ctx context.Context) error {
+	for _, stmt := range schemaMigrations {
+		opCtx, cancel := s.withTimeout(ctx)
+		_, err := s.db.ExecContext(opCtx, stmt)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("migrate %q: %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// withTimeout derives a per-operation context from the caller's context
+func (s *SupabaseStore) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, s.queryTimeout)
+}
+
+func (s *SupabaseStore) Put(ctx context.Context, mission *models.Mission) {
+	placeholders := make([]string, len(missionColumns))
+	for i := range missionColumns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	updates := make([]string, len(missionUpdateColumns))
+	for i, col := range missionUpdateColumns {
+		updates[i] = col + " = EXCLUDED." + col
+	}
+
+	query := `INSERT INTO missions (` + strings.Join(missionColumns, ", ") + `)
+		VALUES (` + strings.Join(placeholders, ", ") + `)
+		ON CONFLICT (id) DO UPDATE SET ` + strings.Join(updates, ", ")
+
+	args, err := missionArgs(mission)
+	if err != nil {
+		log.Printf("Error encoding mission %s: %v", mission.ID, err)
+		return
+	}
+
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	_, err = s.db.ExecContext(opCtx, query, args...)
 	if err != nil {
 		log.Printf("Error saving mission %s: %v", mission.ID, err)
 		return
@@ -52,20 +164,21 @@ func (s *SupabaseStore) Put(mission *models.Mission) {
 
 	// Update agents
 	for _, agent := range mission.AgentMetrics {
-		s.PutAgent(agent)
+		s.PutAgent(ctx, agent)
 	}
 	
 	// Note: RecentEvents are not bulk updated here to avoid perf issues. 
 	// The event logger should handle them, or we assume they are inserted via AddActionLog
 }
 
-func (s *SupabaseStore) PutAgent(agent *models.Agent) {
+func (s *SupabaseStore) PutAgent(ctx context.Context, agent *models.Agent) {
 	query := `
 		INSERT INTO agents (
 			id, mission_id, status, current_url, error_count, success_count,
-			total_latency_ms, consecutive_errors, last_action_at
+			total_latency_ms, consecutive_errors, last_action_at, steps_completed,
+			failure_reason, execution_mode, criteria_met, unique_urls_visited, retry_of
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
 		)
 		ON CONFLICT (id) DO UPDATE SET
 			status = EXCLUDED.status,
@@ -74,35 +187,66 @@ func (s *SupabaseStore) PutAgent(agent *models.Agent) {
 			success_count = EXCLUDED.success_count,
 			total_latency_ms = EXCLUDED.total_latency_ms,
 			consecutive_errors = EXCLUDED.consecutive_errors,
-			last_action_at = EXCLUDED.last_action_at;
+			last_action_at = EXCLUDED.last_action_at,
+			steps_completed = EXCLUDED.steps_completed,
+			failure_reason = EXCLUDED.failure_reason,
+			execution_mode = COALESCE(EXCLUDED.execution_mode, agents.execution_mode),
+			criteria_met = EXCLUDED.criteria_met,
+			unique_urls_visited = EXCLUDED.unique_urls_visited,
+			retry_of = COALESCE(EXCLUDED.retry_of, agents.retry_of);
 	`
-	_, err := s.db.Exec(query,
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(opCtx, query,
 		agent.ID, agent.MissionID, agent.Status, agent.CurrentURL,
 		agent.ErrorCount, agent.SuccessCount, agent.TotalLatencyMS,
-		agent.ConsecutiveErrors, agent.LastActionAt,
+		agent.ConsecutiveErrors, agent.LastActionAt, agent.StepsCompleted,
+		ToNullString(agent.FailureReason), ToNullString(string(agent.ExecutionMode)), agent.CriteriaMet,
+		agent.UniqueURLsVisited, ToNullString(agent.RetryOf),
 	)
 	if err != nil {
 		log.Printf("Error saving agent %s: %v", agent.ID, err)
 	}
 }
 
-func (s *SupabaseStore) Get(id string) (*models.Mission, bool) {
+// GetAgent loads a single agent of a mission
+func (s *SupabaseStore) GetAgent(ctx context.Context, missionID, agentID string) (*models.Agent, bool) {
+	query := `SELECT id, mission_id, status, current_url, error_count, success_count, total_latency_ms, consecutive_errors, last_action_at, steps_completed, failure_reason, execution_mode, criteria_met, unique_urls_visited, retry_of FROM agents WHERE mission_id = $1 AND id = $2`
+
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	a := &models.Agent{}
+	var failureReason, executionMode, retryOf sql.NullString
+	err := s.db.QueryRowContext(opCtx, query, missionID, agentID).Scan(
+		&a.ID, &a.MissionID, &a.Status, &a.CurrentURL, &a.ErrorCount,
+		&a.SuccessCount, &a.TotalLatencyMS, &a.ConsecutiveErrors, &a.LastActionAt,
+		&a.StepsCompleted, &failureReason, &executionMode, &a.CriteriaMet, &a.UniqueURLsVisited,
+		&retryOf,
+	)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Error getting agent %s: %v", agentID, err)
+		}
+		return nil, false
+	}
+	a.FailureReason = failureReason.String
+	a.ExecutionMode = models.ExecutionMode(executionMode.String)
+	a.RetryOf = retryOf.String
+	return a, true
+}
+
+func (s *SupabaseStore) Get(ctx context.Context, id string) (*models.Mission, bool) {
 	m := &models.Mission{}
+
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
 	
 	// Get Mission
-	query := `
-		SELECT id, name, target_url, num_agents, goal, max_duration_seconds,
-		       rate_limit_per_second, initial_system_prompt, status, created_at,
-		       started_at, completed_at, total_actions, total_errors,
-		       average_latency_ms, completed_agents, failed_agents
-		FROM missions WHERE id = $1`
-		
-	err := s.db.QueryRow(query, id).Scan(
-		&m.ID, &m.Name, &m.TargetURL, &m.NumAgents, &m.Goal, &m.MaxDurationSeconds,
-		&m.RateLimitPerSecond, &m.InitialSystemPrompt, &m.Status, &m.CreatedAt,
-		&m.StartedAt, &m.CompletedAt, &m.TotalActions, &m.TotalErrors,
-		&m.AverageLatencyMS, &m.CompletedAgents, &m.FailedAgents,
-	)
+	query := `SELECT ` + strings.Join(missionColumns, ", ") + ` FROM missions WHERE id = $1`
+
+	err := scanMission(s.db.QueryRowContext(opCtx, query, id), m)
 	if err == sql.ErrNoRows {
 		return nil, false
 	}
@@ -113,107 +257,773 @@ func (s *SupabaseStore) Get(id string) (*models.Mission, bool) {
 
 	// Get Agents
 	m.AgentMetrics = make(map[string]*models.Agent)
-	agentQuery := `SELECT id, mission_id, status, current_url, error_count, success_count, total_latency_ms, consecutive_errors, last_action_at FROM agents WHERE mission_id = $1`
-	rows, err := s.db.Query(agentQuery, id)
+	agentQuery := `SELECT id, mission_id, status, current_url, error_count, success_count, total_latency_ms, consecutive_errors, last_action_at, steps_completed, failure_reason, execution_mode, criteria_met, unique_urls_visited, retry_of FROM agents WHERE mission_id = $1`
+	rows, err := s.db.QueryContext(opCtx, agentQuery, id)
 	if err != nil {
 		log.Printf("Error getting agents for mission %s: %v", id, err)
 	} else {
 		defer rows.Close()
 		for rows.Next() {
 			a := &models.Agent{}
+			var failureReason, executionMode, retryOf sql.NullString
 			if err := rows.Scan(
 				&a.ID, &a.MissionID, &a.Status, &a.CurrentURL, &a.ErrorCount,
 				&a.SuccessCount, &a.TotalLatencyMS, &a.ConsecutiveErrors, &a.LastActionAt,
+				&a.StepsCompleted, &failureReason, &executionMode, &a.CriteriaMet, &a.UniqueURLsVisited,
+				&retryOf,
 			); err != nil {
 				continue
 			}
+			a.FailureReason = failureReason.String
+			a.ExecutionMode = models.ExecutionMode(executionMode.String)
+			a.RetryOf = retryOf.String
 			m.AgentMetrics[a.ID] = a
 		}
 	}
 	
-	// Get Recent Events (Logs)
-	// We'll just get the last 20 logs
+	m.RecentEvents = s.recentEvents(opCtx, id)
+
+	return m, true
+}
+
+// recentEvents loads a mission's latest RecentEventsLimit action logs, newest
+// first, however long the mission has run
+func (s *SupabaseStore) recentEvents(ctx context.Context, id string) []models.ActionLog {
 	logQuery := `
-		SELECT timestamp, agent_id, action, selector, result, latency_ms, error_message, new_url
+		SELECT ` + actionLogColumns + `
 		FROM action_logs
 		WHERE mission_id = $1
 		ORDER BY id DESC
-		LIMIT 20`
-		
-	logRows, err := s.db.Query(logQuery, id)
+		LIMIT $2`
+
+	logRows, err := s.db.QueryContext(ctx, logQuery, id, s.RecentEventsLimit)
 	if err != nil {
 		log.Printf("Error getting logs for mission %s: %v", id, err)
-	} else {
-		defer logRows.Close()
-		for logRows.Next() {
-			l := models.ActionLog{}
-			var selector, errMsg, newUrl sql.NullString
-			if err := logRows.Scan(
-				&l.Timestamp, &l.AgentID, &l.Action, &selector, &l.Result,
-				&l.LatencyMS, &errMsg, &newUrl,
-			); err != nil {
-				continue
-			}
-			l.Selector = selector.String
-			l.ErrorMessage = errMsg.String
-			l.NewURL = newUrl.String
-			
-			m.RecentEvents = append(m.RecentEvents, l)
+		return nil
+	}
+	defer logRows.Close()
+	return scanActionLogs(logRows, id)
+}
+
+func (s *SupabaseStore) List(ctx context.Context, opts ListOptions) ([]*models.Mission, int) {
+	opts = opts.Normalize()
+
+	query := `SELECT ` + strings.Join(missionColumns, ", ") + `
+		FROM missions`
+
+	var conditions []string
+	args := []any{}
+	if opts.Status != "" {
+		args = append(args, opts.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if len(opts.Tags) > 0 {
+		tags, err := json.Marshal(opts.Tags)
+		if err != nil {
+			log.Printf("Error encoding tag filter: %v", err)
+			return []*models.Mission{}, 0
 		}
+		args = append(args, string(tags))
+		conditions = append(conditions, fmt.Sprintf("tags @> $%d::jsonb", len(args)))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	return m, true
-}
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
 
-func (s *SupabaseStore) List() []*models.Mission {
-	query := `
-		SELECT id, name, target_url, num_agents, goal, max_duration_seconds,
-		       rate_limit_per_second, initial_system_prompt, status, created_at,
-		       started_at, completed_at, total_actions, total_errors,
-		       average_latency_ms, completed_agents, failed_agents
-		FROM missions ORDER BY created_at DESC LIMIT 50`
-		
-	rows, err := s.db.Query(query)
+	var total int
+	if err := s.db.QueryRowContext(opCtx, `SELECT COUNT(*) FROM missions`+where, args...).Scan(&total); err != nil {
+		log.Printf("Error counting missions: %v", err)
+		return []*models.Mission{}, 0
+	}
+
+	args = append(args, opts.Limit, opts.Offset)
+	query += where + " " + opts.orderByClause() +
+		fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := s.db.QueryContext(opCtx, query, args...)
 	if err != nil {
 		log.Printf("Error listing missions: %v", err)
-		return []*models.Mission{}
+		return []*models.Mission{}, 0
 	}
 	defer rows.Close()
 	
-	var missions []*models.Mission
+	missions := []*models.Mission{}
 	for rows.Next() {
 		m := &models.Mission{}
-		if err := rows.Scan(
-			&m.ID, &m.Name, &m.TargetURL, &m.NumAgents, &m.Goal, &m.MaxDurationSeconds,
-			&m.RateLimitPerSecond, &m.InitialSystemPrompt, &m.Status, &m.CreatedAt,
-			&m.StartedAt, &m.CompletedAt, &m.TotalActions, &m.TotalErrors,
-			&m.AverageLatencyMS, &m.CompletedAgents, &m.FailedAgents,
-		); err != nil {
+		if err := scanMission(rows, m); err != nil {
 			continue
 		}
 		// Note: We don't populate Agents/Logs for list view to keep it fast
 		missions = append(missions, m)
 	}
-	return missions
+	return missions, total
 }
 
-func (s *SupabaseStore) AddActionLog(logEntry models.ActionLog, missionID string) {
+// FindByIdempotencyKey returns the latest mission the caller created with key
+// since the given time
+func (s *SupabaseStore) FindByIdempotencyKey(ctx context.Context, caller, key string, since time.Time) (*models.Mission, bool) {
+	query := `SELECT ` + strings.Join(missionColumns, ", ") + `
+		FROM missions
+		WHERE idempotency_key = $1 AND COALESCE(idempotency_caller, '') = $2 AND created_at >= $3
+		ORDER BY created_at DESC
+		LIMIT 1`
+
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	m := &models.Mission{}
+	err := scanMission(s.db.QueryRowContext(opCtx, query, key, caller, since), m)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Error looking up idempotency key: %v", err)
+		}
+		return nil, false
+	}
+	return m, true
+}
+
+func (s *SupabaseStore) AddActionLog(ctx context.Context, logEntry models.ActionLog, missionID string) {
 	query := `
 		INSERT INTO action_logs (
 			timestamp, mission_id, agent_id, action, selector, result, 
-			latency_ms, error_message, new_url
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+			latency_ms, error_message, new_url, text_input, status_code, redirect_url,
+			reasoning, expected_next_state, frame
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`
 	
-	_, err := s.db.Exec(query,
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(opCtx, query,
 		logEntry.Timestamp, missionID, logEntry.AgentID, logEntry.Action,
 		ToNullString(logEntry.Selector), logEntry.Result, logEntry.LatencyMS,
 		ToNullString(logEntry.ErrorMessage), ToNullString(logEntry.NewURL),
+		ToNullString(logEntry.TextInput), toNullInt(logEntry.StatusCode),
+		ToNullString(logEntry.RedirectURL), ToNullString(logEntry.Reasoning),
+		ToNullString(logEntry.ExpectedNextState), ToNullString(logEntry.Frame),
 	)
 	if err != nil {
 		log.Printf("Error adding log: %v", err)
 	}
 }
 
+// actionLogBatchSize caps rows per multi-row INSERT (15 params each, well under
+// Postgres' 65535 bind-parameter limit)
+const actionLogBatchSize = 500
+
+// AddActionLogs inserts a batch of logs using multi-row INSERT statements
+func (s *SupabaseStore) AddActionLogs(ctx context.Context, logs []models.ActionLog, missionID string) {
+	for start := 0; start < len(logs); start += actionLogBatchSize {
+		end := start + actionLogBatchSize
+		if end > len(logs) {
+			end = len(logs)
+		}
+		s.insertActionLogBatch(ctx, logs[start:end], missionID)
+	}
+}
+
+func (s *SupabaseStore) insertActionLogBatch(ctx context.Context, logs []models.ActionLog, missionID string) {
+	if len(logs) == 0 {
+		return
+	}
+
+	const columnsPerRow = 15
+	placeholders := make([]string, 0, len(logs))
+	args := make([]any, 0, len(logs)*columnsPerRow)
+
+	for i, logEntry := range logs {
+		base := i * columnsPerRow
+		placeholders = append(placeholders, fmt.Sprintf(
+			"($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8, base+9, base+10, base+11, base+12,
+			base+13, base+14, base+15,
+		))
+		args = append(args,
+			logEntry.Timestamp, missionID, logEntry.AgentID, logEntry.Action,
+			ToNullString(logEntry.Selector), logEntry.Result, logEntry.LatencyMS,
+			ToNullString(logEntry.ErrorMessage), ToNullString(logEntry.NewURL),
+			ToNullString(logEntry.TextInput), toNullInt(logEntry.StatusCode),
+			ToNullString(logEntry.RedirectURL), ToNullString(logEntry.Reasoning),
+			ToNullString(logEntry.ExpectedNextState), ToNullString(logEntry.Frame),
+		)
+	}
+
+	query := `
+		INSERT INTO action_logs (
+			timestamp, mission_id, agent_id, action, selector, result,
+			latency_ms, error_message, new_url, text_input, status_code, redirect_url,
+			reasoning, expected_next_state, frame
+		) VALUES ` + strings.Join(placeholders, ", ")
+
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(opCtx, query, args...); err != nil {
+		log.Printf("Error adding %d logs for mission %s: %v", len(logs), missionID, err)
+	}
+}
+
+// ListActionLogs returns every action log of a mission, oldest first
+func (s *SupabaseStore) ListActionLogs(ctx context.Context, missionID string) []models.ActionLog {
+	query := `
+		SELECT ` + actionLogColumns + `
+		FROM action_logs
+		WHERE mission_id = $1
+		ORDER BY id ASC`
+
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(opCtx, query, missionID)
+	if err != nil {
+		log.Printf("Error listing logs for mission %s: %v", missionID, err)
+		return nil
+	}
+	defer rows.Close()
+	return scanActionLogs(rows, missionID)
+}
+
+// ListAgentActionLogs returns one agent's action logs in the order they were recorded
+func (s *SupabaseStore) ListAgentActionLogs(ctx context.Context, missionID, agentID string) []models.ActionLog {
+	query := `
+		SELECT ` + actionLogColumns + `
+		FROM action_logs
+		WHERE mission_id = $1 AND agent_id = $2
+		ORDER BY id ASC`
+
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(opCtx, query, missionID, agentID)
+	if err != nil {
+		log.Printf("Error listing logs for agent %s: %v", agentID, err)
+		return nil
+	}
+	defer rows.Close()
+	return scanActionLogs(rows, missionID)
+}
+
+// actionLogColumns are the action_logs columns scanActionLogs reads, in order
+const actionLogColumns = `timestamp, agent_id, action, selector, result, latency_ms, error_message, new_url, text_input,
+			status_code, redirect_url, reasoning, expected_next_state, frame`
+
+// scanActionLogs reads the rows of an action log query, skipping unreadable rows
+func scanActionLogs(rows *sql.Rows, missionID string) []models.ActionLog {
+	var logs []models.ActionLog
+	for rows.Next() {
+		l := models.ActionLog{MissionID: missionID}
+		var selector, errMsg, newURL, textInput, redirectURL, reasoning, expectedNextState, frame sql.NullString
+		var statusCode sql.NullInt64
+		if err := rows.Scan(
+			&l.Timestamp, &l.AgentID, &l.Action, &selector, &l.Result,
+			&l.LatencyMS, &errMsg, &newURL, &textInput, &statusCode, &redirectURL,
+			&reasoning, &expectedNextState, &frame,
+		); err != nil {
+			continue
+		}
+		l.Reasoning = reasoning.String
+		l.ExpectedNextState = expectedNextState.String
+		l.StatusCode = int(statusCode.Int64)
+		l.RedirectURL = redirectURL.String
+		l.Selector = selector.String
+		l.ErrorMessage = errMsg.String
+		l.NewURL = newURL.String
+		l.TextInput = textInput.String
+		l.Frame = frame.String
+
+		logs = append(logs, l)
+	}
+	return logs
+}
+
+// findingBatchSize caps rows per multi-row findings INSERT (13 params each)
+const findingBatchSize = 500
+
+// AddFindings inserts findings using multi-row INSERT statements
+func (s *SupabaseStore) AddFindings(ctx context.Context, findings []models.Finding) {
+	for start := 0; start < len(findings); start += findingBatchSize {
+		end := start + findingBatchSize
+		if end > len(findings) {
+			end = len(findings)
+		}
+		s.insertFindingBatch(ctx, findings[start:end])
+	}
+}
+
+func (s *SupabaseStore) insertFindingBatch(ctx context.Context, findings []models.Finding) {
+	if len(findings) == 0 {
+		return
+	}
+
+	const columnsPerRow = 13
+	placeholders := make([]string, 0, len(findings))
+	args := make([]any, 0, len(findings)*columnsPerRow)
+
+	for i, f := range findings {
+		base := i * columnsPerRow
+		placeholders = append(placeholders, fmt.Sprintf(
+			"($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8, base+9, base+10, base+11, base+12, base+13,
+		))
+		args = append(args,
+			f.MissionID, f.AgentID, f.Timestamp, f.Type, ToNullString(f.PageURL),
+			ToNullString(f.Action), ToNullString(f.Message), ToNullString(f.RequestURL),
+			ToNullString(f.Method), toNullInt(f.StatusCode), severityOrDefault(f.Severity),
+			toNullInt64(f.DurationMS), toNullInt64(f.ContentLength),
+		)
+	}
+
+	query := `
+		INSERT INTO findings (
+			mission_id, agent_id, timestamp, type, page_url,
+			action, message, request_url, method, status_code, severity,
+			duration_ms, content_length
+		) VALUES ` + strings.Join(placeholders, ", ")
+
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(opCtx, query, args...); err != nil {
+		log.Printf("Error adding %d findings: %v", len(findings), err)
+	}
+}
+
+// ListFindings returns a page of a mission's findings, oldest first, and the
+// total number matching the filter
+func (s *SupabaseStore) ListFindings(ctx context.Context, missionID string, filter FindingFilter) ([]models.Finding, int) {
+	filter = filter.Normalize()
+
+	args := []any{missionID}
+	where := "mission_id = $1"
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		where += fmt.Sprintf(" AND type = $%d", len(args))
+	}
+	if filter.AgentID != "" {
+		args = append(args, filter.AgentID)
+		where += fmt.Sprintf(" AND agent_id = $%d", len(args))
+	}
+	if filter.Severity != "" {
+		args = append(args, filter.Severity)
+		where += fmt.Sprintf(" AND severity = $%d", len(args))
+	}
+	if filter.MinStatus > 0 {
+		args = append(args, filter.MinStatus)
+		where += fmt.Sprintf(" AND status_code >= $%d", len(args))
+	}
+	if filter.MaxStatus > 0 {
+		args = append(args, filter.MaxStatus)
+		where += fmt.Sprintf(" AND status_code <= $%d", len(args))
+	}
+
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var total int
+	if err := s.db.QueryRowContext(opCtx, `SELECT COUNT(*) FROM findings WHERE `+where, args...).Scan(&total); err != nil {
+		log.Printf("Error counting findings for mission %s: %v", missionID, err)
+		return nil, 0
+	}
+
+	query := fmt.Sprintf(`
+		SELECT `+findingColumns+`
+		FROM findings
+		WHERE %s
+		ORDER BY id ASC
+		LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := s.db.QueryContext(opCtx, query, args...)
+	if err != nil {
+		log.Printf("Error listing findings for mission %s: %v", missionID, err)
+		return nil, 0
+	}
+	defer rows.Close()
+	return scanFindings(rows, missionID), total
+}
+
+// findingColumns are the findings columns scanFindings reads, in order
+const findingColumns = `timestamp, agent_id, type, severity, page_url, action, message, request_url, method, status_code, duration_ms, content_length`
+
+func scanFindings(rows *sql.Rows, missionID string) []models.Finding {
+	findings := []models.Finding{}
+	for rows.Next() {
+		f := models.Finding{MissionID: missionID}
+		var pageURL, action, message, requestURL, method sql.NullString
+		var statusCode, durationMS, contentLength sql.NullInt64
+		if err := rows.Scan(
+			&f.Timestamp, &f.AgentID, &f.Type, &f.Severity, &pageURL, &action,
+			&message, &requestURL, &method, &statusCode, &durationMS, &contentLength,
+		); err != nil {
+			continue
+		}
+		f.PageURL = pageURL.String
+		f.Action = action.String
+		f.Message = message.String
+		f.RequestURL = requestURL.String
+		f.Method = method.String
+		f.StatusCode = int(statusCode.Int64)
+		f.DurationMS = durationMS.Int64
+		f.ContentLength = contentLength.Int64
+
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// PerformanceOffenders lists the mission's performance findings with the
+// longest load times and with the largest pages, worst first
+func (s *SupabaseStore) PerformanceOffenders(ctx context.Context, missionID string, limit int) (slowest, largest []models.Finding) {
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	worst := func(column string) []models.Finding {
+		// column is one of two constants below, never user input
+		rows, err := s.db.QueryContext(opCtx, `
+			SELECT `+findingColumns+`
+			FROM findings
+			WHERE mission_id = $1 AND type = 'performance' AND `+column+` > 0
+			ORDER BY `+column+` DESC
+			LIMIT $2`, missionID, limit)
+		if err != nil {
+			log.Printf("Error listing performance findings for mission %s: %v", missionID, err)
+			return nil
+		}
+		defer rows.Close()
+		return scanFindings(rows, missionID)
+	}
+	return worst("duration_ms"), worst("content_length")
+}
+
+// PutCoverage saves a mission's coverage snapshot, replacing the previous one
+func (s *SupabaseStore) PutCoverage(ctx context.Context, coverage *models.Coverage) {
+	data, err := json.Marshal(coverage)
+	if err != nil {
+		log.Printf("Error encoding coverage for mission %s: %v", coverage.MissionID, err)
+		return
+	}
+
+	query := `
+		INSERT INTO mission_coverage (mission_id, data, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (mission_id) DO UPDATE SET
+			data = EXCLUDED.data,
+			updated_at = EXCLUDED.updated_at`
+
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(opCtx, query, coverage.MissionID, data, coverage.UpdatedAt); err != nil {
+		log.Printf("Error saving coverage for mission %s: %v", coverage.MissionID, err)
+	}
+}
+
+// GetCoverage loads a mission's latest coverage snapshot
+func (s *SupabaseStore) GetCoverage(ctx context.Context, missionID string) (*models.Coverage, bool) {
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var data []byte
+	err := s.db.QueryRowContext(opCtx, `SELECT data FROM mission_coverage WHERE mission_id = $1`, missionID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error getting coverage for mission %s: %v", missionID, err)
+		return nil, false
+	}
+
+	coverage := &models.Coverage{}
+	if err := json.Unmarshal(data, coverage); err != nil {
+		log.Printf("Error decoding coverage for mission %s: %v", missionID, err)
+		return nil, false
+	}
+	return coverage, true
+}
+
+// PutSitemap saves a crawl mission's sitemap, replacing the previous one
+func (s *SupabaseStore) PutSitemap(ctx context.Context, sitemap *models.Sitemap) {
+	data, err := json.Marshal(sitemap)
+	if err != nil {
+		log.Printf("Error encoding sitemap for mission %s: %v", sitemap.MissionID, err)
+		return
+	}
+
+	query := `
+		INSERT INTO mission_sitemaps (mission_id, data, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (mission_id) DO UPDATE SET
+			data = EXCLUDED.data,
+			updated_at = EXCLUDED.updated_at`
+
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(opCtx, query, sitemap.MissionID, data, sitemap.UpdatedAt); err != nil {
+		log.Printf("Error saving sitemap for mission %s: %v", sitemap.MissionID, err)
+	}
+}
+
+// GetSitemap loads a crawl mission's latest sitemap
+func (s *SupabaseStore) GetSitemap(ctx context.Context, missionID string) (*models.Sitemap, bool) {
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var data []byte
+	err := s.db.QueryRowContext(opCtx, `SELECT data FROM mission_sitemaps WHERE mission_id = $1`, missionID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error getting sitemap for mission %s: %v", missionID, err)
+		return nil, false
+	}
+
+	sitemap := &models.Sitemap{}
+	if err := json.Unmarshal(data, sitemap); err != nil {
+		log.Printf("Error decoding sitemap for mission %s: %v", missionID, err)
+		return nil, false
+	}
+	return sitemap, true
+}
+
+// CreateTemplate saves a new template, failing with ErrTemplateExists when its
+// name is taken
+func (s *SupabaseStore) CreateTemplate(ctx context.Context, template *models.MissionTemplate) error {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return fmt.Errorf("encode template: %w", err)
+	}
+
+	query := `
+		INSERT INTO mission_templates (id, name, data, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO NOTHING`
+
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.db.ExecContext(opCtx, query, template.ID, template.Name, data, template.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("save template: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrTemplateExists
+	}
+	return nil
+}
+
+// GetTemplate loads a template by ID or, failing that, by name
+func (s *SupabaseStore) GetTemplate(ctx context.Context, idOrName string) (*models.MissionTemplate, bool) {
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var data []byte
+	err := s.db.QueryRowContext(opCtx, `
+		SELECT data FROM mission_templates
+		WHERE id = $1 OR name = $1
+		ORDER BY (id = $1) DESC
+		LIMIT 1`, idOrName).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error getting template %s: %v", idOrName, err)
+		return nil, false
+	}
+
+	template := &models.MissionTemplate{}
+	if err := json.Unmarshal(data, template); err != nil {
+		log.Printf("Error decoding template %s: %v", idOrName, err)
+		return nil, false
+	}
+	return template, true
+}
+
+// ListTemplates returns every template, by name
+func (s *SupabaseStore) ListTemplates(ctx context.Context) []*models.MissionTemplate {
+	opCtx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(opCtx, `SELECT data FROM mission_templates ORDER BY name`)
+	if err != nil {
+		log.Printf("Error listing templates: %v", err)
+		return []*models.MissionTemplate{}
+	}
+	defer rows.Close()
+
+	templates := []*models.MissionTemplate{}
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			continue
+		}
+		template := &models.MissionTemplate{}
+		if err := json.Unmarshal(data, template); err != nil {
+			log.Printf("Error decoding template: %v", err)
+			continue
+		}
+		templates = append(templates, template)
+	}
+	return templates
+}
+
+// missionColumns lists the persisted mission columns in the order used by
+// missionArgs and scanMission
+var missionColumns = []string{
+	"id", "name", "target_url", "num_agents", "goal", "max_duration_seconds",
+	"rate_limit_per_second", "initial_system_prompt", "status", "created_at",
+	"started_at", "completed_at", "total_actions", "total_errors",
+	"average_latency_ms", "completed_agents", "failed_agents", "execution_mode",
+	"options", "decision_cache_hits", "decision_cache_misses",
+	"total_prompt_tokens", "total_output_tokens", "estimated_cost_usd",
+	"claimed_completions", "verified_completions", "replay_of",
+	"unique_urls", "agent_url_visits", "js_errors", "network_failures",
+	"tags", "idempotency_key", "target_auth", "scheduled_from", "next_run_at",
+	"template_id", "stop_reason", "dropped_events",
+	"idempotency_caller", "idempotency_body_hash",
+}
+
+// missionUpdateColumns are overwritten when an existing mission is saved again;
+// the remaining columns are fixed at creation
+var missionUpdateColumns = []string{
+	"status", "started_at", "completed_at", "total_actions", "total_errors",
+	"average_latency_ms", "completed_agents", "failed_agents",
+	"decision_cache_hits", "decision_cache_misses",
+	"total_prompt_tokens", "total_output_tokens", "estimated_cost_usd",
+	"claimed_completions", "verified_completions",
+	"unique_urls", "agent_url_visits", "js_errors", "network_failures",
+	"next_run_at", "stop_reason", "dropped_events",
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// storedTargetAuth is models.TargetAuth without its redacting MarshalJSON, so
+// the secrets are persisted; they are kept out of the options column
+type storedTargetAuth models.TargetAuth
+
+func missionArgs(m *models.Mission) ([]any, error) {
+	opts := m.MissionOptions
+	opts.TargetAuth = nil
+	options, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	var targetAuth []byte
+	if m.TargetAuth != nil {
+		if targetAuth, err = json.Marshal((*storedTargetAuth)(m.TargetAuth)); err != nil {
+			return nil, err
+		}
+	}
+	tags := m.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return []any{
+		m.ID, m.Name, m.TargetURL, m.NumAgents, m.Goal,
+		m.MaxDurationSeconds, m.RateLimitPerSecond, m.InitialSystemPrompt,
+		m.Status, m.CreatedAt, m.StartedAt, m.CompletedAt,
+		m.TotalActions, m.TotalErrors, m.AverageLatencyMS,
+		m.CompletedAgents, m.FailedAgents, executionModeOrDefault(m.ExecutionMode),
+		options, m.DecisionCacheHits, m.DecisionCacheMisses,
+		m.TotalPromptTokens, m.TotalOutputTokens, m.EstimatedCostUSD,
+		m.ClaimedCompletions, m.VerifiedCompletions, ToNullString(m.ReplayOf),
+		m.UniqueURLs, m.AgentURLVisits, m.JSErrors, m.NetworkFailures,
+		tagsJSON, ToNullString(m.IdempotencyKey), targetAuth,
+		ToNullString(m.ScheduledFrom), m.NextRunAt,
+		ToNullString(m.TemplateID), ToNullString(m.StopReason), m.DroppedEvents,
+		ToNullString(m.IdempotencyCaller), ToNullString(m.IdempotencyBodyHash),
+	}, nil
+}
+
+func scanMission(row rowScanner, m *models.Mission) error {
+	var options, tags, targetAuth []byte
+	var replayOf, idempotencyKey, scheduledFrom, templateID, stopReason sql.NullString
+	var idempotencyCaller, idempotencyBodyHash sql.NullString
+	if err := row.Scan(
+		&m.ID, &m.Name, &m.TargetURL, &m.NumAgents, &m.Goal, &m.MaxDurationSeconds,
+		&m.RateLimitPerSecond, &m.InitialSystemPrompt, &m.Status, &m.CreatedAt,
+		&m.StartedAt, &m.CompletedAt, &m.TotalActions, &m.TotalErrors,
+		&m.AverageLatencyMS, &m.CompletedAgents, &m.FailedAgents, &m.ExecutionMode,
+		&options, &m.DecisionCacheHits, &m.DecisionCacheMisses,
+		&m.TotalPromptTokens, &m.TotalOutputTokens, &m.EstimatedCostUSD,
+		&m.ClaimedCompletions, &m.VerifiedCompletions, &replayOf,
+		&m.UniqueURLs, &m.AgentURLVisits, &m.JSErrors, &m.NetworkFailures,
+		&tags, &idempotencyKey, &targetAuth,
+		&scheduledFrom, &m.NextRunAt,
+		&templateID, &stopReason, &m.DroppedEvents,
+		&idempotencyCaller, &idempotencyBodyHash,
+	); err != nil {
+		return err
+	}
+	m.ReplayOf = replayOf.String
+	m.IdempotencyKey = idempotencyKey.String
+	m.IdempotencyCaller = idempotencyCaller.String
+	m.IdempotencyBodyHash = idempotencyBodyHash.String
+	m.ScheduledFrom = scheduledFrom.String
+	m.TemplateID = templateID.String
+	m.StopReason = stopReason.String
+
+	if len(options) > 0 {
+		if err := json.Unmarshal(options, &m.MissionOptions); err != nil {
+			return fmt.Errorf("decode options for mission %s: %w", m.ID, err)
+		}
+	}
+	m.Tags = []string{}
+	if len(tags) > 0 {
+		if err := json.Unmarshal(tags, &m.Tags); err != nil {
+			return fmt.Errorf("decode tags for mission %s: %w", m.ID, err)
+		}
+	}
+	if len(targetAuth) > 0 {
+		auth := &storedTargetAuth{}
+		if err := json.Unmarshal(targetAuth, auth); err != nil {
+			return fmt.Errorf("decode target auth for mission %s: %w", m.ID, err)
+		}
+		m.TargetAuth = (*models.TargetAuth)(auth)
+	}
+	return nil
+}
+
+func executionModeOrDefault(mode models.ExecutionMode) models.ExecutionMode {
+	if mode == "" {
+		return models.ExecutionModeHTTP
+	}
+	return mode
+}
+
+func severityOrDefault(severity string) string {
+	if severity == "" {
+		return models.SeverityMedium
+	}
+	return severity
+}
+
+func toNullInt(n int) sql.NullInt64 {
+	if n == 0 {
+		return sql.NullInt64{Valid: false}
+	}
+	return sql.NullInt64{Int64: int64(n), Valid: true}
+}
+
+func toNullInt64(n int64) sql.NullInt64 {
+	if n == 0 {
+		return sql.NullInt64{Valid: false}
+	}
+	return sql.NullInt64{Int64: n, Valid: true}
+}
+
 func ToNullString(s string) sql.NullString {
 	if s == "" {
 		return sql.NullString{Valid: false}
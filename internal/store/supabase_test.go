@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// logDB is a database/sql driver that answers the recent events query from
+// an in-memory action_logs table the way Postgres would: newest first, up to
+// the LIMIT argument
+type logDB struct {
+	logs   map[string][]driver.Value // timestamps by mission, in insert order
+	limits []int64                   // LIMIT arguments received
+}
+
+func (d *logDB) Connect(ctx context.Context) (driver.Conn, error) { return d, nil }
+func (d *logDB) Driver() driver.Driver                            { return nil }
+func (d *logDB) Prepare(query string) (driver.Stmt, error) {
+	if !strings.Contains(query, "FROM action_logs") || !strings.Contains(query, "ORDER BY id DESC") {
+		return nil, fmt.Errorf("unexpected query: %s", query)
+	}
+	return d, nil
+}
+func (d *logDB) Close() error              { return nil }
+func (d *logDB) Begin() (driver.Tx, error) { return nil, fmt.Errorf("transactions are not supported") }
+func (d *logDB) NumInput() int             { return 2 }
+func (d *logDB) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("exec is not supported")
+}
+
+func (d *logDB) Query(args []driver.Value) (driver.Rows, error) {
+	logs := d.logs[args[0].(string)]
+	limit := args[1].(int64)
+	d.limits = append(d.limits, limit)
+	rows := &logRows{}
+	for i := len(logs) - 1; i >= 0 && int64(len(rows.timestamps)) < limit; i-- {
+		rows.timestamps = append(rows.timestamps, logs[i])
+	}
+	return rows, nil
+}
+
+type logRows struct {
+	timestamps []driver.Value
+	next       int
+}
+
+func (r *logRows) Columns() []string {
+	return []string{"timestamp", "agent_id", "action", "selector", "result", "latency_ms", "error_message", "new_url", "text_input",
+		"status_code", "redirect_url", "reasoning", "expected_next_state", "frame"}
+}
+func (r *logRows) Close() error { return nil }
+func (r *logRows) Next(dest []driver.Value) error {
+	if r.next == len(r.timestamps) {
+		return io.EOF
+	}
+	copy(dest, []driver.Value{r.timestamps[r.next], "m1-agent-0", "click", "a#next", "success", int64(12), nil, "https://example.test/", nil,
+		int64(200), nil, "The next page lists more products", "Page 2 of the product list", nil})
+	r.next++
+	return nil
+}
+
+func TestRecentEventsBounded(t *testing.T) {
+	const logged = 10000
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	db := &logDB{logs: map[string][]driver.Value{}}
+	for i := 0; i < logged; i++ {
+		db.logs["m1"] = append(db.logs["m1"], start.Add(time.Duration(i)*time.Second))
+	}
+	s := NewSupabaseStore(sql.OpenDB(db))
+
+	for _, limit := range []int{DefaultRecentEvents, 1, MaxRecentEvents} {
+		t.Run(fmt.Sprint(limit), func(t *testing.T) {
+			s.RecentEventsLimit = limit
+			events := s.recentEvents(context.Background(), "m1")
+			if len(events) != limit {
+				t.Fatalf("loaded %d of %d events, want the cap of %d", len(events), logged, limit)
+			}
+			if newest := start.Add((logged - 1) * time.Second); !events[0].Timestamp.Equal(newest) {
+				t.Errorf("first event at %s, want the newest at %s", events[0].Timestamp, newest)
+			}
+			if events[0].Reasoning == "" || events[0].ExpectedNextState == "" {
+				t.Errorf("event reasoning = %q, expected_next_state = %q, want both loaded", events[0].Reasoning, events[0].ExpectedNextState)
+			}
+			if got := db.limits[len(db.limits)-1]; got != int64(limit) {
+				t.Errorf("queried with LIMIT %d, want %d", got, limit)
+			}
+		})
+	}
+
+	if events := s.recentEvents(context.Background(), "m2"); len(events) != 0 {
+		t.Errorf("loaded %d events for a mission without any", len(events))
+	}
+}
@@ -0,0 +1,80 @@
+// Package tracing traces missions, agents and their steps with OpenTelemetry.
+// Spans are no-ops until Setup installs an exporter.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer returns the tracer for one of the server's components
+func Tracer(component string) trace.Tracer {
+	return otel.Tracer("swarmtest/" + component)
+}
+
+// Setup exports spans over OTLP/HTTP to endpoint, e.g. http://localhost:4318,
+// and returns a function that flushes and stops the exporter
+func Setup(ctx context.Context, endpoint, serviceName string) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// Fail marks span as failed with err
+func Fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// End ends span, marking it failed when err is set
+func End(span trace.Span, err error) {
+	if err != nil {
+		Fail(span, err)
+	}
+	span.End()
+}
+
+// LogFields renders the trace and span IDs of ctx for log lines, so they can
+// be correlated with traces; empty when ctx isn't traced
+func LogFields(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+	return fmt.Sprintf(" trace_id=%s span_id=%s", sc.TraceID(), sc.SpanID())
+}
+
+// Attributes used across spans
+var (
+	MissionID = attribute.Key("swarmtest.mission.id")
+	AgentID   = attribute.Key("swarmtest.agent.id")
+	Action    = attribute.Key("swarmtest.action")
+	URL       = attribute.Key("url.full")
+)
@@ -0,0 +1,56 @@
+package utils
+
+import "strings"
+
+// botWallMaxElements is the most interactive elements a page can have and
+// still count as a CAPTCHA wall. Real pages often embed a CAPTCHA on one form;
+// a wall is little more than the CAPTCHA itself.
+const botWallMaxElements = 5
+
+// challengeMarkers identify interstitials that gate the whole site, whatever
+// else is on the page
+var challengeMarkers = []struct {
+	marker, kind string
+}{
+	{"challenges.cloudflare.com", "Cloudflare challenge"},
+	{"cf-chl-", "Cloudflare challenge"},
+	{"cf_chl_opt", "Cloudflare challenge"},
+	{"checking your browser before accessing", "browser check"},
+	{"verify you are human", "human verification"},
+	{"are you a robot", "human verification"},
+	{"px-captcha", "PerimeterX challenge"},
+	{"captcha-delivery.com", "DataDome challenge"},
+}
+
+// captchaMarkers identify CAPTCHA widgets, which only count as a wall on a
+// page with little else on it
+var captchaMarkers = []struct {
+	marker, kind string
+}{
+	{"google.com/recaptcha", "reCAPTCHA"},
+	{"g-recaptcha", "reCAPTCHA"},
+	{"hcaptcha.com", "hCaptcha"},
+	{"h-captcha", "hCaptcha"},
+	{"cf-turnstile", "Cloudflare Turnstile"},
+}
+
+// DetectBotWall reports whether html is a CAPTCHA or bot challenge page
+// rather than the target's content, and which kind. interactiveElements is
+// the parsed page's interactive element count.
+func DetectBotWall(html string, interactiveElements int) (kind string, blocked bool) {
+	lower := strings.ToLower(html)
+	for _, m := range challengeMarkers {
+		if strings.Contains(lower, m.marker) {
+			return m.kind, true
+		}
+	}
+	if interactiveElements > botWallMaxElements {
+		return "", false
+	}
+	for _, m := range captchaMarkers {
+		if strings.Contains(lower, m.marker) {
+			return m.kind, true
+		}
+	}
+	return "", false
+}
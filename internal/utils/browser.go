@@ -2,29 +2,188 @@ package utils
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/url"
 	"os/exec"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/chromedp/cdproto/browser"
 	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/cdproto/security"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/cdproto/target"
 	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/device"
+	"github.com/chromedp/chromedp/kb"
 	"swarmtest/internal/models"
 )
 
+// DeviceProfiles are the devices a browser-mode mission can emulate
+var DeviceProfiles = map[string]chromedp.Device{
+	"iphone":    device.IPhone15,
+	"iphone-se": device.IPhoneSE,
+	"pixel":     device.Pixel5,
+	"galaxy":    device.GalaxyS9,
+	"ipad":      device.IPadPro11,
+}
+
+// KeyNames maps the key names accepted by the key action to chromedp keys
+var KeyNames = map[string]string{
+	"Enter":      kb.Enter,
+	"Escape":     kb.Escape,
+	"Tab":        kb.Tab,
+	"Backspace":  kb.Backspace,
+	"Space":      " ",
+	"ArrowDown":  kb.ArrowDown,
+	"ArrowUp":    kb.ArrowUp,
+	"ArrowLeft":  kb.ArrowLeft,
+	"ArrowRight": kb.ArrowRight,
+}
+
+// BrowserOptions configures the tab of a BrowserExecutor
+type BrowserOptions struct {
+	// ViewportWidth and ViewportHeight override the default viewport when both are set
+	ViewportWidth  int
+	ViewportHeight int
+	// Device names one of DeviceProfiles, emulating its screen, user agent and touch support
+	Device string
+	// Proxy routes the tab's traffic through an HTTP or SOCKS5 proxy
+	Proxy *url.URL
+	// IgnoreCertErrors accepts invalid TLS certificates in this tab
+	IgnoreCertErrors bool
+	// UserAgent overrides the browser's User-Agent, including a device's
+	UserAgent string
+	// Language is a language tag for the tab's locale and Accept-Language
+	Language string
+	// Timezone is an IANA time zone the tab reports
+	Timezone string
+	// MaxPageSize fails capturing a DOM larger than this many bytes with
+	// ErrPageTooLarge (no limit when 0)
+	MaxPageSize int
+	// Authorization is added to the tab's requests to AuthOrigin, and no others
+	Authorization string
+	AuthOrigin    *url.URL
+}
+
+// setupActions returns the emulation to apply when the tab starts
+func (o BrowserOptions) setupActions() []chromedp.Action {
+	var actions []chromedp.Action
+	if d, ok := DeviceProfiles[o.Device]; ok {
+		actions = append(actions, chromedp.Emulate(d))
+	} else if o.ViewportWidth > 0 && o.ViewportHeight > 0 {
+		actions = append(actions, chromedp.EmulateViewport(int64(o.ViewportWidth), int64(o.ViewportHeight)))
+	}
+	if o.Language != "" {
+		actions = append(actions, o.languageOverride(), emulation.SetLocaleOverride().WithLocale(ICULocale(o.Language)))
+	} else if o.UserAgent != "" {
+		actions = append(actions, emulation.SetUserAgentOverride(o.UserAgent))
+	}
+	if o.Timezone != "" {
+		actions = append(actions, emulation.SetTimezoneOverride(o.Timezone))
+	}
+	if o.Proxy != nil && o.Proxy.User != nil {
+		// Chrome can't take proxy credentials in the URL; answer auth challenges instead
+		actions = append(actions, fetch.Enable().WithHandleAuthRequests(true))
+	} else if o.Authorization != "" && o.AuthOrigin != nil {
+		// Paused requests to the target get the Authorization header added
+		actions = append(actions, fetch.Enable())
+	}
+	if o.IgnoreCertErrors {
+		actions = append(actions, security.SetIgnoreCertificateErrors(true))
+	}
+	return actions
+}
+
+// languageOverride sets the tab's Accept-Language and navigator.languages. Chrome
+// only takes them with a User-Agent, so it passes on the one otherwise in effect.
+func (o BrowserOptions) languageOverride() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		userAgent := o.UserAgent
+		if d, ok := DeviceProfiles[o.Device]; ok && userAgent == "" {
+			userAgent = d.Device().UserAgent
+		}
+		if userAgent == "" {
+			var err error
+			if _, _, _, userAgent, _, err = browser.GetVersion().Do(ctx); err != nil {
+				return err
+			}
+		}
+		return emulation.SetUserAgentOverride(userAgent).WithAcceptLanguage(AcceptLanguage(o.Language)).Do(ctx)
+	})
+}
+
+// contextOptions gives every tab its own browser context, so agents don't share
+// cookies or storage, with the mission's proxy if any
+func (o BrowserOptions) contextOptions() []chromedp.ContextOption {
+	return []chromedp.ContextOption{
+		chromedp.WithNewBrowserContext(func(p *target.CreateBrowserContextParams) *target.CreateBrowserContextParams {
+			if o.Proxy != nil {
+				return p.WithProxyServer(o.Proxy.Scheme + "://" + o.Proxy.Host)
+			}
+			return p
+		}),
+	}
+}
+
+const (
+	// maxBufferedJSErrors and maxBufferedNetworkFailures cap what is held between two actions
+	maxBufferedJSErrors        = 50
+	maxBufferedNetworkFailures = 50
+	// maxPendingRequests bounds the in-flight requests remembered for failure reports
+	maxPendingRequests = 1000
+)
+
+const (
+	// DefaultBrowserWarmTabs is how many idle tabs the pool keeps started
+	DefaultBrowserWarmTabs = 2
+	// MaxBrowserWarmTabs bounds BROWSER_WARM_TABS
+	MaxBrowserWarmTabs = 20
+	// tabResetTimeout bounds wiping a returned tab for its next agent
+	tabResetTimeout = 10 * time.Second
+	// maxTabOrigins is the most origins a tab may have requested and still be
+	// wiped for reuse; one that went further is closed instead
+	maxTabOrigins = 100
+)
+
 // SharedBrowserPool is the global instance
 var SharedBrowserPool *BrowserPool
 
 // BrowserPool manages a shared Chrome instance
 type BrowserPool struct {
-	allocCtx context.Context
-	cancel   context.CancelFunc
-	mu       sync.Mutex
+	allocCtx   context.Context
+	browserCtx context.Context
+	cancel     context.CancelFunc
+	mu         sync.Mutex
+
+	// ready holds started tabs waiting for an agent; its capacity is the
+	// number kept warm
+	ready chan *warmTab
+}
+
+// warmTab is a started tab in its own browser context, reused across agents
+type warmTab struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu             sync.Mutex
+	origins        map[string]bool // origins requested since the last reset, whose storage is wiped
+	tooManyOrigins bool
 }
 
-// NewBrowserPool creates a new browser pool
-func NewBrowserPool(headless bool) (*BrowserPool, error) {
+// NewBrowserPool creates a new browser pool and starts warming warmTabs idle
+// tabs, so the first agents don't wait for their tab to start
+func NewBrowserPool(headless bool, warmTabs int) (*BrowserPool, error) {
 	// Check if chrome is available
 	path, err := exec.LookPath("google-chrome")
 	if err != nil {
@@ -44,23 +203,166 @@ func NewBrowserPool(headless bool) (*BrowserPool, error) {
 	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
 
 	// Start a shared browser instance
-	ctx, _ := chromedp.NewContext(allocCtx)
-	if err := chromedp.Run(ctx); err != nil {
+	browserCtx, _ := chromedp.NewContext(allocCtx)
+	if err := chromedp.Run(browserCtx); err != nil {
 		cancel()
 		return nil, fmt.Errorf("failed to start browser: %w", err)
 	}
 
-	return &BrowserPool{
-		allocCtx: allocCtx,
-		cancel:   cancel,
-	}, nil
+	p := &BrowserPool{
+		allocCtx:   allocCtx,
+		browserCtx: browserCtx,
+		cancel:     cancel,
+		ready:      make(chan *warmTab, warmTabs),
+	}
+	go func() {
+		for i := 0; i < warmTabs; i++ {
+			p.warm()
+		}
+	}()
+	return p, nil
 }
 
-// GetContext creates a new tab context
-func (p *BrowserPool) GetContext() (context.Context, context.CancelFunc) {
+// GetContext creates a new tab context in the shared browser
+func (p *BrowserPool) GetContext(opts ...chromedp.ContextOption) (context.Context, context.CancelFunc) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	return chromedp.NewContext(p.allocCtx)
+	return chromedp.NewContext(p.browserCtx, opts...)
+}
+
+// tab returns a tab for an agent and the function that gives it back. Every
+// tab has a browser context of its own, so agents never share cookies or
+// storage. Tabs without a proxy come from the warm queue when one is ready,
+// and are reset and queued again when given back; proxied tabs need a new
+// browser context for the proxy and are always new.
+func (p *BrowserPool) tab(opts BrowserOptions) (context.Context, context.CancelFunc) {
+	if opts.Proxy == nil {
+		select {
+		case t := <-p.ready:
+			go p.warm() // replaces t in case it isn't given back in time
+			if err := t.acquire(); err != nil {
+				log.Printf("Discarding warm browser tab: %v", err)
+				t.cancel()
+				break
+			}
+			return t.ctx, func() { go p.recycle(t) }
+		default:
+		}
+	}
+	return p.GetContext(opts.contextOptions()...)
+}
+
+// acquire clears the session of a queued tab again as an agent takes it, so
+// nothing that reached it after its last reset carries over to the agent
+func (t *warmTab) acquire() error {
+	ctx, cancel := context.WithTimeout(t.ctx, tabResetTimeout)
+	defer cancel()
+	return t.clearSession(ctx)
+}
+
+// warm starts a tab and queues it, if the queue has room
+func (p *BrowserPool) warm() {
+	ctx, cancel := p.GetContext(BrowserOptions{}.contextOptions()...)
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		if p.browserCtx.Err() == nil {
+			log.Printf("Failed to warm browser tab: %v", err)
+		}
+		return
+	}
+	t := &warmTab{ctx: ctx, cancel: cancel, origins: make(map[string]bool)}
+	chromedp.ListenTarget(ctx, t.noteRequest)
+	p.offer(t)
+}
+
+// offer queues a tab, closing it when enough are already waiting
+func (p *BrowserPool) offer(t *warmTab) {
+	select {
+	case p.ready <- t:
+	default:
+		t.cancel()
+	}
+}
+
+// recycle wipes a tab given back by an agent and queues it again, closing it
+// if it can't be fully reset
+func (p *BrowserPool) recycle(t *warmTab) {
+	if err := t.reset(); err != nil {
+		if p.browserCtx.Err() == nil {
+			log.Printf("Closing browser tab instead of reusing it: %v", err)
+		}
+		t.cancel()
+		return
+	}
+	p.offer(t)
+}
+
+// noteRequest remembers the origin of each request the tab makes, so reset
+// can wipe what those origins stored
+func (t *warmTab) noteRequest(ev any) {
+	req, ok := ev.(*network.EventRequestWillBeSent)
+	if !ok || req.Request == nil {
+		return
+	}
+	u, err := url.Parse(req.Request.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return
+	}
+	origin := u.Scheme + "://" + u.Host
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.origins[origin] {
+		return
+	}
+	if len(t.origins) >= maxTabOrigins {
+		t.tooManyOrigins = true
+		return
+	}
+	t.origins[origin] = true
+}
+
+// reset returns the tab to the state of a new one for the next agent: no
+// emulation, locale or time zone overrides, request interception or certificate overrides, a blank page, and
+// no cookies, cache or storage from the origins it visited
+func (t *warmTab) reset() error {
+	ctx, cancel := context.WithTimeout(t.ctx, tabResetTimeout)
+	defer cancel()
+	err := chromedp.Run(ctx,
+		fetch.Disable(),
+		security.SetIgnoreCertificateErrors(false),
+		chromedp.EmulateReset(),
+		emulation.SetLocaleOverride(),
+		emulation.SetTimezoneOverride(""),
+		chromedp.Navigate("about:blank"),
+		network.ClearBrowserCache(),
+	)
+	if err != nil {
+		return err
+	}
+	return t.clearSession(ctx)
+}
+
+// clearSession deletes the cookies of the tab's browser context and the
+// storage of the origins it requested since it was last cleared, leaving the
+// tab signed out of everything
+func (t *warmTab) clearSession(ctx context.Context) error {
+	t.mu.Lock()
+	origins, tooMany := t.origins, t.tooManyOrigins
+	t.origins, t.tooManyOrigins = make(map[string]bool), false
+	t.mu.Unlock()
+	if tooMany {
+		return fmt.Errorf("tab requested more than %d origins", maxTabOrigins)
+	}
+
+	actions := []chromedp.Action{
+		storage.ClearCookies().WithBrowserContextID(chromedp.FromContext(t.ctx).BrowserContextID),
+	}
+	for origin := range origins {
+		actions = append(actions, storage.ClearDataForOrigin(origin, string(storage.TypeAll)))
+	}
+	actions = append(actions, network.Disable())
+	return chromedp.Run(ctx, actions...)
 }
 
 // Close shuts down the browser
@@ -72,19 +374,223 @@ func (p *BrowserPool) Close() {
 
 // BrowserExecutor executes actions in a browser
 type BrowserExecutor struct {
-	pool *BrowserPool
-	ctx  context.Context
+	pool   *BrowserPool
+	ctx    context.Context
 	cancel context.CancelFunc
+
+	// JavaScript errors and failed network requests reported by the tab since the last action
+	diagMu          sync.Mutex
+	jsErrors        []models.JSError
+	networkFailures []models.NetworkFailure
+	pendingRequests map[network.RequestID]*network.Request
+
+	// setup runs before the first action: network tracking and device emulation
+	setup     []chromedp.Action
+	setupDone bool
+
+	proxyUser   *url.Userinfo
+	maxPageSize int
+
+	authorization string
+	authOrigin    *url.URL
+
+	// frames are the iframes of the last captured page, by the label their
+	// elements carry, for routing actions into them
+	frames map[string]*cdp.Node
+
+	// uploadCleanups remove generated upload files; Chrome reads them only when
+	// the form is submitted, so they live as long as the tab
+	uploadCleanups []func()
 }
 
 // NewBrowserExecutor creates a new executor for an agent
-func NewBrowserExecutor(pool *BrowserPool) *BrowserExecutor {
-	ctx, cancel := pool.GetContext()
-	return &BrowserExecutor{
-		pool:   pool,
-		ctx:    ctx,
-		cancel: cancel,
+func NewBrowserExecutor(pool *BrowserPool, opts BrowserOptions) *BrowserExecutor {
+	tabCtx, release := pool.tab(opts)
+	// Closing the executor ends ctx, detaching its listeners from a tab that
+	// may go on to serve another agent
+	ctx, cancel := context.WithCancel(tabCtx)
+	e := &BrowserExecutor{
+		pool:            pool,
+		ctx:             ctx,
+		cancel:          func() { cancel(); release() },
+		pendingRequests: make(map[network.RequestID]*network.Request),
+		// Network events are needed to see failing background XHR/fetch calls
+		setup: append([]chromedp.Action{network.Enable()}, opts.setupActions()...),
+	}
+	if opts.Proxy != nil {
+		e.proxyUser = opts.Proxy.User
+	}
+	e.maxPageSize = opts.MaxPageSize
+	if opts.Authorization != "" && opts.AuthOrigin != nil {
+		e.authorization, e.authOrigin = opts.Authorization, opts.AuthOrigin
+	}
+	chromedp.ListenTarget(ctx, e.handleTargetEvent)
+	return e
+}
+
+// handleTargetEvent collects console errors and uncaught exceptions from the tab
+func (e *BrowserExecutor) handleTargetEvent(ev any) {
+	switch ev := ev.(type) {
+	case *runtime.EventConsoleAPICalled:
+		if ev.Type != runtime.APITypeError {
+			return
+		}
+		args := make([]string, 0, len(ev.Args))
+		for _, arg := range ev.Args {
+			args = append(args, remoteObjectString(arg))
+		}
+		e.addJSError(models.JSError{Kind: "console", Message: strings.Join(args, " ")})
+
+	case *runtime.EventExceptionThrown:
+		details := ev.ExceptionDetails
+		if details == nil {
+			return
+		}
+		message := details.Text
+		if details.Exception != nil && details.Exception.Description != "" {
+			message = details.Exception.Description
+		}
+		e.addJSError(models.JSError{Kind: "exception", Message: message, SourceURL: details.URL})
+
+	case *network.EventRequestWillBeSent:
+		e.diagMu.Lock()
+		if len(e.pendingRequests) < maxPendingRequests {
+			e.pendingRequests[ev.RequestID] = ev.Request
+		}
+		e.diagMu.Unlock()
+
+	case *network.EventResponseReceived:
+		if ev.Response == nil || ev.Response.Status < 400 {
+			return
+		}
+		e.addNetworkFailure(ev.RequestID, models.NetworkFailure{
+			URL:          ev.Response.URL,
+			ResourceType: string(ev.Type),
+			StatusCode:   int(ev.Response.Status),
+			ErrorText:    ev.Response.StatusText,
+		})
+
+	case *network.EventLoadingFailed:
+		if ev.Canceled {
+			e.forgetRequest(ev.RequestID)
+			return
+		}
+		e.addNetworkFailure(ev.RequestID, models.NetworkFailure{
+			ResourceType: string(ev.Type),
+			ErrorText:    ev.ErrorText,
+		})
+
+	case *network.EventLoadingFinished:
+		e.forgetRequest(ev.RequestID)
+
+	// Only enabled for proxies with credentials and for target auth
+	case *fetch.EventRequestPaused:
+		e.runAsync(e.continueRequest(ev))
+
+	case *fetch.EventAuthRequired:
+		response := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+		if e.proxyUser != nil && ev.AuthChallenge != nil && ev.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
+			password, _ := e.proxyUser.Password()
+			response = &fetch.AuthChallengeResponse{
+				Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+				Username: e.proxyUser.Username(),
+				Password: password,
+			}
+		}
+		e.runAsync(fetch.ContinueWithAuth(ev.RequestID, response))
+	}
+}
+
+// continueRequest resumes a paused request, adding the target's Authorization
+// header when it is bound for the target's origin
+func (e *BrowserExecutor) continueRequest(ev *fetch.EventRequestPaused) chromedp.Action {
+	params := fetch.ContinueRequest(ev.RequestID)
+	if e.authOrigin == nil || ev.Request == nil {
+		return params
+	}
+	u, err := url.Parse(ev.Request.URL)
+	if err != nil || !SameOrigin(u, e.authOrigin) {
+		return params
+	}
+
+	headers := []*fetch.HeaderEntry{{Name: "Authorization", Value: e.authorization}}
+	for name, value := range ev.Request.Headers {
+		if strings.EqualFold(name, "Authorization") {
+			continue
+		}
+		headers = append(headers, &fetch.HeaderEntry{Name: name, Value: fmt.Sprint(value)})
+	}
+	return params.WithHeaders(headers)
+}
+
+// runAsync sends a CDP command from an event listener, which must not block
+func (e *BrowserExecutor) runAsync(action chromedp.Action) {
+	go func() {
+		c := chromedp.FromContext(e.ctx)
+		if c == nil || c.Target == nil {
+			return
+		}
+		if err := action.Do(cdp.WithExecutor(e.ctx, c.Target)); err != nil && e.ctx.Err() == nil {
+			log.Printf("Browser fetch interception failed: %v", err)
+		}
+	}()
+}
+
+// addNetworkFailure records a failed request, filling in the method and URL from
+// the request that started it
+func (e *BrowserExecutor) addNetworkFailure(id network.RequestID, failure models.NetworkFailure) {
+	e.diagMu.Lock()
+	defer e.diagMu.Unlock()
+
+	if req, ok := e.pendingRequests[id]; ok {
+		failure.Method = req.Method
+		if failure.URL == "" {
+			failure.URL = req.URL
+		}
+		delete(e.pendingRequests, id)
+	}
+	if len(e.networkFailures) < maxBufferedNetworkFailures {
+		e.networkFailures = append(e.networkFailures, failure)
+	}
+}
+
+func (e *BrowserExecutor) forgetRequest(id network.RequestID) {
+	e.diagMu.Lock()
+	delete(e.pendingRequests, id)
+	e.diagMu.Unlock()
+}
+
+func (e *BrowserExecutor) addJSError(jsErr models.JSError) {
+	e.diagMu.Lock()
+	defer e.diagMu.Unlock()
+	if len(e.jsErrors) < maxBufferedJSErrors {
+		e.jsErrors = append(e.jsErrors, jsErr)
+	}
+}
+
+// takeDiagnostics returns and clears the JavaScript errors and network
+// failures collected since the last call
+func (e *BrowserExecutor) takeDiagnostics() ([]models.JSError, []models.NetworkFailure) {
+	e.diagMu.Lock()
+	defer e.diagMu.Unlock()
+	errs, failures := e.jsErrors, e.networkFailures
+	e.jsErrors, e.networkFailures = nil, nil
+	return errs, failures
+}
+
+// remoteObjectString renders a console argument, unquoting plain strings
+func remoteObjectString(obj *runtime.RemoteObject) string {
+	if obj.Description != "" {
+		return obj.Description
+	}
+	if len(obj.Value) > 0 {
+		var s string
+		if err := json.Unmarshal(obj.Value, &s); err == nil {
+			return s
+		}
+		return string(obj.Value)
 	}
+	return string(obj.UnserializableValue)
 }
 
 // Close cleans up the tab
@@ -92,58 +598,157 @@ func (e *BrowserExecutor) Close() {
 	if e.cancel != nil {
 		e.cancel()
 	}
+	for _, cleanup := range e.uploadCleanups {
+		cleanup()
+	}
 }
 
-// ExecuteAction executes an action
+// ExecuteAction executes an action, attaching any JavaScript errors and failed
+// network requests the page reported since the previous action. ctx bounds the
+// action as in ActionExecutor.ExecuteAction.
 func (e *BrowserExecutor) ExecuteAction(ctx context.Context, action models.GeminiDecisionResponse, currentURL string) ExecuteActionResult {
-	// Use executor's context directly - no timeout wrapper to avoid cancellation issues
+	if !e.setupDone {
+		e.setupDone = true
+		if err := chromedp.Run(e.ctx, e.setup...); err != nil {
+			log.Printf("Failed to set up browser tab: %v", err)
+		}
+	}
+
+	// Run the action on a child of the tab's context that ends with ctx, so a
+	// timeout abandons the action without closing the tab
+	tabCtx, cancel := context.WithCancel(e.ctx)
+	stop := context.AfterFunc(ctx, cancel)
+	result := e.executeAction(tabCtx, action, currentURL)
+	stop()
+	cancel()
+
+	result.Error = actionTimeoutError(ctx, result.Error)
+	result.JSErrors, result.NetworkFailures = e.takeDiagnostics()
+	return result
+}
+
+func (e *BrowserExecutor) executeAction(tabCtx context.Context, action models.GeminiDecisionResponse, currentURL string) ExecuteActionResult {
+	// tabCtx is bound to the action timeout; the tab itself lives on e.ctx
 	var htmlContent string
 	var newURL string
-	
+	query, err := e.queryOptions(action)
+	if err != nil {
+		return ExecuteActionResult{Error: err}
+	}
+
 	switch action.Action {
 	case "visit":
-		if err := chromedp.Run(e.ctx,
+		if err := chromedp.Run(tabCtx,
 			chromedp.Navigate(currentURL),
 			chromedp.WaitReady("body"),
-			chromedp.OuterHTML("html", &htmlContent),
+			e.outerHTML(&htmlContent),
 			chromedp.Location(&newURL),
 		); err != nil {
 			return ExecuteActionResult{Error: err}
 		}
 
 	case "click":
-		if err := chromedp.Run(e.ctx,
-			chromedp.Click(action.Selector, chromedp.NodeVisible),
+		if err := chromedp.Run(tabCtx,
+			chromedp.Click(action.Selector, append(query, chromedp.NodeVisible)...),
 			chromedp.WaitReady("body"),
 			chromedp.Sleep(1*time.Second), // Wait for hydration/animations
-			chromedp.OuterHTML("html", &htmlContent),
+			e.outerHTML(&htmlContent),
 			chromedp.Location(&newURL),
 		); err != nil {
 			return ExecuteActionResult{Error: err}
 		}
 
 	case "type":
-		if err := chromedp.Run(e.ctx,
-			chromedp.SendKeys(action.Selector, action.TextInput, chromedp.NodeVisible),
-			chromedp.OuterHTML("html", &htmlContent),
+		if action.Frame == "" {
+			if err := e.checkEditable(tabCtx, action.Selector); err != nil {
+				return ExecuteActionResult{Error: err}
+			}
+		}
+		if err := chromedp.Run(tabCtx,
+			chromedp.SendKeys(action.Selector, action.TextInput, append(query, chromedp.NodeVisible)...),
+			e.outerHTML(&htmlContent),
+			chromedp.Location(&newURL),
+		); err != nil {
+			return ExecuteActionResult{Error: err}
+		}
+
+	case "upload":
+		path, cleanup, err := ResolveUploadFile(action.TextInput)
+		e.uploadCleanups = append(e.uploadCleanups, cleanup)
+		if err != nil {
+			return ExecuteActionResult{Error: err}
+		}
+		if err := chromedp.Run(tabCtx,
+			chromedp.SetUploadFiles(action.Selector, []string{path}, append(query, chromedp.NodeReady)...),
+			e.outerHTML(&htmlContent),
+			chromedp.Location(&newURL),
+		); err != nil {
+			return ExecuteActionResult{Error: err}
+		}
+
+	case "key":
+		key, ok := KeyNames[action.TextInput]
+		if !ok {
+			return ExecuteActionResult{Error: fmt.Errorf("unsupported key %q", action.TextInput)}
+		}
+		var actions []chromedp.Action
+		if action.Selector != "" {
+			actions = append(actions, chromedp.Focus(action.Selector, append(query, chromedp.NodeVisible)...))
+		}
+		actions = append(actions,
+			chromedp.KeyEvent(key),
+			chromedp.Sleep(1*time.Second), // Let handlers navigate or re-render
+			e.outerHTML(&htmlContent),
+			chromedp.Location(&newURL),
+		)
+		if err := chromedp.Run(tabCtx, actions...); err != nil {
+			return ExecuteActionResult{Error: err}
+		}
+
+	case "hover":
+		if err := chromedp.Run(tabCtx,
+			hoverNode(action.Selector, query...),
+			chromedp.Sleep(1*time.Second), // Let menus open and the DOM settle
+			e.outerHTML(&htmlContent),
+			chromedp.Location(&newURL),
+		); err != nil {
+			return ExecuteActionResult{Error: err}
+		}
+
+	case "wait_for":
+		if action.Selector == "" {
+			return ExecuteActionResult{Error: fmt.Errorf("wait_for requires a selector")}
+		}
+		waitCtx, cancel := context.WithTimeout(tabCtx, WaitForTimeout)
+		err := chromedp.Run(waitCtx, chromedp.WaitVisible(action.Selector, query...))
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) {
+			return ExecuteActionResult{
+				Error: fmt.Errorf("%w after %s: %s not visible", ErrWaitForTimeout, WaitForTimeout, action.Selector),
+			}
+		} else if err != nil {
+			return ExecuteActionResult{Error: err}
+		}
+		if err := chromedp.Run(tabCtx,
+			e.outerHTML(&htmlContent),
 			chromedp.Location(&newURL),
 		); err != nil {
 			return ExecuteActionResult{Error: err}
 		}
 
 	case "wait":
-		if err := chromedp.Run(e.ctx,
+		if err := chromedp.Run(tabCtx,
 			chromedp.Sleep(2*time.Second),
-			chromedp.OuterHTML("html", &htmlContent),
+			e.outerHTML(&htmlContent),
 			chromedp.Location(&newURL),
 		); err != nil {
 			return ExecuteActionResult{Error: err}
 		}
-		
+
 	default:
 		// Fallback for getting status
-		if err := chromedp.Run(e.ctx,
-			chromedp.OuterHTML("html", &htmlContent),
+		if err := chromedp.Run(tabCtx,
+			e.outerHTML(&htmlContent),
 			chromedp.Location(&newURL),
 		); err != nil {
 			return ExecuteActionResult{Error: err}
@@ -154,7 +759,40 @@ func (e *BrowserExecutor) ExecuteAction(ctx context.Context, action models.Gemin
 		HTML:   htmlContent,
 		NewURL: newURL,
 		// StatusCode is hard to get in simple chromedp, assuming 200 if successful
-		StatusCode: 200, 
+		StatusCode: 200,
+	}
+}
+
+// editableStateJS reports why the element matching a selector can't be typed
+// into ("missing", "disabled", "readonly", "hidden"), or "" if it can
+const editableStateJS = `(() => {
+	const el = %s;
+	if (!el) return "missing";
+	if (el.disabled || el.closest("fieldset[disabled]")) return "disabled";
+	if (el.readOnly) return "readonly";
+	const style = getComputedStyle(el);
+	if (style.display === "none" || style.visibility === "hidden" || el.getClientRects().length === 0) return "hidden";
+	return "";
+})()`
+
+// checkEditable fails fast, with a clear reason, when a user couldn't type into
+// the selected element, instead of waiting for it to become visible
+func (e *BrowserExecutor) checkEditable(ctx context.Context, selector string) error {
+	var state string
+	if err := chromedp.Run(ctx, chromedp.Evaluate(fmt.Sprintf(editableStateJS, shadowPathJS(selector)), &state)); err != nil {
+		return err
+	}
+	switch state {
+	case "":
+		return nil
+	case "missing":
+		return fmt.Errorf("input not found: %s", selector)
+	case "readonly":
+		return fmt.Errorf("input is read-only: %s", selector)
+	case "hidden":
+		return fmt.Errorf("input is not visible: %s", selector)
+	default:
+		return fmt.Errorf("input is %s: %s", state, selector)
 	}
 }
 
@@ -162,7 +800,7 @@ func (e *BrowserExecutor) ExecuteAction(ctx context.Context, action models.Gemin
 func (e *BrowserExecutor) CaptureDOM(ctx context.Context) (string, string, error) {
 	var htmlContent, urlStr string
 	err := chromedp.Run(e.ctx,
-		chromedp.OuterHTML("html", &htmlContent),
+		e.outerHTML(&htmlContent),
 		chromedp.Location(&urlStr),
 	)
 	if err != nil {
@@ -173,11 +811,90 @@ func (e *BrowserExecutor) CaptureDOM(ctx context.Context) (string, string, error
 	return htmlContent, urlStr, nil
 }
 
-// GetInteractableElements returns interactive nodes (simplified)
+// outerHTML captures the document's HTML, with its open shadow roots and its
+// iframes' content, into htmlContent, failing without transferring it when it
+// is over the max page size
+func (e *BrowserExecutor) outerHTML(htmlContent *string) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var captured any
+		if err := chromedp.Evaluate(fmt.Sprintf(domCaptureJS, e.maxPageSize), &captured).Do(ctx); err != nil {
+			return err
+		}
+		switch v := captured.(type) {
+		case string:
+			frames, err := e.captureFrames(ctx, len(v))
+			if err != nil {
+				return err
+			}
+			*htmlContent = embedFrames(v, frames)
+			return nil
+		case float64:
+			return fmt.Errorf("DOM: %w (%d characters, limit %d)", ErrPageTooLarge, int(v), e.maxPageSize)
+		default:
+			return fmt.Errorf("DOM capture returned %T", captured)
+		}
+	})
+}
+
+// GetInteractableElements returns interactive nodes (simplified), including
+// those inside shadow roots
 func (e *BrowserExecutor) GetInteractableElements(ctx context.Context) ([]*cdp.Node, error) {
 	var nodes []*cdp.Node
-	err := chromedp.Run(e.ctx,
-		chromedp.Nodes("a, button, input, select, textarea", &nodes, chromedp.ByQueryAll),
-	)
+	err := chromedp.Run(e.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		root, err := dom.GetDocument().WithDepth(-1).WithPierce(true).Do(ctx)
+		if err != nil {
+			return err
+		}
+		var walk func(n *cdp.Node)
+		walk = func(n *cdp.Node) {
+			switch n.NodeName {
+			case "A", "BUTTON", "INPUT", "SELECT", "TEXTAREA":
+				nodes = append(nodes, n)
+			}
+			for _, shadow := range n.ShadowRoots {
+				// The browser's own shadow trees (inside inputs, videos, ...) aren't the page's
+				if shadow.ShadowRootType != cdp.ShadowRootTypeUserAgent {
+					walk(shadow)
+				}
+			}
+			for _, child := range n.Children {
+				walk(child)
+			}
+		}
+		walk(root)
+		return nil
+	}))
 	return nodes, err
 }
+
+// hoverNode moves the mouse onto the centre of the element matching sel, so
+// CSS :hover rules and mouseover handlers fire the way they do for a user
+func hoverNode(sel string, query ...chromedp.QueryOption) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var nodes []*cdp.Node
+		if err := chromedp.Nodes(sel, &nodes, append(query, chromedp.NodeVisible)...).Do(ctx); err != nil {
+			return err
+		}
+		node := nodes[0]
+
+		if err := dom.ScrollIntoViewIfNeeded().WithNodeID(node.NodeID).Do(ctx); err != nil {
+			return err
+		}
+		quads, err := dom.GetContentQuads().WithNodeID(node.NodeID).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if len(quads) == 0 || len(quads[0]) < 8 {
+			return fmt.Errorf("element %s has no visible box to hover", sel)
+		}
+
+		var x, y float64
+		quad := quads[0]
+		for i := 0; i < len(quad); i += 2 {
+			x += quad[i]
+			y += quad[i+1]
+		}
+		points := float64(len(quad) / 2)
+		return chromedp.MouseEvent(input.MouseMoved, x/points, y/points).Do(ctx)
+	})
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"swarmtest/internal/models"
+)
+
+// testBrowserPool starts a browser for the test, skipping it where Chrome
+// isn't installed
+func testBrowserPool(t *testing.T, warmTabs int) *BrowserPool {
+	t.Helper()
+	pool, err := NewBrowserPool(true, warmTabs)
+	if err != nil {
+		t.Skipf("browser unavailable: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// browse runs an action in the executor, failing the test if it errors
+func browse(t *testing.T, e *BrowserExecutor, action models.GeminiDecisionResponse, currentURL string) ExecuteActionResult {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	result := e.ExecuteAction(ctx, action, currentURL)
+	if result.Error != nil {
+		t.Fatalf("%s %s: %v", action.Action, action.Selector, result.Error)
+	}
+	return result
+}
+
+func servePage(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, "<!DOCTYPE html><html><head><title>Test</title></head><body>"+body+"</body></html>")
+}
+
+// hoverMenuPage has a submenu that only CSS :hover reveals
+const hoverMenuPage = `<style>
+#menu .submenu { display: none; }
+#menu:hover .submenu { display: block; }
+</style>
+<nav id="menu"><span id="products">Products</span>
+<ul class="submenu"><li><a id="widgets" href="/widgets">Widgets</a></li></ul>
+</nav>`
+
+func TestBrowserHoverRevealsMenu(t *testing.T) {
+	pool := testBrowserPool(t, 0)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { servePage(w, hoverMenuPage) })
+	mux.HandleFunc("/widgets", func(w http.ResponseWriter, r *http.Request) { servePage(w, "<h1>Widgets</h1>") })
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	e := NewBrowserExecutor(pool, BrowserOptions{})
+	defer e.Close()
+	browse(t, e, models.GeminiDecisionResponse{Action: "visit"}, server.URL+"/")
+
+	// Hidden until hovered: a click can't reach it yet
+	var visible bool
+	if err := chromedp.Run(e.ctx, chromedp.Evaluate(`getComputedStyle(document.querySelector(".submenu")).display !== "none"`, &visible)); err != nil {
+		t.Fatal(err)
+	}
+	if visible {
+		t.Fatal("submenu is visible before hovering")
+	}
+
+	browse(t, e, models.GeminiDecisionResponse{Action: "hover", Selector: "span#products"}, server.URL+"/")
+	result := browse(t, e, models.GeminiDecisionResponse{Action: "click", Selector: "a#widgets"}, server.URL+"/")
+	if !strings.HasSuffix(result.NewURL, "/widgets") {
+		t.Errorf("clicking the revealed item led to %s, want /widgets", result.NewURL)
+	}
+}
+
+// sessionSite signs the browser in at /login, with a cookie and a
+// localStorage token, and says at /account who is signed in
+func sessionSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "alice", Path: "/"})
+		servePage(w, `<h1>Welcome</h1><script>localStorage.setItem("token", "alice")</script>`)
+	})
+	mux.HandleFunc("/account", func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err == nil {
+			servePage(w, `<p id="status">Signed in as `+c.Value+`</p>`)
+			return
+		}
+		servePage(w, `<p id="status">Signed out</p>`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// session reports what the tab's session looks like to the site's account page
+func session(t *testing.T, ctx context.Context, server *httptest.Server) (status, token string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	err := chromedp.Run(ctx,
+		chromedp.Navigate(server.URL+"/account"),
+		chromedp.Text("#status", &status, chromedp.ByQuery),
+		chromedp.Evaluate(`localStorage.getItem("token") || ""`, &token),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status, token
+}
+
+func TestBrowserAgentsDontShareSessions(t *testing.T) {
+	pool := testBrowserPool(t, 0)
+	server := sessionSite(t)
+
+	signedIn := NewBrowserExecutor(pool, BrowserOptions{})
+	defer signedIn.Close()
+	other := NewBrowserExecutor(pool, BrowserOptions{})
+	defer other.Close()
+
+	browse(t, signedIn, models.GeminiDecisionResponse{Action: "visit"}, server.URL+"/login")
+	if status, token := session(t, signedIn.ctx, server); status != "Signed in as alice" || token != "alice" {
+		t.Fatalf("after logging in: %q with token %q, want signed in as alice", status, token)
+	}
+	if status, token := session(t, other.ctx, server); status != "Signed out" || token != "" {
+		t.Errorf("another agent: %q with token %q, want signed out", status, token)
+	}
+}
+
+// A warm tab given back by a signed in agent starts the next one signed out
+func TestBrowserRecycledTabStartsSignedOut(t *testing.T) {
+	pool := testBrowserPool(t, 1)
+	server := sessionSite(t)
+
+	var tab *warmTab
+	select {
+	case tab = <-pool.ready:
+	case <-time.After(30 * time.Second):
+		t.Fatal("no warm tab was started")
+	}
+	// Network events are on, as an executor's setup turns them on, so the tab
+	// knows which origins to wipe
+	ctx, cancel := context.WithTimeout(tab.ctx, 30*time.Second)
+	defer cancel()
+	if err := chromedp.Run(ctx, network.Enable(), chromedp.Navigate(server.URL+"/login")); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := session(t, tab.ctx, server); status != "Signed in as alice" {
+		t.Fatalf("after logging in: %q, want signed in as alice", status)
+	}
+
+	// The tab is queued again, and the next agent takes it
+	pool.recycle(tab)
+	next := NewBrowserExecutor(pool, BrowserOptions{})
+	defer next.Close()
+	if chromedp.FromContext(next.ctx).Target.TargetID != chromedp.FromContext(tab.ctx).Target.TargetID {
+		t.Fatal("the next agent didn't get the recycled tab")
+	}
+	if status, token := session(t, next.ctx, server); status != "Signed out" || token != "" {
+		t.Errorf("next agent: %q with token %q, want signed out", status, token)
+	}
+}
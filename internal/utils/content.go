@@ -0,0 +1,230 @@
+package utils
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"swarmtest/internal/models"
+)
+
+// nonHTMLPreviewChars is how much of a textual non-HTML response is shown to the agent
+const nonHTMLPreviewChars = 500
+
+// IsHTMLContentType reports whether a response with this Content-Type should
+// be parsed as HTML. A missing or malformed header is given the benefit of the
+// doubt, as browsers do.
+func IsHTMLContentType(contentType string) bool {
+	mediaType := mediaTypeOf(contentType)
+	return mediaType == "" || mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// ParseResponse parses body as HTML, or for a response whose Content-Type
+// says it is something else, describes it with NonHTMLPage instead of
+// extracting garbage elements from it
+func (p *HTMLParser) ParseResponse(currentURL, contentType, body string) (*models.StrippedPage, error) {
+	if !IsHTMLContentType(contentType) {
+		return NonHTMLPage(currentURL, contentType, body), nil
+	}
+	return p.ParseHTMLString(currentURL, body)
+}
+
+// NonHTMLPage describes a non-HTML response (a PDF, JSON, an image, ...) as a
+// page without elements: its content type, size and, for textual content, a
+// short preview, so the agent can decide what to do with it
+func NonHTMLPage(currentURL, contentType, body string) *models.StrippedPage {
+	mediaType := mediaTypeOf(contentType)
+	text := fmt.Sprintf("This URL returned a %s response of %d bytes, not an HTML page, so there is nothing on it to interact with.", mediaType, len(body))
+	if isTextual(mediaType) && utf8.ValidString(body) {
+		text += " Preview:\n" + truncateString(strings.TrimSpace(body), nonHTMLPreviewChars)
+	} else {
+		text += " It is binary content, such as a download or a media file."
+	}
+
+	return &models.StrippedPage{
+		URL:                 currentURL,
+		Title:               mediaType + " response",
+		ContentType:         mediaType,
+		TextContent:         text,
+		InteractiveElements: []models.Element{},
+		Timestamp:           time.Now(),
+	}
+}
+
+// requireHTML fails for a response that isn't HTML, which has no elements to act on
+func requireHTML(resp *http.Response) error {
+	if contentType := resp.Header.Get("Content-Type"); !IsHTMLContentType(contentType) {
+		return fmt.Errorf("%s is %s, not an HTML page", resp.Request.URL, mediaTypeOf(contentType))
+	}
+	return nil
+}
+
+// mediaTypeOf is contentType without its parameters, lowercased; "" when unparseable
+func mediaTypeOf(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// isTextual reports whether a media type is readable text worth previewing
+func isTextual(mediaType string) bool {
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/x-ndjson":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// ReadBody reads a response body, decoding HTML and text from the charset
+// declared in its Content-Type or <meta charset> (or sniffed, as browsers do)
+// to UTF-8. Other content is returned as is.
+func ReadBody(resp *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return body, err
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if !IsHTMLContentType(contentType) && !isTextual(mediaTypeOf(contentType)) {
+		return body, nil
+	}
+	encoding, name, _ := charset.DetermineEncoding(body, contentType)
+	if name == "utf-8" {
+		return body, nil
+	}
+	decoded, err := encoding.NewDecoder().Bytes(body)
+	if err != nil {
+		return body, nil
+	}
+	return decoded, nil
+}
+
+// decodeContent wraps body to undo a gzip or deflate Content-Encoding; other
+// bodies are returned unchanged
+func decodeContent(contentEncoding string, body io.ReadCloser) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "gzip", "x-gzip":
+		r, err := gzip.NewReader(body)
+		if err == io.EOF {
+			return body, nil // empty body, e.g. a 204
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &decodedBody{Reader: r, body: body}, nil
+	case "deflate":
+		// Meant to be zlib-wrapped, but some servers send raw DEFLATE
+		buffered := bufio.NewReader(body)
+		header, _ := buffered.Peek(2)
+		if len(header) == 0 {
+			return &decodedBody{Reader: buffered, body: body}, nil
+		}
+		if len(header) == 2 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 && header[0]&0x0f == 8 {
+			r, err := zlib.NewReader(buffered)
+			if err != nil {
+				return nil, err
+			}
+			return &decodedBody{Reader: r, body: body}, nil
+		}
+		return &decodedBody{Reader: flate.NewReader(buffered), body: body}, nil
+	}
+	return body, nil
+}
+
+// decodedBody reads decompressed content and closes the original body
+type decodedBody struct {
+	io.Reader
+	body io.ReadCloser
+}
+
+func (b *decodedBody) Close() error {
+	return b.body.Close()
+}
+
+const (
+	// DefaultMaxPageSize caps a page read by missions that don't set max_page_size_bytes
+	DefaultMaxPageSize = 10 << 20
+	// MaxPageSizeLimit bounds max_page_size_bytes
+	MaxPageSizeLimit = 1 << 30
+)
+
+// ErrPageTooLarge is returned reading a response or capturing a DOM larger
+// than the mission's max page size
+var ErrPageTooLarge = errors.New("page too large")
+
+// OversizedPage stands in for a page that exceeded the max page size, so the
+// agent can move on instead of refetching it
+func OversizedPage(currentURL string, maxSize int) *models.StrippedPage {
+	return &models.StrippedPage{
+		URL:                 currentURL,
+		Title:               "Page too large",
+		TextContent:         fmt.Sprintf("This page is larger than the %d byte limit and was not loaded, so there is nothing on it to interact with.", maxSize),
+		InteractiveElements: []models.Element{},
+		Timestamp:           time.Now(),
+	}
+}
+
+// pageSizeTransport fails reads of response bodies, after decompression,
+// beyond max bytes
+type pageSizeTransport struct {
+	next http.RoundTripper
+	max  int
+}
+
+func (t *pageSizeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.ContentLength > int64(t.max) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %w (%d bytes, limit %d)", req.URL, ErrPageTooLarge, resp.ContentLength, t.max)
+	}
+	resp.Body = &limitedBody{body: resp.Body, remaining: int64(t.max)}
+	return resp, nil
+}
+
+// limitedBody reads at most remaining bytes, failing with ErrPageTooLarge
+// rather than truncating when the body has more
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrPageTooLarge
+	}
+	// Read one byte past the limit to tell a body of exactly the limit from a larger one
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), ErrPageTooLarge
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
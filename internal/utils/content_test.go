@@ -0,0 +1,239 @@
+package utils
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"swarmtest/internal/models"
+)
+
+func TestIsHTMLContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"text/html", true},
+		{"text/html; charset=Shift_JIS", true},
+		{"TEXT/HTML", true},
+		{"application/xhtml+xml", true},
+		{"", true},
+		{"not a media type;;", true},
+		{"application/json", false},
+		{"application/pdf", false},
+		{"image/png", false},
+		{"text/plain", false},
+	}
+	for _, tt := range tests {
+		if got := IsHTMLContentType(tt.contentType); got != tt.want {
+			t.Errorf("IsHTMLContentType(%q) = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+}
+
+// downloadSite links to responses of several content types
+func downloadSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	serve := func(contentType, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			io.WriteString(w, body)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", serve("text/html; charset=utf-8", `<html><body>`+
+		`<a id="pdf" href="/report.pdf">Report</a><a id="json" href="/api/items">Items</a>`+
+		`<a id="image" href="/logo.png">Logo</a></body></html>`))
+	// Binary bodies that goquery would happily parse into garbage text and elements
+	mux.HandleFunc("/report.pdf", serve("application/pdf", "%PDF-1.7\n<a href=\"/x\">\x00\xff\xfe stream</a>"))
+	mux.HandleFunc("/api/items", serve("application/json", `{"items": [{"id": 1, "name": "<button>widget</button>"}]}`))
+	mux.HandleFunc("/logo.png", serve("image/png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR<input name=q>"))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNonHTMLResponses(t *testing.T) {
+	server := downloadSite(t)
+	executor, err := NewActionExecutor(NewHTTPClientFactory(HTTPClientOptions{}), server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		link      string
+		mediaType string
+		want      string // in the text shown to the agent
+	}{
+		{"a#pdf", "application/pdf", "It is binary content"},
+		{"a#json", "application/json", `Preview:` + "\n" + `{"items": [{"id": 1, "name": "<button>widget</button>"}]}`},
+		{"a#image", "image/png", "It is binary content"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mediaType, func(t *testing.T) {
+			result := executor.ExecuteAction(context.Background(), models.GeminiDecisionResponse{Action: "click", Selector: tt.link}, server.URL+"/")
+			if result.Error != nil {
+				t.Fatalf("click %s: %v", tt.link, result.Error)
+			}
+			page, err := NewHTMLParser().ParseResponse(result.NewURL, result.ContentType, result.HTML)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page.InteractiveElements) != 0 {
+				t.Errorf("extracted %d elements from a %s response", len(page.InteractiveElements), tt.mediaType)
+			}
+			if page.ContentType != tt.mediaType {
+				t.Errorf("content type = %q, want %q", page.ContentType, tt.mediaType)
+			}
+			if !strings.Contains(page.TextContent, "not an HTML page") || !strings.Contains(page.TextContent, tt.want) {
+				t.Errorf("text = %q, want it to describe the response with %q", page.TextContent, tt.want)
+			}
+
+			// There is nothing to act on in the response
+			result = executor.ExecuteAction(context.Background(), models.GeminiDecisionResponse{Action: "click", Selector: "a"}, result.NewURL)
+			if result.Error == nil || !strings.Contains(result.Error.Error(), "is "+tt.mediaType+", not an HTML page") {
+				t.Errorf("click on the response: error = %v, want it not an HTML page", result.Error)
+			}
+		})
+	}
+}
+
+func TestNonHTMLPagePreviewIsTruncated(t *testing.T) {
+	body := `{"data": "` + strings.Repeat("x", 2*nonHTMLPreviewChars) + `"}`
+	page := NonHTMLPage("http://example.test/api", "application/json; charset=utf-8", body)
+	if page.ContentType != "application/json" {
+		t.Errorf("content type = %q, want it without parameters", page.ContentType)
+	}
+	_, preview, _ := strings.Cut(page.TextContent, "Preview:\n")
+	if len(preview) != nonHTMLPreviewChars+len("...") || !strings.HasSuffix(preview, "...") {
+		t.Errorf("preview is %d bytes, want %d and an ellipsis", len(preview), nonHTMLPreviewChars)
+	}
+}
+
+// fetchPage gets url with a mission's HTTP client and parses it as agents do
+func fetchPage(t *testing.T, url string) *models.StrippedPage {
+	t.Helper()
+	resp, err := NewHTTPClientFactory(HTTPClientOptions{}).Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ReadBody(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := NewHTMLParser().ParseResponse(url, resp.Header.Get("Content-Type"), string(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+const compressedPage = `<html><head><title>Compressed</title></head><body><button id="go">Continue</button></body></html>`
+
+func TestDecompressResponses(t *testing.T) {
+	compress := func(w io.Writer, encoding string) io.WriteCloser {
+		switch encoding {
+		case "gzip":
+			return gzip.NewWriter(w)
+		case "deflate":
+			return zlib.NewWriter(w)
+		}
+		fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return fw
+	}
+	tests := []struct {
+		name     string
+		encoding string // the Content-Encoding sent
+		writer   string // how the body is compressed
+	}{
+		{"gzip", "gzip", "gzip"},
+		{"x-gzip", "x-gzip", "gzip"},
+		{"zlib deflate", "deflate", "deflate"},
+		{"raw deflate", "deflate", "raw"},
+		{"uncompressed", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var accepted string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				accepted = r.Header.Get("Accept-Encoding")
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				if tt.encoding == "" {
+					io.WriteString(w, compressedPage)
+					return
+				}
+				w.Header().Set("Content-Encoding", tt.encoding)
+				cw := compress(w, tt.writer)
+				io.WriteString(cw, compressedPage)
+				cw.Close()
+			}))
+			defer server.Close()
+
+			page := fetchPage(t, server.URL)
+			if accepted != "gzip, deflate" {
+				t.Errorf("Accept-Encoding = %q, want gzip, deflate", accepted)
+			}
+			if page.Title != "Compressed" || len(page.InteractiveElements) != 1 || page.InteractiveElements[0].Text != "Continue" {
+				t.Errorf("page = %q with %v, want the decompressed page", page.Title, page.InteractiveElements)
+			}
+		})
+	}
+}
+
+// shiftJIS is "こんにちは" and "ログイン" in Shift-JIS
+const (
+	shiftJISGreeting = "\x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd"
+	shiftJISLogin    = "\x83\x8d\x83\x4f\x83\x43\x83\x93"
+)
+
+func TestCharsetDecoding(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		head        string
+	}{
+		{"from Content-Type", "text/html; charset=Shift_JIS", ""},
+		{"from meta charset", "text/html", `<meta charset="shift_jis">`},
+		{"from meta http-equiv", "text/html", `<meta http-equiv="Content-Type" content="text/html; charset=Shift_JIS">`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Header().Set("Content-Encoding", "gzip")
+				gw := gzip.NewWriter(w)
+				io.WriteString(gw, `<html><head>`+tt.head+`<title>`+shiftJISGreeting+`</title></head>`+
+					`<body><button id="login">`+shiftJISLogin+`</button></body></html>`)
+				gw.Close()
+			}))
+			defer server.Close()
+
+			page := fetchPage(t, server.URL)
+			if page.Title != "こんにちは" {
+				t.Errorf("title = %q, want こんにちは", page.Title)
+			}
+			if len(page.InteractiveElements) != 1 || page.InteractiveElements[0].Text != "ログイン" {
+				t.Errorf("elements = %v, want the ログイン button", page.InteractiveElements)
+			}
+		})
+	}
+}
+
+// Non-text content is left as the server sent it
+func TestReadBodyLeavesBinaryContent(t *testing.T) {
+	const png = "\x89PNG\r\n\x1a\n\x82\xb1"
+	resp := &http.Response{
+		Header: http.Header{"Content-Type": {"image/png"}},
+		Body:   io.NopCloser(strings.NewReader(png)),
+	}
+	body, err := ReadBody(resp)
+	if err != nil || string(body) != png {
+		t.Errorf("ReadBody() = %q, %v, want the bytes unchanged", body, err)
+	}
+}
@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"swarmtest/internal/models"
+)
+
+const (
+	// DefaultCrawlMaxDepth is how many links away from the target a crawl goes
+	DefaultCrawlMaxDepth = 3
+	// DefaultCrawlMaxPages caps the pages one crawl mission visits
+	DefaultCrawlMaxPages = 500
+)
+
+// Crawl traversal orders
+const (
+	CrawlBFS = "bfs"
+	CrawlDFS = "dfs"
+)
+
+// CrawlTarget is a page handed to a crawling agent
+type CrawlTarget struct {
+	URL   string
+	Depth int
+}
+
+// Crawler is the frontier and visited set a crawl mission's agents share.
+// Each discovered URL is handed out once; the crawl is done when the frontier
+// is empty and no agent is still fetching a page that could add to it.
+type Crawler struct {
+	strategy string
+	maxDepth int
+	maxPages int
+
+	mu        sync.Mutex
+	frontier  []CrawlTarget
+	seen      map[string]bool // queued or visited
+	pages     map[string]*models.SitemapPage
+	order     []string // visited URLs in visit order
+	inFlight  int
+	truncated bool
+	// changed is closed and replaced whenever the frontier may have work
+	changed chan struct{}
+	done    chan struct{}
+	closed  bool
+}
+
+// NewCrawler creates a crawl from startURL. Zero bounds use the defaults.
+func NewCrawler(startURL, strategy string, maxDepth, maxPages int) *Crawler {
+	if strategy == "" {
+		strategy = CrawlBFS
+	}
+	if maxDepth <= 0 {
+		maxDepth = DefaultCrawlMaxDepth
+	}
+	if maxPages <= 0 {
+		maxPages = DefaultCrawlMaxPages
+	}
+
+	c := &Crawler{
+		strategy: strategy,
+		maxDepth: maxDepth,
+		maxPages: maxPages,
+		seen:     make(map[string]bool),
+		pages:    make(map[string]*models.SitemapPage),
+		changed:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	c.enqueue(CrawlTarget{URL: startURL})
+	return c
+}
+
+// Restore resumes from a saved sitemap: its pages count as visited and their
+// links not yet visited are queued again
+func (c *Crawler) Restore(sitemap *models.Sitemap) {
+	if len(sitemap.Pages) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.frontier = nil
+	c.seen = make(map[string]bool, len(sitemap.Pages))
+	for i := range sitemap.Pages {
+		page := sitemap.Pages[i]
+		c.seen[page.URL] = true
+		c.pages[page.URL] = &page
+		c.order = append(c.order, page.URL)
+	}
+	for _, u := range c.order {
+		page := c.pages[u]
+		for _, link := range page.Links {
+			if page.Depth < c.maxDepth {
+				c.enqueue(CrawlTarget{URL: link, Depth: page.Depth + 1})
+			}
+		}
+	}
+	c.finishIfIdle()
+}
+
+// enqueue adds an unseen target to the frontier while the page budget lasts.
+// Callers hold mu.
+func (c *Crawler) enqueue(t CrawlTarget) {
+	if c.seen[t.URL] {
+		return
+	}
+	if len(c.seen) >= c.maxPages {
+		c.truncated = true
+		return
+	}
+	c.seen[t.URL] = true
+	c.frontier = append(c.frontier, t)
+}
+
+// Next hands out the next page to crawl, waiting while the frontier is empty
+// but other agents may still add to it. It returns false once the crawl is
+// done or ctx ends.
+func (c *Crawler) Next(ctx context.Context) (CrawlTarget, bool) {
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return CrawlTarget{}, false
+		}
+		if n := len(c.frontier); n > 0 {
+			var t CrawlTarget
+			if c.strategy == CrawlDFS {
+				t, c.frontier = c.frontier[n-1], c.frontier[:n-1]
+			} else {
+				t, c.frontier = c.frontier[0], c.frontier[1:]
+			}
+			c.inFlight++
+			c.mu.Unlock()
+			return t, true
+		}
+		changed := c.changed
+		c.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return CrawlTarget{}, false
+		}
+	}
+}
+
+// Record stores the outcome of fetching t and queues the same-host links found
+// on it. statusCode is 0 and links nil when the fetch failed.
+func (c *Crawler) Record(t CrawlTarget, statusCode int, fetchErr error, links []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	page := &models.SitemapPage{URL: t.URL, Depth: t.Depth, StatusCode: statusCode, Links: links}
+	if page.Links == nil {
+		page.Links = []string{}
+	}
+	if fetchErr != nil {
+		page.Error = fetchErr.Error()
+	}
+	c.pages[t.URL] = page
+	c.order = append(c.order, t.URL)
+	c.inFlight--
+
+	if t.Depth < c.maxDepth {
+		for _, link := range links {
+			c.enqueue(CrawlTarget{URL: link, Depth: t.Depth + 1})
+		}
+	}
+	c.finishIfIdle()
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// finishIfIdle ends the crawl when nothing is queued or being fetched. Callers hold mu.
+func (c *Crawler) finishIfIdle() {
+	if c.closed || len(c.frontier) > 0 || c.inFlight > 0 {
+		return
+	}
+	c.closed = true
+	close(c.done)
+}
+
+// Done is closed when every reachable page within the bounds has been crawled
+func (c *Crawler) Done() <-chan struct{} {
+	return c.done
+}
+
+// Sitemap returns the URL graph crawled so far
+func (c *Crawler) Sitemap(missionID string) *models.Sitemap {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pages := make([]models.SitemapPage, 0, len(c.order))
+	for _, u := range c.order {
+		pages = append(pages, *c.pages[u])
+	}
+	return &models.Sitemap{
+		MissionID: missionID,
+		Pages:     pages,
+		Truncated: c.truncated,
+		UpdatedAt: time.Now(),
+	}
+}
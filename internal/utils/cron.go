@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronField is one field of a cron expression: the values it admits, and
+// whether it was restricted at all ("*" isn't)
+type cronField struct {
+	values     map[int]bool
+	restricted bool
+}
+
+// CronSchedule is a parsed five-field cron expression (minute hour
+// day-of-month month day-of-week), evaluated in UTC
+type CronSchedule struct {
+	minute, hour, dom, month, dow cronField
+}
+
+// cronBounds are the allowed ranges of the five fields, in order
+var cronBounds = [5]struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 0 and 7 are both Sunday
+}
+
+// cronSearchLimit is how far ahead Next looks before deciding an expression
+// never fires, e.g. "0 0 30 2 *"
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// ParseCron parses a standard five-field cron expression. Each field is "*",
+// a value, a range "a-b", or a list of those, and may carry a "/step".
+func ParseCron(expr string) (*CronSchedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("cron expression must have 5 fields (minute hour day-of-month month day-of-week), got %d", len(parts))
+	}
+
+	var fields [5]cronField
+	for i, part := range parts {
+		f, err := parseCronField(part, cronBounds[i].min, cronBounds[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("cron %s field %q: %w", cronBounds[i].name, part, err)
+		}
+		fields[i] = f
+	}
+	if fields[4].values[7] {
+		fields[4].values[0] = true
+	}
+
+	s := &CronSchedule{minute: fields[0], hour: fields[1], dom: fields[2], month: fields[3], dow: fields[4]}
+	if s.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("cron expression never fires")
+	}
+	return s, nil
+}
+
+func parseCronField(field string, min, max int) (cronField, error) {
+	// As in Vixie cron, a field starting with "*" (including "*/n") counts as unrestricted
+	f := cronField{values: make(map[int]bool), restricted: !strings.HasPrefix(field, "*")}
+	for _, item := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return f, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return f, fmt.Errorf("invalid value %q", from)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return f, fmt.Errorf("invalid value %q", to)
+				}
+			} else if hasStep {
+				// "a/n" means from a to the end of the range
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return f, fmt.Errorf("values must be between %d and %d", min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			f.values[v] = true
+		}
+	}
+	return f, nil
+}
+
+// Next returns the first time after t, to the minute, that the schedule
+// fires, or the zero time if it doesn't within the next five years
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(cronSearchLimit)
+
+	for t.Before(limit) {
+		if !s.month.values[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.hour.values[t.Hour()] {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if !s.minute.values[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches applies cron's day rule: when both day fields are restricted a
+// day matching either one fires
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	dom, dow := s.dom.values[t.Day()], s.dow.values[int(t.Weekday())]
+	if s.dom.restricted && s.dow.restricted {
+		return dom || dow
+	}
+	return dom && dow
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{"* * * *", "must have 5 fields"},
+		{"* * * * * *", "must have 5 fields"},
+		{"60 * * * *", "minute field"},
+		{"* 24 * * *", "hour field"},
+		{"* * 0 * *", "day of month field"},
+		{"* * * 13 *", "month field"},
+		{"* * * * 8", "day of week field"},
+		{"5-1 * * * *", "between 0 and 59"},
+		{"*/0 * * * *", "invalid step"},
+		{"*/x * * * *", "invalid step"},
+		{"a * * * *", "invalid value"},
+		{"1-b * * * *", "invalid value"},
+		{"0 0 30 2 *", "never fires"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseCron(tt.expr); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ParseCron(%q) error = %v, want one saying %q", tt.expr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2026, 1, 14, 10, 30, 45, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"* * * * *", "2026-01-14T10:31"},
+		{"30 10 * * *", "2026-01-15T10:30"}, // strictly after from
+		{"*/15 * * * *", "2026-01-14T10:45"},
+		{"5/20 * * * *", "2026-01-14T10:45"},
+		{"0 9-17 * * *", "2026-01-14T11:00"},
+		{"0 8,20 * * *", "2026-01-14T20:00"},
+		{"0 0 1 * *", "2026-02-01T00:00"},
+		{"0 0 * 3 *", "2026-03-01T00:00"},
+		{"0 12 * * 1", "2026-01-19T12:00"},
+		{"0 12 * * 0", "2026-01-18T12:00"},
+		{"0 12 * * 7", "2026-01-18T12:00"}, // 7 is Sunday too
+		{"0 12 * * 1-5", "2026-01-14T12:00"},
+		// Both day fields restricted: either one matches
+		{"0 0 20 * 5", "2026-01-16T00:00"},
+		{"0 0 15 * 1", "2026-01-15T00:00"},
+		{"0 0 29 2 *", "2028-02-29T00:00"},
+		{"59 23 31 12 *", "2026-12-31T23:59"},
+	}
+	for _, tt := range tests {
+		s, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := s.Next(from).Format("2006-01-02T15:04"); got != tt.want {
+			t.Errorf("%q: Next() = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestCronNextInUTC(t *testing.T) {
+	s, err := ParseCron("0 9 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	// 08:30 in Paris is 07:30 UTC, so 09:00 UTC is the same day
+	next := s.Next(time.Date(2026, 1, 14, 8, 30, 0, 0, paris))
+	if want := time.Date(2026, 1, 14, 9, 0, 0, 0, time.UTC); !next.Equal(want) || next.Location() != time.UTC {
+		t.Errorf("Next() = %s, want %s", next, want)
+	}
+}
@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+	"golang.org/x/net/html"
+	"swarmtest/internal/models"
+)
+
+const (
+	// maxFrames and maxFrameDepth bound the iframes captured with a page
+	maxFrames     = 10
+	maxFrameDepth = 3
+	// frameCaptureTimeout bounds reading one frame's document
+	frameCaptureTimeout = 2 * time.Second
+
+	// frameAttr and frameOriginAttr mark a captured frame's content in the page HTML
+	frameAttr       = "data-swarm-frame"
+	frameOriginAttr = "data-swarm-frame-origin"
+)
+
+// capturedFrame is the body of an iframe's document, labelled for actions to name
+type capturedFrame struct {
+	label  string
+	origin string
+	body   string
+}
+
+// captureFrames reads the documents of the page's iframes, nested ones too,
+// remembering each iframe under its label for actions routed into it. Frames
+// of any origin are read through CDP, which same-origin rules don't apply to;
+// size is the length captured so far, checked against the max page size.
+func (e *BrowserExecutor) captureFrames(ctx context.Context, size int) ([]capturedFrame, error) {
+	e.frames = make(map[string]*cdp.Node)
+	var frames []capturedFrame
+
+	var walk func(parent *cdp.Node, depth int) error
+	walk = func(parent *cdp.Node, depth int) error {
+		var iframes []*cdp.Node
+		query := []chromedp.QueryOption{chromedp.ByQueryAll, chromedp.AtLeast(0)}
+		if parent != nil {
+			query = append(query, chromedp.FromNode(parent))
+		}
+		if err := chromedp.Nodes("iframe, frame", &iframes, query...).Do(ctx); err != nil {
+			return err
+		}
+
+		for _, iframe := range iframes {
+			if len(frames) >= maxFrames {
+				return nil
+			}
+			if iframe.ContentDocument == nil {
+				continue // not loaded
+			}
+			var body string
+			frameCtx, cancel := context.WithTimeout(ctx, frameCaptureTimeout)
+			err := chromedp.OuterHTML("body", &body, chromedp.ByQuery, chromedp.FromNode(iframe)).Do(frameCtx)
+			cancel()
+			if err != nil {
+				continue
+			}
+
+			size += len(body)
+			if e.maxPageSize > 0 && size > e.maxPageSize {
+				return fmt.Errorf("DOM with frames: %w (%d characters, limit %d)", ErrPageTooLarge, size, e.maxPageSize)
+			}
+			label := fmt.Sprintf("frame_%d", len(frames)+1)
+			e.frames[label] = iframe
+			frames = append(frames, capturedFrame{label: label, origin: frameOrigin(iframe), body: body})
+
+			if depth+1 < maxFrameDepth {
+				if err := walk(iframe, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return frames, walk(nil, 0)
+}
+
+// frameOrigin is the scheme, host and port of the document in an iframe, or
+// "" for one without its own URL (about:blank, srcdoc), which shares the page's
+func frameOrigin(iframe *cdp.Node) string {
+	u, err := url.Parse(iframe.ContentDocument.DocumentURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// embedFrames appends the frames' content to the page HTML, each in a
+// <template> marked with its label and origin, so the parser extracts their
+// elements along with the page's but can tell which frame holds each
+func embedFrames(pageHTML string, frames []capturedFrame) string {
+	if len(frames) == 0 {
+		return pageHTML
+	}
+	var b strings.Builder
+	for _, f := range frames {
+		fmt.Fprintf(&b, `<template %s="%s" %s="%s">%s</template>`,
+			frameAttr, html.EscapeString(f.label), frameOriginAttr, html.EscapeString(f.origin), f.body)
+	}
+	if i := strings.LastIndex(pageHTML, "</body>"); i >= 0 {
+		return pageHTML[:i] + b.String() + pageHTML[i:]
+	}
+	return pageHTML + b.String()
+}
+
+// isFrameRoot reports whether n holds an embedded frame's content
+func isFrameRoot(n *html.Node) bool {
+	if n.Type != html.ElementNode || n.Data != "template" {
+		return false
+	}
+	for _, attr := range n.Attr {
+		if attr.Key == frameAttr {
+			return true
+		}
+	}
+	return false
+}
+
+// frameOf is the label and origin of the embedded frame holding n, if any
+func frameOf(n *html.Node) (label, origin string) {
+	for ; n != nil; n = n.Parent {
+		if !isFrameRoot(n) {
+			continue
+		}
+		for _, attr := range n.Attr {
+			switch attr.Key {
+			case frameAttr:
+				label = attr.Val
+			case frameOriginAttr:
+				origin = attr.Val
+			}
+		}
+		return label, origin
+	}
+	return "", ""
+}
+
+// queryOptions find the element an action targets: in the iframe its frame
+// names, or else in the page and its shadow roots
+func (e *BrowserExecutor) queryOptions(action models.GeminiDecisionResponse) ([]chromedp.QueryOption, error) {
+	if action.Frame == "" {
+		return []chromedp.QueryOption{byShadowPath(action.Selector)}, nil
+	}
+	iframe, ok := e.frames[action.Frame]
+	if !ok {
+		return nil, fmt.Errorf("frame %s is not on the page", action.Frame)
+	}
+	return []chromedp.QueryOption{chromedp.ByQuery, chromedp.FromNode(iframe)}, nil
+}
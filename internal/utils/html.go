@@ -2,6 +2,8 @@ package utils
 
 import (
 	"io"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -10,14 +12,48 @@ import (
 	"swarmtest/internal/models"
 )
 
+// ElementTypes are the element types a parser can be limited to; "input"
+// covers file inputs too
+var ElementTypes = map[string]bool{
+	"link":   true,
+	"button": true,
+	"input":  true,
+	"form":   true,
+}
+
+// MaxMetaRefreshDelay is the longest meta refresh treated as a redirect;
+// slower ones are periodic reloads or pages meant to be read first
+const MaxMetaRefreshDelay = 10 * time.Second
+
 // HTMLParser parses HTML and extracts interactive elements
-type HTMLParser struct{}
+type HTMLParser struct {
+	// types limits extraction to these ElementTypes; nil extracts all of them
+	types map[string]bool
+}
 
 // NewHTMLParser creates a new HTML parser
 func NewHTMLParser() *HTMLParser {
 	return &HTMLParser{}
 }
 
+// NewHTMLParserFor creates an HTML parser that only extracts the given
+// ElementTypes, or all of them when types is empty
+func NewHTMLParserFor(types []string) *HTMLParser {
+	if len(types) == 0 {
+		return NewHTMLParser()
+	}
+	p := &HTMLParser{types: make(map[string]bool, len(types))}
+	for _, t := range types {
+		p.types[t] = true
+	}
+	return p
+}
+
+// extracts reports whether the parser extracts elements of this type
+func (p *HTMLParser) extracts(elementType string) bool {
+	return p.types == nil || p.types[elementType]
+}
+
 // ParseHTML parses HTML from an io.Reader
 func (p *HTMLParser) ParseHTML(currentURL string, r io.Reader) (*models.StrippedPage, error) {
 	doc, err := goquery.NewDocumentFromReader(r)
@@ -58,33 +94,98 @@ func (p *HTMLParser) parseDocument(currentURL string, doc *goquery.Document) (*m
 		}
 	}
 
+	page.MetaRefresh = metaRefreshTarget(currentURL, doc)
+
 	// Extract interactive elements
 	page.InteractiveElements = p.extractElements(doc)
 
 	return page, nil
 }
 
+// metaRefreshTarget returns the absolute URL a <meta http-equiv="refresh">
+// sends the page on to within MaxMetaRefreshDelay, or "" if it has none. A
+// refresh that only reloads the page doesn't count.
+func metaRefreshTarget(currentURL string, doc *goquery.Document) string {
+	var content string
+	doc.Find("meta[http-equiv]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if !strings.EqualFold(strings.TrimSpace(s.AttrOr("http-equiv", "")), "refresh") {
+			return true
+		}
+		content = s.AttrOr("content", "")
+		return false
+	})
+
+	// content is "<seconds>[;, ][url=]<url>", the URL optionally quoted
+	content = strings.TrimSpace(content)
+	end := strings.IndexAny(content, ";, ")
+	if end < 0 {
+		return ""
+	}
+	delay, err := strconv.ParseFloat(content[:end], 64)
+	if err != nil || delay < 0 || delay > MaxMetaRefreshDelay.Seconds() {
+		return ""
+	}
+	target := strings.TrimLeft(content[end:], ";, ")
+	if len(target) > 3 && strings.EqualFold(target[:3], "url") {
+		if rest := strings.TrimSpace(target[3:]); strings.HasPrefix(rest, "=") {
+			target = strings.TrimSpace(rest[1:])
+		}
+	}
+	target = strings.Trim(target, `'"`)
+	if target == "" {
+		return ""
+	}
+
+	base, err := url.Parse(currentURL)
+	if err != nil {
+		return ""
+	}
+	ref, err := url.Parse(target)
+	if err != nil {
+		return ""
+	}
+	resolved := base.ResolveReference(ref)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return ""
+	}
+	resolved.Fragment = ""
+	current := *base
+	current.Fragment = ""
+	if resolved.String() == current.String() {
+		return ""
+	}
+	return resolved.String()
+}
+
 // extractElements extracts all interactive elements from the page
 func (p *HTMLParser) extractElements(doc *goquery.Document) []models.Element {
 	elements := []models.Element{}
 	elementID := 0
+	selectors := newSelectorGenerator()
 
 	// Links and buttons with href or onclick
 	doc.Find("a, button, [onclick], [role='button']").Each(func(i int, s *goquery.Selection) {
-		selector := generateSelector(s)
+		isLink := s.Get(0).Data == "a"
+		if !p.extracts("link") && isLink || !p.extracts("button") && !isLink {
+			return
+		}
+		selector := selectors.selector(s.Get(0))
+		frame, frameOrigin := frameOf(s.Get(0))
 
 		// Check if it's a link
-		if tag := s.Get(0).Data; tag == "a" {
+		if isLink {
 			href, _ := s.Attr("href")
 			text := strings.TrimSpace(s.Text())
 
 			if href != "" || s.HasClass("btn") || s.HasClass("button") {
 				elements = append(elements, models.Element{
-					ID:       generateElementID(elementID),
-					Type:     "link",
-					Text:     truncateString(text, 100),
-					Selector: selector,
-					Href:     href,
+					ID:          generateElementID(elementID),
+					Type:        "link",
+					Text:        truncateString(text, 100),
+					Selector:    selector,
+					Frame:       frame,
+					FrameOrigin: frameOrigin,
+					Href:        href,
 				})
 				elementID++
 			}
@@ -97,10 +198,12 @@ func (p *HTMLParser) extractElements(doc *goquery.Document) []models.Element {
 			}
 
 			elements = append(elements, models.Element{
-				ID:       generateElementID(elementID),
-				Type:     "button",
-				Text:     truncateString(text, 100),
-				Selector: selector,
+				ID:          generateElementID(elementID),
+				Type:        "button",
+				Text:        truncateString(text, 100),
+				Selector:    selector,
+				Frame:       frame,
+				FrameOrigin: frameOrigin,
 			})
 			elementID++
 		}
@@ -108,7 +211,11 @@ func (p *HTMLParser) extractElements(doc *goquery.Document) []models.Element {
 
 	// Input fields
 	doc.Find("input, textarea, select").Each(func(i int, s *goquery.Selection) {
-		selector := generateSelector(s)
+		if !p.extracts("input") {
+			return
+		}
+		selector := selectors.selector(s.Get(0))
+		frame, frameOrigin := frameOf(s.Get(0))
 
 		inputType, _ := s.Attr("type")
 		if inputType == "" {
@@ -123,20 +230,35 @@ func (p *HTMLParser) extractElements(doc *goquery.Document) []models.Element {
 		name, _ := s.Attr("name")
 		placeholder, _ := s.Attr("placeholder")
 
+		// File inputs take the upload action rather than typed text
+		elementType := "input"
+		if strings.EqualFold(inputType, "file") {
+			elementType = "file_input"
+		}
+
+		_, readonly := s.Attr("readonly")
 		elements = append(elements, models.Element{
 			ID:          generateElementID(elementID),
-			Type:        "input",
+			Type:        elementType,
 			Selector:    selector,
+			Frame:       frame,
+			FrameOrigin: frameOrigin,
 			Name:        name,
 			Placeholder: placeholder,
 			InputType:   inputType,
+			Disabled:    controlDisabled(s),
+			ReadOnly:    readonly,
 		})
 		elementID++
 	})
 
 	// Forms
 	doc.Find("form").Each(func(i int, s *goquery.Selection) {
-		selector := generateSelector(s)
+		if !p.extracts("form") {
+			return
+		}
+		selector := selectors.selector(s.Get(0))
+		frame, frameOrigin := frameOf(s.Get(0))
 		action, _ := s.Attr("action")
 		method, _ := s.Attr("method")
 		if method == "" {
@@ -144,10 +266,12 @@ func (p *HTMLParser) extractElements(doc *goquery.Document) []models.Element {
 		}
 
 		elements = append(elements, models.Element{
-			ID:       generateElementID(elementID),
-			Type:     "form",
-			Text:     method + " " + action,
-			Selector: selector,
+			ID:          generateElementID(elementID),
+			Type:        "form",
+			Text:        method + " " + action,
+			Selector:    selector,
+			Frame:       frame,
+			FrameOrigin: frameOrigin,
 		})
 		elementID++
 	})
@@ -155,78 +279,115 @@ func (p *HTMLParser) extractElements(doc *goquery.Document) []models.Element {
 	return elements
 }
 
-// generateSelector generates a simple CSS selector for an element
-func generateSelector(s *goquery.Selection) string {
-	node := s.Get(0)
+// maxSelectorParts bounds how many ancestors a selector names within its scope
+const maxSelectorParts = 3
+
+// selectorGenerator generates the selectors of a document's elements. It
+// remembers the nth-child positions it works out, so the children of a wide
+// list are counted once rather than once per extracted element.
+type selectorGenerator struct {
+	positions map[*html.Node]int
+}
+
+func newSelectorGenerator() *selectorGenerator {
+	return &selectorGenerator{positions: make(map[*html.Node]int)}
+}
+
+// selector generates a simple CSS selector for an element
+func (g *selectorGenerator) selector(node *html.Node) string {
 	if node == nil {
 		return ""
 	}
 
-	var parts []string
+	// Each shadow root the element is inside adds a part, queried from its host
+	var scopes []string
+	for n := node; n != nil; {
+		var sel string
+		sel, n = g.scopeSelector(n)
+		scopes = append([]string{sel}, scopes...)
+	}
+	return strings.Join(scopes, ShadowSeparator)
+}
+
+// scopeSelector generates the selector for node within its document, frame or
+// shadow root, returning the shadow root's host (nil otherwise)
+func (g *selectorGenerator) scopeSelector(node *html.Node) (string, *html.Node) {
+	// Innermost first; the walk carries on past the last part only to find the scope
+	parts := make([]string, 0, maxSelectorParts)
+	stopped := false
 
 	// Walk up the tree to build a selector
-	for n := node; n != nil; n = n.Parent {
-		if n.Type == html.ElementNode {
-			tag := n.Data
-			var classPart, idPart string
+	n := node
+	for ; n != nil && !isShadowRoot(n) && !isFrameRoot(n); n = n.Parent {
+		if n.Type != html.ElementNode || stopped {
+			continue
+		}
+		tag := n.Data
+		var classPart, idPart string
 
-			// Add ID if present
-			for _, attr := range n.Attr {
-				if attr.Key == "id" && attr.Val != "" {
-					idPart = "#" + attr.Val
-					break
-				}
+		// Add ID if present
+		for _, attr := range n.Attr {
+			if attr.Key == "id" && attr.Val != "" {
+				idPart = "#" + attr.Val
+				break
 			}
+		}
 
-			// Add class if no ID
-			if idPart == "" {
-				for _, attr := range n.Attr {
-					if attr.Key == "class" && attr.Val != "" {
-						classes := strings.Fields(attr.Val)
-						if len(classes) > 0 {
-							classPart = "." + strings.Join(classes, ".")
-							break
-						}
+		// Add class if no ID
+		if idPart == "" {
+			for _, attr := range n.Attr {
+				if attr.Key == "class" && attr.Val != "" {
+					classes := strings.Fields(attr.Val)
+					if len(classes) > 0 {
+						classPart = "." + strings.Join(classes, ".")
+						break
 					}
 				}
 			}
+		}
 
-			// Build the part
-			part := tag
-			if idPart != "" {
-				part += idPart
-			} else if classPart != "" {
-				part += classPart
-			} else {
-				// Use nth-child if no class or id
-				if n.Parent != nil {
-					idx := 1
-					for c := n.Parent.FirstChild; c != nil; c = c.NextSibling {
-						if c == n {
-							part = tag + ":nth-child(" + string(rune('0'+idx)) + ")"
-							break
-						}
-						if c.Type == html.ElementNode {
-							idx++
-						}
-					}
-				}
-			}
+		// Build the part
+		part := tag
+		if idPart != "" {
+			part += idPart
+		} else if classPart != "" {
+			part += classPart
+		} else if n.Parent != nil {
+			// Use nth-child if no class or id
+			part = tag + ":nth-child(" + strconv.Itoa(g.position(n)) + ")"
+		}
+		parts = append(parts, part)
 
-			parts = append([]string{part}, parts...)
+		// An ID is unique, and parts further out would be dropped anyway
+		stopped = idPart != "" || len(parts) == maxSelectorParts
+	}
 
-			// If we have an ID, we can stop
-			if idPart != "" {
-				break
-			}
-		}
+	for l, r := 0, len(parts)-1; l < r; l, r = l+1, r-1 {
+		parts[l], parts[r] = parts[r], parts[l]
 	}
 
-	if len(parts) > 3 {
-		parts = parts[len(parts)-3:]
+	// Past a shadow root is its host; a frame's selectors start at its document
+	if n == nil || isFrameRoot(n) {
+		return strings.Join(parts, " "), nil
 	}
+	return strings.Join(parts, " "), n.Parent
+}
 
-	return strings.Join(parts, " ")
+// position is n's nth-child index among its parent's elements, not counting
+// embedded shadow roots and frames. All of the parent's children are indexed
+// on the first lookup.
+func (g *selectorGenerator) position(n *html.Node) int {
+	if pos, ok := g.positions[n]; ok {
+		return pos
+	}
+	idx := 1
+	for c := n.Parent.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && !isShadowRoot(c) && !isFrameRoot(c) {
+			g.positions[c] = idx
+			idx++
+		}
+	}
+	return g.positions[n]
 }
 
 // generateElementID generates a unique element ID
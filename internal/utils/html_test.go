@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"swarmtest/internal/models"
+)
+
+// largeDocument is a synthetic page of a few thousand nodes: wide lists of
+// links and buttons several levels deep, and forms full of inputs
+func largeDocument() string {
+	var b strings.Builder
+	b.WriteString("<html><body><nav><ul>")
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&b, `<li><a href="/nav/%d">Nav %d</a></li>`, i, i)
+	}
+	b.WriteString("</ul></nav><main>")
+	for section := 0; section < 20; section++ {
+		b.WriteString(`<section><div class="card"><div class="body"><ul>`)
+		for i := 0; i < 100; i++ {
+			fmt.Fprintf(&b, `<li><span>Item %d</span><a href="/items/%d-%d">View</a><button>Add</button></li>`, i, section, i)
+		}
+		b.WriteString("</ul></div></div></section>")
+	}
+	for form := 0; form < 10; form++ {
+		fmt.Fprintf(&b, `<form action="/forms/%d" method="post">`, form)
+		for i := 0; i < 20; i++ {
+			fmt.Fprintf(&b, `<label>Field %d<input name="field%d" type="text"></label>`, i, i)
+		}
+		b.WriteString(`<textarea name="notes"></textarea><button type="submit">Send</button></form>`)
+	}
+	b.WriteString("</main></body></html>")
+	return b.String()
+}
+
+func BenchmarkExtractInteractiveElements(b *testing.B) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(largeDocument()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	parser := NewHTMLParser()
+
+	b.ResetTimer()
+	var elements []models.Element
+	for i := 0; i < b.N; i++ {
+		elements = parser.extractElements(doc)
+	}
+	b.ReportMetric(float64(len(elements)), "elements")
+}
+
+func TestNewHTMLParserForTypes(t *testing.T) {
+	const page = `<html><body>
+<a href="/about">About</a>
+<button id="buy">Buy</button>
+<div role="button" onclick="open()">Menu</div>
+<form action="/search" method="get"><input name="q" type="text"><input name="cv" type="file"></form>
+</body></html>`
+	tests := []struct {
+		types []string
+		want  string // element types extracted, in order
+	}{
+		{nil, "link button button input file_input form"},
+		{[]string{}, "link button button input file_input form"},
+		{[]string{"link"}, "link"},
+		{[]string{"button"}, "button button"},
+		{[]string{"input"}, "input file_input"},
+		{[]string{"form"}, "form"},
+		{[]string{"form", "input"}, "input file_input form"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.types), func(t *testing.T) {
+			parsed, err := NewHTMLParserFor(tt.types).ParseHTMLString("http://example.test/", page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, el := range parsed.InteractiveElements {
+				got = append(got, el.Type)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("extracted %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetaRefreshTarget(t *testing.T) {
+	const current = "http://example.test/splash?from=ad#top"
+	tests := []struct {
+		name string
+		meta string
+		want string
+	}{
+		{"relative", `<meta http-equiv="refresh" content="0; url=/home">`, "http://example.test/home"},
+		{"absolute", `<meta http-equiv="refresh" content="0;URL=https://other.test/">`, "https://other.test/"},
+		{"quoted", `<meta http-equiv="refresh" content="1; url='/welcome'">`, "http://example.test/welcome"},
+		{"spaced url", `<meta http-equiv="refresh" content="2, URL = next.html">`, "http://example.test/next.html"},
+		{"without url=", `<meta http-equiv="refresh" content="0 /home">`, "http://example.test/home"},
+		{"header case", `<meta http-equiv="Refresh" content="0;url=/home">`, "http://example.test/home"},
+		{"fragment dropped", `<meta http-equiv="refresh" content="0;url=/home#main">`, "http://example.test/home"},
+		{"at the delay limit", `<meta http-equiv="refresh" content="10;url=/home">`, "http://example.test/home"},
+		{"too slow", `<meta http-equiv="refresh" content="30;url=/home">`, ""},
+		{"reload only", `<meta http-equiv="refresh" content="5">`, ""},
+		{"reloads itself", `<meta http-equiv="refresh" content="0;url=/splash?from=ad#bottom">`, ""},
+		{"not http", `<meta http-equiv="refresh" content="0;url=javascript:alert(1)">`, ""},
+		{"bad delay", `<meta http-equiv="refresh" content="soon;url=/home">`, ""},
+		{"other meta", `<meta http-equiv="content-type" content="0;url=/home">`, ""},
+		{"none", ``, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := NewHTMLParser().ParseHTMLString(current, "<html><head>"+tt.meta+"</head><body>Redirecting</body></html>")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.MetaRefresh != tt.want {
+				t.Errorf("MetaRefresh = %q, want %q", page.MetaRefresh, tt.want)
+			}
+		})
+	}
+}
@@ -1,46 +1,294 @@
 package utils
 
 import (
+	"bytes"
 	"context"
-
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
-	"golang.org/x/net/html"
 	"swarmtest/internal/models"
 )
 
+// DefaultMaxRedirects is how many redirects a client follows when not configured
+const DefaultMaxRedirects = 10
+
+const (
+	// DefaultActionTimeout bounds a single action when the mission doesn't set one
+	DefaultActionTimeout = 30 * time.Second
+	// WaitForTimeout bounds how long a wait_for action waits for its selector
+	WaitForTimeout = 10 * time.Second
+	// waitForPollInterval is how often HTTP mode re-fetches the page while waiting
+	waitForPollInterval = time.Second
+)
+
+// HTTPStatusError is a response whose status marks the request as failed
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("server returned %d", e.StatusCode)
+}
+
+// ErrActionTimeout is returned when an action doesn't finish within its timeout
+var ErrActionTimeout = errors.New("action timed out")
+
+// actionTimeoutError wraps err in ErrActionTimeout when it was caused by ctx's
+// deadline passing
+func actionTimeoutError(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v", ErrActionTimeout, err)
+	}
+	return err
+}
+
+// ErrWaitForTimeout is returned when a wait_for selector doesn't appear in time
+var ErrWaitForTimeout = errors.New("wait_for timed out")
+
+// HTTPClientOptions configures the clients created by an HTTPClientFactory
+type HTTPClientOptions struct {
+	// FollowRedirects chases redirects; when false the 3xx response itself is returned
+	FollowRedirects bool
+	// MaxRedirects caps followed redirects (DefaultMaxRedirects when 0)
+	MaxRedirects int
+	// Proxy routes requests through an HTTP or SOCKS5 proxy instead of the environment's
+	Proxy *url.URL
+	// TLSConfig replaces the default TLS settings, e.g. to present a client certificate
+	TLSConfig *tls.Config
+	// UserAgent replaces the default SwarmTest/1.0 User-Agent on every request
+	UserAgent string
+	// Language is a language tag sent as the Accept-Language of every request
+	Language string
+	// MaxPageSize fails reading a decompressed response body beyond this many
+	// bytes with ErrPageTooLarge (no limit when 0)
+	MaxPageSize int
+	// Authorization is sent as the Authorization header on requests to
+	// AuthOrigin's scheme and host, and nowhere else
+	Authorization string
+	AuthOrigin    *url.URL
+}
+
 // HTTPClientFactory creates a new HTTP client for an agent
-type HTTPClientFactory func() *http.Client
+type HTTPClientFactory func(opts HTTPClientOptions) *http.Client
 
 // NewHTTPClientFactory creates an HTTP client with cookie support
-func NewHTTPClientFactory() *http.Client {
+func NewHTTPClientFactory(opts HTTPClientOptions) *http.Client {
+	client := &http.Client{
+		Timeout:       30 * time.Second,
+		CheckRedirect: redirectPolicy(opts),
+	}
+	if opts.Proxy != nil || opts.TLSConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if opts.Proxy != nil {
+			transport.Proxy = http.ProxyURL(opts.Proxy)
+		}
+		if opts.TLSConfig != nil {
+			transport.TLSClientConfig = opts.TLSConfig
+		}
+		client.Transport = transport
+	}
+	if opts.UserAgent != "" {
+		client.Transport = &headerTransport{next: client.Transport, name: "User-Agent", value: opts.UserAgent}
+	}
+	if opts.Language != "" {
+		client.Transport = &headerTransport{next: client.Transport, name: "Accept-Language", value: AcceptLanguage(opts.Language)}
+	}
+	if opts.Authorization != "" && opts.AuthOrigin != nil {
+		client.Transport = &authTransport{next: client.Transport, origin: opts.AuthOrigin, authorization: opts.Authorization}
+	}
+	client.Transport = &decompressTransport{next: client.Transport}
+	if opts.MaxPageSize > 0 {
+		client.Transport = &pageSizeTransport{next: client.Transport, max: opts.MaxPageSize}
+	}
+
 	jar, err := cookiejar.New(nil)
 	if err != nil {
 		log.Printf("Failed to create cookie jar: %v", err)
-		return &http.Client{
-			Timeout: 30 * time.Second,
+		return client
+	}
+	client.Jar = jar
+	return client
+}
+
+// ParseProxyURL parses a mission's proxy setting. Only http, https and socks5
+// proxies with a host are accepted; an empty setting returns nil.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL")
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("proxy scheme must be http, https or socks5")
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL must include a host")
+	}
+	return u, nil
+}
+
+// ClientTLSConfig builds the TLS settings for a mission, or nil when it uses the
+// defaults. The certificate and key are PEM files on the server.
+func ClientTLSConfig(insecureSkipVerify bool, certFile, keyFile string) (*tls.Config, error) {
+	if !insecureSkipVerify && certFile == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
 		}
+		config.Certificates = []tls.Certificate{cert}
 	}
+	return config, nil
+}
 
-	return &http.Client{
-		Timeout: 30 * time.Second,
-		Jar:     jar,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			// Allow up to 10 redirects
-			if len(via) >= 10 {
-				return fmt.Errorf("too many redirects")
-			}
-			return nil
-		},
+// headerTransport sets one of the agent's headers (User-Agent, Accept-Language)
+// on every request it sends, whatever the caller set
+type headerTransport struct {
+	next  http.RoundTripper
+	name  string
+	value string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.Header.Set(t.name, t.value)
+	return next.RoundTrip(req)
+}
+
+// authTransport authenticates requests to the target's origin. It sees every
+// redirect hop, so credentials are never forwarded to another host.
+type authTransport struct {
+	next          http.RoundTripper
+	origin        *url.URL
+	authorization string
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if !SameOrigin(req.URL, t.origin) {
+		return next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", t.authorization)
+	return next.RoundTrip(req)
+}
+
+// SameOrigin reports whether u has origin's scheme, host and port
+func SameOrigin(u, origin *url.URL) bool {
+	return strings.EqualFold(u.Scheme, origin.Scheme) && strings.EqualFold(hostPort(u), hostPort(origin))
+}
+
+// hostPort is u's host with its scheme's default port made explicit
+func hostPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return u.Host
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+		return u.Host + ":443"
+	case "http":
+		return u.Host + ":80"
 	}
+	return u.Host
+}
+
+// decompressTransport asks for gzip and deflate and decodes either. Go's
+// transport only undoes gzip, and only when it set Accept-Encoding itself.
+type decompressTransport struct {
+	next http.RoundTripper
+}
+
+func (t *decompressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if req.Header.Get("Accept-Encoding") != "" || req.Method == http.MethodHead {
+		return next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := decodeContent(resp.Header.Get("Content-Encoding"), resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("decode %s response: %w", resp.Header.Get("Content-Encoding"), err)
+	}
+	if body != resp.Body {
+		resp.Body = body
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+	}
+	return resp, nil
+}
+
+// redirectPolicy builds a CheckRedirect function for opts
+func redirectPolicy(opts HTTPClientOptions) func(req *http.Request, via []*http.Request) error {
+	if !opts.FollowRedirects {
+		return func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	maxRedirects := opts.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = DefaultMaxRedirects
+	}
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("too many redirects (max %d)", maxRedirects)
+		}
+		return nil
+	}
+}
+
+// redirectTarget returns the resolved Location of a redirect response that was
+// not followed, or "" for any other response
+func redirectTarget(resp *http.Response) string {
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return ""
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return ""
+	}
+	return location.String()
 }
 
 // ExecuteAction executes a single action on a webpage
@@ -48,34 +296,52 @@ type ActionExecutor struct {
 	client  *http.Client
 	parser  *HTMLParser
 	baseURL *url.URL
+	// formContentType forces one of FormEncodings for every POST form; empty follows enctype
+	formContentType string
 }
 
 // NewActionExecutor creates a new action executor
-func NewActionExecutor(client *http.Client, baseURL string) (*ActionExecutor, error) {
+func NewActionExecutor(client *http.Client, baseURL, formContentType string) (*ActionExecutor, error) {
 	parsedURL, err := url.Parse(baseURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid base URL: %w", err)
 	}
 
 	return &ActionExecutor{
-		client:  client,
-		parser:  NewHTMLParser(),
-		baseURL: parsedURL,
+		client:          client,
+		parser:          NewHTMLParser(),
+		baseURL:         parsedURL,
+		formContentType: formContentType,
 	}, nil
 }
 
 // ExecuteActionResult is the result of executing an action
 type ExecuteActionResult struct {
-	HTML       string
-	NewURL     string
-	StatusCode int
-	Error      error
+	HTML string
+	// ContentType is the Content-Type of the response HTML was read from (HTTP only)
+	ContentType string
+	NewURL      string
+	StatusCode  int
+	// RedirectURL is set when a redirect was returned instead of followed;
+	// NewURL is then empty and the agent stays on its current page
+	RedirectURL string
+	// LinkURL is the href a click followed, set even when following it failed
+	LinkURL string
+	// JSErrors and NetworkFailures are what the page reported during the action (browser mode only)
+	JSErrors        []models.JSError
+	NetworkFailures []models.NetworkFailure
+	Error           error
 }
 
-// ExecuteAction executes an action and returns the resulting HTML
+// ExecuteAction executes an action and returns the resulting HTML. When ctx
+// has a deadline and it passes, the error wraps ErrActionTimeout.
 func (e *ActionExecutor) ExecuteAction(ctx context.Context, action models.GeminiDecisionResponse, currentURL string) ExecuteActionResult {
+	result := e.executeAction(ctx, action, currentURL)
+	result.Error = actionTimeoutError(ctx, result.Error)
+	return result
+}
 
-
+func (e *ActionExecutor) executeAction(ctx context.Context, action models.GeminiDecisionResponse, currentURL string) ExecuteActionResult {
 	switch action.Action {
 	case "click":
 		return e.executeClick(ctx, action, currentURL)
@@ -83,6 +349,18 @@ func (e *ActionExecutor) ExecuteAction(ctx context.Context, action models.Gemini
 		return e.executeType(ctx, action, currentURL)
 	case "wait":
 		return e.executeWait()
+	case "wait_for":
+		return e.executeWaitFor(ctx, action, currentURL)
+	case "upload":
+		return e.executeUpload(ctx, action, currentURL)
+	case "key":
+		return ExecuteActionResult{
+			Error: fmt.Errorf("key action is not supported in http execution mode"),
+		}
+	case "hover":
+		return ExecuteActionResult{
+			Error: fmt.Errorf("hover action is not supported in http execution mode"),
+		}
 	case "go_back":
 		return ExecuteActionResult{
 			Error: fmt.Errorf("go_back should be handled by agent, not executor"),
@@ -102,15 +380,22 @@ func (e *ActionExecutor) executeClick(ctx context.Context, action models.GeminiD
 		return ExecuteActionResult{Error: err}
 	}
 	defer resp.Body.Close()
+	if err := requireHTML(resp); err != nil {
+		return ExecuteActionResult{Error: err}
+	}
 
 	// Parse to find element
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	body, err := ReadBody(resp)
+	if err != nil {
+		return ExecuteActionResult{Error: fmt.Errorf("read response: %w", err)}
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
 	if err != nil {
 		return ExecuteActionResult{Error: fmt.Errorf("parse HTML: %w", err)}
 	}
 
 	// Try to find the element and its href
-	element := doc.Find(action.Selector)
+	element := doc.Find(lightSelector(action.Selector))
 	if element.Length() == 0 {
 		return ExecuteActionResult{Error: fmt.Errorf("element not found: %s", action.Selector)}
 	}
@@ -126,22 +411,28 @@ func (e *ActionExecutor) executeClick(ctx context.Context, action models.GeminiD
 		// Follow the link
 		linkResp, err := e.fetchWithRetry(ctx, targetURL)
 		if err != nil {
-			return ExecuteActionResult{Error: err}
+			return ExecuteActionResult{LinkURL: targetURL, Error: err}
 		}
 		defer linkResp.Body.Close()
 
-		bodyBytes, _ := io.ReadAll(linkResp.Body)
+		if target := redirectTarget(linkResp); target != "" {
+			return ExecuteActionResult{StatusCode: linkResp.StatusCode, RedirectURL: target, LinkURL: targetURL}
+		}
+
+		bodyBytes, _ := ReadBody(linkResp)
 		return ExecuteActionResult{
-			HTML:       string(bodyBytes),
-			NewURL:     linkResp.Request.URL.String(),
-			StatusCode: linkResp.StatusCode,
+			HTML:        string(bodyBytes),
+			ContentType: linkResp.Header.Get("Content-Type"),
+			NewURL:      linkResp.Request.URL.String(),
+			StatusCode:  linkResp.StatusCode,
+			LinkURL:     targetURL,
 		}
 	}
 
 	// Check if it's a button within a form
 	form := element.Closest("form")
 	if form.Length() > 0 {
-		return e.submitForm(ctx, form, currentURL, action)
+		return e.submitForm(ctx, form, currentURL, action, "")
 	}
 
 	// Try clicking a button (might need to follow onclick, but for HTTP-only we do best effort)
@@ -158,14 +449,21 @@ func (e *ActionExecutor) executeType(ctx context.Context, action models.GeminiDe
 		return ExecuteActionResult{Error: err}
 	}
 	defer resp.Body.Close()
+	if err := requireHTML(resp); err != nil {
+		return ExecuteActionResult{Error: err}
+	}
 
 	// Parse to find input
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	body, err := ReadBody(resp)
+	if err != nil {
+		return ExecuteActionResult{Error: fmt.Errorf("read response: %w", err)}
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
 	if err != nil {
 		return ExecuteActionResult{Error: fmt.Errorf("parse HTML: %w", err)}
 	}
 
-	input := doc.Find(action.Selector)
+	input := doc.Find(lightSelector(action.Selector))
 	if input.Length() == 0 {
 		return ExecuteActionResult{Error: fmt.Errorf("input not found: %s", action.Selector)}
 	}
@@ -177,7 +475,47 @@ func (e *ActionExecutor) executeType(ctx context.Context, action models.GeminiDe
 	}
 
 	// Submit form with the input value
-	return e.submitForm(ctx, form, currentURL, action)
+	return e.submitForm(ctx, form, currentURL, action, "")
+}
+
+// executeUpload attaches a fixture file to a file input and submits its form
+func (e *ActionExecutor) executeUpload(ctx context.Context, action models.GeminiDecisionResponse, currentURL string) ExecuteActionResult {
+	resp, err := e.fetchWithRetry(ctx, currentURL)
+	if err != nil {
+		return ExecuteActionResult{Error: err}
+	}
+	defer resp.Body.Close()
+	if err := requireHTML(resp); err != nil {
+		return ExecuteActionResult{Error: err}
+	}
+
+	body, err := ReadBody(resp)
+	if err != nil {
+		return ExecuteActionResult{Error: fmt.Errorf("read response: %w", err)}
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return ExecuteActionResult{Error: fmt.Errorf("parse HTML: %w", err)}
+	}
+
+	input := doc.Find(lightSelector(action.Selector))
+	if input.Length() == 0 {
+		return ExecuteActionResult{Error: fmt.Errorf("input not found: %s", action.Selector)}
+	}
+	if inputType, _ := input.Attr("type"); !strings.EqualFold(inputType, "file") {
+		return ExecuteActionResult{Error: fmt.Errorf("not a file input: %s", action.Selector)}
+	}
+	form := input.Closest("form")
+	if form.Length() == 0 {
+		return ExecuteActionResult{Error: fmt.Errorf("no form found for input: %s", action.Selector)}
+	}
+
+	path, cleanup, err := ResolveUploadFile(action.TextInput)
+	defer cleanup()
+	if err != nil {
+		return ExecuteActionResult{Error: err}
+	}
+	return e.submitForm(ctx, form, currentURL, action, path)
 }
 
 // executeWait executes a wait action
@@ -191,8 +529,58 @@ func (e *ActionExecutor) executeWait() ExecuteActionResult {
 	}
 }
 
-// submitForm submits a form
-func (e *ActionExecutor) submitForm(ctx context.Context, form *goquery.Selection, currentURL string, action models.GeminiDecisionResponse) ExecuteActionResult {
+// executeWaitFor re-fetches the current page until action.Selector matches or
+// WaitForTimeout passes. The page that matched is returned so the agent needn't
+// fetch it again.
+func (e *ActionExecutor) executeWaitFor(ctx context.Context, action models.GeminiDecisionResponse, currentURL string) ExecuteActionResult {
+	if action.Selector == "" {
+		return ExecuteActionResult{Error: fmt.Errorf("wait_for requires a selector")}
+	}
+
+	deadline := time.Now().Add(WaitForTimeout)
+	for {
+		resp, err := e.fetchWithRetry(ctx, currentURL)
+		if err != nil {
+			return ExecuteActionResult{Error: err}
+		}
+		body, err := ReadBody(resp)
+		resp.Body.Close()
+		if err != nil {
+			return ExecuteActionResult{Error: fmt.Errorf("read response: %w", err)}
+		}
+		if err := requireHTML(resp); err != nil {
+			return ExecuteActionResult{Error: err}
+		}
+
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+		if err != nil {
+			return ExecuteActionResult{Error: fmt.Errorf("parse HTML: %w", err)}
+		}
+		if doc.Find(lightSelector(action.Selector)).Length() > 0 {
+			return ExecuteActionResult{
+				HTML:        string(body),
+				ContentType: resp.Header.Get("Content-Type"),
+				NewURL:      resp.Request.URL.String(),
+				StatusCode:  resp.StatusCode,
+			}
+		}
+
+		if time.Now().Add(waitForPollInterval).After(deadline) {
+			return ExecuteActionResult{
+				Error: fmt.Errorf("%w after %s: %s not found", ErrWaitForTimeout, WaitForTimeout, action.Selector),
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ExecuteActionResult{Error: ctx.Err()}
+		case <-time.After(waitForPollInterval):
+		}
+	}
+}
+
+// submitForm submits a form. uploadPath, when set, is attached to the file input
+// targeted by action, which makes the body multipart.
+func (e *ActionExecutor) submitForm(ctx context.Context, form *goquery.Selection, currentURL string, action models.GeminiDecisionResponse, uploadPath string) ExecuteActionResult {
 	// Get form action
 	actionURL, _ := form.Attr("action")
 	method, _ := form.Attr("method")
@@ -207,10 +595,14 @@ func (e *ActionExecutor) submitForm(ctx context.Context, form *goquery.Selection
 		return ExecuteActionResult{Error: fmt.Errorf("resolve form action: %w", err)}
 	}
 
-	// Build form data
+	// Build form data; names keeps the fields in document order, as a browser sends them
 	formData := url.Values{}
+	var names []string
+	var fileField string
+	var targetErr error
 
 	// Collect all form inputs
+	selectors := newSelectorGenerator()
 	form.Find("input, select, textarea").Each(func(i int, s *goquery.Selection) {
 		name, _ := s.Attr("name")
 		if name == "" {
@@ -225,8 +617,35 @@ func (e *ActionExecutor) submitForm(ctx context.Context, form *goquery.Selection
 			return
 		}
 
+		// Browsers never submit disabled controls
+		// The parser generated action.Selector from this same HTML
+		targeted := action.Selector != "" && selectors.selector(s.Get(0)) == action.Selector
+		if controlDisabled(s) {
+			if targeted {
+				targetErr = fmt.Errorf("input is disabled: %s", action.Selector)
+			}
+			return
+		}
+
+		// Only the uploaded file input carries a value
+		if inputType == "file" {
+			if uploadPath != "" && targeted {
+				fileField = name
+			}
+			return
+		}
+
 		var value string
-		if action.Selector != "" && generateSelectorForNode(s.Get(0)) == action.Selector {
+		if targeted && action.Action == "type" {
+			// A user can't type into fields they can't see or edit
+			if _, readonly := s.Attr("readonly"); readonly {
+				targetErr = fmt.Errorf("input is read-only: %s", action.Selector)
+				return
+			}
+			if hiddenByMarkup(s) {
+				targetErr = fmt.Errorf("input is not visible: %s", action.Selector)
+				return
+			}
 			value = action.TextInput
 		} else {
 			// Use default value
@@ -248,17 +667,30 @@ func (e *ActionExecutor) submitForm(ctx context.Context, form *goquery.Selection
 			return
 		}
 
+		if _, seen := formData[name]; !seen {
+			names = append(names, name)
+		}
 		formData.Set(name, value)
 	})
 
+	if targetErr != nil {
+		return ExecuteActionResult{Error: targetErr}
+	}
+
 	// Submit form
 	var req *http.Request
 	if method == "POST" {
-		req, err = http.NewRequestWithContext(ctx, "POST", targetURL, strings.NewReader(formData.Encode()))
+		body, contentType, err := e.encodeForm(form, formData, names, fileField, uploadPath)
+		if err != nil {
+			return ExecuteActionResult{Error: err}
+		}
+		req, err = http.NewRequestWithContext(ctx, "POST", targetURL, body)
 		if err != nil {
 			return ExecuteActionResult{Error: fmt.Errorf("create POST request: %w", err)}
 		}
-		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Content-Type", contentType)
+	} else if fileField != "" {
+		return ExecuteActionResult{Error: fmt.Errorf("file upload requires a POST form")}
 	} else {
 		req, err = http.NewRequestWithContext(ctx, "GET", targetURL+"?"+formData.Encode(), nil)
 		if err != nil {
@@ -277,12 +709,160 @@ func (e *ActionExecutor) submitForm(ctx context.Context, form *goquery.Selection
 	}
 	defer resp.Body.Close()
 
-	bodyBytes, _ := io.ReadAll(resp.Body)
+	if target := redirectTarget(resp); target != "" {
+		return ExecuteActionResult{StatusCode: resp.StatusCode, RedirectURL: target}
+	}
+
+	bodyBytes, _ := ReadBody(resp)
 	return ExecuteActionResult{
-		HTML:       string(bodyBytes),
-		NewURL:     resp.Request.URL.String(),
-		StatusCode: resp.StatusCode,
+		HTML:        string(bodyBytes),
+		ContentType: resp.Header.Get("Content-Type"),
+		NewURL:      resp.Request.URL.String(),
+		StatusCode:  resp.StatusCode,
+	}
+}
+
+// controlDisabled reports whether a form control is disabled, directly or by
+// an enclosing disabled fieldset
+func controlDisabled(s *goquery.Selection) bool {
+	if _, disabled := s.Attr("disabled"); disabled {
+		return true
 	}
+	return s.ParentsFiltered("fieldset[disabled]").Length() > 0
+}
+
+// hiddenByMarkup reports whether markup alone hides a control: type=hidden, the
+// hidden attribute, or an inline display:none / visibility:hidden on it or an
+// ancestor. Stylesheets and scripts are out of reach in HTTP mode.
+func hiddenByMarkup(s *goquery.Selection) bool {
+	if inputType, _ := s.Attr("type"); strings.EqualFold(inputType, "hidden") {
+		return true
+	}
+	hidden := false
+	s.Parents().AddBack().EachWithBreak(func(i int, n *goquery.Selection) bool {
+		if _, ok := n.Attr("hidden"); ok {
+			hidden = true
+			return false
+		}
+		style, _ := n.Attr("style")
+		style = strings.ReplaceAll(strings.ToLower(style), " ", "")
+		if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+			hidden = true
+			return false
+		}
+		return true
+	})
+	return hidden
+}
+
+// Form encodings understood by encodeForm
+const (
+	FormEncodingURLEncoded = "application/x-www-form-urlencoded"
+	FormEncodingMultipart  = "multipart/form-data"
+	FormEncodingTextPlain  = "text/plain"
+	FormEncodingJSON       = "application/json"
+)
+
+// FormEncodings are the encodings a mission may force for every form
+var FormEncodings = map[string]bool{
+	FormEncodingURLEncoded: true,
+	FormEncodingMultipart:  true,
+	FormEncodingJSON:       true,
+}
+
+// encodeForm builds a POST body the way a browser would for the form's enctype,
+// unless the mission forces an encoding. An attached file always means multipart.
+// Fields are written in the order of names; urlencoded bodies sort them instead.
+func (e *ActionExecutor) encodeForm(form *goquery.Selection, fields url.Values, names []string, fileField, uploadPath string) (io.Reader, string, error) {
+	encoding := e.formContentType
+	if encoding == "" {
+		enctype, _ := form.Attr("enctype")
+		encoding = strings.ToLower(strings.TrimSpace(enctype))
+	}
+	if fileField != "" {
+		encoding = FormEncodingMultipart
+	}
+
+	switch encoding {
+	case FormEncodingMultipart:
+		return multipartBody(fields, names, fileField, uploadPath)
+	case FormEncodingJSON:
+		return jsonFormBody(fields, names)
+	case FormEncodingTextPlain:
+		var b strings.Builder
+		for _, name := range names {
+			for _, v := range fields[name] {
+				b.WriteString(name + "=" + v + "\r\n")
+			}
+		}
+		return strings.NewReader(b.String()), FormEncodingTextPlain, nil
+	default:
+		// Also the browser fallback for missing or unknown enctypes
+		return strings.NewReader(fields.Encode()), FormEncodingURLEncoded, nil
+	}
+}
+
+// jsonFormBody encodes form fields as a JSON object with its keys in the order
+// of names; repeated fields become arrays
+func jsonFormBody(fields url.Values, names []string) (io.Reader, string, error) {
+	body := &bytes.Buffer{}
+	body.WriteByte('{')
+	for i, name := range names {
+		var value any = fields[name]
+		if len(fields[name]) == 1 {
+			value = fields[name][0]
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, "", fmt.Errorf("encode form: %w", err)
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, "", fmt.Errorf("encode form: %w", err)
+		}
+		if i > 0 {
+			body.WriteByte(',')
+		}
+		body.Write(key)
+		body.WriteByte(':')
+		body.Write(data)
+	}
+	body.WriteByte('}')
+	return body, FormEncodingJSON, nil
+}
+
+// multipartBody encodes form fields in the order of names, plus a file when
+// fileField is set, as multipart/form-data
+func multipartBody(fields url.Values, names []string, fileField, path string) (io.Reader, string, error) {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	for _, name := range names {
+		for _, v := range fields[name] {
+			if err := w.WriteField(name, v); err != nil {
+				return nil, "", fmt.Errorf("encode form: %w", err)
+			}
+		}
+	}
+
+	if fileField != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("open upload file: %w", err)
+		}
+		defer f.Close()
+
+		part, err := w.CreateFormFile(fileField, filepath.Base(path))
+		if err != nil {
+			return nil, "", fmt.Errorf("encode form: %w", err)
+		}
+		if _, err := io.Copy(part, f); err != nil {
+			return nil, "", fmt.Errorf("read upload file: %w", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", fmt.Errorf("encode form: %w", err)
+	}
+	return body, w.FormDataContentType(), nil
 }
 
 // fetchWithRetry fetches a URL with retry logic
@@ -300,21 +880,28 @@ func (e *ActionExecutor) fetchWithRetry(ctx context.Context, urlStr string) (*ht
 		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml")
 
 		resp, err := e.client.Do(req)
+		if errors.Is(err, ErrDisallowedByRobots) {
+			return nil, err
+		}
+		var backoff time.Duration
 		if err != nil {
 			lastErr = err
-			time.Sleep(time.Duration(attempt+1) * time.Second)
-			continue
-		}
-
-		// Check for rate limiting or server errors
-		if resp.StatusCode == 429 || resp.StatusCode >= 500 {
+			backoff = time.Duration(attempt+1) * time.Second
+		} else if resp.StatusCode == 429 || resp.StatusCode >= 500 {
+			// Rate limited or a server error
 			resp.Body.Close()
-			lastErr = fmt.Errorf("server returned %d", resp.StatusCode)
-			time.Sleep(time.Duration(attempt+1) * 2 * time.Second)
-			continue
+			lastErr = &HTTPStatusError{URL: urlStr, StatusCode: resp.StatusCode}
+			backoff = time.Duration(attempt+1) * 2 * time.Second
+		} else {
+			return resp, nil
 		}
 
-		return resp, nil
+		// A timed out or cancelled action stops retrying
+		select {
+		case <-ctx.Done():
+			return nil, lastErr
+		case <-time.After(backoff):
+		}
 	}
 
 	return nil, fmt.Errorf("failed after %d attempts: %w", maxRetries, lastErr)
@@ -338,23 +925,3 @@ func (e *ActionExecutor) resolveURL(base, rel string) (string, error) {
 
 	return baseURL.ResolveReference(relURL).String(), nil
 }
-
-// generateSelectorForNode generates a selector for a node (simplified version)
-func generateSelectorForNode(node *html.Node) string {
-	if node == nil {
-		return ""
-	}
-	// Simplified - in production you'd match the logic in html_parser.go
-	if node.Parent != nil {
-		idx := 1
-		for c := node.Parent.FirstChild; c != nil; c = c.NextSibling {
-			if c == node {
-				return node.Data + ":nth-child(" + fmt.Sprint(idx) + ")"
-			}
-			if c.Type == html.ElementNode {
-				idx++
-			}
-		}
-	}
-	return node.Data
-}
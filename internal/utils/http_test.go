@@ -0,0 +1,337 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"swarmtest/internal/models"
+)
+
+// submission is a form post as the server received it
+type submission struct {
+	contentType string // media type, without parameters
+	fields      map[string]string
+}
+
+// formSite serves page at / and records what is posted to /submit
+func formSite(t *testing.T, page string) (*httptest.Server, <-chan submission) {
+	t.Helper()
+	submissions := make(chan submission, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, page)
+	})
+	mux.HandleFunc("/submit", func(w http.ResponseWriter, r *http.Request) {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		s := submission{contentType: mediaType, fields: make(map[string]string)}
+		switch mediaType {
+		case FormEncodingJSON:
+			json.NewDecoder(r.Body).Decode(&s.fields)
+		case FormEncodingTextPlain:
+			body, _ := io.ReadAll(r.Body)
+			for _, line := range strings.Split(strings.TrimSpace(string(body)), "\r\n") {
+				if name, value, ok := strings.Cut(line, "="); ok {
+					s.fields[name] = value
+				}
+			}
+		case FormEncodingMultipart:
+			r.ParseMultipartForm(1 << 20)
+			for name := range r.MultipartForm.Value {
+				s.fields[name] = r.FormValue(name)
+			}
+		default:
+			r.ParseForm()
+			for name := range r.Form {
+				s.fields[name] = r.Form.Get(name)
+			}
+		}
+		submissions <- s
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, "<html><body>Thanks</body></html>")
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, submissions
+}
+
+// typeInto types text into the element selected on the site's page, which
+// submits its form, and returns what was submitted
+func typeInto(t *testing.T, server *httptest.Server, submissions <-chan submission, formContentType, selector, text string) (submission, ExecuteActionResult) {
+	t.Helper()
+	executor, err := NewActionExecutor(NewHTTPClientFactory(HTTPClientOptions{}), server.URL, formContentType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := executor.ExecuteAction(context.Background(), models.GeminiDecisionResponse{Action: "type", Selector: selector, TextInput: text}, server.URL+"/")
+	select {
+	case s := <-submissions:
+		return s, result
+	default:
+		return submission{}, result
+	}
+}
+
+func TestSubmitFormEncodings(t *testing.T) {
+	tests := []struct {
+		name            string
+		enctype         string
+		formContentType string // forced by the mission
+		want            string
+	}{
+		{"default", "", "", FormEncodingURLEncoded},
+		{"urlencoded", `enctype="application/x-www-form-urlencoded"`, "", FormEncodingURLEncoded},
+		{"multipart", `enctype="multipart/form-data"`, "", FormEncodingMultipart},
+		{"text/plain", `enctype="text/plain"`, "", FormEncodingTextPlain},
+		{"enctype case", `enctype="Multipart/Form-Data"`, "", FormEncodingMultipart},
+		{"unknown enctype", `enctype="application/x-custom"`, "", FormEncodingURLEncoded},
+		{"mission forces JSON", `enctype="multipart/form-data"`, FormEncodingJSON, FormEncodingJSON},
+		{"mission forces multipart", "", FormEncodingMultipart, FormEncodingMultipart},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, submissions := formSite(t, `<html><body><form method="post" action="/submit" `+tt.enctype+`>`+
+				`<input id="q" name="q" type="text"><input name="lang" type="hidden" value="en">`+
+				`<select name="size"><option value="m">Medium</option><option value="l">Large</option></select>`+
+				`<button type="submit">Go</button></form></body></html>`)
+
+			s, result := typeInto(t, server, submissions, tt.formContentType, "input#q", "blue widget")
+			if result.Error != nil {
+				t.Fatalf("type: %v", result.Error)
+			}
+			if s.contentType != tt.want {
+				t.Errorf("submitted as %q, want %q", s.contentType, tt.want)
+			}
+			want := map[string]string{"q": "blue widget", "lang": "en", "size": "m"}
+			for name, value := range want {
+				if s.fields[name] != value {
+					t.Errorf("field %s = %q, want %q (fields %v)", name, s.fields[name], value, s.fields)
+				}
+			}
+		})
+	}
+}
+
+func TestSubmitFormFieldOrder(t *testing.T) {
+	// Document order, which sorting by name would change
+	want := []string{"zip", "name", "age"}
+	for _, enctype := range []string{FormEncodingMultipart, FormEncodingTextPlain, FormEncodingJSON} {
+		t.Run(enctype, func(t *testing.T) {
+			order := make(chan []string, 1)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				io.WriteString(w, `<html><body><form method="post" action="/submit" enctype="`+enctype+`">`+
+					`<input id="zip" name="zip" type="text"><input name="name" type="text" value="Ada">`+
+					`<input name="age" type="text" value="36"></form></body></html>`)
+			})
+			mux.HandleFunc("/submit", func(w http.ResponseWriter, r *http.Request) {
+				order <- postedNames(t, r)
+				io.WriteString(w, "<html><body>Thanks</body></html>")
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			executor, err := NewActionExecutor(NewHTTPClientFactory(HTTPClientOptions{}), server.URL, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := executor.ExecuteAction(context.Background(), models.GeminiDecisionResponse{Action: "type", Selector: "input#zip", TextInput: "90210"}, server.URL+"/")
+			if result.Error != nil {
+				t.Fatalf("type: %v", result.Error)
+			}
+			if got := <-order; !slices.Equal(got, want) {
+				t.Errorf("fields posted in order %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// postedNames returns the names of the fields of a form post in the order they
+// appear in its body
+func postedNames(t *testing.T, r *http.Request) []string {
+	t.Helper()
+	var names []string
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case FormEncodingMultipart:
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			names = append(names, part.FormName())
+		}
+	case FormEncodingTextPlain:
+		body, _ := io.ReadAll(r.Body)
+		for _, line := range strings.Split(strings.TrimSpace(string(body)), "\r\n") {
+			name, _, _ := strings.Cut(line, "=")
+			names = append(names, name)
+		}
+	case FormEncodingJSON:
+		dec := json.NewDecoder(r.Body)
+		dec.Token() // {
+		for dec.More() {
+			key, _ := dec.Token()
+			names = append(names, key.(string))
+			var value any
+			dec.Decode(&value)
+		}
+	default:
+		t.Errorf("unexpected form encoding %q", mediaType)
+	}
+	return names
+}
+
+func TestSubmitFormSkipsUnavailableControls(t *testing.T) {
+	server, submissions := formSite(t, `<html><body><form method="post" action="/submit">`+
+		`<input id="q" name="q" type="text">`+
+		`<input name="coupon" type="text" value="SAVE10" disabled>`+
+		`<fieldset disabled><input name="gift" type="text" value="yes"></fieldset>`+
+		`<input name="account" type="text" value="acct-7" readonly>`+
+		`<input name="token" type="hidden" value="csrf">`+
+		`<input name="newsletter" type="checkbox" value="on">`+
+		`<input name="terms" type="checkbox" value="agreed" checked>`+
+		`</form></body></html>`)
+
+	s, result := typeInto(t, server, submissions, "", "input#q", "widget")
+	if result.Error != nil {
+		t.Fatalf("type: %v", result.Error)
+	}
+	want := map[string]string{"q": "widget", "account": "acct-7", "token": "csrf", "terms": "agreed"}
+	if len(s.fields) != len(want) {
+		t.Errorf("submitted %v, want %v", s.fields, want)
+	}
+	for name, value := range want {
+		if s.fields[name] != value {
+			t.Errorf("field %s = %q, want %q", name, s.fields[name], value)
+		}
+	}
+}
+
+func TestTypeIntoUnavailableControl(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"disabled", `<input id="target" name="target" type="text" disabled>`, "disabled"},
+		{"in a disabled fieldset", `<fieldset disabled><input id="target" name="target" type="text"></fieldset>`, "disabled"},
+		{"readonly", `<input id="target" name="target" type="text" value="fixed" readonly>`, "read-only"},
+		{"hidden attribute", `<input id="target" name="target" type="text" hidden>`, "not visible"},
+		{"display none", `<div style="display: none"><input id="target" name="target" type="text"></div>`, "not visible"},
+		{"visibility hidden", `<input id="target" name="target" type="text" style="visibility:hidden">`, "not visible"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, submissions := formSite(t, `<html><body><form method="post" action="/submit">`+tt.input+`</form></body></html>`)
+
+			s, result := typeInto(t, server, submissions, "", "input#target", "typed")
+			if result.Error == nil || !strings.Contains(result.Error.Error(), tt.wantErr) {
+				t.Errorf("type error = %v, want one saying %q", result.Error, tt.wantErr)
+			}
+			if s.fields != nil {
+				t.Errorf("form was submitted anyway: %v", s.fields)
+			}
+		})
+	}
+}
+
+// Hover menus need a browser; HTTP mode refuses the action
+func TestHoverUnsupportedOverHTTP(t *testing.T) {
+	server, _ := formSite(t, "<html><body><nav id=\"menu\">Products</nav></body></html>")
+	executor, err := NewActionExecutor(NewHTTPClientFactory(HTTPClientOptions{}), server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := executor.ExecuteAction(context.Background(), models.GeminiDecisionResponse{Action: "hover", Selector: "nav#menu"}, server.URL+"/")
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "not supported in http execution mode") {
+		t.Errorf("hover error = %v, want it unsupported in http mode", result.Error)
+	}
+}
+
+// slowSite serves a page whose link and form lead to an endpoint that never
+// answers within a test's patience
+func slowSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, `<html><body><a id="slow" href="/slow">Slow</a>`+
+			`<form method="post" action="/slow"><input id="q" name="q" type="text"></form></body></html>`)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		// Reading the body lets the server notice the client giving up
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExecuteActionTimeout(t *testing.T) {
+	server := slowSite(t)
+	executor, err := NewActionExecutor(NewHTTPClientFactory(HTTPClientOptions{}), server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		action models.GeminiDecisionResponse
+	}{
+		{"click", models.GeminiDecisionResponse{Action: "click", Selector: "a#slow"}},
+		{"form submit", models.GeminiDecisionResponse{Action: "type", Selector: "input#q", TextInput: "widget"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			result := executor.ExecuteAction(ctx, tt.action, server.URL+"/")
+			if !errors.Is(result.Error, ErrActionTimeout) {
+				t.Errorf("error = %v, want ErrActionTimeout", result.Error)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("action returned after %s, want soon after its 200ms timeout", elapsed)
+			}
+		})
+	}
+}
+
+// Only a passed deadline is a timeout; other failures keep their own error
+func TestExecuteActionErrorsWithoutTimeout(t *testing.T) {
+	server := slowSite(t)
+	executor, err := NewActionExecutor(NewHTTPClientFactory(HTTPClientOptions{}), server.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result := executor.ExecuteAction(ctx, models.GeminiDecisionResponse{Action: "click", Selector: "a#missing"}, server.URL+"/")
+	if result.Error == nil || errors.Is(result.Error, ErrActionTimeout) {
+		t.Errorf("missing element error = %v, want a non-timeout error", result.Error)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	result = executor.ExecuteAction(ctx, models.GeminiDecisionResponse{Action: "click", Selector: "a#slow"}, server.URL+"/")
+	if result.Error == nil || errors.Is(result.Error, ErrActionTimeout) {
+		t.Errorf("cancelled action error = %v, want a non-timeout error", result.Error)
+	}
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// MaxCheckedLinks caps how many distinct links one mission's link check visits
+const MaxCheckedLinks = 2000
+
+// LinkChecker hands out each link of a mission once, so agents share the work
+// of checking every link they discover
+type LinkChecker struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// NewLinkChecker creates an empty link checker
+func NewLinkChecker() *LinkChecker {
+	return &LinkChecker{seen: make(map[string]bool)}
+}
+
+// Claim reports whether the caller should check link: it hasn't been claimed
+// before and the mission's MaxCheckedLinks budget isn't spent
+func (c *LinkChecker) Claim(link string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.seen[link] || len(c.seen) >= MaxCheckedLinks {
+		return false
+	}
+	c.seen[link] = true
+	return true
+}
+
+// SameSiteLinks resolves hrefs against pageURL and returns the distinct http(s)
+// links on the page's host, fragments removed
+func SameSiteLinks(pageURL string, hrefs []string) []string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(hrefs))
+	var links []string
+	for _, href := range hrefs {
+		u, err := base.Parse(href)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host != base.Host {
+			continue
+		}
+		u.Fragment = ""
+		link := u.String()
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// CheckLink requests link and returns its status code. A redirect the client
+// doesn't follow counts as a working link.
+func CheckLink(ctx context.Context, client *http.Client, link string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "SwarmTest/1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// languageTagPattern matches BCP 47 language tags the way agents use them: a
+// 2-3 letter language, then optional script, region and variant subtags
+// ("fr", "fr-CA", "zh-Hant-TW")
+var languageTagPattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// ValidLanguageTag reports whether tag is a language tag agents can present
+func ValidLanguageTag(tag string) bool {
+	return len(tag) <= 35 && languageTagPattern.MatchString(tag)
+}
+
+// AcceptLanguage is the Accept-Language header preferring tag, falling back to
+// its bare language ("fr-CA" gives "fr-CA,fr;q=0.9")
+func AcceptLanguage(tag string) string {
+	base, _, found := strings.Cut(tag, "-")
+	if !found {
+		return tag
+	}
+	return tag + "," + base + ";q=0.9"
+}
+
+// ICULocale converts a language tag to the ICU locale Chrome's locale override
+// takes ("fr-CA" gives "fr_CA")
+func ICULocale(tag string) string {
+	return strings.ReplaceAll(tag, "-", "_")
+}
@@ -25,32 +25,49 @@ func NewRateLimiter(rate float64, capacity int) *RateLimiter {
 	}
 }
 
-// Wait blocks until a token is available
+// Wait blocks until a token is available. Waiters are served in the order
+// they arrive: each reserves the next token up front, letting the bucket go
+// negative, and sleeps until that token has refilled, so callers sharing a
+// limiter get an even share instead of racing for each token.
 func (rl *RateLimiter) Wait(ctx context.Context) error {
-	for {
-		// Try to acquire token
-		if rl.tryAcquire() {
-			return nil
-		}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-		// Check context
-		if ctx.Err() != nil {
-			return ctx.Err()
-		}
+	waitTime := rl.reserve()
+	if waitTime <= 0 {
+		return nil
+	}
 
-		// Calculate wait time and sleep
-		waitTime := rl.calculateWaitTime()
-		if waitTime > 0 {
-			select {
-			case <-time.After(waitTime):
-				// Try again
-			case <-ctx.Done():
-				return ctx.Err()
-			}
-		}
+	timer := time.NewTimer(waitTime)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		rl.cancelReservation()
+		return ctx.Err()
 	}
 }
 
+// Allow takes a token if one is available, without blocking
+func (rl *RateLimiter) Allow() bool {
+	return rl.tryAcquire()
+}
+
+// NextTokenIn returns how long until a token will be available
+func (rl *RateLimiter) NextTokenIn() time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.refill()
+
+	if rl.tokens >= 1.0 {
+		return 0
+	}
+	return time.Duration((1.0 - rl.tokens) / rl.rate * float64(time.Second))
+}
+
 // tryAcquire attempts to acquire a token without blocking
 func (rl *RateLimiter) tryAcquire() bool {
 	rl.mu.Lock()
@@ -83,22 +100,31 @@ func (rl *RateLimiter) refill() {
 	}
 }
 
-// calculateWaitTime calculates how long to wait for next token
-func (rl *RateLimiter) calculateWaitTime() time.Duration {
+// reserve takes the next token, borrowing against future refills when the
+// bucket is empty, and returns how long until the borrowed token exists
+func (rl *RateLimiter) reserve() time.Duration {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
 	rl.refill()
+	rl.tokens -= 1.0
 
-	if rl.tokens >= 1.0 {
+	if rl.tokens >= 0 {
 		return 0
 	}
+	return time.Duration(-rl.tokens / rl.rate * float64(time.Second))
+}
 
-	tokensNeeded := 1.0 - rl.tokens
-	waitSeconds := tokensNeeded / rl.rate
+// cancelReservation returns a token reserved by a Wait that gave up
+func (rl *RateLimiter) cancelReservation() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
 
-	// Add small buffer to avoid tight loops
-	return time.Duration(waitSeconds*1.1) * time.Second
+	rl.refill()
+	rl.tokens += 1.0
+	if rl.tokens > float64(rl.capacity) {
+		rl.tokens = float64(rl.capacity)
+	}
 }
 
 // RateLimiterRegistry manages rate limiters for missions
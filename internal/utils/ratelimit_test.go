@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Agents sharing a mission's limiter each get a roughly even share of it
+func TestRateLimiterWaitIsFair(t *testing.T) {
+	const (
+		waiters = 10
+		rate    = 200
+	)
+	limiter := NewRateLimiter(rate, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	grants := make([]int, waiters)
+	var wg sync.WaitGroup
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for limiter.Wait(ctx) == nil {
+				grants[i]++
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for _, n := range grants {
+		total += n
+	}
+	mean := float64(total) / waiters
+	if mean < 2 {
+		t.Fatalf("only %d grants in total, too few to judge fairness", total)
+	}
+	for i, n := range grants {
+		if float64(n) < mean/2 || float64(n) > mean*2 {
+			t.Errorf("waiter %d got %d grants, mean %.1f; grants %v", i, n, mean, grants)
+		}
+	}
+}
+
+// At 10 tokens a second the 11th token of a full bucket is 100ms away: not
+// truncated to 0 (a busy spin) or rounded up to a whole second
+func TestRateLimiterSubSecondWait(t *testing.T) {
+	limiter := NewRateLimiter(10, 10)
+	for i := 0; i < 10; i++ {
+		if !limiter.Allow() {
+			t.Fatalf("token %d of a full bucket was refused", i+1)
+		}
+	}
+
+	if next := limiter.NextTokenIn(); next < 50*time.Millisecond || next > 100*time.Millisecond {
+		t.Errorf("NextTokenIn() = %v, want about 100ms", next)
+	}
+
+	start := time.Now()
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond || waited > 500*time.Millisecond {
+		t.Errorf("11th token took %v, want about 100ms", waited)
+	}
+}
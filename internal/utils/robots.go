@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// robotsAgentToken is the product token matched against User-agent lines
+	robotsAgentToken = "swarmtest"
+	robotsCacheTTL   = time.Hour
+	robotsTimeout    = 10 * time.Second
+	// maxRobotsSize caps how much of a robots.txt is read
+	maxRobotsSize = 512 * 1024
+)
+
+// ErrDisallowedByRobots is returned for requests robots.txt does not allow
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+// RobotsRules are the Allow/Disallow rules that apply to SwarmTest on one host
+type RobotsRules struct {
+	rules []robotsRule
+}
+
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// Allowed reports whether path (including any query) may be fetched. The
+// longest matching rule wins; on a tie Allow wins.
+func (r *RobotsRules) Allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+
+	best, allowed := -1, true
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > best || (len(rule.pattern) == best && rule.allow) {
+			best, allowed = len(rule.pattern), rule.allow
+		}
+	}
+	return allowed
+}
+
+// robotsMatch matches a path against a robots.txt pattern, where * matches any
+// run of characters and a trailing $ anchors the end of the path
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	parts := strings.Split(strings.TrimSuffix(pattern, "$"), "*")
+
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+
+	last := parts[len(parts)-1]
+	if anchored {
+		return strings.HasSuffix(rest, last)
+	}
+	return strings.Contains(rest, last)
+}
+
+// ParseRobots extracts the rules for SwarmTest from a robots.txt, falling back
+// to the "*" group when no group names SwarmTest
+func ParseRobots(r io.Reader) *RobotsRules {
+	var specific, wildcard []robotsRule
+	var haveSpecific bool
+
+	var agents []string
+	inRules := false // a rule line ends a group's User-agent list
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				agents = nil
+				inRules = false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				// An empty Disallow allows everything
+				continue
+			}
+			rule := robotsRule{pattern: value, allow: key == "allow"}
+			for _, agent := range agents {
+				switch {
+				case agent == "*":
+					wildcard = append(wildcard, rule)
+				case agent == robotsAgentToken || strings.HasPrefix(agent, robotsAgentToken+"/"):
+					specific = append(specific, rule)
+					haveSpecific = true
+				}
+			}
+		}
+	}
+
+	if haveSpecific {
+		return &RobotsRules{rules: specific}
+	}
+	return &RobotsRules{rules: wildcard}
+}
+
+// RobotsCache fetches and caches robots.txt rules per host
+type RobotsCache struct {
+	client *http.Client
+
+	mu      sync.Mutex
+	entries map[string]robotsEntry
+}
+
+type robotsEntry struct {
+	rules     *RobotsRules
+	fetchedAt time.Time
+}
+
+// NewRobotsCache creates an empty robots.txt cache
+func NewRobotsCache() *RobotsCache {
+	return &RobotsCache{
+		client:  &http.Client{Timeout: robotsTimeout},
+		entries: make(map[string]robotsEntry),
+	}
+}
+
+// Allowed reports whether rawURL may be fetched, loading the host's robots.txt
+// on first use
+func (c *RobotsCache) Allowed(ctx context.Context, rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return true
+	}
+	return c.allowedURL(ctx, u)
+}
+
+func (c *RobotsCache) allowedURL(ctx context.Context, u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return true
+	}
+	// robots.txt itself is always fetchable
+	if u.Path == "/robots.txt" {
+		return true
+	}
+	return c.rulesFor(ctx, u).Allowed(u.RequestURI())
+}
+
+// rulesFor returns the cached rules for u's host, fetching them when missing or stale
+func (c *RobotsCache) rulesFor(ctx context.Context, u *url.URL) *RobotsRules {
+	origin := u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	entry, ok := c.entries[origin]
+	c.mu.Unlock()
+	if ok && time.Since(entry.fetchedAt) < robotsCacheTTL {
+		return entry.rules
+	}
+
+	rules := c.fetch(ctx, origin)
+
+	c.mu.Lock()
+	c.entries[origin] = robotsEntry{rules: rules, fetchedAt: time.Now()}
+	c.mu.Unlock()
+	return rules
+}
+
+// fetch downloads and parses origin's robots.txt. A missing or unreachable
+// robots.txt allows everything.
+func (c *RobotsCache) fetch(ctx context.Context, origin string) *RobotsRules {
+	req, err := http.NewRequestWithContext(ctx, "GET", origin+"/robots.txt", nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("User-Agent", "SwarmTest/1.0")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		log.Printf("Failed to fetch robots.txt for %s, allowing all paths: %v", origin, err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	return ParseRobots(io.LimitReader(resp.Body, maxRobotsSize))
+}
+
+// Transport wraps next so that requests robots.txt disallows fail with
+// ErrDisallowedByRobots instead of being sent
+func (c *RobotsCache) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &robotsTransport{next: next, cache: c}
+}
+
+type robotsTransport struct {
+	next  http.RoundTripper
+	cache *RobotsCache
+}
+
+func (t *robotsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.cache.allowedURL(req.Context(), req.URL) {
+		return nil, fmt.Errorf("%w: %s", ErrDisallowedByRobots, req.URL)
+	}
+	return t.next.RoundTrip(req)
+}
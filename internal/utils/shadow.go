@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"golang.org/x/net/html"
+)
+
+// ShadowSeparator joins the parts of a selector for an element inside shadow
+// roots: each part after the first is queried in the shadow root of the
+// element matched by the part before it, e.g. "x-login >>> form button"
+const ShadowSeparator = " >>> "
+
+// domCaptureJS serializes the document with its open shadow roots inlined as
+// declarative <template shadowrootmode> elements, so the parser sees elements
+// inside web components. It returns only the length when that exceeds max
+// (0 is no limit), so an oversized DOM is never transferred. Browsers without
+// Element.getHTML fall back to the plain outerHTML, without shadow roots.
+const domCaptureJS = `(() => {
+	const max = %d;
+	const root = document.documentElement;
+	const shadowRoots = [];
+	const collect = (scope) => {
+		for (const el of scope.querySelectorAll("*")) {
+			if (el.shadowRoot) {
+				shadowRoots.push(el.shadowRoot);
+				collect(el.shadowRoot);
+			}
+		}
+	};
+	if (typeof root.getHTML === "function") collect(document);
+
+	let html = root.outerHTML;
+	if (shadowRoots.length > 0) {
+		const tag = root.cloneNode(false).outerHTML;
+		html = tag.slice(0, tag.lastIndexOf("</")) + root.getHTML({shadowRoots}) + "</html>";
+	}
+	return max > 0 && html.length > max ? html.length : html;
+})()`
+
+// isShadowRoot reports whether n is a shadow root serialized as a declarative
+// <template shadowrootmode>, whose children are its host's shadow tree
+func isShadowRoot(n *html.Node) bool {
+	if n.Type != html.ElementNode || n.Data != "template" {
+		return false
+	}
+	for _, attr := range n.Attr {
+		if attr.Key == "shadowrootmode" {
+			return true
+		}
+	}
+	return false
+}
+
+// byShadowPath returns the query option for sel: the default search for a
+// plain selector, or a lookup through each shadow root for one with
+// ShadowSeparator. Like the default, it waits for the element to appear.
+func byShadowPath(sel string) chromedp.QueryOption {
+	if !strings.Contains(sel, ShadowSeparator) {
+		return chromedp.BySearch
+	}
+	expr := shadowPathJS(sel)
+	return chromedp.ByFunc(func(ctx context.Context, _ *cdp.Node) ([]cdp.NodeID, error) {
+		obj, exp, err := runtime.Evaluate(expr).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if exp != nil {
+			return nil, exp
+		}
+		if obj.ObjectID == "" {
+			return []cdp.NodeID{}, nil // not there (yet)
+		}
+		defer runtime.ReleaseObject(obj.ObjectID).Do(ctx)
+
+		nodeID, err := dom.RequestNode(obj.ObjectID).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if nodeID == cdp.EmptyNodeID {
+			return []cdp.NodeID{}, nil
+		}
+		return []cdp.NodeID{nodeID}, nil
+	})
+}
+
+// shadowPathJS is the JavaScript expression finding the element sel matches,
+// or undefined. The parts are quoted, so a selector can't inject script.
+func shadowPathJS(sel string) string {
+	var b strings.Builder
+	b.WriteString("document")
+	for i, part := range strings.Split(sel, ShadowSeparator) {
+		if i > 0 {
+			b.WriteString("?.shadowRoot")
+		}
+		quoted, _ := json.Marshal(strings.TrimSpace(part))
+		b.WriteString("?.querySelector(")
+		b.Write(quoted)
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+// lightSelector makes a selector with ShadowSeparator usable on a parsed
+// document, where a declarative shadow root's children sit inside its host
+func lightSelector(sel string) string {
+	return strings.ReplaceAll(sel, ShadowSeparator, " ")
+}
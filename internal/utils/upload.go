@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UploadFixturesDir is the only directory upload actions may read files from.
+// When empty, uploads use a generated placeholder file.
+var UploadFixturesDir string
+
+// generatedUploadContent fills the placeholder file used when no fixture is named
+const generatedUploadContent = "SwarmTest upload test file\n"
+
+// ResolveUploadFile maps an upload action's text_input to a file on disk. A
+// name is looked up inside UploadFixturesDir and must not escape it; an empty
+// name (or no fixtures directory) gets a generated temp file. cleanup removes
+// any generated file and must always be called.
+func ResolveUploadFile(name string) (path string, cleanup func(), err error) {
+	cleanup = func() {}
+	if name == "" || UploadFixturesDir == "" {
+		if name != "" {
+			return "", cleanup, fmt.Errorf("upload fixtures are not configured on this server")
+		}
+		return generateUploadFile()
+	}
+
+	root, err := filepath.EvalSymlinks(UploadFixturesDir)
+	if err != nil {
+		return "", cleanup, fmt.Errorf("upload fixtures directory unavailable: %w", err)
+	}
+
+	// Resolve symlinks too, so a link inside the directory can't point outside it
+	resolved, err := filepath.EvalSymlinks(filepath.Join(root, filepath.Clean("/"+name)))
+	if err != nil {
+		return "", cleanup, fmt.Errorf("upload fixture not found: %s", name)
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", cleanup, fmt.Errorf("upload fixture outside fixtures directory: %s", name)
+	}
+
+	info, err := os.Stat(resolved)
+	if err != nil || !info.Mode().IsRegular() {
+		return "", cleanup, fmt.Errorf("upload fixture is not a file: %s", name)
+	}
+	return resolved, cleanup, nil
+}
+
+// generateUploadFile writes a small placeholder file to the temp directory
+func generateUploadFile() (string, func(), error) {
+	f, err := os.CreateTemp("", "swarmtest-upload-*.txt")
+	if err != nil {
+		return "", func() {}, fmt.Errorf("create upload file: %w", err)
+	}
+	defer f.Close()
+
+	cleanup := func() { os.Remove(f.Name()) }
+	if _, err := f.WriteString(generatedUploadContent); err != nil {
+		cleanup()
+		return "", func() {}, fmt.Errorf("write upload file: %w", err)
+	}
+	return f.Name(), cleanup, nil
+}
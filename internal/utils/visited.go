@@ -0,0 +1,53 @@
+package utils
+
+import "sync"
+
+// MaxSharedVisitedURLs caps the URLs one mission's shared visited set holds
+const MaxSharedVisitedURLs = 10000
+
+// VisitedSet records which agent first reached each URL of a mission, so
+// agents can steer away from pages another agent already covered
+type VisitedSet struct {
+	mu        sync.Mutex
+	firstBy   map[string]string // URL -> agent that first visited it
+	order     []string
+	truncated bool
+}
+
+// NewVisitedSet creates an empty visited set
+func NewVisitedSet() *VisitedSet {
+	return &VisitedSet{firstBy: make(map[string]string)}
+}
+
+// Visit records that agentID reached url. It reports whether another agent
+// got there first. Once the set is full new URLs are no longer recorded.
+func (s *VisitedSet) Visit(url, agentID string) (visitedByOther bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if first, ok := s.firstBy[url]; ok {
+		return first != agentID
+	}
+	if len(s.order) >= MaxSharedVisitedURLs {
+		s.truncated = true
+		return false
+	}
+	s.firstBy[url] = agentID
+	s.order = append(s.order, url)
+	return false
+}
+
+// VisitedByOther reports whether an agent other than agentID has visited url
+func (s *VisitedSet) VisitedByOther(url, agentID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	first, ok := s.firstBy[url]
+	return ok && first != agentID
+}
+
+// URLs returns the visited URLs in the order they were first reached, and
+// whether the set filled up
+func (s *VisitedSet) URLs() ([]string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.order...), s.truncated
+}